@@ -1,11 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"go.bug.st/serial"
@@ -22,28 +30,116 @@ var (
 func main() {
 	var config gps.Config
 	var showVersion bool
+	var constellations string
+	var sentences string
+	var csvColumns string
+	var jsonOut string
+	var sentenceRates string
+	var sentenceIntervals string
+	var waypoints string
+	var replay string
+	var generate bool
+	var metricsAddr string
+	var script string
+	var configFile string
+	var dumpConfig bool
 
 	// Define command line flags
 	flag.BoolVar(&showVersion, "version", false, "Show version information and exit")
+	flag.StringVar(&configFile, "config", "", "YAML or JSON file of Config settings to start from (e.g., setup.yaml), keyed by Config field name with durations such as 30s and string lists as lists or comma-separated strings; flags given explicitly override it")
+	flag.BoolVar(&dumpConfig, "dump-config", false, "Print the effective configuration as YAML, for use with -config, and exit")
 	flag.Float64Var(&config.Latitude, "lat", 37.7749, "Initial latitude (decimal degrees)")
 	flag.Float64Var(&config.Longitude, "lon", -122.4194, "Initial longitude (decimal degrees)")
 	flag.Float64Var(&config.Radius, "radius", 100.0, "Wandering radius in meters")
 	flag.Float64Var(&config.Altitude, "altitude", 45.0, "Starting altitude in meters")
 	flag.Float64Var(&config.Jitter, "jitter", 0.0, "GPS position jitter factor (0.0=stable, 1.0=high jitter)")
 	flag.Float64Var(&config.AltitudeJitter, "altitude-jitter", 0.0, "Altitude jitter factor (0.0=stable, 1.0=high variation)")
+	flag.Float64Var(&config.ClimbRate, "climb-rate", 0.0, "Meters per second to climb, negative to descend (0 = altitude only jitters)")
+	flag.Float64Var(&config.TargetAltitude, "target-altitude", 0.0, "Altitude in meters to level off at when climbing or descending toward it")
+	flag.Float64Var(&config.GeoidSeparation, "geoid-sep", 0.0, "Geoid height above the WGS84 ellipsoid in meters reported in GGA")
+	flag.BoolVar(&config.AutoGeoidSeparation, "auto-geoid", false, "Approximate the GGA geoid separation from the current position (overrides -geoid-sep)")
+	flag.IntVar(&config.FixQuality, "fix-quality", 0, "GGA fix quality while locked (1=GPS, 2=DGPS, 4=RTK fixed, 5=RTK float, 6=estimated). Default is 1")
+	flag.Float64Var(&config.DGPSAge, "dgps-age", gps.DefaultDGPSAge, "Age of differential corrections in seconds reported in GGA for DGPS and RTK fix qualities")
+	flag.IntVar(&config.DGPSStationID, "dgps-station", 0, "Differential reference station ID (0-1023) reported in GGA for DGPS and RTK fix qualities")
+	flag.Float64Var(&config.BiasMeters, "bias-meters", 0.0, "Constant position error in meters applied to NMEA output, as from a datum offset (0 = none)")
+	flag.Float64Var(&config.BiasBearing, "bias-bearing", 0.0, "Direction of -bias-meters in degrees from true north")
+	flag.Float64Var(&config.DOPJitter, "dop-jitter", 0.0, "DOP variation factor (0.0=geometry only, 1.0=up to ±50%)")
 	flag.Float64Var(&config.Speed, "speed", 0.0, "Static speed in knots")
 	flag.Float64Var(&config.Course, "course", 0.0, "Static course in degrees (0-359)")
+	flag.Float64Var(&config.MinSpeedForCourse, "min-course-speed", 0.5, "Speed in knots below which course is held and left empty in RMC and VTG (0 = always report course)")
+	flag.Float64Var(&config.MagneticDeclination, "declination", 0.0, "Magnetic declination in degrees, positive east, reported in RMC and VTG")
+	flag.BoolVar(&config.EmitMagneticVariation, "magvar", false, "Populate RMC/VTG magnetic variation fields even when -declination is 0")
 	flag.IntVar(&config.Satellites, "satellites", 8, "Number of satellites to simulate (4-12)")
+	flag.StringVar(&config.SkyCondition, "sky", "", "Sky condition presetting satellite elevation and SNR ranges (open, urban, indoor)")
+	flag.Var(optionalInt{&config.MinElevation}, "min-elevation", "Lowest satellite elevation in degrees. Default from -sky, or 5")
+	flag.Var(optionalInt{&config.MaxElevation}, "max-elevation", "Highest satellite elevation in degrees. Default from -sky, or 85")
+	flag.Var(optionalInt{&config.MinSNR}, "min-snr", "Weakest satellite SNR in dB. Default from -sky, or 15")
+	flag.Var(optionalInt{&config.MaxSNR}, "max-snr", "Strongest satellite SNR in dB. Default from -sky, or 55")
+	flag.BoolVar(&config.SatelliteChurn, "satellite-churn", false, "Satellites rise and set over time, keeping the count within 2 of -satellites")
 	flag.DurationVar(&config.TimeToLock, "lock-time", 2*time.Second, "Time to GPS lock simulation")
+	flag.StringVar(&config.StartMode, "start", "cold", "Receiver start mode scaling -lock-time (cold, warm=50%, hot=10%)")
+	flag.StringVar(&config.AcquisitionProfile, "acquisition", "progressive", "How satellites are acquired before lock (progressive, instant, gradual)")
+	flag.StringVar(&config.RouteMode, "route-mode", gps.RouteModeWander, "How the receiver moves around the center (wander, or roadlike for smooth turns and gradual speed changes)")
+	flag.StringVar(&config.Pattern, "pattern", gps.PatternWander, "Movement pattern around the center at -speed (wander, circle of -radius, figure-eight, grid survey, or random-walk)")
+	flag.Float64Var(&config.GridSpacing, "grid-spacing", 0, "Meters between grid pattern survey lines. Default is a fifth of -radius")
+	flag.Float64Var(&config.MaxTurnRate, "max-turn-rate", gps.DefaultMaxTurnRate, "Road-like turn rate limit in degrees per second")
 	flag.DurationVar(&config.OutputRate, "rate", 1*time.Second, "NMEA output rate")
+	flag.DurationVar(&config.UpdateRate, "update-rate", 0, "Position integration interval between output cycles (e.g., 20ms). Default integrates once per output")
+	flag.IntVar(&config.TimePrecision, "time-precision", 0, "Decimal places of seconds in every sentence time field (0-3). 0 is whole seconds, or hundredths when -rate is under 1s")
 	flag.StringVar(&config.SerialPort, "serial", "", "Serial port for NMEA output (e.g., /dev/ttyUSB0, COM1)")
 	flag.IntVar(&config.BaudRate, "baud", 9600, "Serial port baud rate")
+	flag.BoolVar(&config.PaceOutput, "pace", false, "Spread each burst across the output interval at -baud transmission speed instead of writing it at once")
 	flag.BoolVar(&config.Quiet, "quiet", false, "Suppress info messages (only output NMEA data)")
 	flag.BoolVar(&config.GPXEnabled, "gpx", false, "Generate GPX track file with timestamp-based filename")
+	flag.BoolVar(&config.GPXExtensions, "gpx-extensions", false, "Record speed, course, satellites and HDOP in GPX track points")
+	flag.BoolVar(&config.GeoJSONEnabled, "geojson", false, "Generate GeoJSON track file with timestamp-based filename")
+	flag.BoolVar(&config.GeoJSONPoints, "geojson-points", false, "Write a Point feature per GeoJSON track point instead of coordinateProperties arrays")
+	flag.BoolVar(&config.KMLEnabled, "kml", false, "Generate KML track file with timestamp-based filename for Google Earth")
+	flag.StringVar(&config.KMLName, "kml-name", "", "Name of the KML document and track (default \"GPS Simulator Track\")")
+	flag.StringVar(&config.CSVFile, "csv", "", "Record each output cycle as a row in this CSV file (e.g., out.csv)")
+	flag.StringVar(&csvColumns, "csv-columns", "", "Comma-separated CSV columns to write, in order (timestamp, lat, lon, alt, speed_knots, course, satellites, hdop, locked). Default is all")
 	flag.DurationVar(&config.Duration, "duration", 0, "How long to run the simulation (e.g., 30s, 5m, 1h). Default is indefinite")
-	flag.StringVar(&config.ReplayFile, "replay", "", "GPX file to replay instead of simulating (e.g., track.gpx)")
+	flag.StringVar(&replay, "replay", "", "GPX or KML file to replay instead of simulating (e.g., track.gpx, route.kml), or a comma-separated playlist of files and globs replayed back to back (e.g., \"legs/*.gpx\")")
+	flag.StringVar(&config.ReplayGapBehavior, "replay-gap", gps.ReplayGapJump, "How a replay playlist moves between files (jump, or simulate to drive there at -speed)")
 	flag.Float64Var(&config.ReplaySpeed, "replay-speed", 1.0, "Replay speed multiplier (1.0=real-time, 2.0=2x speed, 0.5=half speed)")
 	flag.BoolVar(&config.ReplayLoop, "replay-loop", false, "Loop the GPX replay continuously (default: stop after one pass)")
+	flag.BoolVar(&config.ReplayInterpolate, "replay-interpolate", true, "Interpolate between GPX replay points instead of snapping to them (-replay-interpolate=false snaps)")
+	flag.BoolVar(&config.ReplayReverse, "replay-reverse", false, "Replay the GPX track backwards from the last point to the first")
+	flag.BoolVar(&config.ReplaySegmentGaps, "replay-segment-gaps", false, "Hold position through time gaps between GPX track segments instead of collapsing them")
+	flag.BoolVar(&config.ReplayHonorSegments, "replay-honor-segments", false, "Report no fix briefly at each GPX track segment boundary, as when the recording lost signal")
+	flag.BoolVar(&config.ReplayUseOriginalTime, "replay-original-time", false, "Timestamp output with the replayed track's recorded times instead of the current time")
+	flag.StringVar(&config.ReplayNMEAFile, "replay-nmea", "", "Recorded NMEA log to re-emit verbatim, paced by its RMC/GGA/ZDA timestamps (honors -replay-speed and -replay-loop)")
+	flag.BoolVar(&config.ReplayNMEAFixedRate, "replay-nmea-fixed-rate", false, "Re-emit one -replay-nmea epoch per -rate output cycle instead of pacing by the log's timestamps")
+	flag.BoolVar(&config.NMEAStrict, "nmea-strict", false, "Drop -replay-nmea sentences with a missing or bad checksum instead of passing them through unchanged")
+	flag.BoolVar(&config.ReplayRewriteTime, "replay-rewrite-time", false, "Rewrite the time and date fields of -replay-nmea sentences to the current time")
+	flag.StringVar(&config.TalkerID, "talker", "", "NMEA talker ID prefix override (e.g., GP, GL, GA, GB, GN). Default is GP, or GN with multiple constellations")
+	flag.StringVar(&config.RouteFile, "route", "", "GPX file with route waypoints to follow at -speed (e.g., route.gpx)")
+	flag.BoolVar(&config.RouteLoop, "route-loop", false, "Loop back to the first waypoint after reaching the last (default: stop)")
+	flag.StringVar(&waypoints, "waypoints", "", "Semicolon-separated lat,lon waypoints to navigate between at -speed (e.g., \"37.7749,-122.4194;37.8080,-122.4177\")")
+	flag.BoolVar(&config.WaypointLoop, "waypoint-loop", false, "Loop back to the first of -waypoints after reaching the last (default: stop)")
+	flag.StringVar(&config.NMEAVersion, "nmea-version", gps.NMEAVersion23, "NMEA output version (2.3, or 4.1 adding GNS and GSA/GSV system and signal IDs)")
+	flag.StringVar(&constellations, "constellations", "", "Comma-separated constellations to simulate (GPS, GLONASS, Galileo, BeiDou). Default is GPS only")
+	flag.StringVar(&sentences, "sentences", "", "Comma-separated NMEA sentences to emit (GGA, RMC, GLL, VTG, GSA, GSV, ZDA, GST, HDT, ROT, GRS, GBS). Default is all but HDT, ROT, GRS and GBS")
+	flag.BoolVar(&config.EmitIntegritySentences, "integrity", false, "Emit GRS range residuals and GBS satellite fault detection sentences for RAIM-aware consumers")
+	flag.StringVar(&sentenceRates, "sentence-rates", "", "Per-sentence output rate divisors (e.g., GSV=5,GSA=5 emits GSV and GSA every 5th cycle). Default is every cycle")
+	flag.StringVar(&sentenceIntervals, "sentence-intervals", "", "Per-sentence output intervals (e.g., GSV=5s,GSA=5s emits GSV and GSA every 5 seconds), overriding -sentence-rates")
+	flag.StringVar(&config.TCPListen, "tcp", "", "TCP address to stream output to connected clients (e.g., :10110)")
+	flag.StringVar(&config.UDPTarget, "udp", "", "UDP host:port to send each sentence to (e.g., 255.255.255.255:10110 for broadcast)")
+	flag.BoolVar(&config.GpsdMode, "gpsd", false, "Output gpsd-compatible JSON (TPV/SKY) reports instead of NMEA sentences")
+	flag.StringVar(&config.GpsdListen, "gpsd-listen", "", "TCP address to serve gpsd JSON (TPV/SKY) reports on alongside NMEA output (e.g., :2947)")
+	flag.StringVar(&config.OutputFormat, "output-format", "", "What each output cycle writes: nmea (default), json (one JSON fix object per line instead of NMEA) or both (NMEA, with JSON lines written to -json-out)")
+	flag.StringVar(&jsonOut, "json-out", "", "File to write JSON fix lines to alongside NMEA (implies -output-format both)")
+	flag.Float64Var(&config.TimeScale, "time-scale", 1.0, "Simulated time multiplier (e.g., 60 emits an hour of data per minute)")
+	flag.DurationVar(&config.TimeOffset, "time-offset", 0, "Offset added to every emitted timestamp (e.g., -18s for the GPS-UTC leap second difference)")
+	flag.StringVar(&config.SimulatedDate, "simulated-date", "", "RFC3339 date and time output starts at, advancing with simulated time (e.g., 2024-12-31T23:59:58Z)")
+	flag.DurationVar(&config.DropoutInterval, "dropout-interval", 0, "Time locked before the GPS signal is lost again (e.g., 5m). Default is never")
+	flag.DurationVar(&config.DropoutDuration, "dropout-duration", 10*time.Second, "How long the fix stays lost during a dropout before re-acquisition starts")
+	flag.BoolVar(&generate, "generate", false, "Generate -duration of output in virtual time as fast as possible instead of in real time")
+	flag.StringVar(&metricsAddr, "metrics", "", "HTTP address to serve Prometheus metrics on at /metrics and JSON metrics at /api/metrics, and the GPX and GeoJSON tracks at /api/gpx and /api/geojson (e.g., :9100)")
+	flag.StringVar(&script, "script", "", "JSON file of timed speed, course, jitter and satellite changes to apply during the run")
+	flag.StringVar(&config.RecordScenario, "record-scenario", "", "JSON file to record the session to (config, seed and live config changes) for playback with -scenario")
+	flag.StringVar(&config.ScenarioFile, "scenario", "", "Recorded scenario to play back; its settings replace all but the output flags")
+	flag.Int64Var(&config.Seed, "seed", 0, "Random seed for reproducible satellites, jitter and motion. Default (0) seeds from the current time")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
@@ -65,37 +161,116 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Validate input parameters
-	if config.Satellites < 4 || config.Satellites > 12 {
-		log.Fatal("Number of satellites must be between 4 and 12")
+	if configFile != "" {
+		unknown, err := applyConfigFile(flag.CommandLine, &config, configFile)
+		if err != nil {
+			log.Fatalf("Failed to load config file: %v", err)
+		}
+		if len(unknown) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring unknown keys in %s: %s\n", configFile, strings.Join(unknown, ", "))
+		}
 	}
 
-	if config.Radius < 0 {
-		log.Fatal("Radius must be positive")
+	if constellations != "" {
+		config.Constellations = strings.Split(constellations, ",")
 	}
 
-	if config.Jitter < 0.0 || config.Jitter > 1.0 {
-		log.Fatal("Jitter must be between 0.0 and 1.0")
+	if sentences != "" {
+		config.Sentences = strings.Split(sentences, ",")
 	}
 
-	if config.AltitudeJitter < 0.0 || config.AltitudeJitter > 1.0 {
-		log.Fatal("Altitude jitter must be between 0.0 and 1.0")
+	if csvColumns != "" {
+		config.CSVColumns = strings.Split(csvColumns, ",")
+		for i, column := range config.CSVColumns {
+			config.CSVColumns[i] = strings.TrimSpace(column)
+		}
 	}
 
-	if config.BaudRate <= 0 {
-		log.Fatal("Baud rate must be positive")
+	if jsonOut != "" {
+		if config.OutputFormat == "" {
+			config.OutputFormat = gps.OutputFormatBoth
+		}
+		if config.OutputFormat != gps.OutputFormatBoth {
+			log.Fatal("-json-out can only be used with -output-format both")
+		}
+		file, err := os.Create(jsonOut)
+		if err != nil {
+			log.Fatalf("Failed to create JSON output file: %v", err)
+		}
+		defer file.Close()
+		config.JSONWriter = file
 	}
 
-	if config.Speed < 0.0 {
-		log.Fatal("Speed must be non-negative")
+	if waypoints != "" {
+		parsed, err := gps.ParseWaypoints(waypoints)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Waypoints = parsed
 	}
 
-	if config.Course < 0.0 || config.Course >= 360.0 {
-		log.Fatal("Course must be between 0.0 and 359.9 degrees")
+	if replay != "" {
+		files, err := gps.ParseReplayFiles(replay)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(files) == 1 {
+			config.ReplayFile = files[0]
+		} else {
+			config.ReplayFiles = files
+		}
 	}
 
-	if config.ReplaySpeed <= 0.0 {
-		log.Fatal("Replay speed must be positive")
+	if sentenceRates != "" {
+		rates, err := gps.ParseSentenceRates(sentenceRates)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.SentenceRates = rates
+	}
+
+	if sentenceIntervals != "" {
+		intervals, err := gps.ParseSentenceIntervals(sentenceIntervals)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.SentenceIntervals = intervals
+	}
+
+	if script != "" {
+		steps, err := gps.LoadScript(script)
+		if err != nil {
+			log.Fatalf("Failed to load script: %v", err)
+		}
+		config.Script = steps
+	}
+
+	// A played back scenario brings its own settings
+	if config.ScenarioFile != "" {
+		scenario, err := gps.LoadScenario(config.ScenarioFile)
+		if err != nil {
+			log.Fatalf("Failed to load scenario: %v", err)
+		}
+		config = scenario.PlaybackConfig(config)
+	}
+
+	// Validate input parameters
+	if err := config.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if dumpConfig {
+		data, err := gps.MarshalConfigYAML(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(data)
+		os.Exit(0)
+	}
+
+	// Offline generation needs to know how much simulated time to produce
+	if generate && config.Duration <= 0 {
+		log.Fatal("Duration greater than 0 must be specified when using -generate flag (e.g., -duration 8h)")
 	}
 
 	// Handle GPX filename generation and validation
@@ -108,6 +283,22 @@ func main() {
 		config.GPXFile = fmt.Sprintf("%s.gpx", time.Now().Format("20060102_150405"))
 	}
 
+	// Handle GeoJSON filename generation and validation the same way
+	if config.GeoJSONEnabled {
+		if config.Duration <= 0 {
+			log.Fatal("Duration greater than 0 must be specified when using -geojson flag (e.g., -duration 30s)")
+		}
+		config.GeoJSONFile = fmt.Sprintf("%s.geojson", time.Now().Format("20060102_150405"))
+	}
+
+	// Handle KML filename generation and validation the same way
+	if config.KMLEnabled {
+		if config.Duration <= 0 {
+			log.Fatal("Duration greater than 0 must be specified when using -kml flag (e.g., -duration 30s)")
+		}
+		config.KMLFile = fmt.Sprintf("%s.kml", time.Now().Format("20060102_150405"))
+	}
+
 	// Setup output writer (serial port or stdout)
 	var nmeaWriter io.Writer = os.Stdout
 	var serialPort serial.Port
@@ -135,9 +326,33 @@ func main() {
 
 	// Log to stderr so it doesn't interfere with NMEA output
 	if !config.Quiet {
-		if config.ReplayFile != "" {
+		if config.ScenarioFile != "" {
+			fmt.Fprintf(os.Stderr, "Playing back scenario from: %s\n", config.ScenarioFile)
+		} else if config.ReplayFile != "" {
 			fmt.Fprintf(os.Stderr, "Starting GPS replay from: %s\n", config.ReplayFile)
 			fmt.Fprintf(os.Stderr, "Replay speed: %.1fx\n", config.ReplaySpeed)
+			if !strings.EqualFold(filepath.Ext(config.ReplayFile), ".kml") {
+				if _, summary, err := gps.ReadGPXFileSummary(config.ReplayFile); err == nil {
+					fmt.Fprintf(os.Stderr, "Loaded %s\n", summary)
+				}
+			}
+		} else if len(config.ReplayFiles) > 0 {
+			fmt.Fprintf(os.Stderr, "Starting GPS replay of %d files: %s\n", len(config.ReplayFiles), strings.Join(config.ReplayFiles, ", "))
+			fmt.Fprintf(os.Stderr, "Replay speed: %.1fx\n", config.ReplaySpeed)
+		} else if config.ReplayNMEAFile != "" {
+			fmt.Fprintf(os.Stderr, "Starting NMEA replay from: %s\n", config.ReplayNMEAFile)
+			fmt.Fprintf(os.Stderr, "Replay speed: %.1fx\n", config.ReplaySpeed)
+			if summary, err := gps.ReadNMEAFileSummary(config.ReplayNMEAFile, config.NMEAStrict); err == nil {
+				fmt.Fprintf(os.Stderr, "Loaded %s\n", summary)
+			}
+		} else if config.RouteFile != "" {
+			fmt.Fprintf(os.Stderr, "Starting GPS route following from: %s\n", config.RouteFile)
+			fmt.Fprintf(os.Stderr, "GPS jitter: %.1f (%.0f%% jitter)\n", config.Jitter, config.Jitter*100)
+			fmt.Fprintf(os.Stderr, "Speed: %.1f knots\n", config.Speed)
+		} else if len(config.Waypoints) > 0 {
+			fmt.Fprintf(os.Stderr, "Starting GPS waypoint navigation through %d waypoints\n", len(config.Waypoints))
+			fmt.Fprintf(os.Stderr, "GPS jitter: %.1f (%.0f%% jitter)\n", config.Jitter, config.Jitter*100)
+			fmt.Fprintf(os.Stderr, "Speed: %.1f knots\n", config.Speed)
 		} else {
 			fmt.Fprintf(os.Stderr, "Starting GPS simulator...\n")
 			fmt.Fprintf(os.Stderr, "Initial position: %.6f, %.6f, %.1fm\n", config.Latitude, config.Longitude, config.Altitude)
@@ -148,13 +363,52 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Course: %.1f degrees\n", config.Course)
 		}
 		fmt.Fprintf(os.Stderr, "Satellites: %d\n", config.Satellites)
-		fmt.Fprintf(os.Stderr, "Time to lock: %v\n", config.TimeToLock)
+		if config.SkyCondition != "" {
+			fmt.Fprintf(os.Stderr, "Sky condition: %s\n", config.SkyCondition)
+		}
+		if len(config.Constellations) > 0 {
+			fmt.Fprintf(os.Stderr, "Constellations: %s\n", strings.Join(config.Constellations, ", "))
+		}
+		fmt.Fprintf(os.Stderr, "Time to lock: %v (%s start)\n", config.TimeToLock, config.StartMode)
+		if config.DropoutInterval > 0 {
+			fmt.Fprintf(os.Stderr, "Signal dropouts: %v without fix every %v\n", config.DropoutDuration, config.DropoutInterval)
+		}
 		fmt.Fprintf(os.Stderr, "Output rate: %v\n", config.OutputRate)
+		if config.TimeScale != 1.0 {
+			fmt.Fprintf(os.Stderr, "Time scale: %.1fx\n", config.TimeScale)
+		}
+		if config.SimulatedDate != "" {
+			fmt.Fprintf(os.Stderr, "Simulated date: %s\n", config.SimulatedDate)
+		}
+		if config.TimeOffset != 0 {
+			fmt.Fprintf(os.Stderr, "Time offset: %v\n", config.TimeOffset)
+		}
 		if config.SerialPort != "" {
 			fmt.Fprintf(os.Stderr, "NMEA output: %s (%d baud)\n", config.SerialPort, config.BaudRate)
 		} else {
 			fmt.Fprintf(os.Stderr, "NMEA output: stdout\n")
 		}
+		if config.PaceOutput {
+			fmt.Fprintf(os.Stderr, "Output pacing: %d baud\n", config.BaudRate)
+		}
+		if config.TCPListen != "" {
+			fmt.Fprintf(os.Stderr, "TCP output: %s\n", config.TCPListen)
+		}
+		if config.UDPTarget != "" {
+			fmt.Fprintf(os.Stderr, "UDP output: %s\n", config.UDPTarget)
+		}
+		if config.GpsdMode {
+			fmt.Fprintf(os.Stderr, "Output format: gpsd JSON\n")
+		}
+		if config.GpsdListen != "" {
+			fmt.Fprintf(os.Stderr, "gpsd server: %s\n", config.GpsdListen)
+		}
+		switch config.OutputFormat {
+		case gps.OutputFormatJSON:
+			fmt.Fprintf(os.Stderr, "Output format: JSON fixes\n")
+		case gps.OutputFormatBoth:
+			fmt.Fprintf(os.Stderr, "JSON output: %s\n", jsonOut)
+		}
 		fmt.Fprintf(os.Stderr, "\nPress Ctrl+C to stop\n\n")
 	}
 
@@ -168,6 +422,126 @@ func main() {
 	if config.GPXEnabled && !config.Quiet {
 		fmt.Fprintf(os.Stderr, "GPX output: %s\n", config.GPXFile)
 	}
+	if config.GeoJSONEnabled && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "GeoJSON output: %s\n", config.GeoJSONFile)
+	}
+	if config.KMLEnabled && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "KML output: %s\n", config.KMLFile)
+	}
+	if config.CSVFile != "" && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "CSV output: %s\n", config.CSVFile)
+	}
+	if config.RecordScenario != "" && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "Recording scenario to: %s\n", config.RecordScenario)
+	}
+
+	if generate {
+		count, err := simulator.GenerateTo(nmeaWriter, config.Duration)
+		if err != nil {
+			log.Fatalf("Failed to generate output: %v", err)
+		}
+		if !config.Quiet {
+			fmt.Fprintf(os.Stderr, "Generated %d sentences\n", count)
+		}
+		return
+	}
+
+	var metricsServer *http.Server
+	if metricsAddr != "" {
+		// Listen before running so a bad address fails immediately
+		listener, err := net.Listen("tcp", metricsAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen for metrics on %s: %v", metricsAddr, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", simulator.MetricsHandler())
+		mux.Handle("/api/metrics", simulator.MetricsJSONHandler())
+		mux.Handle("/api/gpx", simulator.GPXHandler())
+		mux.Handle("/api/gpx/info", simulator.GPXInfoHandler())
+		mux.Handle("/api/geojson", simulator.GeoJSONHandler())
+		metricsServer = &http.Server{Handler: mux}
+		go func() {
+			if err := metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+		if !config.Quiet {
+			fmt.Fprintf(os.Stderr, "Metrics: http://%s/metrics\n", listener.Addr())
+		}
+	}
+
+	// Stop cleanly on Ctrl+C or SIGTERM so the outputs are closed and the GPX
+	// file is completed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go stopOnSignal(simulator, signals, config.Quiet)
 
 	simulator.Run()
+
+	if metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			log.Printf("Failed to shut down metrics server: %v", err)
+		}
+	}
+}
+
+// stopOnSignal stops the simulator when the first signal arrives, which
+// makes Run close the outputs and return
+func stopOnSignal(simulator *gps.GPSSimulator, signals <-chan os.Signal, quiet bool) {
+	sig, ok := <-signals
+	if !ok {
+		return
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "\nReceived %v, stopping\n", sig)
+	}
+	simulator.Stop()
+}
+
+// applyConfigFile loads filename over config, keeping the values of flags
+// set explicitly in set, which must be bound to config's fields: explicit
+// flags win over the file, which wins over the flag defaults. It returns
+// the file's unknown keys.
+func applyConfigFile(set *flag.FlagSet, config *gps.Config, filename string) ([]string, error) {
+	explicit := make(map[string]string)
+	set.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	loaded, unknown, err := gps.LoadConfigFile(filename, *config)
+	if err != nil {
+		return nil, err
+	}
+	*config = loaded
+
+	for name, value := range explicit {
+		if err := set.Set(name, value); err != nil {
+			return nil, fmt.Errorf("failed to reapply -%s: %v", name, err)
+		}
+	}
+	return unknown, nil
+}
+
+// optionalInt is a flag setting an optional Config integer, which stays nil
+// unless the flag is given so that an explicit 0 can be told from the default
+type optionalInt struct {
+	value **int
+}
+
+func (o optionalInt) String() string {
+	if o.value == nil || *o.value == nil {
+		return ""
+	}
+	return strconv.Itoa(**o.value)
+}
+
+func (o optionalInt) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*o.value = &v
+	return nil
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -485,3 +488,98 @@ func TestQuietModeIntegration(t *testing.T) {
 		t.Error("Simulator should preserve non-quiet mode setting")
 	}
 }
+
+func TestStopOnSignal(t *testing.T) {
+	gpxFile := filepath.Join(t.TempDir(), "signal.gpx")
+	config := gps.Config{
+		Latitude:    37.7749,
+		Longitude:   -122.4194,
+		Radius:      100.0,
+		Satellites:  8,
+		TimeToLock:  0,
+		OutputRate:  10 * time.Millisecond,
+		BaudRate:    9600,
+		ReplaySpeed: 1.0,
+		Quiet:       true,
+		GPXEnabled:  true,
+		GPXFile:     gpxFile,
+	}
+
+	simulator, err := gps.NewGPSSimulator(config, io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	go stopOnSignal(simulator, signals, true)
+
+	done := make(chan struct{})
+	go func() {
+		simulator.Run()
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	signals <- syscall.SIGTERM
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+
+	if reason := simulator.CompletionReason(); reason != gps.CompletionStopped {
+		t.Errorf("Expected completion reason %q, got %q", gps.CompletionStopped, reason)
+	}
+	content, err := os.ReadFile(gpxFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	if !strings.Contains(string(content), "</gpx>") {
+		t.Error("Expected the GPX file to be completed after stopping")
+	}
+}
+
+func TestApplyConfigFilePrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setup.yaml")
+	content := "Latitude: 51.5\nSpeed: 12\nOutputRate: 250ms\nSatellites: 10\nUnknownKey: 1\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	var config gps.Config
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.Float64Var(&config.Latitude, "lat", 37.7749, "")
+	set.Float64Var(&config.Speed, "speed", 0.0, "")
+	set.Float64Var(&config.Radius, "radius", 100.0, "")
+	set.DurationVar(&config.OutputRate, "rate", time.Second, "")
+	set.IntVar(&config.Satellites, "satellites", 8, "")
+	if err := set.Parse([]string{"-speed", "3", "-rate", "2s"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	unknown, err := applyConfigFile(set, &config, path)
+	if err != nil {
+		t.Fatalf("applyConfigFile failed: %v", err)
+	}
+
+	// Explicit flags win over the file
+	if config.Speed != 3 || config.OutputRate != 2*time.Second {
+		t.Errorf("Expected explicit -speed 3 and -rate 2s kept, got %.1f and %v", config.Speed, config.OutputRate)
+	}
+	// The file wins over flag defaults
+	if config.Latitude != 51.5 || config.Satellites != 10 {
+		t.Errorf("Expected latitude and satellites from the file, got %.4f and %d", config.Latitude, config.Satellites)
+	}
+	// Defaults remain for settings in neither
+	if config.Radius != 100.0 {
+		t.Errorf("Expected default radius kept, got %.1f", config.Radius)
+	}
+	if len(unknown) != 1 || unknown[0] != "UnknownKey" {
+		t.Errorf("Expected UnknownKey reported, got %v", unknown)
+	}
+
+	if _, err := applyConfigFile(set, &config, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing config file")
+	}
+}
@@ -2,7 +2,10 @@ module github.com/Bucknalla/go-gps-simulator
 
 go 1.23
 
-require go.bug.st/serial v1.6.4
+require (
+	go.bug.st/serial v1.6.4
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/creack/goselect v0.1.2 // indirect
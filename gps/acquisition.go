@@ -0,0 +1,166 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Receiver start modes, from no stored almanac or ephemeris (cold) to a
+// recent fix with current ephemeris (hot)
+const (
+	StartCold = "cold"
+	StartWarm = "warm"
+	StartHot  = "hot"
+)
+
+// startModeScale is the fraction of TimeToLock each start mode takes to acquire a fix
+var startModeScale = map[string]float64{
+	StartCold: 1.0,
+	StartWarm: 0.5,
+	StartHot:  0.1,
+}
+
+// Acquisition profiles describing how satellites are acquired before lock
+const (
+	AcquisitionProgressive = "progressive" // First three satellites acquired one by one, the rest at lock
+	AcquisitionInstant     = "instant"     // No satellites until the fix is locked
+	AcquisitionGradual     = "gradual"     // Every satellite acquired one by one, with an estimated fix from four
+)
+
+// AcquisitionProfiles lists the supported acquisition profiles
+var AcquisitionProfiles = []string{AcquisitionProgressive, AcquisitionInstant, AcquisitionGradual}
+
+// progressiveLeads are the fractions of the acquisition time before lock at
+// which the first satellites are acquired in the progressive profile. The
+// remaining satellites are acquired at lock, so a 3D fix never appears with
+// fewer than four.
+var progressiveLeads = []float64{0.6, 0.4, 0.2}
+
+// estimatedFixSatellites is the number of acquired satellites at which the
+// gradual profile reports an estimated fix before lock
+const estimatedFixSatellites = 4
+
+// ParseStartMode converts a start mode name (case-insensitive) to its
+// canonical form. An empty name is a cold start.
+func ParseStartMode(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return StartCold, nil
+	}
+	if _, ok := startModeScale[normalized]; !ok {
+		return "", fmt.Errorf("unknown start mode %q (valid: %s, %s, %s)", name, StartCold, StartWarm, StartHot)
+	}
+	return normalized, nil
+}
+
+// ParseAcquisitionProfile converts an acquisition profile name
+// (case-insensitive) to its canonical form. An empty name is progressive.
+func ParseAcquisitionProfile(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return AcquisitionProgressive, nil
+	}
+	for _, profile := range AcquisitionProfiles {
+		if normalized == profile {
+			return profile, nil
+		}
+	}
+	return "", fmt.Errorf("unknown acquisition profile %q (valid: %s)", name, strings.Join(AcquisitionProfiles, ", "))
+}
+
+// acquisitionProfile returns the configured acquisition profile
+func (s *GPSSimulator) acquisitionProfile() string {
+	profile, err := ParseAcquisitionProfile(s.Config.AcquisitionProfile)
+	if err != nil {
+		return AcquisitionProgressive
+	}
+	return profile
+}
+
+// acquisitionTime returns how long acquiring a fix takes for the configured start mode
+func (s *GPSSimulator) acquisitionTime() time.Duration {
+	mode, err := ParseStartMode(s.Config.StartMode)
+	if err != nil {
+		mode = StartCold
+	}
+	return time.Duration(float64(s.Config.TimeToLock) * startModeScale[mode])
+}
+
+// scheduleAcquisition starts acquiring a fix at start. Satellites are
+// acquired in a random order at times staggered by the acquisition profile
+// and the fix is locked once the acquisition time for the start mode has
+// passed.
+func (s *GPSSimulator) scheduleAcquisition(start time.Time) {
+	acquisition := s.acquisitionTime()
+	s.lockTime = start.Add(acquisition)
+
+	leads := s.acquisitionLeads()
+	for k, i := range s.random().Perm(len(s.Satellites)) {
+		var lead time.Duration
+		if k < len(leads) {
+			lead = time.Duration(leads[k] * float64(acquisition))
+		}
+		s.Satellites[i].acquireLead = lead
+	}
+}
+
+// acquisitionLeads returns the fractions of the acquisition time before lock
+// at which satellites are acquired, in acquisition order. Satellites beyond
+// the returned leads are acquired at lock.
+func (s *GPSSimulator) acquisitionLeads() []float64 {
+	switch s.acquisitionProfile() {
+	case AcquisitionInstant:
+		return nil
+	case AcquisitionGradual:
+		// Spread every satellite evenly so the last is acquired at lock
+		n := len(s.Satellites)
+		leads := make([]float64, n)
+		for k := range leads {
+			leads[k] = float64(n-1-k) / float64(n)
+		}
+		return leads
+	default:
+		return progressiveLeads
+	}
+}
+
+// acquiredSatellites returns the satellites acquired so far while acquiring a fix
+func (s *GPSSimulator) acquiredSatellites() []Satellite {
+	now := s.now()
+	var sats []Satellite
+	for _, sat := range s.Satellites {
+		if !now.Before(s.lockTime.Add(-sat.acquireLead)) {
+			sats = append(sats, sat)
+		}
+	}
+	return sats
+}
+
+// acquisitionSNR scales a satellite's signal strength while acquiring a fix,
+// rising from half strength when it is acquired to full strength at lock
+func (s *GPSSimulator) acquisitionSNR(sat Satellite, snr float64) float64 {
+	if s.isLocked || sat.acquireLead <= 0 {
+		return snr
+	}
+	tracked := float64(s.now().Sub(s.lockTime.Add(-sat.acquireLead))) / float64(sat.acquireLead)
+	return snr * (0.5 + 0.5*math.Max(0, math.Min(tracked, 1)))
+}
+
+// isEstimatedFix reports whether the gradual profile has acquired enough
+// satellites for an estimated fix while converging on lock
+func (s *GPSSimulator) isEstimatedFix() bool {
+	return !s.isLocked && s.acquisitionProfile() == AcquisitionGradual &&
+		len(s.acquiredSatellites()) >= estimatedFixSatellites
+}
+
+// estimatedHDOP returns the HDOP of the acquired satellites, inflated while
+// the solution converges so it falls steadily toward the locked value
+func (s *GPSSimulator) estimatedHDOP(acquired []Satellite) float64 {
+	converging := 0.0
+	if acquisition := s.acquisitionTime(); acquisition > 0 {
+		converging = math.Max(0, float64(s.lockTime.Sub(s.now()))/float64(acquisition))
+	}
+	return math.Min(calculateDOP(acquired).HDOP*(1+2*converging), maxDOP)
+}
@@ -0,0 +1,294 @@
+package gps
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStartMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"", StartCold, false},
+		{"cold", StartCold, false},
+		{"Warm", StartWarm, false},
+		{" HOT ", StartHot, false},
+		{"lukewarm", "", true},
+	}
+
+	for _, tt := range tests {
+		mode, err := ParseStartMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStartMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if mode != tt.expected {
+			t.Errorf("ParseStartMode(%q) = %q, want %q", tt.input, mode, tt.expected)
+		}
+	}
+
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.StartMode = "lukewarm"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown start mode")
+	}
+}
+
+func TestStartModeScalesAcquisition(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected time.Duration
+	}{
+		{"", 30 * time.Second},
+		{StartCold, 30 * time.Second},
+		{StartWarm, 15 * time.Second},
+		{StartHot, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		config := createTestConfig()
+		config.StartMode = tt.mode
+
+		sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("Failed to create GPS simulator: %v", err)
+		}
+		if got := sim.lockTime.Sub(sim.startTime); got != tt.expected {
+			t.Errorf("Start mode %q: expected lock after %v, got %v", tt.mode, tt.expected, got)
+		}
+	}
+}
+
+func TestProgressiveAcquisition(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 20 * time.Second
+	config.OutputRate = time.Second
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	if _, err := sim.GenerateTo(buffer, 30*time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lastCount := 0
+	counts := map[int]bool{}
+	fixTypes := []string{}
+	gsvBeforeLock := false
+	locked := false
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\r\n") {
+		fields := strings.Split(strings.Split(line, "*")[0], ",")
+		switch {
+		case strings.HasPrefix(line, "$GPGGA"):
+			count, _ := strconv.Atoi(fields[7])
+			locked = fields[6] != "0"
+			if !locked {
+				if count < lastCount {
+					t.Errorf("GGA satellite count decreased during acquisition: %d -> %d", lastCount, count)
+				}
+				lastCount = count
+				counts[count] = true
+			} else if count < 4 {
+				t.Errorf("Fix reported with only %d satellites", count)
+			}
+		case strings.HasPrefix(line, "$GPGSA"):
+			used := 0
+			for _, id := range fields[3:15] {
+				if id != "" {
+					used++
+				}
+			}
+			if fields[2] == "3" && used < 4 {
+				t.Errorf("3D fix reported with only %d satellites: %s", used, line)
+			}
+			if len(fixTypes) == 0 || fixTypes[len(fixTypes)-1] != fields[2] {
+				fixTypes = append(fixTypes, fields[2])
+			}
+		case strings.HasPrefix(line, "$GPGSV"):
+			if !locked {
+				gsvBeforeLock = true
+			}
+		}
+	}
+
+	// Satellites are acquired one at a time before the fix
+	for _, count := range []int{0, 1, 2, 3} {
+		if !counts[count] {
+			t.Errorf("Expected GGA to report %d satellites during acquisition, saw %v", count, counts)
+		}
+	}
+	if strings.Join(fixTypes, ",") != "1,2,3" {
+		t.Errorf("Expected GSA fix type to progress 1,2,3, got %v", fixTypes)
+	}
+	if !gsvBeforeLock {
+		t.Error("Expected GSV to report acquired satellites before lock")
+	}
+	if !locked {
+		t.Error("Expected fix after acquisition")
+	}
+}
+
+func TestAcquisitionSNRRises(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.Config.TimeToLock = 10 * time.Second
+	sim.scheduleAcquisition(clock.Now())
+
+	// Follow the first satellite acquired from acquisition to lock
+	var first Satellite
+	for _, sat := range sim.Satellites {
+		if sat.acquireLead > first.acquireLead {
+			first = sat
+		}
+	}
+	clock.Advance(sim.lockTime.Add(-first.acquireLead).Sub(clock.Now()))
+
+	previous := sim.signalSNR(first)
+	if previous >= first.SNR {
+		t.Errorf("Expected reduced SNR on acquisition, got %d of %d", previous, first.SNR)
+	}
+	for i := 0; i < 6; i++ {
+		clock.Advance(time.Second)
+		snr := sim.signalSNR(first)
+		if snr < previous {
+			t.Errorf("Expected SNR to rise during acquisition, got %d after %d", snr, previous)
+		}
+		previous = snr
+	}
+	if previous != first.SNR {
+		t.Errorf("Expected full SNR by lock, got %d of %d", previous, first.SNR)
+	}
+}
+
+func TestParseAcquisitionProfile(t *testing.T) {
+	for input, expected := range map[string]string{
+		"":            AcquisitionProgressive,
+		"progressive": AcquisitionProgressive,
+		"Instant":     AcquisitionInstant,
+		" GRADUAL ":   AcquisitionGradual,
+	} {
+		profile, err := ParseAcquisitionProfile(input)
+		if err != nil || profile != expected {
+			t.Errorf("ParseAcquisitionProfile(%q) = %q, %v; want %q", input, profile, err, expected)
+		}
+	}
+
+	if _, err := ParseAcquisitionProfile("slow"); err == nil {
+		t.Error("Expected error for unknown acquisition profile")
+	}
+
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.AcquisitionProfile = "slow"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown acquisition profile in config")
+	}
+}
+
+// stepAcquisition advances a simulator on a fake clock through its lock
+// acquisition, returning the GGA fields output after each update
+func stepAcquisition(t *testing.T, profile string) [][]string {
+	t.Helper()
+
+	config := createTestConfig()
+	config.TimeToLock = 16 * time.Second
+	config.Satellites = 8
+	config.AcquisitionProfile = profile
+	config.Sentences = []string{SentenceGGA}
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	var ggas [][]string
+	for i := 0; i < 20; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		ggas = append(ggas, strings.Split(strings.Split(strings.TrimSpace(emittedGGA(sim)), "*")[0], ","))
+	}
+	return ggas
+}
+
+// emittedGGA returns the GGA sentence output by one cycle
+func emittedGGA(sim *GPSSimulator) string {
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+	sim.outputNMEA()
+	return buffer.String()
+}
+
+func TestGradualAcquisitionQualityProgression(t *testing.T) {
+	ggas := stepAcquisition(t, AcquisitionGradual)
+
+	var qualities []string
+	lastCount := 0
+	var estimatedHDOP []float64
+	for _, gga := range ggas {
+		quality := gga[6]
+		if len(qualities) == 0 || qualities[len(qualities)-1] != quality {
+			qualities = append(qualities, quality)
+		}
+
+		count, _ := strconv.Atoi(gga[7])
+		if count < lastCount {
+			t.Errorf("Satellite count decreased during acquisition: %d -> %d", lastCount, count)
+		}
+		lastCount = count
+
+		switch quality {
+		case "6":
+			if count < estimatedFixSatellites {
+				t.Errorf("Estimated fix reported with only %d satellites", count)
+			}
+			if gga[2] == "" || gga[4] == "" {
+				t.Errorf("Estimated fix should report a position, got %v", gga)
+			}
+			hdop, _ := strconv.ParseFloat(gga[8], 64)
+			estimatedHDOP = append(estimatedHDOP, hdop)
+		case "1":
+			if count != 8 {
+				t.Errorf("Expected all 8 satellites once locked, got %d", count)
+			}
+		}
+	}
+
+	if strings.Join(qualities, ",") != "0,6,1" {
+		t.Errorf("Expected GGA quality to progress 0,6,1, got %v", qualities)
+	}
+	if len(estimatedHDOP) < 2 || estimatedHDOP[len(estimatedHDOP)-1] >= estimatedHDOP[0] {
+		t.Errorf("Expected HDOP to fall during the estimated fix, got %v", estimatedHDOP)
+	}
+}
+
+func TestInstantAcquisition(t *testing.T) {
+	ggas := stepAcquisition(t, AcquisitionInstant)
+
+	for i, gga := range ggas {
+		if gga[6] == "0" && gga[7] != "00" {
+			t.Errorf("Step %d: expected no satellites before lock, got %s", i, gga[7])
+		}
+		if gga[6] == "6" {
+			t.Errorf("Step %d: instant profile should not report an estimated fix", i)
+		}
+	}
+	if last := ggas[len(ggas)-1]; last[6] != "1" || last[7] != "08" {
+		t.Errorf("Expected lock with 8 satellites, got quality %s with %s", last[6], last[7])
+	}
+}
@@ -0,0 +1,37 @@
+package gps
+
+import "time"
+
+// minimumAltitude is the lowest altitude reported, in meters, so descents
+// and jitter don't go too far below sea level
+const minimumAltitude = -50.0
+
+// updateClimb ramps the altitude at Config.ClimbRate over dt, leveling off at
+// Config.TargetAltitude when climbing or descending toward it, and adds up to
+// 1-21 meters of AltitudeJitter noise around the ramp
+func (s *GPSSimulator) updateClimb(dt time.Duration) {
+	if dt > 0 {
+		previous := s.altitudeBase
+		target := s.Config.TargetAltitude
+		s.altitudeBase += s.Config.ClimbRate * dt.Seconds()
+
+		// Don't overshoot a target ahead of the climb
+		towardTarget := (target-previous)*s.Config.ClimbRate >= 0
+		if towardTarget && (s.altitudeBase-target)*(previous-target) <= 0 {
+			s.altitudeBase = target
+		}
+		if s.altitudeBase < minimumAltitude {
+			s.altitudeBase = minimumAltitude
+		}
+	}
+
+	altitude := s.altitudeBase
+	if s.Config.AltitudeJitter > 0 {
+		maxNoise := 1.0 + (s.Config.AltitudeJitter * 20.0)
+		altitude += (s.random().Float64() - 0.5) * 2 * maxNoise
+	}
+	if altitude < minimumAltitude {
+		altitude = minimumAltitude
+	}
+	s.currentAlt = altitude
+}
@@ -0,0 +1,160 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// createClimbSimulator returns a locked simulator on a fake clock climbing
+// from altitude at climbRate toward target
+func createClimbSimulator(t *testing.T, altitude, climbRate, target float64) (*GPSSimulator, *fakeClock) {
+	t.Helper()
+	config := createTestConfig()
+	config.Altitude = altitude
+	config.AltitudeJitter = 0
+	config.ClimbRate = climbRate
+	config.TargetAltitude = target
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.altitudeTime = clock.Now()
+	sim.isLocked = true
+	return sim, clock
+}
+
+func TestClimbRateSlope(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 100, 2, 200)
+
+	for i := 1; i <= 10; i++ {
+		clock.Advance(time.Second)
+		sim.updateAltitude()
+		if want := 100 + 2*float64(i); math.Abs(sim.currentAlt-want) > 1e-9 {
+			t.Fatalf("After %ds expected altitude %f, got %f", i, want, sim.currentAlt)
+		}
+	}
+
+	// Levels off at the target without overshooting
+	clock.Advance(47 * time.Second)
+	sim.updateAltitude()
+	if sim.currentAlt != 200 {
+		t.Errorf("Expected to level off at 200, got %f", sim.currentAlt)
+	}
+	clock.Advance(time.Minute)
+	sim.updateAltitude()
+	if sim.currentAlt != 200 {
+		t.Errorf("Expected to hold 200, got %f", sim.currentAlt)
+	}
+
+	// The profile is reported in GGA
+	gga := sentenceFields(sim.generateGGA(clock.Now()))
+	if alt, err := strconv.ParseFloat(gga[9], 64); err != nil || alt != 200 {
+		t.Errorf("Expected GGA altitude 200, got %q", gga[9])
+	}
+}
+
+func TestClimbRateDescent(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 500, -5, 300)
+
+	clock.Advance(20 * time.Second)
+	sim.updateAltitude()
+	if sim.currentAlt != 400 {
+		t.Errorf("Expected altitude 400 after 20s descending at 5 m/s, got %f", sim.currentAlt)
+	}
+	clock.Advance(time.Minute)
+	sim.updateAltitude()
+	if sim.currentAlt != 300 {
+		t.Errorf("Expected to level off at 300, got %f", sim.currentAlt)
+	}
+}
+
+func TestClimbRateClampsBelowSeaLevel(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 0, -5, -1000)
+
+	clock.Advance(time.Minute)
+	sim.updateAltitude()
+	if sim.currentAlt != minimumAltitude {
+		t.Errorf("Expected descent clamped at %f, got %f", minimumAltitude, sim.currentAlt)
+	}
+}
+
+func TestClimbRateAwayFromTarget(t *testing.T) {
+	// A target behind the climb doesn't stop it
+	sim, clock := createClimbSimulator(t, 100, 1, 0)
+
+	clock.Advance(100 * time.Second)
+	sim.updateAltitude()
+	if sim.currentAlt != 200 {
+		t.Errorf("Expected altitude 200 climbing away from the target, got %f", sim.currentAlt)
+	}
+}
+
+func TestClimbRateJitterNoise(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 100, 1, 1000)
+	sim.Config.AltitudeJitter = 0.5
+
+	for i := 1; i <= 50; i++ {
+		clock.Advance(time.Second)
+		sim.updateAltitude()
+		// Noise is at most 11 meters either side of the ramp at jitter 0.5
+		if ramp := 100 + float64(i); math.Abs(sim.currentAlt-ramp) > 11 {
+			t.Fatalf("After %ds altitude %f is more than 11m off the ramp %f", i, sim.currentAlt, ramp)
+		}
+	}
+}
+
+func TestClimbRateReachesTargetInTime(t *testing.T) {
+	// Takeoff to cruise: 2950 m at 8 m/s takes 368.75 seconds
+	sim, clock := createClimbSimulator(t, 50, 8, 3000)
+
+	reached := 0
+	for i := 1; i <= 400 && reached == 0; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		if sim.currentAlt == 3000 {
+			reached = i
+		}
+	}
+	if reached != 369 {
+		t.Errorf("Expected to reach 3000 m after 369 one second updates, got %d", reached)
+	}
+
+	// Then cruise at the target
+	clock.Advance(10 * time.Minute)
+	sim.update()
+	if sim.currentAlt != 3000 {
+		t.Errorf("Expected to hold 3000 m, got %f", sim.currentAlt)
+	}
+}
+
+func TestClimbRateUpdateStartsFromCurrentAltitude(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 100, 0, 0)
+	sim.Config.AltitudeJitter = 0.5
+
+	// Wander away from the configured altitude before climbing
+	for i := 0; i < 20; i++ {
+		clock.Advance(time.Second)
+		sim.updateAltitude()
+	}
+	sim.Config.AltitudeJitter = 0
+	start := sim.currentAlt
+
+	config := sim.Config
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.ClimbRate = 2
+	config.TargetAltitude = 1000
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	clock.Advance(time.Second)
+	sim.updateAltitude()
+	if want := start + 2; math.Abs(sim.currentAlt-want) > 1e-9 {
+		t.Errorf("Expected the climb to start from %f and reach %f, got %f", start, want, sim.currentAlt)
+	}
+}
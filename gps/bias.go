@@ -0,0 +1,28 @@
+package gps
+
+import (
+	"errors"
+	"math"
+)
+
+// validateBias checks the constant position error
+func (c Config) validateBias() error {
+	if c.BiasMeters < 0 || math.IsNaN(c.BiasMeters) || math.IsInf(c.BiasMeters, 0) {
+		return errors.New("Bias distance must be a non-negative number of meters")
+	}
+	if math.IsNaN(c.BiasBearing) || math.IsInf(c.BiasBearing, 0) {
+		return errors.New("Bias bearing must be a number of degrees")
+	}
+	return nil
+}
+
+// reportedPosition returns the position reported in NMEA sentences and gpsd
+// reports: the current position offset by BiasMeters toward BiasBearing.
+// Movement, snapshots' true position and track output use the unbiased
+// position.
+func (s *GPSSimulator) reportedPosition() (float64, float64) {
+	if s.Config.BiasMeters == 0 {
+		return s.currentLat, s.currentLon
+	}
+	return s.calculateDestination(s.currentLat, s.currentLon, s.Config.BiasBearing, s.Config.BiasMeters)
+}
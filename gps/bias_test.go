@@ -0,0 +1,129 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// biasRun runs the simulator for a few cycles with a GPX writer and returns
+// the GPX track points and the GGA positions reported alongside them
+func biasRun(t *testing.T, meters, bearing float64) ([]TrackPoint, [][2]float64, string) {
+	t.Helper()
+	sim := createTestSimulator()
+	sim.Config.Seed = 1
+	sim.Config.Speed = 8
+	sim.Config.BiasMeters = meters
+	sim.Config.BiasBearing = bearing
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+	gpxWriter, err := NewGPXWriter(t.TempDir() + "/bias.gpx")
+	if err != nil {
+		t.Fatalf("Failed to create GPX writer: %v", err)
+	}
+	sim.gpxWriter = gpxWriter
+
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+	var reported [][2]float64
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		start := buffer.Len()
+		sim.outputNMEA()
+		sim.updateTracks()
+
+		for _, line := range strings.Split(buffer.String()[start:], "\r\n") {
+			if strings.HasPrefix(line, "$GPGGA") {
+				gga := sentenceFields(line)
+				reported = append(reported, [2]float64{
+					parseNMEACoordinate(t, gga[2], gga[3], 2),
+					parseNMEACoordinate(t, gga[4], gga[5], 3),
+				})
+			}
+		}
+	}
+	return gpxWriter.gpx.Track.TrackSegment.TrackPoints, reported, buffer.String()
+}
+
+func TestBiasOffsetsNMEAPosition(t *testing.T) {
+	sim := createTestSimulator()
+	for _, bearing := range []float64{0, 90, 225} {
+		points, reported, _ := biasRun(t, 3, bearing)
+		if len(points) != 5 || len(reported) != 5 {
+			t.Fatalf("Expected 5 GPX points and GGA sentences, got %d and %d", len(points), len(reported))
+		}
+		for i, p := range points {
+			// GGA coordinates are rounded to a few tens of centimeters
+			if distance := sim.calculateDistance(p.Lat, p.Lon, reported[i][0], reported[i][1]); math.Abs(distance-3) > 0.25 {
+				t.Errorf("Bearing %.0f: expected GGA 3m from the GPX point, got %.3fm", bearing, distance)
+			}
+		}
+	}
+}
+
+func TestBiasDirection(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.BiasMeters = 100
+	for _, bearing := range []float64{0, 90, 180, 225, 315} {
+		sim.Config.BiasBearing = bearing
+		lat, lon := sim.reportedPosition()
+		got := sim.calculateBearing(sim.currentLat, sim.currentLon, lat, lon)
+		if diff := math.Abs(math.Mod(got-bearing+540, 360) - 180); diff > 1 {
+			t.Errorf("Expected the bias toward %.0f°, got %.2f°", bearing, got)
+		}
+	}
+}
+
+func TestZeroBiasMatchesUnbiasedOutput(t *testing.T) {
+	points, reported, output := biasRun(t, 0, 90)
+	_, _, unbiased := biasRun(t, 0, 0)
+	sim := createTestSimulator()
+	if output != unbiased {
+		t.Error("Expected a zero bias to leave the output unchanged regardless of bearing")
+	}
+	for i, p := range points {
+		if sim.calculateDistance(p.Lat, p.Lon, reported[i][0], reported[i][1]) > 0.25 {
+			t.Errorf("Expected GGA at the GPX point without bias, point %d differs", i)
+		}
+	}
+}
+
+func TestBiasInSnapshot(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.BiasMeters = 50
+	sim.Config.BiasBearing = 0
+
+	snapshot := sim.Snapshot()
+	if snapshot.Latitude != sim.currentLat || snapshot.Longitude != sim.currentLon {
+		t.Error("Expected the snapshot to keep the true position")
+	}
+	if distance := sim.calculateDistance(snapshot.Latitude, snapshot.Longitude, snapshot.ReportedLatitude, snapshot.ReportedLongitude); math.Abs(distance-50) > 0.01 {
+		t.Errorf("Expected the reported position 50m from the true one, got %.3fm", distance)
+	}
+}
+
+func TestBiasValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.BiasMeters = 5
+	config.BiasBearing = 270
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid bias config, got: %v", err)
+	}
+
+	config.BiasMeters = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative bias distance")
+	}
+
+	config.BiasMeters = math.NaN()
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for NaN bias distance")
+	}
+}
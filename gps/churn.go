@@ -0,0 +1,104 @@
+package gps
+
+// Chances, as one in N updates, of a satellite rising above the horizon
+// while fewer than Config.Satellites are in view and while at least as many
+// are in view
+const (
+	riseChanceBelowTarget = 20
+	riseChanceAtTarget    = 120
+)
+
+// satelliteCountBounds returns the fewest and most satellites kept in view
+// with SatelliteChurn: within 2 of Config.Satellites and within 4-12
+func (c Config) satelliteCountBounds() (int, int) {
+	lo, hi := c.Satellites-2, c.Satellites+2
+	if lo < 4 {
+		lo = 4
+	}
+	if hi > 12 {
+		hi = 12
+	}
+	return lo, hi
+}
+
+// orbitStep returns the extra degree of elevation sat moves this update as
+// it rises or sets, in one of every four updates
+func (s *GPSSimulator) orbitStep(sat Satellite) int {
+	if s.random().Intn(4) != 0 {
+		return 0
+	}
+	if sat.setting {
+		return -1
+	}
+	return 1
+}
+
+// churnSatellites removes satellites that have set below the lowest
+// elevation in bounds and occasionally adds one rising from it, keeping the
+// count within satelliteCountBounds. Satellites reaching the highest
+// elevation start to set.
+func (s *GPSSimulator) churnSatellites(bounds valueRange) {
+	lo, hi := s.Config.satelliteCountBounds()
+
+	n := len(s.Satellites)
+	kept := s.Satellites[:0]
+	for _, sat := range s.Satellites {
+		if sat.setting && sat.Elevation <= bounds.min {
+			if n > lo {
+				n-- // Set below the horizon
+				continue
+			}
+			sat.setting = false // Too few would be left in view
+		} else if !sat.setting && sat.Elevation >= bounds.max {
+			sat.setting = true // Passed overhead
+		}
+		kept = append(kept, sat)
+	}
+	s.Satellites = kept
+
+	chance := riseChanceAtTarget
+	if len(s.Satellites) < s.Config.Satellites {
+		chance = riseChanceBelowTarget
+	}
+	if len(s.Satellites) < hi && s.random().Intn(chance) == 0 {
+		s.riseSatellite(bounds.min)
+	}
+}
+
+// riseSatellite adds a satellite rising from elevation, in the constellation
+// with the fewest satellites in view and with a PRN not in use
+func (s *GPSSimulator) riseSatellite(elevation int) {
+	inView := make(map[Constellation]int)
+	for _, sat := range s.Satellites {
+		inView[sat.Constellation]++
+	}
+	constellations := s.constellations()
+	c := constellations[0]
+	for _, candidate := range constellations[1:] {
+		if inView[candidate] < inView[c] {
+			c = candidate
+		}
+	}
+
+	used := make(map[int]bool)
+	for _, sat := range s.Satellites {
+		if sat.Constellation == c {
+			used[sat.ID] = true
+		}
+	}
+	first, last := c.PRNRange()
+	var free []int
+	for id := first; id <= last; id++ {
+		if !used[id] {
+			free = append(free, id)
+		}
+	}
+	if len(free) == 0 {
+		return
+	}
+
+	sat := s.newSatellite(free[s.random().Intn(len(free))], c)
+	sat.Elevation = elevation
+	sat.setting = false
+	s.Satellites = append(s.Satellites, sat)
+}
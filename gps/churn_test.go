@@ -0,0 +1,95 @@
+package gps
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSatelliteChurn(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.SatelliteChurn = true
+	sim.Config.Seed = 1
+	sim.rng = nil
+	sim.initializeSatellites()
+
+	initial := make(map[int]bool)
+	for _, sat := range sim.Satellites {
+		initial[sat.ID] = true
+	}
+
+	lo, hi := sim.Config.satelliteCountBounds()
+	counts := make(map[int]bool)
+	changed := false
+	for step := 0; step < 5000; step++ {
+		sim.updateSatellites()
+
+		n := len(sim.Satellites)
+		if n < lo || n > hi {
+			t.Fatalf("Step %d: %d satellites in view, expected %d-%d", step, n, lo, hi)
+		}
+		counts[n] = true
+
+		seen := make(map[int]bool)
+		for _, sat := range sim.Satellites {
+			if seen[sat.ID] {
+				t.Fatalf("Step %d: PRN %d in view twice", step, sat.ID)
+			}
+			seen[sat.ID] = true
+			if !initial[sat.ID] {
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		t.Error("Expected the set of PRNs in view to change")
+	}
+	if len(counts) < 2 {
+		t.Error("Expected the number of satellites in view to fluctuate")
+	}
+
+	// GSV reports the current count and GSA lists every satellite in view
+	gsv := strings.Split(sim.generateGSV()[0], ",")
+	if inView, _ := strconv.Atoi(gsv[3]); inView != len(sim.Satellites) {
+		t.Errorf("Expected GSV to report %d satellites in view, got %d", len(sim.Satellites), inView)
+	}
+	gsa := strings.Split(sim.generateGSA()[0], ",")
+	listed := 0
+	for _, field := range gsa[3:15] {
+		if field != "" {
+			listed++
+		}
+	}
+	if listed != len(sim.Satellites) {
+		t.Errorf("Expected GSA to list %d satellites, got %d", len(sim.Satellites), listed)
+	}
+}
+
+func TestSatelliteChurnDisabled(t *testing.T) {
+	sim := createTestSimulator()
+	sim.initializeSatellites()
+
+	for step := 0; step < 1000; step++ {
+		sim.updateSatellites()
+	}
+	if len(sim.Satellites) != sim.Config.Satellites {
+		t.Errorf("Expected a fixed %d satellites without churn, got %d", sim.Config.Satellites, len(sim.Satellites))
+	}
+}
+
+func TestSatelliteCountBounds(t *testing.T) {
+	tests := []struct {
+		satellites, lo, hi int
+	}{
+		{4, 4, 6},
+		{8, 6, 10},
+		{12, 10, 12},
+	}
+	for _, tt := range tests {
+		lo, hi := Config{Satellites: tt.satellites}.satelliteCountBounds()
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("satelliteCountBounds() for %d = %d-%d, expected %d-%d", tt.satellites, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
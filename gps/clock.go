@@ -0,0 +1,177 @@
+package gps
+
+import "time"
+
+// Clock supplies the current time and output tickers to the simulator. It can
+// be replaced with SetClock to control or accelerate simulated time.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at a fixed interval, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is the default Clock backed by the system time
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker adapts time.Ticker to the Ticker interface
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// scaledClock runs scale times faster than its base clock from the moment it
+// is created. Ticker intervals are in simulated time, so a 1s ticker at scale
+// 60 fires every 1/60th of a second of base time.
+type scaledClock struct {
+	base   Clock
+	scale  float64
+	origin time.Time
+}
+
+// newScaledClock returns a Clock advancing scale times faster than base
+func newScaledClock(base Clock, scale float64) *scaledClock {
+	return &scaledClock{base: base, scale: scale, origin: base.Now()}
+}
+
+func (c *scaledClock) Now() time.Time {
+	elapsed := c.base.Now().Sub(c.origin)
+	return c.origin.Add(time.Duration(float64(elapsed) * c.scale))
+}
+
+func (c *scaledClock) NewTicker(d time.Duration) Ticker {
+	interval := time.Duration(float64(d) / c.scale)
+	if interval <= 0 {
+		interval = 1
+	}
+	return c.base.NewTicker(interval)
+}
+
+// steppedClock is a Clock that only moves when advanced, used to step the
+// simulation in virtual time. Its tickers never fire.
+type steppedClock struct {
+	now time.Time
+}
+
+func (c *steppedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *steppedClock) NewTicker(d time.Duration) Ticker {
+	return stoppedTicker{}
+}
+
+// Advance moves the clock forward by d
+func (c *steppedClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// stoppedTicker is a Ticker that never fires
+type stoppedTicker struct{}
+
+func (stoppedTicker) C() <-chan time.Time {
+	return nil
+}
+
+func (stoppedTicker) Stop() {}
+
+// SetClock replaces the clock driving the simulation. Simulation timers (lock
+// acquisition, dropouts, replay progression, road-like legs and position
+// updates) are rebased onto the new clock so their progress is preserved.
+// Config.TimeScale is not applied to clocks set this way.
+func (s *GPSSimulator) SetClock(c Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offset := c.Now().Sub(s.now())
+	s.startTime = s.startTime.Add(offset)
+	s.lockTime = s.lockTime.Add(offset)
+	s.lastUpdateTime = s.lastUpdateTime.Add(offset)
+	s.altitudeTime = s.altitudeTime.Add(offset)
+	s.replayStartTime = s.replayStartTime.Add(offset)
+	if !s.dropoutAt.IsZero() {
+		s.dropoutAt = s.dropoutAt.Add(offset)
+	}
+	if !s.previousCourseTime.IsZero() {
+		s.previousCourseTime = s.previousCourseTime.Add(offset)
+	}
+	if !s.road.ends.IsZero() {
+		s.road.ends = s.road.ends.Add(offset)
+	}
+	if s.paused {
+		s.pausedAt = s.pausedAt.Add(offset)
+	}
+	s.clock = c
+}
+
+// now returns the current simulated time
+func (s *GPSSimulator) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// fixTime returns the UTC time reported for simulated time now. With
+// Config.ReplayUseOriginalTime it is the replayed track's recorded time, and
+// with Config.SimulatedDate set it starts at that date and advances with
+// simulated time since the start. Config.TimeOffset is added on top. Each output
+// cycle formats every sentence from the one fix time so time and date
+// fields agree.
+func (s *GPSSimulator) fixTime(now time.Time) time.Time {
+	if recorded, ok := s.replayTime(now); ok {
+		now = recorded
+	} else if s.Config.SimulatedDate != "" {
+		if start, err := time.Parse(time.RFC3339, s.Config.SimulatedDate); err == nil {
+			now = start.Add(now.Sub(s.startTime))
+		}
+	}
+	return now.Add(s.Config.TimeOffset).UTC()
+}
+
+// replayTime returns the recorded time at the replay position with
+// Config.ReplayUseOriginalTime: the first track point's time advanced by the
+// replay time elapsed at ReplaySpeed, held at the last point's time once the
+// track has finished. It reports false for tracks without sequential
+// timestamps and while driving between playlist files.
+func (s *GPSSimulator) replayTime(now time.Time) (time.Time, bool) {
+	if !s.Config.ReplayUseOriginalTime || !s.isReplayMode() || s.transition != nil || !s.hasSequentialTimestamps() {
+		return time.Time{}, false
+	}
+	speed := s.Config.ReplaySpeed
+	if speed <= 0 {
+		speed = 1.0
+	}
+	recorded := s.replayPoints[0].Time.Add(time.Duration(float64(now.Sub(s.replayStartTime)) * speed))
+	if last := s.replayPoints[len(s.replayPoints)-1].Time; recorded.After(last) {
+		recorded = last
+	}
+	return recorded, true
+}
+
+// newTicker returns a ticker firing every d of simulated time
+func (s *GPSSimulator) newTicker(d time.Duration) Ticker {
+	if s.clock == nil {
+		return realClock{}.NewTicker(d)
+	}
+	return s.clock.NewTicker(d)
+}
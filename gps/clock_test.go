@@ -0,0 +1,397 @@
+package gps
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced Clock for tests
+type fakeClock struct {
+	now     time.Time
+	mu      sync.Mutex // Guards tickers, created by Run on its own goroutine
+	tickers []*fakeTicker
+}
+
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	ticker := &fakeTicker{interval: d, ch: make(chan time.Time, 1)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tickers = append(c.tickers, ticker)
+	return ticker
+}
+
+// tickerIntervals returns the intervals of the tickers created so far
+func (c *fakeClock) tickerIntervals() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	intervals := make([]time.Duration, len(c.tickers))
+	for i, ticker := range c.tickers {
+		intervals[i] = ticker.interval
+	}
+	return intervals
+}
+
+// Advance moves the clock forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeTicker records the interval it was created with
+type fakeTicker struct {
+	interval time.Duration
+	ch       chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.ch }
+func (t *fakeTicker) Stop()               {}
+
+func TestScaledClock(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	base := newFakeClock(start)
+	clock := newScaledClock(base, 60)
+
+	base.Advance(time.Minute)
+	if got := clock.Now().Sub(start); got != time.Hour {
+		t.Errorf("Expected one hour of simulated time after one minute, got %v", got)
+	}
+
+	clock.NewTicker(time.Second)
+	if interval := base.tickers[0].interval; interval != time.Second/60 {
+		t.Errorf("Expected base ticker interval %v, got %v", time.Second/60, interval)
+	}
+}
+
+func TestTimeScaleValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.TimeScale = 60
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid time scale, got: %v", err)
+	}
+
+	config.TimeScale = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative time scale")
+	}
+}
+
+func TestNewGPSSimulatorTimeScale(t *testing.T) {
+	config := createTestConfig()
+	config.TimeScale = 3600
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	if _, ok := sim.clock.(*scaledClock); !ok {
+		t.Fatalf("Expected a scaled clock, got %T", sim.clock)
+	}
+
+	// 10ms of wall time is 36s of simulated time at this scale
+	time.Sleep(10 * time.Millisecond)
+	if elapsed := sim.now().Sub(sim.startTime); elapsed < 30*time.Second {
+		t.Errorf("Expected simulated time to run faster than wall time, got %v", elapsed)
+	}
+}
+
+func TestSetClockRebasesTimers(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 5 * time.Second
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if remaining := sim.lockTime.Sub(clock.Now()); remaining < 4*time.Second || remaining > 5*time.Second {
+		t.Errorf("Expected about 5s until lock on the new clock, got %v", remaining)
+	}
+
+	clock.Advance(4 * time.Second)
+	sim.update()
+	if sim.isLocked {
+		t.Error("Should not lock before TimeToLock has elapsed on the fake clock")
+	}
+
+	clock.Advance(2 * time.Second)
+	sim.update()
+	if !sim.isLocked {
+		t.Error("Should lock once TimeToLock has elapsed on the fake clock")
+	}
+}
+
+func TestLongSessionWithFakeClock(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Jitter = 0
+	config.Radius = 0
+	config.Speed = 10.0
+	config.Course = 0.0
+	config.Sentences = []string{"ZDA"}
+	config.Quiet = true
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	sim.SetClock(clock)
+
+	// One hour of simulated output at 1s intervals, without sleeping
+	for i := 0; i < 3600; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\r\n")
+	if len(lines) != 3600 {
+		t.Fatalf("Expected 3600 ZDA sentences, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "$GPZDA,100001") {
+		t.Errorf("Expected first ZDA at 10:00:01, got %s", lines[0])
+	}
+	if !strings.HasPrefix(lines[len(lines)-1], "$GPZDA,110000") {
+		t.Errorf("Expected last ZDA at 11:00:00, got %s", lines[len(lines)-1])
+	}
+
+	// 10 knots due north for an hour is 10 nautical miles (~0.1667 degrees)
+	if moved := sim.currentLat - config.Latitude; moved < 0.16 || moved > 0.17 {
+		t.Errorf("Expected about 0.1667 degrees of northward travel, got %f", moved)
+	}
+}
+
+func TestGPXTimestampsFollowClock(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.GPXEnabled = true
+	config.GPXFile = filepath.Join(t.TempDir(), "session.gpx")
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	for i := 0; i < 60; i++ {
+		clock.Advance(time.Minute)
+		sim.tick()
+	}
+
+	points := sim.gpxWriter.gpx.Track.TrackSegment.TrackPoints
+	if len(points) != 60 {
+		t.Fatalf("Expected 60 GPX points, got %d", len(points))
+	}
+	if got := points[len(points)-1].Time.Sub(points[0].Time); got != 59*time.Minute {
+		t.Errorf("Expected GPX timestamps to span 59m, got %v", got)
+	}
+}
+
+func TestReplayWithFakeClock(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+		{Lat: 37.0020, Lon: -122.0000, Time: base.Add(20 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, false)
+	clock := newFakeClock(base)
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	clock.Advance(15 * time.Second)
+	sim.updateReplayPosition()
+	if sim.replayIndex != 1 {
+		t.Errorf("Expected index 1 after 15s of simulated time, got %d", sim.replayIndex)
+	}
+}
+
+// emittedFields returns the fields of each sentence output by one cycle, by
+// sentence type
+func emittedFields(sim *GPSSimulator) map[string][]string {
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+	sim.outputNMEA()
+
+	sentences := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\r\n") {
+		fields := sentenceFields(line)
+		sentences[fields[0][3:]] = fields
+	}
+	return sentences
+}
+
+func TestSimulatedDateMidnightRollover(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.SimulatedDate = "2024-12-31T23:59:58Z"
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.startTime = clock.Now()
+
+	expected := []struct {
+		time    string
+		rmcDate string
+		zdaDate []string // Day, month, year
+	}{
+		{"235958", "311224", []string{"31", "12", "2024"}},
+		{"235959", "311224", []string{"31", "12", "2024"}},
+		{"000000", "010125", []string{"01", "01", "2025"}},
+		{"000001", "010125", []string{"01", "01", "2025"}},
+	}
+
+	for _, want := range expected {
+		sentences := emittedFields(sim)
+		gga, rmc, gll, zda := sentences["GGA"], sentences["RMC"], sentences["GLL"], sentences["ZDA"]
+		if gga[1] != want.time || rmc[1] != want.time {
+			t.Errorf("Expected GGA and RMC time %s, got %s and %s", want.time, gga[1], rmc[1])
+		}
+		if !strings.HasPrefix(gll[5], want.time) || !strings.HasPrefix(zda[1], want.time) {
+			t.Errorf("Expected GLL and ZDA time %s, got %s and %s", want.time, gll[5], zda[1])
+		}
+		if rmc[9] != want.rmcDate {
+			t.Errorf("At %s expected RMC date %s, got %s", want.time, want.rmcDate, rmc[9])
+		}
+		if strings.Join(zda[2:5], "/") != strings.Join(want.zdaDate, "/") {
+			t.Errorf("At %s expected ZDA date %v, got %v", want.time, want.zdaDate, zda[2:5])
+		}
+		clock.Advance(time.Second)
+	}
+}
+
+func TestTimeOffset(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.SimulatedDate = "2025-01-01T00:00:10Z"
+	sim.Config.TimeOffset = -18 * time.Second // GPS time ahead of UTC
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.startTime = clock.Now()
+
+	rmc := emittedFields(sim)["RMC"]
+	if rmc[1] != "235952" || rmc[9] != "311224" {
+		t.Errorf("Expected RMC at 235952 on 311224, got %s on %s", rmc[1], rmc[9])
+	}
+
+	// Without a simulated date the offset shifts the clock time
+	sim.Config.SimulatedDate = ""
+	sim.Config.TimeOffset = time.Hour
+	if got, want := sim.fixTime(clock.Now()), clock.Now().Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Expected fix time %v, got %v", want, got)
+	}
+}
+
+func TestValidateSimulatedDate(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.SimulatedDate = "2024-12-31T23:59:58Z"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Unexpected error for a valid simulated date: %v", err)
+	}
+
+	config.SimulatedDate = "31/12/2024"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for a simulated date that is not RFC3339")
+	}
+}
+
+func TestReplayUseOriginalTime(t *testing.T) {
+	recorded := time.Date(2019, 7, 4, 16, 30, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: recorded},
+		{Lat: 37.0010, Lon: -122.0000, Time: recorded.Add(10 * time.Second)},
+		{Lat: 37.0020, Lon: -122.0000, Time: recorded.Add(20 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	sim.Config.ReplayUseOriginalTime = true
+	sim.Config.ReplaySpeed = 2.0
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	expected := []struct {
+		advance time.Duration
+		time    string
+	}{
+		{0, "163000"},
+		{3 * time.Second, "163006"}, // 3s at 2x is 6s into the recording
+		{4 * time.Second, "163014"},
+		{time.Minute, "163020"}, // Held at the last point once finished
+	}
+	for _, want := range expected {
+		clock.Advance(want.advance)
+		sim.updateReplayPosition()
+
+		sentences := emittedFields(sim)
+		gga, rmc, zda := sentences["GGA"], sentences["RMC"], sentences["ZDA"]
+		if gga[1] != want.time || rmc[1] != want.time || !strings.HasPrefix(zda[1], want.time) {
+			t.Errorf("Expected GGA, RMC and ZDA time %s, got %s, %s and %s", want.time, gga[1], rmc[1], zda[1])
+		}
+		if rmc[9] != "040719" {
+			t.Errorf("Expected the recorded RMC date 040719, got %s", rmc[9])
+		}
+		if strings.Join(zda[2:5], "/") != "04/07/2019" {
+			t.Errorf("Expected the recorded ZDA date, got %v", zda[2:5])
+		}
+	}
+
+	// Without the option output follows the clock
+	sim.Config.ReplayUseOriginalTime = false
+	if rmc := emittedFields(sim)["RMC"]; rmc[9] != "010624" {
+		t.Errorf("Expected the clock's RMC date 010624, got %s", rmc[9])
+	}
+}
+
+func TestReplayUseOriginalTimeWithoutTimestamps(t *testing.T) {
+	// KML-style tracks without times fall back to the clock
+	points := []TrackPoint{{Lat: 37.0000, Lon: -122.0000}, {Lat: 37.0010, Lon: -122.0000}}
+	sim := createReplaySimulator(points, false)
+	sim.Config.ReplayUseOriginalTime = true
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if got := sim.fixTime(clock.Now()); !got.Equal(clock.Now()) {
+		t.Errorf("Expected the clock time %v, got %v", clock.Now(), got)
+	}
+}
+
+func TestValidateReplayUseOriginalTime(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.ReplayUseOriginalTime = true
+	if err := config.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	config.SimulatedDate = "2024-12-31T23:59:58Z"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for original replay time with a simulated date")
+	}
+}
@@ -0,0 +1,130 @@
+package gps
+
+import "sync"
+
+// Reasons Run stopped, passed to OnComplete callbacks
+const (
+	CompletionReplay   = "replay_complete"  // A non-looping replay reached the end of the track
+	CompletionRoute    = "route_complete"   // A non-looping route reached its last waypoint
+	CompletionDuration = "duration_elapsed" // Config.Duration elapsed
+	CompletionStopped  = "stopped"          // Stop was called
+)
+
+// runState tracks whether Run is active and how it last finished
+type runState struct {
+	mu         sync.Mutex
+	running    bool
+	reason     string        // Why the last Run finished (empty until then)
+	stop       chan struct{} // Closed by Stop (nil until first needed)
+	stopped    bool
+	done       chan struct{} // Closed when Run first finishes (nil until first needed)
+	finished   bool
+	onComplete []func(reason string)
+}
+
+// OnComplete registers fn to be called once each time Run returns, with the
+// reason it stopped: CompletionReplay, CompletionRoute, CompletionDuration
+// or CompletionStopped. Callbacks run on the Run goroutine after outputs
+// are closed and IsRunning reports false.
+func (s *GPSSimulator) OnComplete(fn func(reason string)) {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	s.run.onComplete = append(s.run.onComplete, fn)
+}
+
+// OnReplayComplete registers fn to be called when Run returns because a
+// non-looping replay reached the end of the track, for chaining scenarios.
+// It runs on the Run goroutine like OnComplete callbacks.
+func (s *GPSSimulator) OnReplayComplete(fn func()) {
+	s.OnComplete(func(reason string) {
+		if reason == CompletionReplay {
+			fn()
+		}
+	})
+}
+
+// Done returns a channel that is closed the first time Run returns, whether
+// the replay or route completed, Duration elapsed or Stop was called. It is
+// closed after the OnComplete callbacks have run.
+func (s *GPSSimulator) Done() <-chan struct{} {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	if s.run.done == nil {
+		s.run.done = make(chan struct{})
+		if s.run.finished {
+			close(s.run.done)
+		}
+	}
+	return s.run.done
+}
+
+// Stop ends Run, which closes the outputs and reports CompletionStopped.
+// Stopping is permanent: Run returns immediately if called again. It is
+// safe to call from any goroutine, more than once.
+func (s *GPSSimulator) Stop() {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	if s.run.stop == nil {
+		s.run.stop = make(chan struct{})
+	}
+	if !s.run.stopped {
+		close(s.run.stop)
+		s.run.stopped = true
+	}
+}
+
+// IsRunning reports whether Run is active
+func (s *GPSSimulator) IsRunning() bool {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	return s.run.running
+}
+
+// CompletionReason returns why the last Run finished, or an empty string if
+// Run has not finished yet
+func (s *GPSSimulator) CompletionReason() string {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	return s.run.reason
+}
+
+// startRun marks Run as active and returns the channel closed by Stop
+func (s *GPSSimulator) startRun() <-chan struct{} {
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	if s.run.stop == nil {
+		s.run.stop = make(chan struct{})
+	}
+	s.run.running = true
+	s.run.reason = ""
+	return s.run.stop
+}
+
+// finishRun records why Run finished and notifies the event and OnComplete
+// callbacks
+func (s *GPSSimulator) finishRun(reason string) {
+	s.run.mu.Lock()
+	s.run.running = false
+	s.run.reason = reason
+	callbacks := s.run.onComplete
+	s.run.mu.Unlock()
+
+	if reason == CompletionReplay {
+		s.mu.RLock()
+		event := s.newEvent(EventReplayComplete)
+		s.mu.RUnlock()
+		s.dispatchEvent(event)
+	}
+	for _, fn := range callbacks {
+		fn(reason)
+	}
+
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	if !s.run.finished {
+		s.run.finished = true
+		if s.run.done != nil {
+			close(s.run.done)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gps
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runUntilComplete runs sim in the background and returns the completion
+// reasons reported once Run has returned
+func runUntilComplete(t *testing.T, sim *GPSSimulator, whileRunning func()) []string {
+	var mu sync.Mutex
+	var reasons []string
+	sim.OnComplete(func(reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		reasons = append(reasons, reason)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+
+	// Wait for Run to start
+	deadline := time.Now().Add(time.Second)
+	for !sim.IsRunning() {
+		select {
+		case <-done:
+			t.Fatal("Run returned before it was seen running")
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Run did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if whileRunning != nil {
+		whileRunning()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not complete")
+	}
+
+	if sim.IsRunning() {
+		t.Error("Expected IsRunning to be false after Run returned")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return reasons
+}
+
+func TestOnCompleteReplay(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "short.gpx")
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="37.774900" lon="-122.419400"><ele>50.0</ele></trkpt>
+    <trkpt lat="37.775000" lon="-122.419300"><ele>52.0</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 20.0
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	reasons := runUntilComplete(t, sim, nil)
+	if len(reasons) != 1 || reasons[0] != CompletionReplay {
+		t.Errorf("Expected one %q completion, got %v", CompletionReplay, reasons)
+	}
+	if sim.CompletionReason() != CompletionReplay {
+		t.Errorf("Expected completion reason %q, got %q", CompletionReplay, sim.CompletionReason())
+	}
+}
+
+func TestOnReplayComplete(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "short.gpx")
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="37.774900" lon="-122.419400"><ele>50.0</ele></trkpt>
+    <trkpt lat="37.775000" lon="-122.419300"><ele>52.0</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 20.0
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	var mu sync.Mutex
+	fired := 0
+	sim.OnReplayComplete(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		fired++
+	})
+	done := sim.Done()
+
+	runUntilComplete(t, sim, nil)
+	select {
+	case <-done:
+	default:
+		t.Error("Expected Done to be closed after the replay completed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fired != 1 {
+		t.Errorf("Expected the replay complete callback to fire once, fired %d times", fired)
+	}
+}
+
+func TestDoneClosedOnce(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	replayFired := false
+	sim.OnReplayComplete(func() { replayFired = true })
+
+	sim.Stop()
+	sim.Run()
+	sim.Run() // Finishing again must not close Done twice
+
+	// Done is closed even when first asked for after Run returned
+	select {
+	case <-sim.Done():
+	default:
+		t.Error("Expected Done to be closed after Run returned")
+	}
+	if replayFired {
+		t.Error("Expected no replay complete callback when stopped")
+	}
+}
+
+func TestOnCompleteDuration(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.Duration = 50 * time.Millisecond
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	reasons := runUntilComplete(t, sim, nil)
+	if len(reasons) != 1 || reasons[0] != CompletionDuration {
+		t.Errorf("Expected one %q completion, got %v", CompletionDuration, reasons)
+	}
+}
+
+func TestStop(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	if sim.CompletionReason() != "" {
+		t.Errorf("Expected no completion reason before Run, got %q", sim.CompletionReason())
+	}
+
+	reasons := runUntilComplete(t, sim, func() {
+		sim.Stop()
+		sim.Stop() // Stopping twice must be safe
+	})
+	if len(reasons) != 1 || reasons[0] != CompletionStopped {
+		t.Errorf("Expected one %q completion, got %v", CompletionStopped, reasons)
+	}
+	if sim.CompletionReason() != CompletionStopped {
+		t.Errorf("Expected completion reason %q, got %q", CompletionStopped, sim.CompletionReason())
+	}
+}
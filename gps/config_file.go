@@ -0,0 +1,223 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadConfigFile reads a YAML or JSON configuration file over base and
+// returns the result along with the keys that match no Config field, sorted,
+// so callers can warn about them. Keys are Config field names, matched
+// case-insensitively as in recorded scenarios. Durations accept strings such
+// as "30s", and string lists accept a comma-separated string such as
+// "GGA, RMC". Files ending in .json, or starting with {, are read as JSON,
+// others as YAML. The result is not validated.
+func LoadConfigFile(filename string, base Config) (Config, []string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return base, nil, err
+	}
+
+	var values map[string]any
+	if strings.EqualFold(filepath.Ext(filename), ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return base, nil, fmt.Errorf("invalid config file %s: %v", filename, err)
+	}
+
+	config, unknown, err := applyConfigValues(base, values)
+	if err != nil {
+		return base, nil, fmt.Errorf("invalid config file %s: %v", filename, err)
+	}
+	return config, unknown, nil
+}
+
+// configFields returns the Config fields that can be set from a file, by
+// lowercase name
+func configFields() map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			fields[strings.ToLower(field.Name)] = field
+		}
+	}
+	return fields
+}
+
+// applyConfigValues sets the Config fields named by the keys of values over
+// base, replacing their values, and returns the unknown keys
+func applyConfigValues(base Config, values map[string]any) (Config, []string, error) {
+	fields := configFields()
+	config := reflect.ValueOf(&base).Elem()
+	set := make(map[string]string)
+	var unknown []string
+
+	// Go through the keys in order so errors are reproducible
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		if previous, ok := set[field.Name]; ok {
+			return base, nil, fmt.Errorf("%s and %s both set %s", previous, key, field.Name)
+		}
+		set[field.Name] = key
+
+		converted, err := convertConfigValue(values[key], field.Type)
+		if err != nil {
+			return base, nil, fmt.Errorf("%s: %v", key, err)
+		}
+		data, err := json.Marshal(converted)
+		if err != nil {
+			return base, nil, fmt.Errorf("%s: %v", key, err)
+		}
+		value := reflect.New(field.Type)
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return base, nil, fmt.Errorf("%s: expected %s, got %s", key, describeConfigType(field.Type), data)
+		}
+		config.FieldByIndex(field.Index).Set(value.Elem())
+	}
+	return base, unknown, nil
+}
+
+// convertConfigValue converts duration strings in value to nanoseconds when
+// the field is a time.Duration or a map of them, and splits a
+// comma-separated string for a list of strings
+func convertConfigValue(value any, t reflect.Type) (any, error) {
+	switch {
+	case t == durationType:
+		return parseConfigDuration(value)
+	case t.Kind() == reflect.Map && t.Elem() == durationType:
+		entries, ok := value.(map[string]any)
+		if !ok {
+			return value, nil
+		}
+		converted := make(map[string]any, len(entries))
+		for key, entry := range entries {
+			d, err := parseConfigDuration(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			converted[key] = d
+		}
+		return converted, nil
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		items := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	return value, nil
+}
+
+// parseConfigDuration converts a duration string such as "30s" to
+// nanoseconds, leaving numbers, already in nanoseconds, as they are
+func parseConfigDuration(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q", s)
+	}
+	return int64(d), nil
+}
+
+// describeConfigType describes the values a Config field of type t accepts,
+// for errors
+func describeConfigType(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "a duration such as 30s"
+	case t.Kind() == reflect.Map && t.Elem() == durationType:
+		return "a mapping of names to durations"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return "a list or comma-separated string"
+		}
+		return "a list"
+	case reflect.Map:
+		return "a mapping"
+	case reflect.Pointer:
+		return describeConfigType(t.Elem())
+	}
+	return t.String()
+}
+
+// MarshalConfigYAML returns c as a YAML configuration file readable by
+// LoadConfigFile, with every field in Config order and durations written as
+// strings such as "30s"
+func MarshalConfigYAML(c Config) ([]byte, error) {
+	var out bytes.Buffer
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		var value any = v.Field(i).Interface()
+		switch d := value.(type) {
+		case time.Duration:
+			value = d.String()
+		case map[string]time.Duration:
+			if d != nil {
+				durations := make(map[string]string, len(d))
+				for key, interval := range d {
+					durations[key] = interval.String()
+				}
+				value = durations
+			}
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %v", field.Name, err)
+		}
+		fmt.Fprintf(&out, "%s: %s\n", field.Name, data)
+	}
+	return out.Bytes(), nil
+}
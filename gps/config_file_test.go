@@ -0,0 +1,205 @@
+package gps
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes content to a file with the given name in a
+// temporary directory and returns its path
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "setup.yaml", `# Reproducible test setup
+---
+Latitude: 51.5074
+longitude: -0.1278   # keys match case-insensitively
+Satellites: 10
+OutputRate: 500ms
+Duration: "2m"
+Quiet: true
+TalkerID: 'GN'
+SkyCondition: urban # trailing comment
+Constellations: [GPS, GLONASS]
+Sentences:
+  - GGA
+  - "RMC"
+SentenceIntervals: {"GSV": "5s", "GSA": "10s"}
+Waypoints: [{"Lat": 51.5, "Lon": -0.1}]
+Colour: blue
+Mystery: 1
+`)
+
+	base := createTestConfig()
+	config, unknown, err := LoadConfigFile(path, base)
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+
+	if config.Latitude != 51.5074 || config.Longitude != -0.1278 || config.Satellites != 10 {
+		t.Errorf("Expected position and satellites from the file, got %.4f, %.4f, %d", config.Latitude, config.Longitude, config.Satellites)
+	}
+	if config.OutputRate != 500*time.Millisecond || config.Duration != 2*time.Minute {
+		t.Errorf("Expected durations 500ms and 2m, got %v and %v", config.OutputRate, config.Duration)
+	}
+	if !config.Quiet || config.TalkerID != "GN" || config.SkyCondition != SkyUrban {
+		t.Errorf("Expected Quiet, GN and urban, got %v, %q and %q", config.Quiet, config.TalkerID, config.SkyCondition)
+	}
+	if !reflect.DeepEqual(config.Constellations, []string{"GPS", "GLONASS"}) || !reflect.DeepEqual(config.Sentences, []string{"GGA", "RMC"}) {
+		t.Errorf("Expected flow and block lists, got %v and %v", config.Constellations, config.Sentences)
+	}
+	if config.SentenceIntervals["GSV"] != 5*time.Second || config.SentenceIntervals["GSA"] != 10*time.Second {
+		t.Errorf("Expected sentence intervals parsed as durations, got %v", config.SentenceIntervals)
+	}
+	if len(config.Waypoints) != 1 || config.Waypoints[0] != (Coordinate{Lat: 51.5, Lon: -0.1}) {
+		t.Errorf("Expected one waypoint, got %v", config.Waypoints)
+	}
+	// Settings missing from the file keep the base values
+	if config.Radius != base.Radius || config.TimeToLock != base.TimeToLock {
+		t.Errorf("Expected base radius and lock time kept, got %.1f and %v", config.Radius, config.TimeToLock)
+	}
+	if !reflect.DeepEqual(unknown, []string{"Colour", "Mystery"}) {
+		t.Errorf("Expected unknown keys Colour and Mystery, got %v", unknown)
+	}
+}
+
+func TestLoadConfigFileYAMLBlocks(t *testing.T) {
+	path := writeConfigFile(t, "setup.yaml", `SentenceRates:
+  GSV: 5
+  GSA: 2
+SentenceIntervals:
+  ZDA: 10s
+Waypoints:
+  - Lat: 51.5
+    Lon: -0.1
+  - {Lat: 51.6, Lon: -0.2}
+Sentences: GGA, RMC ,GSV
+Constellations: GPS
+MinSNR: 0
+`)
+
+	config, _, err := LoadConfigFile(path, createTestConfig())
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(config.SentenceRates, map[string]int{"GSV": 5, "GSA": 2}) {
+		t.Errorf("Expected rates from a block mapping, got %v", config.SentenceRates)
+	}
+	if config.SentenceIntervals["ZDA"] != 10*time.Second {
+		t.Errorf("Expected a 10s ZDA interval, got %v", config.SentenceIntervals)
+	}
+	if !reflect.DeepEqual(config.Waypoints, []Coordinate{{Lat: 51.5, Lon: -0.1}, {Lat: 51.6, Lon: -0.2}}) {
+		t.Errorf("Expected two waypoints, got %v", config.Waypoints)
+	}
+	if !reflect.DeepEqual(config.Sentences, []string{"GGA", "RMC", "GSV"}) || !reflect.DeepEqual(config.Constellations, []string{"GPS"}) {
+		t.Errorf("Expected lists split from comma-separated strings, got %v and %v", config.Sentences, config.Constellations)
+	}
+	if config.MinSNR == nil || *config.MinSNR != 0 || config.MaxSNR != nil {
+		t.Errorf("Expected an explicit minimum SNR of 0 and no maximum, got %v and %v", config.MinSNR, config.MaxSNR)
+	}
+}
+
+func TestLoadConfigFileTypeErrors(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"Satellites: many\n", "Satellites: expected a whole number"},
+		{"Quiet: sometimes\n", "Quiet: expected true or false"},
+		{"Sentences: {GGA: 1}\n", "Sentences: expected a list or comma-separated string"},
+		{"SentenceRates: [GSV]\n", "SentenceRates: expected a mapping"},
+	}
+	for _, tt := range tests {
+		path := writeConfigFile(t, "bad.yaml", tt.content)
+		_, _, err := LoadConfigFile(path, createTestConfig())
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected an error containing %q for %q, got %v", tt.expected, tt.content, err)
+		}
+	}
+}
+
+func TestLoadConfigFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "setup.json", `{"Speed": 12.5, "TimeToLock": "45s", "DropoutInterval": 60000000000, "Extra": true}`)
+
+	config, unknown, err := LoadConfigFile(path, createTestConfig())
+	if err != nil {
+		t.Fatalf("LoadConfigFile failed: %v", err)
+	}
+	if config.Speed != 12.5 || config.TimeToLock != 45*time.Second || config.DropoutInterval != time.Minute {
+		t.Errorf("Expected speed 12.5, 45s lock and 1m dropouts, got %.1f, %v and %v", config.Speed, config.TimeToLock, config.DropoutInterval)
+	}
+	if !reflect.DeepEqual(unknown, []string{"Extra"}) {
+		t.Errorf("Expected unknown key Extra, got %v", unknown)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"Malformed JSON", "bad.json", `{"Speed": `},
+		{"Missing colon", "bad.yaml", "Speed 12\n"},
+		{"Mapping for a list", "bad.yaml", "Waypoints:\n  Lat: 1\n"},
+		{"List without key", "bad.yaml", "- GGA\n"},
+		{"Duplicate key", "bad.yaml", "Speed: 1\nSpeed: 2\n"},
+		{"Duplicate key in another case", "bad.yaml", "Speed: 1\nspeed: 2\n"},
+		{"Bad duration", "bad.yaml", "OutputRate: fast\n"},
+		{"Wrong type", "bad.yaml", "Satellites: many\n"},
+		{"Unterminated string", "bad.yaml", "TalkerID: \"GP\n"},
+		{"Unterminated flow value", "bad.yaml", "Waypoints: [{Lat: 1}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+			if _, _, err := LoadConfigFile(path, createTestConfig()); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if _, _, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), createTestConfig()); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestMarshalConfigYAMLRoundTrip(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.TalkerID = "GN"
+	config.Constellations = []string{"GPS", "Galileo"}
+	config.SentenceIntervals = map[string]time.Duration{"GSV": 5 * time.Second}
+	config.Waypoints = []Coordinate{{Lat: 1, Lon: 2}, {Lat: 3, Lon: 4}}
+	config.Geofences = []Geofence{{Name: "yard", Lat: 1, Lon: 2, Radius: 30}}
+	config.DropoutInterval = 90 * time.Second
+
+	data, err := MarshalConfigYAML(config)
+	if err != nil {
+		t.Fatalf("MarshalConfigYAML failed: %v", err)
+	}
+	path := writeConfigFile(t, "dump.yaml", string(data))
+
+	loaded, unknown, err := LoadConfigFile(path, Config{})
+	if err != nil {
+		t.Fatalf("Failed to load dumped config: %v\n%s", err, data)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("Expected no unknown keys in a dump, got %v", unknown)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("Expected the dumped config to load back unchanged:\n%+v\n%+v", loaded, config)
+	}
+}
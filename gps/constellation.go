@@ -0,0 +1,156 @@
+package gps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constellation identifies a GNSS satellite system
+type Constellation int
+
+const (
+	ConstellationGPS Constellation = iota
+	ConstellationGLONASS
+	ConstellationGalileo
+	ConstellationBeiDou
+)
+
+// MultiConstellationTalkerID is the talker ID used for combined multi-constellation fixes
+const MultiConstellationTalkerID = "GN"
+
+// String returns the display name of the constellation
+func (c Constellation) String() string {
+	switch c {
+	case ConstellationGLONASS:
+		return "GLONASS"
+	case ConstellationGalileo:
+		return "Galileo"
+	case ConstellationBeiDou:
+		return "BeiDou"
+	default:
+		return "GPS"
+	}
+}
+
+// TalkerID returns the NMEA talker ID used for the constellation's own sentences
+func (c Constellation) TalkerID() string {
+	switch c {
+	case ConstellationGLONASS:
+		return "GL"
+	case ConstellationGalileo:
+		return "GA"
+	case ConstellationBeiDou:
+		return "GB"
+	default:
+		return "GP"
+	}
+}
+
+// SystemID returns the NMEA 4.1 GNSS system ID reported in GSA and GSV
+func (c Constellation) SystemID() int {
+	switch c {
+	case ConstellationGLONASS:
+		return 2
+	case ConstellationGalileo:
+		return 3
+	case ConstellationBeiDou:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// SignalID returns the NMEA 4.1 signal ID of the constellation's primary
+// civil signal (L1 C/A, G1 C/A, E1 or B1I), reported in GSV
+func (c Constellation) SignalID() int {
+	if c == ConstellationGalileo {
+		return 7
+	}
+	return 1
+}
+
+// PRNRange returns the first and last satellite IDs used by the constellation
+func (c Constellation) PRNRange() (int, int) {
+	switch c {
+	case ConstellationGLONASS:
+		return 65, 96
+	case ConstellationGalileo:
+		return 1, 36
+	case ConstellationBeiDou:
+		return 1, 63
+	default:
+		return 1, 32
+	}
+}
+
+// ParseConstellation converts a constellation name (case-insensitive) to a Constellation
+func ParseConstellation(name string) (Constellation, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "GPS":
+		return ConstellationGPS, nil
+	case "GLONASS":
+		return ConstellationGLONASS, nil
+	case "GALILEO":
+		return ConstellationGalileo, nil
+	case "BEIDOU":
+		return ConstellationBeiDou, nil
+	default:
+		return ConstellationGPS, fmt.Errorf("unknown constellation %q (valid: GPS, GLONASS, Galileo, BeiDou)", name)
+	}
+}
+
+// parseConstellations converts constellation names to Constellations, rejecting unknown or duplicate names
+func parseConstellations(names []string) ([]Constellation, error) {
+	var constellations []Constellation
+	seen := make(map[Constellation]bool)
+	for _, name := range names {
+		c, err := ParseConstellation(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("duplicate constellation %q", name)
+		}
+		seen[c] = true
+		constellations = append(constellations, c)
+	}
+	return constellations, nil
+}
+
+// constellations returns the enabled constellations, defaulting to GPS only
+func (s *GPSSimulator) constellations() []Constellation {
+	// GPS only is the common case, checked for every sentence
+	if len(s.Config.Constellations) == 0 {
+		return gpsOnly
+	}
+	constellations, err := parseConstellations(s.Config.Constellations)
+	if err != nil || len(constellations) == 0 {
+		return gpsOnly
+	}
+	return constellations
+}
+
+// gpsOnly is the default constellation list. It is shared, so callers must
+// not modify the slice returned by constellations.
+var gpsOnly = []Constellation{ConstellationGPS}
+
+// isMultiConstellation reports whether more than one constellation is enabled
+func (s *GPSSimulator) isMultiConstellation() bool {
+	return len(s.constellations()) > 1
+}
+
+// satellitesByConstellation returns the simulated satellites belonging to the given constellation
+func (s *GPSSimulator) satellitesByConstellation(c Constellation) []Satellite {
+	return filterConstellation(s.Satellites, c)
+}
+
+// filterConstellation returns the satellites belonging to the given constellation
+func filterConstellation(sats []Satellite, c Constellation) []Satellite {
+	var filtered []Satellite
+	for _, sat := range sats {
+		if sat.Constellation == c {
+			filtered = append(filtered, sat)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,260 @@
+package gps
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConstellation(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Constellation
+		shouldError bool
+	}{
+		{"GPS", "GPS", ConstellationGPS, false},
+		{"GLONASS lowercase", "glonass", ConstellationGLONASS, false},
+		{"Galileo mixed case", "Galileo", ConstellationGalileo, false},
+		{"BeiDou with spaces", " BeiDou ", ConstellationBeiDou, false},
+		{"Unknown", "QZSS", ConstellationGPS, true},
+		{"Empty", "", ConstellationGPS, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseConstellation(tt.input)
+			if (err != nil) != tt.shouldError {
+				t.Fatalf("Expected error: %v, got: %v", tt.shouldError, err)
+			}
+			if !tt.shouldError && result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestConstellationValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.Constellations = []string{"GPS", "GLONASS"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid constellations, got: %v", err)
+	}
+
+	config.Constellations = []string{"GPS", "Unknown"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown constellation")
+	}
+
+	config.Constellations = []string{"GPS", "gps"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for duplicate constellation")
+	}
+}
+
+func TestDefaultConstellationIsGPS(t *testing.T) {
+	config := createTestConfig()
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	for i, sat := range sim.Satellites {
+		if sat.Constellation != ConstellationGPS {
+			t.Errorf("Satellite %d: expected GPS, got %v", i, sat.Constellation)
+		}
+		if sat.ID != i+1 {
+			t.Errorf("Satellite %d: expected ID %d, got %d", i, i+1, sat.ID)
+		}
+	}
+
+	if sim.talkerID() != "GP" {
+		t.Errorf("Expected GP talker, got %s", sim.talkerID())
+	}
+}
+
+func TestMultiConstellationTalkerIDs(t *testing.T) {
+	config := createTestConfig()
+	config.Satellites = 12
+	config.Constellations = []string{"GPS", "GLONASS", "Galileo"}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+	positionSentences := []string{
+		sim.generateGGA(testTime),
+		sim.generateRMC(testTime),
+		sim.generateGLL(testTime),
+		sim.generateVTG(),
+		sim.generateZDA(testTime),
+	}
+	for _, sentence := range positionSentences {
+		if !strings.HasPrefix(sentence, "$GN") {
+			t.Errorf("Expected GN talker for multi-constellation position sentence, got: %s", sentence)
+		}
+	}
+
+	// An explicit talker ID still wins over the derived GN talker
+	sim.Config.TalkerID = "GP"
+	if !strings.HasPrefix(sim.generateGGA(testTime), "$GPGGA") {
+		t.Error("Explicit TalkerID should override multi-constellation talker")
+	}
+
+	// A single non-GPS constellation uses its own talker
+	sim.Config.TalkerID = ""
+	sim.Config.Constellations = []string{"GLONASS"}
+	if !strings.HasPrefix(sim.generateGGA(testTime), "$GLGGA") {
+		t.Error("Single GLONASS constellation should use GL talker")
+	}
+}
+
+func TestMultiConstellationPRNRanges(t *testing.T) {
+	config := createTestConfig()
+	config.Satellites = 12
+	config.Constellations = []string{"GPS", "GLONASS", "Galileo", "BeiDou"}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	counts := make(map[Constellation]int)
+	for _, sat := range sim.Satellites {
+		first, last := sat.Constellation.PRNRange()
+		if sat.ID < first || sat.ID > last {
+			t.Errorf("%v satellite PRN %d outside range %d-%d", sat.Constellation, sat.ID, first, last)
+		}
+		counts[sat.Constellation]++
+	}
+
+	// 12 satellites split evenly across 4 constellations
+	for _, c := range []Constellation{ConstellationGPS, ConstellationGLONASS, ConstellationGalileo, ConstellationBeiDou} {
+		if counts[c] != 3 {
+			t.Errorf("Expected 3 %v satellites, got %d", c, counts[c])
+		}
+	}
+}
+
+func TestMultiConstellationGSVGrouping(t *testing.T) {
+	config := createTestConfig()
+	config.Satellites = 11
+	config.Constellations = []string{"GPS", "GLONASS"}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	sentences := sim.generateGSV()
+
+	// 6 GPS satellites (2 sentences) followed by 5 GLONASS satellites (2 sentences)
+	expectedPrefixes := []string{"$GPGSV,2,1,06", "$GPGSV,2,2,06", "$GLGSV,2,1,05", "$GLGSV,2,2,05"}
+	if len(sentences) != len(expectedPrefixes) {
+		t.Fatalf("Expected %d GSV sentences, got %d: %v", len(expectedPrefixes), len(sentences), sentences)
+	}
+	for i, prefix := range expectedPrefixes {
+		if !strings.HasPrefix(sentences[i], prefix) {
+			t.Errorf("GSV sentence %d: expected prefix %s, got %s", i, prefix, sentences[i])
+		}
+		parts := strings.Split(strings.TrimSuffix(sentences[i], "\r\n"), "*")
+		if calculateChecksum(parts[0]) != parts[1] {
+			t.Errorf("Invalid checksum for sentence: %s", sentences[i])
+		}
+	}
+
+	// GLONASS satellites must carry GLONASS PRNs
+	glonassFields := strings.Split(sentences[2], ",")
+	if glonassFields[4] != "65" {
+		t.Errorf("Expected first GLONASS PRN 65, got %s", glonassFields[4])
+	}
+}
+
+func TestMultiConstellationGSA(t *testing.T) {
+	config := createTestConfig()
+	config.Satellites = 10
+	config.Constellations = []string{"GPS", "GLONASS", "BeiDou"}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	sentences := sim.generateGSA()
+	if len(sentences) != 3 {
+		t.Fatalf("Expected one GSA per constellation (3), got %d", len(sentences))
+	}
+
+	// 10 satellites round-robin: 4 GPS, 3 GLONASS, 3 BeiDou
+	expected := []struct {
+		constellation Constellation
+		count         int
+	}{
+		{ConstellationGPS, 4},
+		{ConstellationGLONASS, 3},
+		{ConstellationBeiDou, 3},
+	}
+
+	for i, sentence := range sentences {
+		if !strings.HasPrefix(sentence, "$GNGSA,") {
+			t.Errorf("GSA %d: expected GN talker, got %s", i, sentence)
+		}
+
+		parts := strings.Split(strings.Split(sentence, "*")[0], ",")
+		var ids []string
+		for j := 3; j < 15; j++ {
+			if parts[j] != "" {
+				ids = append(ids, parts[j])
+			}
+		}
+		if len(ids) != expected[i].count {
+			t.Errorf("GSA %d: expected %d satellites, got %d", i, expected[i].count, len(ids))
+		}
+
+		// Every listed ID must come from this constellation's satellites
+		for _, id := range ids {
+			found := false
+			for _, sat := range sim.satellitesByConstellation(expected[i].constellation) {
+				if id == fmt.Sprintf("%02d", sat.ID) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("GSA %d: satellite %s does not belong to %v", i, id, expected[i].constellation)
+			}
+		}
+	}
+}
+
+func TestMultiConstellationOutputNMEA(t *testing.T) {
+	config := createTestConfig()
+	config.Satellites = 8
+	config.Constellations = []string{"GPS", "Galileo"}
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+
+	sim.outputNMEA()
+	output := buffer.String()
+
+	for _, prefix := range []string{"$GNGGA", "$GNRMC", "$GNGSA", "$GPGSV,1,1,04", "$GAGSV,1,1,04"} {
+		if !strings.Contains(output, prefix) {
+			t.Errorf("Expected %s in multi-constellation output", prefix)
+		}
+	}
+	if strings.Count(output, "$GNGSA") != 2 {
+		t.Errorf("Expected 2 GSA sentences, got %d", strings.Count(output, "$GNGSA"))
+	}
+}
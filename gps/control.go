@@ -0,0 +1,370 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Pause freezes the simulation: no position updates or sentences are emitted
+// and replay progression stops, while outputs such as the GPX writer, TCP
+// clients and subscriptions stay open. Pausing an already paused simulator
+// has no effect.
+func (s *GPSSimulator) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+	s.paused = true
+	s.pausedAt = s.now()
+}
+
+// Resume continues a paused simulation from where it left off. The simulation
+// clocks are shifted by the paused duration so replay does not skip track
+// points and lock acquisition does not advance while paused.
+func (s *GPSSimulator) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.paused {
+		return
+	}
+	pausedFor := s.now().Sub(s.pausedAt)
+
+	s.replayStartTime = s.replayStartTime.Add(pausedFor)
+	s.lastUpdateTime = s.lastUpdateTime.Add(pausedFor)
+	s.altitudeTime = s.altitudeTime.Add(pausedFor)
+	s.startTime = s.startTime.Add(pausedFor)
+	if !s.isLocked {
+		s.lockTime = s.lockTime.Add(pausedFor)
+	}
+	if !s.dropoutAt.IsZero() {
+		s.dropoutAt = s.dropoutAt.Add(pausedFor)
+	}
+	if !s.previousCourseTime.IsZero() {
+		s.previousCourseTime = s.previousCourseTime.Add(pausedFor)
+	}
+	if !s.road.ends.IsZero() {
+		s.road.ends = s.road.ends.Add(pausedFor)
+	}
+
+	s.paused = false
+	s.pausedAt = time.Time{}
+}
+
+// IsPaused reports whether the simulation is currently paused
+func (s *GPSSimulator) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused
+}
+
+// UpdateConfig applies the live-tunable parameters of config to the running
+// simulation from the next cycle, without resetting position, lock or start
+// time. It is safe to call concurrently with Run.
+//
+// Speed, Course, Jitter, AltitudeJitter, ClimbRate, TargetAltitude,
+// TransitionDuration and OutputRate change in place, restarting the output
+// ticker for a new rate. A new ClimbRate or TargetAltitude climbs from the
+// current altitude. A new Satellites count adds or removes satellites while
+// keeping the fix. Geofences and GeofenceDwell replace the geofences,
+// which keep whether the position is inside them by name.
+//
+// Latitude, Longitude and Radius define the scenario. A new center, or a
+// radius that no longer contains the receiver, starts a transition: the
+// receiver drives straight toward the new center at Speed, or over
+// TransitionDuration when set, instead of jumping there, and a movement
+// pattern starts again around the new center and radius. A route or replay
+// keeps driving the position. Other output, route and replay settings are
+// fixed when the simulator is created and are ignored.
+//
+// With Config.RecordScenario set, each update is saved to the scenario file
+// with its time so playback can apply it again.
+func (s *GPSSimulator) UpdateConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.OutputRate <= 0 {
+		return errors.New("Output rate must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.applyConfigUpdate(config)
+	s.recordScenarioUpdate(config)
+	return nil
+}
+
+// applyConfigUpdate applies the live-tunable parameters of a validated
+// config, as described on UpdateConfig
+func (s *GPSSimulator) applyConfigUpdate(config Config) {
+	s.Config.Speed = config.Speed
+	s.Config.Course = config.Course
+	s.Config.Jitter = config.Jitter
+	s.Config.AltitudeJitter = config.AltitudeJitter
+	if config.ClimbRate != s.Config.ClimbRate || config.TargetAltitude != s.Config.TargetAltitude {
+		// Start the new climb from the reported altitude, not from where the
+		// configured altitude or an earlier climb left the ramp
+		s.altitudeBase = s.currentAlt
+	}
+	s.Config.ClimbRate = config.ClimbRate
+	s.Config.TargetAltitude = config.TargetAltitude
+	previousRadius := s.Config.Radius
+	s.Config.Radius = config.Radius
+	s.Config.TransitionDuration = config.TransitionDuration
+	s.Config.Geofences = config.Geofences
+	s.Config.GeofenceDwell = config.GeofenceDwell
+
+	recenter := config.Latitude != s.Config.Latitude || config.Longitude != s.Config.Longitude
+	s.Config.Latitude = config.Latitude
+	s.Config.Longitude = config.Longitude
+	if config.Radius > 0 && s.distanceFromCenter(s.currentLat, s.currentLon) > config.Radius {
+		recenter = true
+	}
+	if recenter && !s.isRouteMode() && len(s.replayPoints) == 0 {
+		s.startTransition(Coordinate{Lat: config.Latitude, Lon: config.Longitude})
+	}
+	if recenter || config.Radius != previousRadius {
+		// Rebuild the movement pattern around the new center and radius
+		s.pattern = nil
+	}
+
+	if config.OutputRate != s.Config.OutputRate {
+		s.Config.OutputRate = config.OutputRate
+		// Replace any rate change Run has not picked up yet
+		select {
+		case <-s.rateChange:
+		default:
+		}
+		select {
+		case s.rateChange <- config.OutputRate:
+		default:
+		}
+	}
+
+	if config.Satellites != s.Config.Satellites {
+		s.Config.Satellites = config.Satellites
+		s.resizeSatellites(config.Satellites)
+	}
+
+	s.notifyEvent(EventConfigUpdate)
+}
+
+// tick runs one simulation cycle unless the simulator is paused
+func (s *GPSSimulator) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		s.ticks.last = time.Time{}
+		return
+	}
+	s.ticks.record(s.now(), s.Config.OutputRate)
+	s.update()
+	s.output()
+	s.checkThroughput()
+	s.updateTracks()
+}
+
+// step runs one integration step between output cycles unless the simulator
+// is paused
+func (s *GPSSimulator) step() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return
+	}
+	now := s.now()
+	s.betweenOutputs = true
+	s.integrate(now)
+	s.betweenOutputs = false
+	s.fix = s.currentFix(now)
+}
+
+// integratesBetweenOutputs reports whether Config.UpdateRate asks for
+// integration steps between output cycles
+func (s *GPSSimulator) integratesBetweenOutputs() bool {
+	return s.Config.UpdateRate > 0 && s.Config.UpdateRate < s.Config.OutputRate
+}
+
+// SeekToIndex moves GPX replay to the track point at index i so the next
+// update resumes from there. It returns an error when no replay is loaded or
+// the index is out of range.
+func (s *GPSSimulator) SeekToIndex(i int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.replayPoints) == 0 {
+		return errors.New("no GPX replay is loaded")
+	}
+	if i < 0 || i >= len(s.replayPoints) {
+		return fmt.Errorf("replay index %d out of range (0-%d)", i, len(s.replayPoints)-1)
+	}
+
+	offset := time.Duration(i) * time.Second
+	if s.hasSequentialTimestamps() {
+		offset = s.replayPoints[i].Time.Sub(s.replayPoints[0].Time)
+	}
+	s.seekReplay(i, offset)
+	return nil
+}
+
+// SeekToTime moves GPX replay to the given offset into the track. With
+// sequential timestamps the offset is measured from the first point's time;
+// otherwise points are one second apart, as in index-based replay.
+func (s *GPSSimulator) SeekToTime(offset time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.replayPoints) == 0 {
+		return errors.New("no GPX replay is loaded")
+	}
+	if offset < 0 {
+		return fmt.Errorf("replay offset %v must not be negative", offset)
+	}
+
+	if !s.hasSequentialTimestamps() {
+		index := int(offset / time.Second)
+		if index >= len(s.replayPoints) {
+			return fmt.Errorf("replay offset %v is past the end of the track (%v)", offset, time.Duration(len(s.replayPoints)-1)*time.Second)
+		}
+		s.seekReplay(index, offset)
+		return nil
+	}
+
+	if offset > s.replayDuration() {
+		return fmt.Errorf("replay offset %v is past the end of the track (%v)", offset, s.replayDuration())
+	}
+	s.seekReplayOffset(offset)
+	return nil
+}
+
+// SeekReplay moves GPX replay to a fraction of the way through the track,
+// from 0 at the first point to 1 at the last. Fractions outside that range
+// are clamped, so seeking past the end lands on the last point and the next
+// update completes or loops the replay.
+func (s *GPSSimulator) SeekReplay(fraction float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.replayPoints) == 0 {
+		return errors.New("no GPX replay is loaded")
+	}
+	if math.IsNaN(fraction) {
+		return errors.New("replay fraction must be a number")
+	}
+
+	fraction = math.Max(0, math.Min(1, fraction))
+	s.seekReplayOffset(time.Duration(fraction * float64(s.replayDuration())))
+	return nil
+}
+
+// SeekReplayToTime moves GPX replay to the last track point recorded at or
+// before t. Times outside the track are clamped to its first or last point.
+// It returns an error when the track has no sequential timestamps to seek by.
+func (s *GPSSimulator) SeekReplayToTime(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.replayPoints) == 0 {
+		return errors.New("no GPX replay is loaded")
+	}
+	if !s.hasSequentialTimestamps() {
+		return errors.New("replay track has no sequential timestamps")
+	}
+
+	offset := t.Sub(s.replayPoints[0].Time)
+	if offset < 0 {
+		offset = 0
+	}
+	if duration := s.replayDuration(); offset > duration {
+		offset = duration
+	}
+	s.seekReplayOffset(offset)
+	return nil
+}
+
+// replayDuration returns the track time from the first replay point to the
+// last, with points one second apart when replay is index-based
+func (s *GPSSimulator) replayDuration() time.Duration {
+	if s.hasSequentialTimestamps() {
+		return s.replayPoints[len(s.replayPoints)-1].Time.Sub(s.replayPoints[0].Time)
+	}
+	return time.Duration(len(s.replayPoints)-1) * time.Second
+}
+
+// replayProgress returns how far replay has progressed through the current
+// track as a percentage
+func (s *GPSSimulator) replayProgress() float64 {
+	if len(s.replayPoints) == 0 {
+		return 0
+	}
+	if s.replayCompleted && !s.Config.ReplayLoop {
+		return 100
+	}
+	duration := s.replayDuration()
+	if duration <= 0 {
+		return 0
+	}
+
+	anchor := s.now()
+	if s.paused {
+		anchor = s.pausedAt
+	}
+	speed := s.Config.ReplaySpeed
+	if speed <= 0 {
+		speed = 1.0
+	}
+	elapsed := float64(anchor.Sub(s.replayStartTime)) * speed
+
+	// An untimed replay of one track loops by index without restarting its
+	// clock, showing each point for a second
+	if s.Config.ReplayLoop && s.Config.replayTracks() == 1 && !s.hasSequentialTimestamps() {
+		elapsed = math.Mod(elapsed, float64(time.Duration(len(s.replayPoints))*time.Second))
+	}
+	return math.Max(0, math.Min(100, 100*elapsed/float64(duration)))
+}
+
+// seekReplayOffset moves replay to offset into the track, which must not be
+// past its end
+func (s *GPSSimulator) seekReplayOffset(offset time.Duration) {
+	if !s.hasSequentialTimestamps() {
+		s.seekReplay(int(offset/time.Second), offset)
+		return
+	}
+
+	// Find the last track point at or before the target time
+	target := s.replayPoints[0].Time.Add(offset)
+	index := 0
+	for i, point := range s.replayPoints {
+		if point.Time.After(target) {
+			break
+		}
+		index = i
+	}
+	s.seekReplay(index, offset)
+}
+
+// seekReplay sets the replay clock so that offset of track time has elapsed at
+// the current replay speed. While paused the clock is anchored to the pause
+// time so Resume does not shift past the seek target.
+func (s *GPSSimulator) seekReplay(index int, offset time.Duration) {
+	anchor := s.now()
+	if s.paused {
+		anchor = s.pausedAt
+	}
+
+	speed := s.Config.ReplaySpeed
+	if speed <= 0 {
+		speed = 1.0
+	}
+
+	s.replayStartTime = anchor.Add(-time.Duration(float64(offset) / speed))
+	s.replayIndex = index
+	s.replayCompleted = false
+}
@@ -0,0 +1,130 @@
+package gps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Control message types accepted by HandleControlMessage
+const (
+	ControlConfig  = "config"  // Data is a partial Config applied with UpdateConfig
+	ControlCommand = "command" // Data is one of the Command constants
+	ControlStatus  = "status"  // No data; asks for the simulator state in the reply
+)
+
+// Commands carried by a ControlCommand message
+const (
+	CommandPause  = "pause"
+	CommandResume = "resume"
+	CommandStop   = "stop"
+)
+
+// controlMinInterval is the least time between config messages, so a client
+// streaming updates such as joystick moves cannot flood the simulator
+const controlMinInterval = 50 * time.Millisecond
+
+// ErrControlRateLimited is returned for a config message arriving less than
+// controlMinInterval after the last one applied
+var ErrControlRateLimited = errors.New("config updates are arriving too fast")
+
+// ControlMessage steers a running simulator from a client connection, for
+// example {"type": "config", "data": {"Speed": 20}} or
+// {"type": "command", "data": "pause"}
+type ControlMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ControlReply answers a control message on the connection it arrived on.
+// The JSON field names are stable.
+type ControlReply struct {
+	Type   string         `json:"type"`             // Type of the message answered (empty if it could not be read)
+	Error  string         `json:"error,omitempty"`  // Why the message was not applied
+	Status *StateSnapshot `json:"status,omitempty"` // Simulator state once the message was applied
+}
+
+// controlLimiter spaces out config messages
+type controlLimiter struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// allow reports whether a config message may be applied at now, recording
+// it if so
+func (l *controlLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() && now.Sub(l.last) < controlMinInterval {
+		return false
+	}
+	l.last = now
+	return true
+}
+
+// HandleControlMessage applies a JSON ControlMessage received from a client.
+// A config message's data is merged over the current configuration, so it
+// only needs the settings to change, and applied with UpdateConfig. Config
+// messages closer together than 50ms are rejected with
+// ErrControlRateLimited. A command message pauses, resumes or stops the
+// simulation, and a status message changes nothing. It is safe to call
+// concurrently with Run.
+func (s *GPSSimulator) HandleControlMessage(message []byte) error {
+	var msg ControlMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("invalid control message: %v", err)
+	}
+
+	switch msg.Type {
+	case ControlConfig:
+		if !s.control.allow(time.Now()) {
+			return ErrControlRateLimited
+		}
+		s.mu.RLock()
+		config := s.Config
+		s.mu.RUnlock()
+		if err := json.Unmarshal(msg.Data, &config); err != nil {
+			return fmt.Errorf("invalid config update: %v", err)
+		}
+		return s.UpdateConfig(config)
+	case ControlCommand:
+		var command string
+		if err := json.Unmarshal(msg.Data, &command); err != nil {
+			return fmt.Errorf("invalid command: %v", err)
+		}
+		switch command {
+		case CommandPause:
+			s.Pause()
+		case CommandResume:
+			s.Resume()
+		case CommandStop:
+			s.Stop()
+		default:
+			return fmt.Errorf("unknown command %q (valid: %s, %s, %s)", command, CommandPause, CommandResume, CommandStop)
+		}
+		return nil
+	case ControlStatus:
+		return nil
+	}
+	return fmt.Errorf("unknown control message type %q (valid: %s, %s, %s)", msg.Type, ControlConfig, ControlCommand, ControlStatus)
+}
+
+// ReplyToControlMessage applies message like HandleControlMessage and
+// returns the reply acknowledging it: the error when the message was
+// malformed or rejected, otherwise the simulator state after applying it
+func (s *GPSSimulator) ReplyToControlMessage(message []byte) ControlReply {
+	var reply ControlReply
+	var msg ControlMessage
+	if json.Unmarshal(message, &msg) == nil {
+		reply.Type = msg.Type
+	}
+	if err := s.HandleControlMessage(message); err != nil {
+		reply.Error = err.Error()
+		return reply
+	}
+	status := s.Snapshot()
+	reply.Status = &status
+	return reply
+}
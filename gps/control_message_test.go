@@ -0,0 +1,147 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleControlMessageConfig(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Speed = 5
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	if err := sim.HandleControlMessage([]byte(`{"type":"config","data":{"speed":20,"course":90}}`)); err != nil {
+		t.Fatalf("HandleControlMessage failed: %v", err)
+	}
+	if sim.Config.Speed != 20 || sim.Config.Course != 90 {
+		t.Errorf("Expected speed 20 and course 90, got %v and %v", sim.Config.Speed, sim.Config.Course)
+	}
+	// Settings the message leaves out are kept
+	if sim.Config.Jitter != config.Jitter {
+		t.Errorf("Expected jitter to stay %v, got %v", config.Jitter, sim.Config.Jitter)
+	}
+
+	// Updates closer together than the minimum interval are rejected
+	err = sim.HandleControlMessage([]byte(`{"type":"config","data":{"speed":30}}`))
+	if !errors.Is(err, ErrControlRateLimited) {
+		t.Errorf("Expected ErrControlRateLimited, got %v", err)
+	}
+	if sim.Config.Speed != 20 {
+		t.Errorf("Expected rate limited update to be ignored, got speed %v", sim.Config.Speed)
+	}
+
+	time.Sleep(controlMinInterval)
+	if err := sim.HandleControlMessage([]byte(`{"type":"config","data":{"speed":30}}`)); err != nil {
+		t.Fatalf("HandleControlMessage failed after the minimum interval: %v", err)
+	}
+	if sim.Config.Speed != 30 {
+		t.Errorf("Expected speed 30, got %v", sim.Config.Speed)
+	}
+}
+
+func TestHandleControlMessageCommands(t *testing.T) {
+	sim := createTestSimulator()
+
+	if err := sim.HandleControlMessage([]byte(`{"type":"command","data":"pause"}`)); err != nil {
+		t.Fatalf("Pause command failed: %v", err)
+	}
+	if !sim.IsPaused() {
+		t.Error("Expected simulator to be paused")
+	}
+	if err := sim.HandleControlMessage([]byte(`{"type":"command","data":"resume"}`)); err != nil {
+		t.Fatalf("Resume command failed: %v", err)
+	}
+	if sim.IsPaused() {
+		t.Error("Expected simulator to be resumed")
+	}
+	if err := sim.HandleControlMessage([]byte(`{"type":"command","data":"stop"}`)); err != nil {
+		t.Fatalf("Stop command failed: %v", err)
+	}
+	sim.run.mu.Lock()
+	stopped := sim.run.stopped
+	sim.run.mu.Unlock()
+	if !stopped {
+		t.Error("Expected stop command to stop the simulator")
+	}
+}
+
+func TestHandleControlMessageInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		err     string
+	}{
+		{"Not JSON", `speed=20`, "invalid control message"},
+		{"Unknown type", `{"type":"reset"}`, "unknown control message type"},
+		{"Unknown command", `{"type":"command","data":"reboot"}`, "unknown command"},
+		{"Command not a string", `{"type":"command","data":1}`, "invalid command"},
+		{"Config not an object", `{"type":"config","data":"fast"}`, "invalid config update"},
+		{"Invalid config", `{"type":"config","data":{"jitter":2}}`, "Jitter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("Failed to create GPS simulator: %v", err)
+			}
+			err = sim.HandleControlMessage([]byte(tt.message))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestReplyToControlMessage(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	reply := sim.ReplyToControlMessage([]byte(`{"type":"status"}`))
+	if reply.Type != ControlStatus || reply.Error != "" || reply.Status == nil {
+		t.Fatalf("Expected a status reply, got %+v", reply)
+	}
+	if reply.Status.Paused {
+		t.Error("Expected status to report the simulator running")
+	}
+
+	reply = sim.ReplyToControlMessage([]byte(`{"type":"command","data":"pause"}`))
+	if reply.Type != ControlCommand || reply.Error != "" || reply.Status == nil || !reply.Status.Paused {
+		t.Errorf("Expected the reply to acknowledge the pause, got %+v", reply)
+	}
+
+	reply = sim.ReplyToControlMessage([]byte(`{"type":"config","data":{"jitter":2}}`))
+	if reply.Type != ControlConfig || reply.Error == "" || reply.Status != nil {
+		t.Errorf("Expected an error reply for an invalid config, got %+v", reply)
+	}
+
+	reply = sim.ReplyToControlMessage([]byte(`{"type":`))
+	if reply.Type != "" || !strings.Contains(reply.Error, "invalid control message") {
+		t.Errorf("Expected an error reply for a malformed message, got %+v", reply)
+	}
+
+	data, err := json.Marshal(ControlReply{Type: ControlConfig, Error: "bad"})
+	if err != nil {
+		t.Fatalf("Failed to marshal reply: %v", err)
+	}
+	if expected := `{"type":"config","error":"bad"}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
@@ -0,0 +1,829 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPauseStopsOutput(t *testing.T) {
+	sim := createTestSimulator()
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+
+	sim.Pause()
+	if !sim.IsPaused() {
+		t.Fatal("Expected simulator to be paused")
+	}
+
+	sim.tick()
+	if buffer.Len() != 0 {
+		t.Errorf("Expected no output while paused, got %q", buffer.String())
+	}
+
+	sim.Resume()
+	if sim.IsPaused() {
+		t.Fatal("Expected simulator to be resumed")
+	}
+
+	sim.tick()
+	if buffer.Len() == 0 {
+		t.Error("Expected output after resume")
+	}
+}
+
+func TestPauseResumeIdempotent(t *testing.T) {
+	sim := createTestSimulator()
+
+	// Resuming a running simulator does nothing
+	replayStart := sim.replayStartTime
+	sim.Resume()
+	if sim.IsPaused() || !sim.replayStartTime.Equal(replayStart) {
+		t.Error("Resume on a running simulator should have no effect")
+	}
+
+	// A second Pause keeps the original pause time
+	sim.Pause()
+	pausedAt := sim.pausedAt
+	time.Sleep(5 * time.Millisecond)
+	sim.Pause()
+	if !sim.pausedAt.Equal(pausedAt) {
+		t.Error("Second Pause should not reset the pause time")
+	}
+}
+
+func TestPauseHoldsClimb(t *testing.T) {
+	sim, clock := createClimbSimulator(t, 100, 2, 1000)
+
+	clock.Advance(time.Second)
+	sim.tick()
+	if sim.currentAlt != 102 {
+		t.Fatalf("Expected to climb to 102m, got %f", sim.currentAlt)
+	}
+
+	// The altitude holds while paused, and the pause is not climb time
+	sim.Pause()
+	clock.Advance(100 * time.Second)
+	sim.tick()
+	if sim.currentAlt != 102 {
+		t.Errorf("Expected the altitude to hold at 102m while paused, got %f", sim.currentAlt)
+	}
+	sim.Resume()
+	clock.Advance(time.Second)
+	sim.tick()
+	if sim.currentAlt != 104 {
+		t.Errorf("Expected to climb to 104m one second after resuming, got %f", sim.currentAlt)
+	}
+}
+
+func TestPauseDoesNotSkipReplayPoints(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(1 * time.Second)},
+		{Lat: 37.0020, Lon: -122.0000, Time: base.Add(2 * time.Second)},
+		{Lat: 37.0030, Lon: -122.0000, Time: base.Add(3 * time.Second)},
+	}
+
+	// Replay started 3.5s ago and was paused 2s ago, so 1.5s of replay has elapsed
+	sim := createReplaySimulator(points, false)
+	sim.replayStartTime = time.Now().Add(-3500 * time.Millisecond)
+	sim.Pause()
+	sim.pausedAt = time.Now().Add(-2 * time.Second)
+	sim.Resume()
+
+	sim.updateReplayPosition()
+	if sim.replayIndex != 1 {
+		t.Errorf("Expected replay to resume at index 1, got %d", sim.replayIndex)
+	}
+}
+
+func TestPauseDelaysLock(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Second)
+
+	sim.Pause()
+	sim.pausedAt = sim.pausedAt.Add(-10 * time.Second)
+	sim.Resume()
+
+	sim.update()
+	if sim.isLocked {
+		t.Error("Lock acquisition should not advance while paused")
+	}
+}
+
+// seekTestPoints returns track points ten seconds apart
+func seekTestPoints() []TrackPoint {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	var points []TrackPoint
+	for i := 0; i < 5; i++ {
+		points = append(points, TrackPoint{
+			Lat:  37.0 + float64(i)*0.001,
+			Lon:  -122.0,
+			Time: base.Add(time.Duration(i) * 10 * time.Second),
+		})
+	}
+	return points
+}
+
+func TestSeekToIndex(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	sim.Config.ReplaySpeed = 2.0
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if err := sim.SeekToIndex(3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sim.updateReplayPosition()
+	if sim.replayIndex != 3 || sim.currentLat != sim.replayPoints[3].Lat {
+		t.Errorf("Expected to resume at point 3, got index %d lat %f", sim.replayIndex, sim.currentLat)
+	}
+
+	// Progression continues from the seek target at the replay speed
+	clock.Advance(5 * time.Second)
+	sim.updateReplayPosition()
+	if sim.replayIndex != 4 {
+		t.Errorf("Expected index 4 five seconds after seeking at 2x, got %d", sim.replayIndex)
+	}
+}
+
+func TestSeekToIndexOutOfRange(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+
+	for _, index := range []int{-1, 5} {
+		if err := sim.SeekToIndex(index); err == nil {
+			t.Errorf("Expected error seeking to index %d", index)
+		}
+	}
+
+	empty := createTestSimulator()
+	if err := empty.SeekToIndex(0); err == nil {
+		t.Error("Expected error seeking without a replay loaded")
+	}
+}
+
+func TestSeekToTime(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if err := sim.SeekToTime(25 * time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sim.updateReplayPosition()
+	if sim.replayIndex != 2 {
+		t.Errorf("Expected index 2 at 25s, got %d", sim.replayIndex)
+	}
+
+	if err := sim.SeekToTime(41 * time.Second); err == nil {
+		t.Error("Expected error seeking past the end of the track")
+	}
+	if err := sim.SeekToTime(-time.Second); err == nil {
+		t.Error("Expected error seeking to a negative offset")
+	}
+}
+
+func TestSeekToTimeIndexBased(t *testing.T) {
+	points := seekTestPoints()
+	points[1].Time, points[2].Time = points[2].Time, points[1].Time
+
+	sim := createReplaySimulator(points, false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if err := sim.SeekToTime(3500 * time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sim.updateReplayPosition()
+	if sim.replayIndex != 3 {
+		t.Errorf("Expected index 3 at 3.5s with one point per second, got %d", sim.replayIndex)
+	}
+
+	if err := sim.SeekToTime(5 * time.Second); err == nil {
+		t.Error("Expected error seeking past the last point")
+	}
+}
+
+func TestSeekClearsCompletion(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	sim.replayStartTime = time.Now().Add(-time.Minute)
+	sim.updateReplayPosition()
+	if !sim.replayCompleted {
+		t.Fatal("Expected replay to be completed")
+	}
+
+	if err := sim.SeekToIndex(0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sim.replayCompleted {
+		t.Error("Seeking should clear replay completion")
+	}
+}
+
+func TestSeekWhilePaused(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	sim.Pause()
+	clock.Advance(time.Minute)
+	if err := sim.SeekToIndex(2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	sim.Resume()
+
+	sim.updateReplayPosition()
+	if sim.replayIndex != 2 {
+		t.Errorf("Expected to resume at the seek target, got index %d", sim.replayIndex)
+	}
+}
+
+func TestSeekReplayFraction(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	tests := []struct {
+		fraction float64
+		index    int
+	}{
+		{0, 0},
+		{0.5, 2},
+		{0.6, 2},
+		{0.75, 3},
+		{-1, 0},
+		{2, 4},
+	}
+	for _, tt := range tests {
+		if err := sim.SeekReplay(tt.fraction); err != nil {
+			t.Fatalf("Unexpected error seeking to %v: %v", tt.fraction, err)
+		}
+		sim.updateReplayPosition()
+		if sim.replayIndex != tt.index || sim.currentLat != sim.replayPoints[tt.index].Lat {
+			t.Errorf("Seeking to %v: expected point %d, got index %d lat %f", tt.fraction, tt.index, sim.replayIndex, sim.currentLat)
+		}
+	}
+
+	if err := sim.SeekReplay(math.NaN()); err == nil {
+		t.Error("Expected error seeking to NaN")
+	}
+	if err := createTestSimulator().SeekReplay(0.5); err == nil {
+		t.Error("Expected error seeking without a replay loaded")
+	}
+}
+
+func TestSeekReplayPastEndCompletes(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	if err := sim.SeekReplay(1.5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Second)
+	sim.updateReplayPosition()
+	if !sim.replayCompleted {
+		t.Error("Expected seeking past the end to complete the replay")
+	}
+	if progress := sim.Snapshot().ReplayProgress; progress != 100 {
+		t.Errorf("Expected 100%% progress after completing, got %f", progress)
+	}
+}
+
+func TestSeekReplayToTime(t *testing.T) {
+	points := seekTestPoints()
+	sim := createReplaySimulator(points, false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	tests := []struct {
+		at    time.Time
+		index int
+	}{
+		{points[0].Time.Add(25 * time.Second), 2},
+		{points[3].Time, 3},
+		{points[0].Time.Add(-time.Hour), 0},
+		{points[4].Time.Add(time.Hour), 4},
+	}
+	for _, tt := range tests {
+		if err := sim.SeekReplayToTime(tt.at); err != nil {
+			t.Fatalf("Unexpected error seeking to %v: %v", tt.at, err)
+		}
+		sim.updateReplayPosition()
+		if sim.replayIndex != tt.index {
+			t.Errorf("Seeking to %v: expected index %d, got %d", tt.at, tt.index, sim.replayIndex)
+		}
+	}
+
+	// Progression continues from the seek target
+	if err := sim.SeekReplayToTime(points[1].Time); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(10 * time.Second)
+	sim.updateReplayPosition()
+	if sim.replayIndex != 2 {
+		t.Errorf("Expected index 2 ten seconds after seeking to point 1, got %d", sim.replayIndex)
+	}
+
+	points[1].Time, points[2].Time = points[2].Time, points[1].Time
+	unordered := createReplaySimulator(points, false)
+	if err := unordered.SeekReplayToTime(points[0].Time); err == nil {
+		t.Error("Expected error seeking by time without sequential timestamps")
+	}
+}
+
+func TestSeekReplayReverse(t *testing.T) {
+	points := seekTestPoints()
+	sim := createReplaySimulator(reverseTrackPoints(points), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	// A quarter of the way back from the last point is the fourth point
+	if err := sim.SeekReplay(0.25); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sim.updateReplayPosition()
+	if sim.currentLat != points[3].Lat {
+		t.Errorf("Expected lat %f a quarter into the reversed track, got %f", points[3].Lat, sim.currentLat)
+	}
+	if math.Abs(sim.currentCourse-180) > 1.0 {
+		t.Errorf("Expected reversed replay to head south, got course %f", sim.currentCourse)
+	}
+}
+
+func TestSeekReplayWhilePaused(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	sim.Pause()
+	if err := sim.SeekReplay(0.5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if progress := sim.Snapshot().ReplayProgress; progress != 50 {
+		t.Errorf("Expected progress to hold at 50%% while paused, got %f", progress)
+	}
+	sim.Resume()
+
+	sim.updateReplayPosition()
+	if sim.replayIndex != 2 {
+		t.Errorf("Expected to resume at the seek target, got index %d", sim.replayIndex)
+	}
+}
+
+func TestReplayProgress(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	clock.Advance(10 * time.Second)
+	if progress := sim.Snapshot().ReplayProgress; progress != 25 {
+		t.Errorf("Expected 25%% progress 10s into a 40s track, got %f", progress)
+	}
+
+	if progress := createTestSimulator().Snapshot().ReplayProgress; progress != 0 {
+		t.Errorf("Expected no progress without a replay, got %f", progress)
+	}
+}
+
+func TestReplayProgressUntimedLoop(t *testing.T) {
+	points := seekTestPoints()
+	for i := range points {
+		points[i].Time = time.Time{}
+	}
+	sim := createReplaySimulator(points, false)
+	sim.Config.ReplayLoop = true
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	// Five untimed points take 4s from first to last and loop every 5s
+	clock.Advance(2 * time.Second)
+	sim.updateReplayPosition()
+	if progress := sim.Snapshot().ReplayProgress; progress != 50 {
+		t.Errorf("Expected 50%% progress at the middle point, got %f", progress)
+	}
+
+	// The second pass starts again from zero
+	clock.Advance(5 * time.Second)
+	sim.updateReplayPosition()
+	if sim.replayIndex != 2 || sim.replayLoops != 1 {
+		t.Fatalf("Expected the middle point of the second pass, got index %d after %d loops", sim.replayIndex, sim.replayLoops)
+	}
+	if progress := sim.Snapshot().ReplayProgress; progress != 50 {
+		t.Errorf("Expected 50%% progress at the middle point of the second pass, got %f", progress)
+	}
+}
+
+func TestPauseFreezesReplayIndex(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	clock.Advance(12 * time.Second)
+	sim.tick()
+	if sim.replayIndex != 1 {
+		t.Fatalf("Expected index 1 before pausing, got %d", sim.replayIndex)
+	}
+
+	// Ticks during the pause window neither emit nor advance the replay
+	sim.Pause()
+	buffer.Reset()
+	for i := 0; i < 30; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+		if sim.replayIndex != 1 {
+			t.Fatalf("Replay index advanced to %d while paused", sim.replayIndex)
+		}
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Expected no sentences while paused, got %q", buffer.String())
+	}
+
+	// After resuming, replay continues from 12s into the track
+	sim.Resume()
+	sim.tick()
+	if sim.replayIndex != 1 {
+		t.Errorf("Expected index 1 immediately after resume, got %d", sim.replayIndex)
+	}
+	clock.Advance(9 * time.Second)
+	sim.tick()
+	if sim.replayIndex != 2 {
+		t.Errorf("Expected index 2 at 21s of replay time, got %d", sim.replayIndex)
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	sim := createTestSimulator()
+	lat, lon := sim.currentLat, sim.currentLon
+
+	config := sim.Config
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Speed = 25
+	config.Course = 270
+	config.Jitter = 0
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if sim.Config.Speed != 25 || sim.Config.Course != 270 || sim.Config.Jitter != 0 {
+		t.Errorf("Expected updated speed, course and jitter, got %+v", sim.Config)
+	}
+	if sim.currentLat != lat || sim.currentLon != lon || !sim.isLocked {
+		t.Error("Expected position and lock to be preserved")
+	}
+
+	config.Speed = -1
+	if err := sim.UpdateConfig(config); err == nil {
+		t.Error("Expected an error for an invalid config")
+	}
+	if sim.Config.Speed != 25 {
+		t.Errorf("Expected invalid config to be rejected, speed is %.1f", sim.Config.Speed)
+	}
+}
+
+func TestUpdateConfigWhileRunning(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.OutputRate = 2 * time.Millisecond
+	config.TimeToLock = 10 * time.Millisecond
+	config.Duration = 100 * time.Millisecond
+	config.Quiet = true
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+
+	// Steer the simulation and read its state until Run finishes; run with
+	// -race to check the shared state is guarded
+	for i := 0; ; i++ {
+		select {
+		case <-done:
+			if i == 0 {
+				t.Error("Expected at least one update while running")
+			}
+			return
+		default:
+		}
+
+		config.Speed = float64(i % 50)
+		config.Course = float64(i % 360)
+		if err := sim.UpdateConfig(config); err != nil {
+			t.Fatalf("UpdateConfig failed: %v", err)
+		}
+		sim.Snapshot()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUpdateConfigMutableFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(*Config)
+		check  func(Config) bool
+	}{
+		{"Speed", func(c *Config) { c.Speed = 12.5 }, func(c Config) bool { return c.Speed == 12.5 }},
+		{"Course", func(c *Config) { c.Course = 135 }, func(c Config) bool { return c.Course == 135 }},
+		{"Jitter", func(c *Config) { c.Jitter = 0.9 }, func(c Config) bool { return c.Jitter == 0.9 }},
+		{"AltitudeJitter", func(c *Config) { c.AltitudeJitter = 0.7 }, func(c Config) bool { return c.AltitudeJitter == 0.7 }},
+		{"Radius", func(c *Config) { c.Radius = 250 }, func(c Config) bool { return c.Radius == 250 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			sim.startTime = start
+			sim.currentLat, sim.currentLon = 37.7755, -122.4190
+			sim.currentAlt = 45
+
+			config := sim.Config
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			tt.update(&config)
+			if err := sim.UpdateConfig(config); err != nil {
+				t.Fatalf("UpdateConfig failed: %v", err)
+			}
+
+			if !tt.check(sim.Config) {
+				t.Errorf("Expected %s to be updated, got %+v", tt.name, sim.Config)
+			}
+			if sim.currentLat != 37.7755 || sim.currentLon != -122.4190 || sim.currentAlt != 45 {
+				t.Error("Expected position to be preserved")
+			}
+			if !sim.isLocked || !sim.startTime.Equal(start) {
+				t.Error("Expected lock and start time to be preserved")
+			}
+		})
+	}
+}
+
+func TestUpdateConfigRecenterTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		speed    float64       // Knots
+		duration time.Duration // Transition duration
+		maxStep  float64       // Meters per 1s update
+	}{
+		{"At speed", 20, 0, 20 * 0.514444},
+		{"Over duration", 0, 30 * time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			sim.SetClock(clock)
+			sim.lastUpdateTime = clock.Now()
+			sim.Config.Jitter = 0
+			sim.Config.AltitudeJitter = 0
+
+			config := sim.Config
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			config.Speed = tt.speed
+			config.TransitionDuration = tt.duration
+			config.Latitude = 37.7849 // About 1.1km north
+			if err := sim.UpdateConfig(config); err != nil {
+				t.Fatalf("UpdateConfig failed: %v", err)
+			}
+
+			if sim.currentLat != 37.7749 || !sim.IsTransitioning() {
+				t.Fatal("Expected a transition rather than a jump to the new center")
+			}
+
+			maxStep := tt.maxStep
+			if tt.duration > 0 {
+				distance := sim.calculateDistance(37.7749, -122.4194, 37.7849, -122.4194)
+				maxStep = distance / tt.duration.Seconds()
+			}
+
+			for i := 0; i < 200 && sim.IsTransitioning(); i++ {
+				lat, lon := sim.currentLat, sim.currentLon
+				clock.Advance(time.Second)
+				sim.update()
+
+				if step := sim.calculateDistance(lat, lon, sim.currentLat, sim.currentLon); step > maxStep+0.01 {
+					t.Fatalf("Update %d moved %.2fm, more than %.2fm allowed", i, step, maxStep)
+				}
+				if sim.IsTransitioning() && math.Abs(sim.currentCourse) > 0.01 && math.Abs(sim.currentCourse-360) > 0.01 {
+					t.Fatalf("Expected course toward the new center (0), got %.2f", sim.currentCourse)
+				}
+			}
+
+			if sim.IsTransitioning() {
+				t.Fatal("Expected the transition to finish")
+			}
+			if sim.currentLat != 37.7849 || sim.currentLon != -122.4194 {
+				t.Errorf("Expected receiver at the new center, got %.6f, %.6f", sim.currentLat, sim.currentLon)
+			}
+			if !sim.isLocked {
+				t.Error("Expected lock to be preserved when re-centering")
+			}
+		})
+	}
+}
+
+func TestUpdateConfigShrinkRadiusTransitions(t *testing.T) {
+	sim := createTestSimulator()
+	sim.currentLat = 37.7758 // About 100m north of the center
+
+	config := sim.Config
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Radius = 20
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if sim.currentLat != 37.7758 || !sim.IsTransitioning() {
+		t.Error("Expected a transition back inside the smaller radius rather than a jump")
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe to write from Run while a test reads it
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func TestUpdateConfigOutputRate(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	config.TimeToLock = 0
+	config.Duration = 300 * time.Millisecond
+	buffer := &lockedBuffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+	defer func() { <-done }()
+
+	// At the initial 1s rate nothing is emitted within the first 100ms
+	time.Sleep(100 * time.Millisecond)
+	if buffer.Len() != 0 {
+		t.Fatal("Expected no output before the first 1s tick")
+	}
+
+	config.OutputRate = 5 * time.Millisecond
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if buffer.Len() == 0 {
+		t.Error("Expected output after switching to a 5ms rate without restarting Run")
+	}
+
+	config.OutputRate = 0
+	if err := sim.UpdateConfig(config); err == nil {
+		t.Error("Expected an error for a zero output rate")
+	}
+}
+
+func TestUpdateConfigOutputRateRestartsIntegration(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	config.OutputRate = time.Second
+	config.UpdateRate = 200 * time.Millisecond
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	go sim.Run()
+	defer sim.Stop()
+
+	// waitForTickers waits until Run has created the expected tickers
+	waitForTickers := func(expected []time.Duration) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			intervals := clock.tickerIntervals()
+			if reflect.DeepEqual(intervals, expected) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected tickers %v, got %v", expected, intervals)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitForTickers([]time.Duration{time.Second, 200 * time.Millisecond})
+
+	// No integration ticker once the output rate is faster than UpdateRate
+	config.OutputRate = 100 * time.Millisecond
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	waitForTickers([]time.Duration{time.Second, 200 * time.Millisecond, 100 * time.Millisecond})
+
+	// A slower output rate integrates between outputs again
+	config.OutputRate = 2 * time.Second
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	waitForTickers([]time.Duration{time.Second, 200 * time.Millisecond, 100 * time.Millisecond, 2 * time.Second, 200 * time.Millisecond})
+}
+
+func TestUpdateConfigRouteKeepsPosition(t *testing.T) {
+	sim := createTestSimulator()
+	sim.loadRoute(waypointRoute([]Coordinate{{Lat: 37.0, Lon: -122.0}, {Lat: 37.1, Lon: -122.0}}, 0))
+
+	config := sim.Config
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Latitude = 51.5074
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if sim.currentLat != 37.0 || sim.currentLon != -122.0 {
+		t.Errorf("Expected the route to keep driving the position, got %.4f, %.4f", sim.currentLat, sim.currentLon)
+	}
+}
+
+func TestUpdateConfigResizesSatellites(t *testing.T) {
+	sim := createTestSimulator()
+	kept := append([]Satellite(nil), sim.Satellites...)
+
+	config := sim.Config
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Satellites = 10
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	if len(sim.Satellites) != 10 {
+		t.Fatalf("Expected 10 satellites, got %d", len(sim.Satellites))
+	}
+	for i, sat := range kept {
+		if sim.Satellites[i] != sat {
+			t.Errorf("Expected satellite %d to be kept, got %+v", i, sim.Satellites[i])
+		}
+	}
+	seen := make(map[int]bool)
+	for _, sat := range sim.Satellites {
+		if seen[sat.ID] {
+			t.Errorf("Duplicate satellite ID %d after resize", sat.ID)
+		}
+		seen[sat.ID] = true
+	}
+
+	config.Satellites = 5
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if len(sim.Satellites) != 5 || sim.Satellites[0] != kept[0] {
+		t.Errorf("Expected the first 5 satellites to remain, got %+v", sim.Satellites)
+	}
+	if !sim.isLocked {
+		t.Error("Expected lock to be preserved when resizing satellites")
+	}
+	if fields := sentenceFields(sim.generateGGA(time.Now())); fields[7] != "05" {
+		t.Errorf("Expected GGA to report 05 satellites, got %q", fields[7])
+	}
+}
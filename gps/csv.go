@@ -0,0 +1,124 @@
+package gps
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVColumns lists the supported CSV columns in their default order
+var CSVColumns = []string{"timestamp", "lat", "lon", "alt", "speed_knots", "course", "satellites", "hdop", "locked"}
+
+// csvValues formats each CSV column from a fix
+var csvValues = map[string]func(Fix) string{
+	"timestamp":   func(f Fix) string { return f.Timestamp.UTC().Format(time.RFC3339Nano) },
+	"lat":         func(f Fix) string { return strconv.FormatFloat(f.Lat, 'f', 6, 64) },
+	"lon":         func(f Fix) string { return strconv.FormatFloat(f.Lon, 'f', 6, 64) },
+	"alt":         func(f Fix) string { return strconv.FormatFloat(f.Altitude, 'f', 1, 64) },
+	"speed_knots": func(f Fix) string { return strconv.FormatFloat(f.SpeedKnots, 'f', 2, 64) },
+	"course":      func(f Fix) string { return strconv.FormatFloat(f.CourseDeg, 'f', 1, 64) },
+	"satellites":  func(f Fix) string { return strconv.Itoa(f.SatellitesUsed) },
+	"hdop":        func(f Fix) string { return strconv.FormatFloat(f.HDOP, 'f', 1, 64) },
+	"locked":      func(f Fix) string { return strconv.FormatBool(f.Locked) },
+}
+
+// validateCSVColumns checks that every CSV column is known and listed once
+func (c Config) validateCSVColumns() error {
+	seen := make(map[string]bool)
+	for _, column := range c.CSVColumns {
+		if _, ok := csvValues[column]; !ok {
+			return fmt.Errorf("Unknown CSV column %q (valid: %s)", column, strings.Join(CSVColumns, ", "))
+		}
+		if seen[column] {
+			return fmt.Errorf("CSV column %q is listed more than once", column)
+		}
+		seen[column] = true
+	}
+	return nil
+}
+
+// CSVWriter handles writing GPS data to a CSV file for analysis scripts,
+// one row per output cycle after a header row. Rows are buffered and reach
+// the file on each WriteToFile.
+type CSVWriter struct {
+	filename string
+	columns  []string
+	file     *os.File
+	writer   *csv.Writer
+	rows     int
+	closed   bool // Set once Close has been called
+}
+
+// NewCSVWriter creates a new CSV writer for the given columns, or all of
+// CSVColumns when none are given, and writes the header row
+func NewCSVWriter(filename string, columns []string) (*CSVWriter, error) {
+	if len(columns) == 0 {
+		columns = CSVColumns
+	}
+	for _, column := range columns {
+		if _, ok := csvValues[column]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV file %s: %v", filename, err)
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(columns); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write CSV header: %v", err)
+	}
+	return &CSVWriter{filename: filename, columns: columns, file: file, writer: writer}, nil
+}
+
+// AddRow adds the fix as a row of the selected columns
+func (w *CSVWriter) AddRow(fix Fix) {
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		row[i] = csvValues[column](fix)
+	}
+	w.writer.Write(row)
+	w.rows++
+}
+
+// AddFix adds the fix as a row
+func (w *CSVWriter) AddFix(fix Fix) {
+	w.AddRow(fix)
+}
+
+// GetTrackPointCount returns the number of rows added, excluding the header
+func (w *CSVWriter) GetTrackPointCount() int {
+	return w.rows
+}
+
+// WriteToFile flushes the buffered rows to the file
+func (w *CSVWriter) WriteToFile() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV data: %v", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+	return nil
+}
+
+// Close flushes the remaining rows and closes the CSV file
+func (w *CSVWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.WriteToFile()
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
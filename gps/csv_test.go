@@ -0,0 +1,184 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCSVTrack parses a CSV track file into its rows, header included
+func readCSVTrack(t *testing.T, filename string) [][]string {
+	t.Helper()
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Failed to open CSV file: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	return rows
+}
+
+func TestSimulatorCSVTrack(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Speed = 10
+	config.CSVFile = filepath.Join(t.TempDir(), "track.csv")
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	for i := 0; i < 12; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+
+	// Rows are flushed every 10 points, before the file is closed
+	if rows := readCSVTrack(t, config.CSVFile); len(rows) != 11 {
+		t.Errorf("Expected the header and 10 flushed rows before Close, got %d rows", len(rows))
+	}
+	sim.Close()
+
+	rows := readCSVTrack(t, config.CSVFile)
+	if strings.Join(rows[0], ",") != "timestamp,lat,lon,alt,speed_knots,course,satellites,hdop,locked" {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	if len(rows) != 13 {
+		t.Fatalf("Expected the header and 12 rows, got %d rows", len(rows))
+	}
+	for i, row := range rows[1:] {
+		if _, err := time.Parse(time.RFC3339Nano, row[0]); err != nil {
+			t.Errorf("Row %d: invalid timestamp %q", i, row[0])
+		}
+		if speed, err := strconv.ParseFloat(row[4], 64); err != nil || speed <= 0 {
+			t.Errorf("Row %d: expected a positive speed in knots, got %q", i, row[4])
+		}
+		if row[6] != strconv.Itoa(config.Satellites) {
+			t.Errorf("Row %d: expected %d satellites, got %q", i, config.Satellites, row[6])
+		}
+	}
+}
+
+func TestNewCSVWriterError(t *testing.T) {
+	if _, err := NewCSVWriter(filepath.Join(t.TempDir(), "missing", "track.csv"), nil); err == nil {
+		t.Error("Expected error creating CSV file in a missing directory")
+	}
+	if _, err := NewCSVWriter(filepath.Join(t.TempDir(), "track.csv"), []string{"lat", "pressure"}); err == nil {
+		t.Error("Expected error for an unknown CSV column")
+	}
+}
+
+func TestCSVRowPerOutputCycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		replay bool
+	}{
+		{"Simulation", false},
+		{"Replay", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.TimeToLock = 3 * time.Second
+			config.OutputRate = 500 * time.Millisecond
+			config.ReplaySpeed = 1.0
+			config.CSVFile = filepath.Join(t.TempDir(), "session.csv")
+			config.Quiet = true
+			if tt.replay {
+				start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+				for i := 0; i < 20; i++ {
+					config.ReplayPoints = append(config.ReplayPoints, TrackPoint{
+						Lat:  37.7749 + float64(i)*0.0001,
+						Lon:  -122.4194,
+						Time: start.Add(time.Duration(i) * time.Second),
+					})
+				}
+			}
+
+			sim, err := NewGPSSimulator(config, nil)
+			if err != nil {
+				t.Fatalf("Failed to create GPS simulator: %v", err)
+			}
+			duration := 10 * time.Second
+			if _, err := sim.GenerateTo(io.Discard, duration); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			rows := readCSVTrack(t, config.CSVFile)
+			expected := int(duration / config.OutputRate)
+			if len(rows)-1 < expected-1 || len(rows)-1 > expected+1 {
+				t.Fatalf("Expected about %d rows, got %d", expected, len(rows)-1)
+			}
+
+			// Cycles before lock are recorded as unlocked
+			locked := len(rows[0]) - 1
+			if rows[1][locked] != "false" || rows[len(rows)-1][locked] != "true" {
+				t.Errorf("Expected unlocked rows before lock and locked rows after, got %q and %q",
+					rows[1][locked], rows[len(rows)-1][locked])
+			}
+		})
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.CSVFile = filepath.Join(t.TempDir(), "session.csv")
+	config.CSVColumns = []string{"locked", "lon", "lat"}
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	if _, err := sim.GenerateTo(io.Discard, 3*time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows := readCSVTrack(t, config.CSVFile)
+	if strings.Join(rows[0], ",") != "locked,lon,lat" {
+		t.Errorf("Expected the selected columns in order, got %v", rows[0])
+	}
+	for i, row := range rows[1:] {
+		if len(row) != 3 {
+			t.Fatalf("Row %d: expected 3 columns, got %d", i, len(row))
+		}
+		if lat, err := strconv.ParseFloat(row[2], 64); err != nil || lat < 37 || lat > 38 {
+			t.Errorf("Row %d: expected latitude in the last column, got %q", i, row[2])
+		}
+	}
+}
+
+func TestValidateCSVColumns(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.CSVColumns = []string{"timestamp", "lat", "lon"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected known CSV columns to be valid, got %v", err)
+	}
+
+	config.CSVColumns = []string{"timestamp", "altitude"}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "altitude") {
+		t.Errorf("Expected error naming the unknown CSV column, got %v", err)
+	}
+
+	config.CSVColumns = []string{"lat", "lat"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for a repeated CSV column")
+	}
+}
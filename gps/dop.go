@@ -0,0 +1,137 @@
+package gps
+
+import (
+	"math"
+)
+
+// maxDOP is reported when the satellite geometry cannot produce a position solution
+const maxDOP = 99.9
+
+// DOP holds the dilution of precision values computed from satellite geometry
+type DOP struct {
+	PDOP float64 // Position dilution of precision
+	HDOP float64 // Horizontal dilution of precision
+	VDOP float64 // Vertical dilution of precision
+}
+
+// calculateDOP computes PDOP, HDOP and VDOP from the elevations and azimuths of
+// the simulated satellites. Each satellite contributes a row of the geometry
+// matrix A (unit line-of-sight vector in east/north/up plus a receiver clock
+// term); the DOP values come from the diagonal of (AᵀA)⁻¹.
+func (s *GPSSimulator) calculateDOP() DOP {
+	return calculateDOP(s.Satellites)
+}
+
+// updateDOP recomputes the reported DOP from the current satellite geometry.
+// With DOPJitter set, all three values are scaled by the same random factor so
+// they stay consistent with each other.
+func (s *GPSSimulator) updateDOP() {
+	dop := s.calculateDOP()
+	if s.Config.DOPJitter > 0 && dop.PDOP < maxDOP {
+		factor := 1 + s.Config.DOPJitter*(s.random().Float64()-0.5)
+		dop.PDOP = math.Min(dop.PDOP*factor, maxDOP)
+		dop.HDOP = math.Min(dop.HDOP*factor, maxDOP)
+		dop.VDOP = math.Min(dop.VDOP*factor, maxDOP)
+	}
+	s.dop = &dop
+}
+
+// currentDOP returns the DOP reported by GGA, GSA and gpsd SKY, computing it
+// from the satellite geometry if it has not been updated yet
+func (s *GPSSimulator) currentDOP() DOP {
+	if s.dop == nil {
+		return s.calculateDOP()
+	}
+	return *s.dop
+}
+
+// calculateDOP computes the dilution of precision for the given satellites
+func calculateDOP(sats []Satellite) DOP {
+	invalid := DOP{PDOP: maxDOP, HDOP: maxDOP, VDOP: maxDOP}
+
+	// At least four satellites are needed to solve for position and clock
+	if len(sats) < 4 {
+		return invalid
+	}
+
+	// Accumulate the normal matrix AᵀA
+	var normal [4][4]float64
+	for _, sat := range sats {
+		elevation := float64(sat.Elevation) * math.Pi / 180
+		azimuth := float64(sat.Azimuth) * math.Pi / 180
+
+		row := [4]float64{
+			math.Cos(elevation) * math.Sin(azimuth), // East
+			math.Cos(elevation) * math.Cos(azimuth), // North
+			math.Sin(elevation),                     // Up
+			1,                                       // Receiver clock
+		}
+
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				normal[i][j] += row[i] * row[j]
+			}
+		}
+	}
+
+	cofactor, ok := invert4x4(normal)
+	if !ok {
+		return invalid
+	}
+
+	east, north, up := cofactor[0][0], cofactor[1][1], cofactor[2][2]
+	if east < 0 || north < 0 || up < 0 {
+		return invalid
+	}
+
+	return DOP{
+		PDOP: math.Min(math.Sqrt(east+north+up), maxDOP),
+		HDOP: math.Min(math.Sqrt(east+north), maxDOP),
+		VDOP: math.Min(math.Sqrt(up), maxDOP),
+	}
+}
+
+// invert4x4 inverts a 4x4 matrix using Gauss-Jordan elimination with partial
+// pivoting. It reports false if the matrix is singular.
+func invert4x4(m [4][4]float64) ([4][4]float64, bool) {
+	var inverse [4][4]float64
+	for i := 0; i < 4; i++ {
+		inverse[i][i] = 1
+	}
+
+	for col := 0; col < 4; col++ {
+		// Find the pivot row with the largest magnitude in this column
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return inverse, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inverse[col], inverse[pivot] = inverse[pivot], inverse[col]
+
+		// Normalize the pivot row
+		scale := m[col][col]
+		for j := 0; j < 4; j++ {
+			m[col][j] /= scale
+			inverse[col][j] /= scale
+		}
+
+		// Eliminate this column from every other row
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			factor := m[row][col]
+			for j := 0; j < 4; j++ {
+				m[row][j] -= factor * m[col][j]
+				inverse[row][j] -= factor * inverse[col][j]
+			}
+		}
+	}
+
+	return inverse, true
+}
@@ -0,0 +1,166 @@
+package gps
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// spreadSatellites returns n satellites evenly spread in azimuth with alternating elevations
+func spreadSatellites(n int) []Satellite {
+	sats := make([]Satellite, n)
+	elevations := []int{15, 45, 75}
+	for i := 0; i < n; i++ {
+		sats[i] = Satellite{
+			ID:        i + 1,
+			Elevation: elevations[i%len(elevations)],
+			Azimuth:   i * 360 / n,
+			SNR:       40,
+		}
+	}
+	return sats
+}
+
+func TestCalculateDOPDecreasesWithSatelliteCount(t *testing.T) {
+	var previous DOP
+	for i, n := range []int{4, 6, 8, 10, 12} {
+		dop := calculateDOP(spreadSatellites(n))
+
+		if dop.HDOP >= maxDOP || dop.VDOP >= maxDOP || dop.PDOP >= maxDOP {
+			t.Fatalf("%d satellites: expected a valid DOP, got %+v", n, dop)
+		}
+		if i > 0 && (dop.PDOP >= previous.PDOP || dop.HDOP >= previous.HDOP) {
+			t.Errorf("%d satellites: DOP %+v should be lower than previous %+v", n, dop, previous)
+		}
+		previous = dop
+	}
+
+	four := calculateDOP(spreadSatellites(4))
+	twelve := calculateDOP(spreadSatellites(12))
+	if four.PDOP < 1.5*twelve.PDOP {
+		t.Errorf("4 satellites PDOP %.2f should be noticeably worse than 12 satellites PDOP %.2f", four.PDOP, twelve.PDOP)
+	}
+}
+
+func TestCalculateDOPRelationships(t *testing.T) {
+	dop := calculateDOP(spreadSatellites(8))
+
+	// PDOP² = HDOP² + VDOP²
+	if math.Abs(dop.PDOP*dop.PDOP-(dop.HDOP*dop.HDOP+dop.VDOP*dop.VDOP)) > 1e-9 {
+		t.Errorf("PDOP² should equal HDOP² + VDOP², got %+v", dop)
+	}
+	if dop.HDOP < 0.5 || dop.PDOP > 10 {
+		t.Errorf("DOP for well-spread satellites out of realistic range: %+v", dop)
+	}
+}
+
+func TestCalculateDOPPoorGeometry(t *testing.T) {
+	// Four satellites clustered in the same part of the sky
+	clustered := []Satellite{
+		{ID: 1, Elevation: 40, Azimuth: 90},
+		{ID: 2, Elevation: 45, Azimuth: 95},
+		{ID: 3, Elevation: 50, Azimuth: 100},
+		{ID: 4, Elevation: 42, Azimuth: 105},
+	}
+	spread := calculateDOP(spreadSatellites(4))
+	poor := calculateDOP(clustered)
+
+	if poor.PDOP <= spread.PDOP {
+		t.Errorf("Clustered satellites PDOP %.2f should be worse than spread PDOP %.2f", poor.PDOP, spread.PDOP)
+	}
+}
+
+func TestCalculateDOPInsufficientSatellites(t *testing.T) {
+	dop := calculateDOP(spreadSatellites(3))
+	if dop.PDOP != maxDOP || dop.HDOP != maxDOP || dop.VDOP != maxDOP {
+		t.Errorf("Expected maximum DOP with fewer than 4 satellites, got %+v", dop)
+	}
+
+	// Identical satellites make the geometry matrix singular
+	same := []Satellite{{Elevation: 45}, {Elevation: 45}, {Elevation: 45}, {Elevation: 45}}
+	if dop := calculateDOP(same); dop.PDOP != maxDOP {
+		t.Errorf("Expected maximum DOP for singular geometry, got %+v", dop)
+	}
+}
+
+func TestDOPInGGAAndGSA(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Satellites = spreadSatellites(8)
+	dop := sim.calculateDOP()
+
+	gga := strings.Split(sim.generateGGA(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)), ",")
+	ggaHDOP, err := strconv.ParseFloat(gga[8], 64)
+	if err != nil {
+		t.Fatalf("GGA HDOP field should be numeric, got %q", gga[8])
+	}
+	if math.Abs(ggaHDOP-dop.HDOP) > 0.05 {
+		t.Errorf("GGA HDOP %.1f does not match computed %.2f", ggaHDOP, dop.HDOP)
+	}
+
+	gsa := strings.Split(strings.Split(sim.generateGSA()[0], "*")[0], ",")
+	gsaPDOP, _ := strconv.ParseFloat(gsa[15], 64)
+	gsaHDOP, _ := strconv.ParseFloat(gsa[16], 64)
+	gsaVDOP, _ := strconv.ParseFloat(gsa[17], 64)
+	if math.Abs(gsaPDOP-dop.PDOP) > 0.05 || math.Abs(gsaHDOP-dop.HDOP) > 0.05 || math.Abs(gsaVDOP-dop.VDOP) > 0.05 {
+		t.Errorf("GSA DOP %.1f/%.1f/%.1f does not match computed %+v", gsaPDOP, gsaHDOP, gsaVDOP, dop)
+	}
+	if gga[8] != gsa[16] {
+		t.Errorf("GGA HDOP %s and GSA HDOP %s should agree", gga[8], gsa[16])
+	}
+}
+
+func TestDOPUpdatesWithSatellites(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Satellites = spreadSatellites(4)
+	sim.updateSatellites()
+	poor := sim.currentDOP()
+
+	if poor != sim.calculateDOP() {
+		t.Errorf("Expected reported DOP %+v to match geometry %+v without DOP jitter", poor, sim.calculateDOP())
+	}
+
+	sim.Satellites = spreadSatellites(12)
+	sim.updateSatellites()
+	good := sim.currentDOP()
+
+	if good.HDOP >= poor.HDOP || good.PDOP >= poor.PDOP {
+		t.Errorf("Expected 12 satellites (%+v) to report better DOP than 4 (%+v)", good, poor)
+	}
+}
+
+func TestDOPJitter(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.DOPJitter = 1.0
+	sim.Satellites = spreadSatellites(8)
+	base := calculateDOP(sim.Satellites)
+
+	varied := false
+	for i := 0; i < 50; i++ {
+		sim.updateDOP()
+		dop := sim.currentDOP()
+
+		factor := dop.HDOP / base.HDOP
+		if factor < 0.5 || factor > 1.5 {
+			t.Errorf("DOP jitter factor %.2f outside 0.5-1.5", factor)
+		}
+		if math.Abs(dop.PDOP/base.PDOP-factor) > 1e-9 || math.Abs(dop.VDOP/base.VDOP-factor) > 1e-9 {
+			t.Errorf("Expected PDOP, HDOP and VDOP scaled together, got %+v from %+v", dop, base)
+		}
+		if dop != base {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Error("Expected DOP jitter to vary reported DOP")
+	}
+
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.DOPJitter = 1.5
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for DOP jitter above 1.0")
+	}
+}
@@ -0,0 +1,63 @@
+package gps
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// dropoutFadeTime is how long satellite signal strength takes to fade out
+// before the fix is lost
+const dropoutFadeTime = 2 * time.Second
+
+// scheduleDropout plans the next signal dropout DropoutInterval after now.
+// Nothing is scheduled when dropouts are disabled.
+func (s *GPSSimulator) scheduleDropout(now time.Time) {
+	s.signalFade = 0
+	s.signalLost = false
+	if s.Config.DropoutInterval <= 0 {
+		s.dropoutAt = time.Time{}
+		return
+	}
+	s.dropoutAt = now.Add(s.Config.DropoutInterval)
+}
+
+// updateDropout fades satellite signals once a scheduled dropout begins and
+// drops the fix when they have faded out. The fix stays lost for
+// DropoutDuration, after which satellites are re-acquired as at start up.
+func (s *GPSSimulator) updateDropout(now time.Time) {
+	if !s.isLocked || s.dropoutAt.IsZero() || now.Before(s.dropoutAt) {
+		return
+	}
+
+	fading := now.Sub(s.dropoutAt)
+	if fading < dropoutFadeTime {
+		s.signalFade = float64(fading) / float64(dropoutFadeTime)
+		return
+	}
+
+	// Satellites are re-acquired from scratch once the dropout ends
+	s.isLocked = false
+	s.signalLost = true
+	s.signalFade = 0
+	s.dropoutAt = time.Time{}
+	s.scheduleAcquisition(now.Add(s.Config.DropoutDuration))
+
+	if !s.Config.Quiet {
+		fmt.Fprintf(os.Stderr, "GPS SIGNAL LOST after %v\n", now.Sub(s.startTime))
+	}
+}
+
+// signalSNR returns a satellite's reported signal-to-noise ratio, attenuated
+// while the signal is fading during a dropout or still being acquired
+func (s *GPSSimulator) signalSNR(sat Satellite) int {
+	return int(s.acquisitionSNR(sat, float64(sat.SNR)*(1-s.signalFade)))
+}
+
+// IsSignalLost reports whether a signal dropout is in progress, from the
+// moment the fix is lost until it is re-acquired
+func (s *GPSSimulator) IsSignalLost() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.signalLost
+}
@@ -0,0 +1,212 @@
+package gps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createDropoutSimulator creates a simulator on a fake clock that loses its
+// fix every interval for duration
+func createDropoutSimulator(interval, duration time.Duration) (*GPSSimulator, *fakeClock) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.Config.TimeToLock = 5 * time.Second
+	sim.Config.DropoutInterval = interval
+	sim.Config.DropoutDuration = duration
+	sim.Config.Quiet = true
+
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lockTime = clock.Now().Add(sim.Config.TimeToLock)
+	return sim, clock
+}
+
+func TestDropoutCycle(t *testing.T) {
+	sim, clock := createDropoutSimulator(20*time.Second, 10*time.Second)
+
+	step := func(d time.Duration) {
+		clock.Advance(d)
+		sim.update()
+	}
+
+	step(6 * time.Second)
+	if !sim.isLocked {
+		t.Fatal("Expected lock after TimeToLock")
+	}
+
+	// Signal starts fading once the interval has passed, but the fix is kept
+	step(21 * time.Second)
+	if !sim.isLocked {
+		t.Fatal("Fix should be kept while the signal fades")
+	}
+	if sim.signalFade <= 0 || sim.signalFade >= 1 {
+		t.Errorf("Expected partial signal fade, got %.2f", sim.signalFade)
+	}
+	sat := sim.Satellites[0]
+	if sim.signalSNR(sat) >= sat.SNR {
+		t.Errorf("Expected attenuated SNR below %d, got %d", sat.SNR, sim.signalSNR(sat))
+	}
+
+	// Fix is lost once the signal has faded out
+	step(2 * time.Second)
+	if sim.isLocked {
+		t.Fatal("Expected fix to be lost after the signal fades")
+	}
+
+	// Re-acquisition takes DropoutDuration plus TimeToLock
+	step(14 * time.Second)
+	if sim.isLocked {
+		t.Fatal("Fix should stay lost for the dropout duration and lock time")
+	}
+	step(2 * time.Second)
+	if !sim.isLocked {
+		t.Fatal("Expected fix to be re-acquired")
+	}
+	if sim.signalFade != 0 {
+		t.Errorf("Expected signal to be restored after re-acquisition, got fade %.2f", sim.signalFade)
+	}
+}
+
+func TestDropoutDisabled(t *testing.T) {
+	sim, clock := createDropoutSimulator(0, 10*time.Second)
+
+	for i := 0; i < 600; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		if i > 6 && !sim.isLocked {
+			t.Fatalf("Fix should never be lost with dropouts disabled (lost at %ds)", i)
+		}
+	}
+}
+
+func TestDropoutOutput(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 5 * time.Second
+	config.OutputRate = time.Second
+	config.DropoutInterval = time.Minute
+	config.DropoutDuration = 15 * time.Second
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	if _, err := sim.GenerateTo(buffer, 10*time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Count transitions into no-fix in each sentence that reports fix status
+	windows := map[string]int{}
+	valid := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\r\n") {
+		fields := strings.Split(strings.Split(line, "*")[0], ",")
+		var kind string
+		var fix bool
+		switch {
+		case strings.HasPrefix(line, "$GPGGA"):
+			kind, fix = "GGA", fields[6] != "0"
+		case strings.HasPrefix(line, "$GPRMC"):
+			kind, fix = "RMC", fields[2] == "A"
+		case strings.HasPrefix(line, "$GPGSA"):
+			kind, fix = "GSA", fields[2] != "1"
+		default:
+			continue
+		}
+		if valid[kind] && !fix {
+			windows[kind]++
+		}
+		valid[kind] = fix
+	}
+
+	// After the initial lock each cycle is 60s locked, 2s fading and 20s
+	// without fix, so 10 minutes holds 7 dropouts
+	for _, kind := range []string{"GGA", "RMC", "GSA"} {
+		if windows[kind] != 7 {
+			t.Errorf("Expected 7 %s no-fix windows, got %d", kind, windows[kind])
+		}
+	}
+}
+
+func TestDropoutSkipsGPX(t *testing.T) {
+	sim, clock := createDropoutSimulator(10*time.Second, 10*time.Second)
+	gpxWriter, err := NewGPXWriter(t.TempDir() + "/dropout.gpx")
+	if err != nil {
+		t.Fatalf("Failed to create GPX writer: %v", err)
+	}
+	sim.gpxWriter = gpxWriter
+
+	// Over 60s the fix is held from 6-17s and 34-45s, fading included
+	for i := 0; i < 60; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		sim.updateTracks()
+	}
+
+	if count := gpxWriter.GetTrackPointCount(); count < 20 || count > 26 {
+		t.Errorf("Expected GPX points only while locked (20-26), got %d", count)
+	}
+}
+
+func TestDropoutValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.DropoutInterval = time.Minute
+	config.DropoutDuration = 10 * time.Second
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid dropout config, got: %v", err)
+	}
+
+	config.DropoutDuration = -time.Second
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative dropout duration")
+	}
+}
+
+func TestDropoutNoFixSentencesAppearAndDisappear(t *testing.T) {
+	sim, clock := createDropoutSimulator(10*time.Second, 5*time.Second)
+
+	// Record whether each cycle's RMC reports a fix and whether a loss is active
+	var statuses []string
+	var lost []bool
+	for i := 0; i < 40; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+
+		buffer := &bytes.Buffer{}
+		sim.nmeaWriter = buffer
+		sim.outputNMEA()
+		for _, line := range strings.Split(buffer.String(), "\r\n") {
+			if strings.HasPrefix(line, "$GPRMC") {
+				statuses = append(statuses, strings.Split(line, ",")[2])
+			}
+		}
+		lost = append(lost, sim.IsSignalLost())
+	}
+
+	// No fix while acquiring, fix, lost during the dropout, fix again
+	var transitions []string
+	for i, status := range statuses {
+		if i == 0 || status != statuses[i-1] {
+			transitions = append(transitions, status)
+		}
+	}
+	if strings.Join(transitions, ",") != "V,A,V,A" {
+		t.Errorf("Expected RMC status V,A,V,A across a loss cycle, got %v", transitions)
+	}
+
+	// A loss is only reported during the dropout, not the initial acquisition
+	for i, status := range statuses {
+		if lost[i] && status != "V" {
+			t.Errorf("Cycle %d: signal loss reported while RMC has a fix", i)
+		}
+	}
+	if lost[0] || !lost[20] || lost[len(lost)-1] {
+		t.Errorf("Expected signal loss only during the dropout, got %v", lost)
+	}
+}
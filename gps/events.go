@@ -0,0 +1,74 @@
+package gps
+
+import (
+	"sync"
+	"time"
+)
+
+// Simulator status events passed to event callbacks
+const (
+	EventLock           = "lock"            // The receiver got a fix
+	EventLockLost       = "lock_lost"       // The receiver lost its fix, such as in a signal dropout
+	EventReplayComplete = "replay_complete" // A non-looping replay reached the end of the track
+	EventConfigUpdate   = "config_update"   // Live settings changed with UpdateConfig, a script or scenario playback
+)
+
+// Event is a change in the simulator's status. The JSON field names are
+// stable.
+type Event struct {
+	Type      string    `json:"type"`               // One of the Event constants
+	Timestamp time.Time `json:"timestamp"`          // Simulated time of the change
+	Geofence  string    `json:"geofence,omitempty"` // Name of the geofence for geofence events
+}
+
+// eventHub holds the registered event callbacks
+type eventHub struct {
+	mu        sync.Mutex
+	callbacks []func(Event)
+	geofence  []func(GeofenceEvent)
+}
+
+// AddEventCallback registers fn to receive an Event each time the receiver
+// gains or loses its fix, a replay completes, the configuration is updated
+// or the position enters or leaves a geofence, so consumers need not poll
+// for status. It is safe to call while Run is active. Like AddCallback,
+// callbacks other than replay completion run while the simulator is locked,
+// so they must return quickly and must not call back into methods such as
+// Pause, Seek or Snapshot.
+func (s *GPSSimulator) AddEventCallback(fn func(Event)) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.callbacks = append(s.events.callbacks, fn)
+}
+
+// newEvent returns an event of the given type happening now
+func (s *GPSSimulator) newEvent(eventType string) Event {
+	return Event{Type: eventType, Timestamp: s.fixTime(s.now())}
+}
+
+// notifyEvent passes an event of the given type to every event callback
+func (s *GPSSimulator) notifyEvent(eventType string) {
+	s.dispatchEvent(s.newEvent(eventType))
+}
+
+// dispatchEvent passes event to every event callback
+func (s *GPSSimulator) dispatchEvent(event Event) {
+	s.events.mu.Lock()
+	callbacks := s.events.callbacks
+	s.events.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(event)
+	}
+}
+
+// notifyLockChange sends a lock or lock lost event when the lock state now
+// differs from wasLocked
+func (s *GPSSimulator) notifyLockChange(wasLocked bool) {
+	switch {
+	case s.isLocked && !wasLocked:
+		s.notifyEvent(EventLock)
+	case !s.isLocked && wasLocked:
+		s.notifyEvent(EventLockLost)
+	}
+}
@@ -0,0 +1,114 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockEvents(t *testing.T) {
+	sim, clock := createDropoutSimulator(20*time.Second, 10*time.Second)
+
+	var events []Event
+	sim.AddEventCallback(func(event Event) {
+		events = append(events, event)
+	})
+
+	// Lock, lose the fix in a dropout and re-acquire it
+	for i := 0; i < 45; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+	}
+
+	expected := []string{EventLock, EventLockLost, EventLock}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i+1, expected[i], event.Type)
+		}
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 6, 0, time.UTC); !events[0].Timestamp.Equal(want) {
+		t.Errorf("Expected lock at %v, got %v", want, events[0].Timestamp)
+	}
+}
+
+func TestConfigUpdateEvent(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	var events []Event
+	sim.AddEventCallback(func(event Event) {
+		events = append(events, event)
+	})
+
+	config.Speed = 20
+	if err := sim.UpdateConfig(config); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventConfigUpdate {
+		t.Errorf("Expected one config update event, got %v", events)
+	}
+}
+
+func TestReplayCompleteEvent(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "short.gpx")
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="37.774900" lon="-122.419400"><ele>50.0</ele></trkpt>
+    <trkpt lat="37.775000" lon="-122.419300"><ele>52.0</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 20.0
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	events := make(chan Event, 10)
+	sim.AddEventCallback(func(event Event) {
+		events <- event
+	})
+	runUntilComplete(t, sim, nil)
+
+	close(events)
+	var last Event
+	for event := range events {
+		last = event
+	}
+	if last.Type != EventReplayComplete {
+		t.Errorf("Expected the last event to be %s, got %q", EventReplayComplete, last.Type)
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	event := Event{Type: EventLock, Timestamp: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+	if expected := `{"type":"lock","timestamp":"2024-01-15T12:00:00Z"}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
@@ -0,0 +1,53 @@
+package gps
+
+import "time"
+
+// Fix is the position fix the simulator reports, matching the fields of the
+// GGA sentence for the same cycle
+type Fix struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Lat            float64   `json:"lat"`            // Decimal degrees
+	Lon            float64   `json:"lon"`            // Decimal degrees
+	Altitude       float64   `json:"altitude"`       // Meters above mean sea level
+	SpeedKnots     float64   `json:"speedKnots"`     // Speed over ground
+	CourseDeg      float64   `json:"courseDeg"`      // Course over ground from true north
+	Locked         bool      `json:"locked"`         // Whether the receiver has a fix
+	SatellitesUsed int       `json:"satellitesUsed"` // Satellites used, as reported in GGA
+	HDOP           float64   `json:"hdop"`           // 0 without a fix
+}
+
+// CurrentFix returns the fix recorded at the end of the most recent update,
+// so position, motion and lock state always come from the same cycle. It is
+// safe to call concurrently with Run.
+func (s *GPSSimulator) CurrentFix() Fix {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fix
+}
+
+// currentFix builds the fix reported in GGA from the current state
+func (s *GPSSimulator) currentFix(timestamp time.Time) Fix {
+	fix := Fix{
+		Timestamp:  timestamp,
+		Lat:        s.currentLat,
+		Lon:        s.currentLon,
+		Altitude:   s.currentAlt,
+		SpeedKnots: s.currentSpeed,
+		CourseDeg:  s.currentCourse,
+		Locked:     s.isLocked,
+	}
+
+	switch {
+	case s.isLocked:
+		fix.SatellitesUsed = len(s.Satellites)
+		fix.HDOP = s.currentDOP().HDOP
+	case s.isEstimatedFix():
+		acquired := s.acquiredSatellites()
+		fix.SatellitesUsed = len(acquired)
+		fix.HDOP = s.estimatedHDOP(acquired)
+	default:
+		fix.SatellitesUsed = len(s.acquiredSatellites())
+	}
+
+	return fix
+}
@@ -0,0 +1,99 @@
+package gps
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseNMEACoordinate converts an NMEA DDMM.MMMM or DDDMM.MMMM field and its
+// hemisphere to decimal degrees
+func parseNMEACoordinate(t *testing.T, value, hemisphere string, degreeDigits int) float64 {
+	t.Helper()
+	degrees, err := strconv.ParseFloat(value[:degreeDigits], 64)
+	if err != nil {
+		t.Fatalf("Invalid coordinate %q: %v", value, err)
+	}
+	minutes, err := strconv.ParseFloat(value[degreeDigits:], 64)
+	if err != nil {
+		t.Fatalf("Invalid coordinate %q: %v", value, err)
+	}
+	decimal := degrees + minutes/60
+	if hemisphere == "S" || hemisphere == "W" {
+		decimal = -decimal
+	}
+	return decimal
+}
+
+func TestCurrentFixMatchesGGA(t *testing.T) {
+	sim := createTestSimulator()
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+	sim.currentAlt = 45
+	sim.Config.Speed = 8
+
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+		fix := sim.CurrentFix()
+
+		buffer := &bytes.Buffer{}
+		sim.nmeaWriter = buffer
+		sim.outputNMEA()
+
+		var gga []string
+		for _, line := range strings.Split(buffer.String(), "\r\n") {
+			if strings.HasPrefix(line, "$GPGGA") {
+				gga = sentenceFields(line)
+			}
+		}
+		if gga == nil {
+			t.Fatal("Expected a GGA sentence")
+		}
+
+		if !fix.Locked || !fix.Timestamp.Equal(clock.Now()) {
+			t.Errorf("Expected a locked fix at %v, got %+v", clock.Now(), fix)
+		}
+		if lat := parseNMEACoordinate(t, gga[2], gga[3], 2); math.Abs(lat-fix.Lat) > 2e-6 {
+			t.Errorf("GGA latitude %.7f does not match fix %.7f", lat, fix.Lat)
+		}
+		if lon := parseNMEACoordinate(t, gga[4], gga[5], 3); math.Abs(lon-fix.Lon) > 2e-6 {
+			t.Errorf("GGA longitude %.7f does not match fix %.7f", lon, fix.Lon)
+		}
+		if gga[7] != fmt.Sprintf("%02d", fix.SatellitesUsed) || gga[8] != strconv.FormatFloat(fix.HDOP, 'f', 1, 64) || gga[9] != strconv.FormatFloat(fix.Altitude, 'f', 1, 64) {
+			t.Errorf("GGA satellites/HDOP/altitude %v do not match fix %+v", gga[7:10], fix)
+		}
+	}
+}
+
+func TestCurrentFixBeforeLock(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Hour)
+	sim.update()
+
+	fix := sim.CurrentFix()
+	if fix.Locked || fix.SatellitesUsed != 0 || fix.HDOP != 0 {
+		t.Errorf("Expected an unlocked fix without satellites, got %+v", fix)
+	}
+}
+
+func TestNMEADataCarriesFix(t *testing.T) {
+	sim := createTestSimulator()
+	var received NMEAData
+	sim.AddCallback(func(data NMEAData) { received = data })
+	sim.outputNMEA()
+
+	fix := received.Fix
+	if fix.Lat != received.Latitude || fix.Lon != received.Longitude || fix.SatellitesUsed != received.Satellites || fix.HDOP != received.HDOP {
+		t.Errorf("Expected the fix to match the NMEAData fields, got %+v and %+v", fix, received)
+	}
+	if !fix.Locked || !fix.Timestamp.Equal(received.Timestamp) {
+		t.Errorf("Expected a locked fix at the cycle timestamp, got %+v", fix)
+	}
+}
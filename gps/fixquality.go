@@ -0,0 +1,94 @@
+package gps
+
+import "fmt"
+
+// GGA fix quality indicators
+const (
+	FixQualityInvalid   = 0
+	FixQualityGPS       = 1
+	FixQualityDGPS      = 2
+	FixQualityPPS       = 3
+	FixQualityRTKFixed  = 4
+	FixQualityRTKFloat  = 5
+	FixQualityEstimated = 6
+	FixQualityManual    = 7
+	FixQualitySimulated = 8
+)
+
+// fixQualityModes maps each locked GGA fix quality to the NMEA 2.3 mode
+// indicator reported in RMC, GLL and VTG
+var fixQualityModes = map[int]string{
+	FixQualityGPS:       "A", // Autonomous
+	FixQualityDGPS:      "D", // Differential
+	FixQualityPPS:       "P", // Precise
+	FixQualityRTKFixed:  "R", // RTK fixed
+	FixQualityRTKFloat:  "F", // RTK float
+	FixQualityEstimated: "E", // Estimated (dead reckoning)
+	FixQualityManual:    "M", // Manual input
+	FixQualitySimulated: "S", // Simulator
+}
+
+// fixQualityNoise is the default position noise in meters for each fix
+// quality, used when Config.FixQuality is set and Config.Jitter is zero
+var fixQualityNoise = map[int]float64{
+	FixQualityGPS:       2.0,
+	FixQualityDGPS:      0.5,
+	FixQualityPPS:       0.5,
+	FixQualityRTKFixed:  0.02,
+	FixQualityRTKFloat:  0.2,
+	FixQualityEstimated: 5.0,
+}
+
+// DefaultDGPSAge is the age of differential corrections in seconds reported
+// for DGPS and RTK fixes when Config.DGPSAge is zero
+const DefaultDGPSAge = 1.0
+
+// MaxDGPSStationID is the largest differential reference station ID GGA can report
+const MaxDGPSStationID = 1023
+
+// fixQuality returns the GGA quality indicator reported while locked,
+// defaulting to a standard GPS fix when Config.FixQuality is unset
+func (s *GPSSimulator) fixQuality() int {
+	if s.Config.FixQuality == 0 {
+		return FixQualityGPS
+	}
+	return s.Config.FixQuality
+}
+
+// modeIndicator returns the RMC, GLL and VTG mode indicator for the fix quality
+func (s *GPSSimulator) modeIndicator() string {
+	return fixQualityModes[s.fixQuality()]
+}
+
+// qualityNoiseDistance returns the maximum position noise in meters implied
+// by an explicitly configured fix quality. It is zero when Config.Jitter is
+// set, since the jitter factor then controls position noise.
+func (s *GPSSimulator) qualityNoiseDistance() float64 {
+	if s.Config.FixQuality == 0 || s.Config.Jitter > 0 {
+		return 0
+	}
+	return fixQualityNoise[s.Config.FixQuality]
+}
+
+// isDifferential reports whether the fix quality uses corrections from a
+// differential reference station (DGPS, RTK fixed or RTK float)
+func (s *GPSSimulator) isDifferential() bool {
+	switch s.fixQuality() {
+	case FixQualityDGPS, FixQualityRTKFixed, FixQualityRTKFloat:
+		return true
+	}
+	return false
+}
+
+// differentialFields returns the GGA and GNS age of differential data in
+// seconds and reference station ID, both empty unless the fix is differential
+func (s *GPSSimulator) differentialFields() (string, string) {
+	if !s.isDifferential() {
+		return "", ""
+	}
+	age := s.Config.DGPSAge
+	if age == 0 {
+		age = DefaultDGPSAge
+	}
+	return fmt.Sprintf("%.1f", age), fmt.Sprintf("%04d", s.Config.DGPSStationID)
+}
@@ -0,0 +1,190 @@
+package gps
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentenceFields splits an NMEA sentence into its fields without the checksum
+func sentenceFields(sentence string) []string {
+	return strings.Split(strings.Split(sentence, "*")[0], ",")
+}
+
+func TestFixQualityIndicators(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		quality       int
+		ggaQuality    string
+		modeIndicator string
+	}{
+		{0, "1", "A"},
+		{FixQualityGPS, "1", "A"},
+		{FixQualityDGPS, "2", "D"},
+		{FixQualityRTKFixed, "4", "R"},
+		{FixQualityRTKFloat, "5", "F"},
+		{FixQualityEstimated, "6", "E"},
+		{FixQualitySimulated, "8", "S"},
+	}
+
+	for _, tt := range tests {
+		sim := createTestSimulator()
+		sim.Config.FixQuality = tt.quality
+
+		if gga := sentenceFields(sim.generateGGA(testTime)); gga[6] != tt.ggaQuality {
+			t.Errorf("Quality %d: expected GGA quality %s, got %s", tt.quality, tt.ggaQuality, gga[6])
+		}
+		if rmc := sentenceFields(sim.generateRMC(testTime)); rmc[2] != "A" || rmc[12] != tt.modeIndicator {
+			t.Errorf("Quality %d: expected RMC status A mode %s, got %s mode %s", tt.quality, tt.modeIndicator, rmc[2], rmc[12])
+		}
+		if gll := sentenceFields(sim.generateGLL(testTime)); gll[6] != "A" || gll[7] != tt.modeIndicator {
+			t.Errorf("Quality %d: expected GLL status A mode %s, got %s mode %s", tt.quality, tt.modeIndicator, gll[6], gll[7])
+		}
+		if vtg := sentenceFields(sim.generateVTG()); vtg[9] != tt.modeIndicator {
+			t.Errorf("Quality %d: expected VTG mode %s, got %s", tt.quality, tt.modeIndicator, vtg[9])
+		}
+	}
+}
+
+func TestFixQualityNoise(t *testing.T) {
+	maxStep := func(quality int, jitter float64) float64 {
+		sim := createTestSimulator()
+		sim.Config.FixQuality = quality
+		sim.Config.Jitter = jitter
+		sim.Config.Radius = 0
+		sim.currentSpeed = 0
+
+		largest := 0.0
+		for i := 0; i < 200; i++ {
+			lat, lon := sim.currentLat, sim.currentLon
+			sim.lastUpdateTime = time.Now().Add(-time.Second)
+			sim.updatePosition()
+			if d := sim.calculateDistance(lat, lon, sim.currentLat, sim.currentLon); d > largest {
+				largest = d
+			}
+		}
+		return largest
+	}
+
+	if step := maxStep(0, 0); step != 0 {
+		t.Errorf("Expected no noise without fix quality or jitter, got %.3fm", step)
+	}
+	if step := maxStep(FixQualityRTKFixed, 0); step == 0 || step > 0.021 {
+		t.Errorf("Expected centimeter-level RTK fixed noise, got %.3fm", step)
+	}
+	if step := maxStep(FixQualityGPS, 0); step < 0.5 || step > 2.01 {
+		t.Errorf("Expected meter-level autonomous noise, got %.3fm", step)
+	}
+	if step := maxStep(FixQualityRTKFixed, 0.5); step < 0.5 {
+		t.Errorf("Expected explicit jitter to override RTK noise, got %.3fm", step)
+	}
+}
+
+func TestFixQualityValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.FixQuality = FixQualityRTKFixed
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid fix quality, got: %v", err)
+	}
+
+	for _, quality := range []int{-1, 9} {
+		config.FixQuality = quality
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for fix quality %d", quality)
+		}
+	}
+}
+
+func TestDifferentialFields(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		quality   int
+		age       float64
+		stationID int
+		wantAge   string
+		wantID    string
+	}{
+		{FixQualityGPS, 3.5, 42, "", ""},
+		{FixQualityDGPS, 0, 0, "1.0", "0000"},
+		{FixQualityDGPS, 3.5, 42, "3.5", "0042"},
+		{FixQualityRTKFixed, 0.8, 1023, "0.8", "1023"},
+		{FixQualityRTKFloat, 2, 7, "2.0", "0007"},
+		{FixQualityEstimated, 2, 7, "", ""},
+	}
+
+	for _, tt := range tests {
+		sim := createTestSimulator()
+		sim.Config.FixQuality = tt.quality
+		sim.Config.DGPSAge = tt.age
+		sim.Config.DGPSStationID = tt.stationID
+
+		gga := sentenceFields(sim.generateGGA(testTime))
+		if gga[13] != tt.wantAge || gga[14] != tt.wantID {
+			t.Errorf("Quality %d: expected GGA DGPS age %q and station %q, got %q and %q",
+				tt.quality, tt.wantAge, tt.wantID, gga[13], gga[14])
+		}
+
+		gns := sentenceFields(sim.generateGNS(testTime))
+		if gns[11] != tt.wantAge || gns[12] != tt.wantID {
+			t.Errorf("Quality %d: expected GNS DGPS age %q and station %q, got %q and %q",
+				tt.quality, tt.wantAge, tt.wantID, gns[11], gns[12])
+		}
+	}
+}
+
+func TestFixQualityErrorStats(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	horizontal := func(quality int) float64 {
+		sim := createTestSimulator()
+		sim.Config.FixQuality = quality
+		return sim.calculateErrorStats().SemiMajor
+	}
+
+	gps, dgps, rtkFloat, rtkFixed := horizontal(FixQualityGPS), horizontal(FixQualityDGPS), horizontal(FixQualityRTKFloat), horizontal(FixQualityRTKFixed)
+	if !(gps > dgps && dgps > rtkFloat && rtkFloat > rtkFixed) {
+		t.Errorf("Expected errors to shrink from GPS to RTK fixed, got %.3f, %.3f, %.3f, %.3f", gps, dgps, rtkFloat, rtkFixed)
+	}
+	if rtkFixed > 0.05 {
+		t.Errorf("Expected centimeter-level RTK fixed error, got %.3f m", rtkFixed)
+	}
+
+	// RTK statistics are reported with millimeter resolution
+	sim := createTestSimulator()
+	sim.Config.FixQuality = FixQualityRTKFixed
+	gst := sentenceFields(sim.generateGST(testTime))
+	if !strings.HasPrefix(gst[6], "0.0") || len(gst[6]) != len("0.000") {
+		t.Errorf("Expected a centimeter-level latitude sigma with three decimals, got %q", gst[6])
+	}
+}
+
+func TestDGPSValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.FixQuality = FixQualityDGPS
+
+	config.DGPSAge = 5
+	config.DGPSStationID = MaxDGPSStationID
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid DGPS settings, got: %v", err)
+	}
+
+	config.DGPSAge = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative DGPS age")
+	}
+
+	config.DGPSAge = 5
+	for _, id := range []int{-1, MaxDGPSStationID + 1} {
+		config.DGPSStationID = id
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for DGPS station ID %d", id)
+		}
+	}
+}
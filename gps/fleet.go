@@ -0,0 +1,308 @@
+package gps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ways a fleet attributes each sentence to its vehicle
+const (
+	FleetTagBlock  = "tag-block" // Prefix each line with an NMEA 4.x TAG block naming the vehicle as its source (\s:id*hh\)
+	FleetTagTalker = "talker"    // Give each vehicle its own talker ID instead of adding a prefix
+)
+
+// FleetConfig describes the vehicles of a fleet
+type FleetConfig struct {
+	Vehicles []Config `json:"vehicles"`          // One configuration per vehicle
+	IDs      []string `json:"ids,omitempty"`     // Vehicle IDs in Vehicles order (default vehicle-1, vehicle-2, ...)
+	Tagging  string   `json:"tagging,omitempty"` // FleetTagBlock (default) or FleetTagTalker
+}
+
+// VehicleStatus is the state of one fleet vehicle. The JSON field names are
+// stable.
+type VehicleStatus struct {
+	ID       string        `json:"id"`
+	TalkerID string        `json:"talker_id,omitempty"` // Talker ID assigned with FleetTagTalker
+	Running  bool          `json:"running"`
+	Status   StateSnapshot `json:"status"`
+}
+
+// fleetVehicle is a fleet member and its simulator
+type fleetVehicle struct {
+	id     string
+	sim    *GPSSimulator
+	writer *vehicleWriter
+}
+
+// Fleet runs several simulators at once, multiplexing their output into one
+// writer with each sentence attributed to its vehicle. Vehicle ticks are
+// staggered across the output interval so their bursts do not line up.
+type Fleet struct {
+	vehicles []*fleetVehicle
+	tagging  string
+	out      *fleetWriter
+
+	mu      sync.Mutex
+	started bool
+	stop    chan struct{}
+	stopped bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// fleetWriter serializes the vehicles' writes to the shared writer
+type fleetWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// vehicleWriter writes one vehicle's output to the fleet writer a whole line
+// at a time, with the vehicle's prefix, so sentences from different vehicles
+// never interleave mid-line
+type vehicleWriter struct {
+	out     *fleetWriter
+	prefix  string
+	pending []byte // Start of a line not yet terminated
+}
+
+func (v *vehicleWriter) Write(p []byte) (int, error) {
+	v.pending = append(v.pending, p...)
+	end := bytes.LastIndexByte(v.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+
+	var block bytes.Buffer
+	for _, line := range bytes.SplitAfter(v.pending[:end+1], []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		block.WriteString(v.prefix)
+		block.Write(line)
+	}
+	v.pending = append(v.pending[:0], v.pending[end+1:]...)
+
+	v.out.mu.Lock()
+	defer v.out.mu.Unlock()
+	if _, err := v.out.w.Write(block.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// tagBlock returns the NMEA TAG block naming id as the source station
+func tagBlock(id string) string {
+	tag := "s:" + id
+	return `\` + tag + "*" + calculateChecksum(`\`+tag) + `\`
+}
+
+// NewFleet validates every vehicle's configuration and creates its
+// simulator, writing to w. It returns an error for an invalid vehicle
+// configuration, duplicate or malformed vehicle IDs, or an unknown tagging
+// mode. With FleetTagTalker, vehicles without a TalkerID are given unused
+// ones from VA, VB and so on.
+func NewFleet(config FleetConfig, w io.Writer) (*Fleet, error) {
+	if len(config.Vehicles) == 0 {
+		return nil, errors.New("a fleet needs at least one vehicle")
+	}
+	tagging := config.Tagging
+	if tagging == "" {
+		tagging = FleetTagBlock
+	}
+	if tagging != FleetTagBlock && tagging != FleetTagTalker {
+		return nil, fmt.Errorf("unknown fleet tagging %q (valid: %s, %s)", config.Tagging, FleetTagBlock, FleetTagTalker)
+	}
+	if len(config.IDs) > 0 && len(config.IDs) != len(config.Vehicles) {
+		return nil, fmt.Errorf("got %d vehicle IDs for %d vehicles", len(config.IDs), len(config.Vehicles))
+	}
+
+	ids, err := fleetIDs(config)
+	if err != nil {
+		return nil, err
+	}
+	configs := append([]Config(nil), config.Vehicles...)
+	if tagging == FleetTagTalker {
+		if err := assignTalkerIDs(configs); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &Fleet{tagging: tagging, out: &fleetWriter{w: w}, stop: make(chan struct{}), done: make(chan struct{})}
+	for i, vehicleConfig := range configs {
+		if err := vehicleConfig.Validate(); err != nil {
+			f.close()
+			return nil, fmt.Errorf("vehicle %s: %v", ids[i], err)
+		}
+		writer := &vehicleWriter{out: f.out}
+		if tagging == FleetTagBlock {
+			writer.prefix = tagBlock(ids[i])
+		}
+		sim, err := NewGPSSimulator(vehicleConfig, writer)
+		if err != nil {
+			f.close()
+			return nil, fmt.Errorf("vehicle %s: %v", ids[i], err)
+		}
+		f.vehicles = append(f.vehicles, &fleetVehicle{id: ids[i], sim: sim, writer: writer})
+	}
+	return f, nil
+}
+
+// fleetIDs returns the configured vehicle IDs, or vehicle-1, vehicle-2 and
+// so on, checking they are unique and safe in a TAG block
+func fleetIDs(config FleetConfig) ([]string, error) {
+	ids := config.IDs
+	if len(ids) == 0 {
+		for i := range config.Vehicles {
+			ids = append(ids, fmt.Sprintf("vehicle-%d", i+1))
+		}
+	}
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" || strings.ContainsAny(id, `,*\$! `+"\r\n\t") {
+			return nil, fmt.Errorf("invalid vehicle ID %q", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate vehicle ID %q", id)
+		}
+		seen[id] = true
+	}
+	return ids, nil
+}
+
+// assignTalkerIDs gives each config without a TalkerID an unused one from
+// VA to VZ and checks that no two vehicles share one
+func assignTalkerIDs(configs []Config) error {
+	used := make(map[string]bool)
+	for _, c := range configs {
+		if c.TalkerID == "" {
+			continue
+		}
+		if used[c.TalkerID] {
+			return fmt.Errorf("talker ID %s is used by more than one vehicle", c.TalkerID)
+		}
+		used[c.TalkerID] = true
+	}
+
+	next := 'A'
+	for i := range configs {
+		if configs[i].TalkerID != "" {
+			continue
+		}
+		for ; next <= 'Z' && used["V"+string(next)]; next++ {
+		}
+		if next > 'Z' {
+			return errors.New("too many vehicles to assign distinct talker IDs")
+		}
+		configs[i].TalkerID = "V" + string(next)
+		used[configs[i].TalkerID] = true
+		next++
+	}
+	return nil
+}
+
+// Start runs every vehicle in the background, the i-th of n starting i/n of
+// its output interval after the first. It returns an error if the fleet has
+// already been started or stopped.
+func (f *Fleet) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.started || f.stopped {
+		return errors.New("fleet already started or stopped")
+	}
+	f.started = true
+
+	for i, v := range f.vehicles {
+		delay := v.sim.Config.OutputRate * time.Duration(i) / time.Duration(len(f.vehicles))
+		f.wg.Add(1)
+		go func(sim *GPSSimulator) {
+			defer f.wg.Done()
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-f.stop:
+			}
+			// A stopped simulator's Run returns at once, closing its outputs
+			sim.Run()
+		}(v.sim)
+	}
+	go func() {
+		f.wg.Wait()
+		close(f.done)
+	}()
+	return nil
+}
+
+// Stop stops every vehicle and waits for them to close their outputs. It is
+// safe to call more than once, and before Start.
+func (f *Fleet) Stop() {
+	f.mu.Lock()
+	first := !f.stopped
+	if first {
+		f.stopped = true
+		close(f.stop)
+		for _, v := range f.vehicles {
+			v.sim.Stop()
+		}
+	}
+	started := f.started
+	f.mu.Unlock()
+
+	if started {
+		<-f.done
+	} else if first {
+		f.close()
+	}
+}
+
+// close closes the outputs of vehicles that never ran
+func (f *Fleet) close() {
+	for _, v := range f.vehicles {
+		v.sim.Close()
+	}
+}
+
+// Done returns a channel closed once every vehicle has finished, whether
+// stopped or done with its replay, route or duration. It is never closed
+// for a fleet that was not started.
+func (f *Fleet) Done() <-chan struct{} {
+	return f.done
+}
+
+// Status returns the state of every vehicle in fleet order
+func (f *Fleet) Status() []VehicleStatus {
+	status := make([]VehicleStatus, len(f.vehicles))
+	for i, v := range f.vehicles {
+		status[i] = VehicleStatus{ID: v.id, Running: v.sim.IsRunning(), Status: v.sim.Snapshot()}
+		if f.tagging == FleetTagTalker {
+			status[i].TalkerID = v.sim.Config.TalkerID
+		}
+	}
+	return status
+}
+
+// Vehicle returns the simulator of the vehicle with the given ID, or nil
+func (f *Fleet) Vehicle(id string) *GPSSimulator {
+	for _, v := range f.vehicles {
+		if v.id == id {
+			return v.sim
+		}
+	}
+	return nil
+}
+
+// AddCallback registers fn to receive every vehicle's NMEAData after each of
+// its output cycles, with the vehicle's ID. Like GPSSimulator.AddCallback,
+// fn runs on the vehicle's simulation goroutine while it is locked, and is
+// called concurrently for different vehicles.
+func (f *Fleet) AddCallback(fn func(vehicleID string, data NMEAData)) {
+	for _, v := range f.vehicles {
+		id := v.id
+		v.sim.AddCallback(func(data NMEAData) { fn(id, data) })
+	}
+}
@@ -0,0 +1,141 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxFleetRequest limits the size of a fleet creation request
+const maxFleetRequest = 1 << 20
+
+// FleetServer creates and reports on a fleet over HTTP, one at a time, for
+// mounting at /api/fleet. Each vehicle's configuration is merged over the
+// base configuration, so requests only need the settings that differ, and
+// every fleet writes to the same writer.
+type FleetServer struct {
+	base   Config
+	writer io.Writer
+
+	mu    sync.Mutex
+	fleet *Fleet
+}
+
+// fleetRequest is a fleet creation request before the vehicle
+// configurations are merged over the base
+type fleetRequest struct {
+	Vehicles []json.RawMessage `json:"vehicles"`
+	IDs      []string          `json:"ids"`
+	Tagging  string            `json:"tagging"`
+}
+
+// NewFleetServer returns a server creating fleets from the base
+// configuration
+func NewFleetServer(base Config, writer io.Writer) *FleetServer {
+	return &FleetServer{base: base, writer: writer}
+}
+
+// Fleet returns the running fleet, or nil before the first is created
+func (fs *FleetServer) Fleet() *Fleet {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.fleet
+}
+
+// Stop stops the running fleet, if any, and waits for it to finish
+func (fs *FleetServer) Stop() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.fleet != nil {
+		fs.fleet.Stop()
+	}
+}
+
+// ServeHTTP creates a fleet on POST, replacing the one before it, from a
+// JSON array of vehicle configurations or a FleetConfig object, and
+// responds with the vehicles' status. GET responds with the status of the
+// running fleet and DELETE stops it.
+func (fs *FleetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		fs.create(w, r)
+	case http.MethodGet:
+		fleet := fs.Fleet()
+		if fleet == nil {
+			http.Error(w, "no fleet has been created", http.StatusNotFound)
+			return
+		}
+		writeFleetStatus(w, http.StatusOK, fleet)
+	case http.MethodDelete:
+		fs.Stop()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// create starts a fleet from the request body
+func (fs *FleetServer) create(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFleetRequest))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	config, err := fs.fleetConfig(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fleet, err := NewFleet(config, fs.writer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if fs.fleet != nil {
+		fs.fleet.Stop()
+	}
+	if err := fleet.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fs.fleet = fleet
+	writeFleetStatus(w, http.StatusCreated, fleet)
+}
+
+// fleetConfig decodes a request body, merging each vehicle's settings over
+// the base configuration
+func (fs *FleetServer) fleetConfig(body []byte) (FleetConfig, error) {
+	var request fleetRequest
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(body, &request.Vehicles); err != nil {
+			return FleetConfig{}, fmt.Errorf("invalid fleet: %v", err)
+		}
+	} else if err := json.Unmarshal(body, &request); err != nil {
+		return FleetConfig{}, fmt.Errorf("invalid fleet: %v", err)
+	}
+
+	config := FleetConfig{IDs: request.IDs, Tagging: request.Tagging}
+	for i, data := range request.Vehicles {
+		vehicle := fs.base
+		if err := json.Unmarshal(data, &vehicle); err != nil {
+			return FleetConfig{}, fmt.Errorf("invalid vehicle %d: %v", i+1, err)
+		}
+		config.Vehicles = append(config.Vehicles, vehicle)
+	}
+	return config, nil
+}
+
+// writeFleetStatus responds with the fleet's vehicle status as JSON
+func writeFleetStatus(w http.ResponseWriter, status int, fleet *Fleet) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(fleet.Status())
+}
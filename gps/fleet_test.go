@@ -0,0 +1,270 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while the fleet writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// createFleetConfig returns a fleet of n vehicles locking at once and
+// reporting every 100ms
+func createFleetConfig(n int) FleetConfig {
+	var config FleetConfig
+	for i := 0; i < n; i++ {
+		vehicle := createTestConfig()
+		vehicle.BaudRate = 9600
+		vehicle.ReplaySpeed = 1.0
+		vehicle.Quiet = true
+		vehicle.TimeToLock = time.Millisecond
+		vehicle.OutputRate = 100 * time.Millisecond
+		vehicle.Latitude += float64(i) * 0.01
+		config.Vehicles = append(config.Vehicles, vehicle)
+	}
+	return config
+}
+
+// splitTagBlock returns the source of a TAG-blocked line and the sentence
+// after it, checking the TAG block checksum
+func splitTagBlock(t *testing.T, line string) (string, string) {
+	t.Helper()
+	if !strings.HasPrefix(line, `\`) {
+		t.Fatalf("Expected a TAG block on %q", line)
+	}
+	end := strings.Index(line[1:], `\`) + 1
+	if end < 1 {
+		t.Fatalf("Unterminated TAG block on %q", line)
+	}
+	tag, checksum, _ := strings.Cut(line[1:end], "*")
+	if want := calculateChecksum(`\` + tag); checksum != want {
+		t.Errorf("Expected TAG block checksum %s, got %s in %q", want, checksum, line)
+	}
+	return strings.TrimPrefix(tag, "s:"), line[end+1:]
+}
+
+func TestFleetTagBlocks(t *testing.T) {
+	buffer := &syncBuffer{}
+	fleet, err := NewFleet(createFleetConfig(3), buffer)
+	if err != nil {
+		t.Fatalf("Failed to create fleet: %v", err)
+	}
+	if err := fleet.Start(); err != nil {
+		t.Fatalf("Failed to start fleet: %v", err)
+	}
+	time.Sleep(time.Second)
+	fleet.Stop()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n")
+	gga := make(map[string]int)
+	switches := 0
+	previous := ""
+	for _, line := range lines {
+		source, sentence := splitTagBlock(t, line)
+		if !validateChecksum(sentence) {
+			t.Errorf("Expected a whole sentence after the TAG block, got %q", line)
+		}
+		if strings.HasPrefix(sentence, "$GPGGA") {
+			gga[source]++
+		}
+		if previous != "" && source != previous {
+			switches++
+		}
+		previous = source
+	}
+
+	for _, id := range []string{"vehicle-1", "vehicle-2", "vehicle-3"} {
+		if gga[id] < 5 {
+			t.Errorf("Expected at least 5 GGA sentences from %s in a second, got %d", id, gga[id])
+		}
+	}
+	if len(gga) != 3 {
+		t.Errorf("Expected sentences from exactly 3 vehicles, got %v", gga)
+	}
+	// Bursts from the vehicles take turns rather than arriving in one block each
+	if switches < 10 {
+		t.Errorf("Expected the vehicles' output interleaved, source changed only %d times", switches)
+	}
+
+	select {
+	case <-fleet.Done():
+	default:
+		t.Error("Expected Done closed after Stop")
+	}
+	for _, status := range fleet.Status() {
+		if status.Running {
+			t.Errorf("Expected %s stopped", status.ID)
+		}
+	}
+}
+
+func TestFleetTalkerIDs(t *testing.T) {
+	config := createFleetConfig(3)
+	config.Tagging = FleetTagTalker
+	config.IDs = []string{"truck", "van", "bus"}
+	config.Vehicles[1].TalkerID = "VA"
+
+	buffer := &syncBuffer{}
+	fleet, err := NewFleet(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create fleet: %v", err)
+	}
+	fleet.Start()
+	time.Sleep(300 * time.Millisecond)
+	fleet.Stop()
+
+	talkers := make(map[string]string)
+	for _, status := range fleet.Status() {
+		talkers[status.ID] = status.TalkerID
+	}
+	if talkers["truck"] != "VB" || talkers["van"] != "VA" || talkers["bus"] != "VC" {
+		t.Errorf("Expected talker IDs VB, VA and VC, got %v", talkers)
+	}
+	for _, talker := range []string{"VA", "VB", "VC"} {
+		if !strings.Contains(buffer.String(), "$"+talker+"GGA") {
+			t.Errorf("Expected GGA sentences from talker %s", talker)
+		}
+	}
+	if strings.Contains(buffer.String(), `\s:`) {
+		t.Error("Expected no TAG blocks with talker tagging")
+	}
+}
+
+func TestFleetCallbackVehicleID(t *testing.T) {
+	fleet, err := NewFleet(createFleetConfig(2), &syncBuffer{})
+	if err != nil {
+		t.Fatalf("Failed to create fleet: %v", err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	fleet.AddCallback(func(id string, data NMEAData) {
+		mu.Lock()
+		defer mu.Unlock()
+		if data.Sentences != "" {
+			seen[id]++
+		}
+	})
+	fleet.Start()
+	time.Sleep(300 * time.Millisecond)
+	fleet.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen["vehicle-1"] == 0 || seen["vehicle-2"] == 0 {
+		t.Errorf("Expected callbacks from both vehicles, got %v", seen)
+	}
+}
+
+func TestVehicleWriterKeepsLinesWhole(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := &vehicleWriter{out: &fleetWriter{w: out}, prefix: tagBlock("a")}
+
+	writer.Write([]byte("$GPGGA,1"))
+	if out.Len() != 0 {
+		t.Fatalf("Expected a partial line held back, got %q", out.String())
+	}
+	writer.Write([]byte("*00\r\n$GPRMC,2*00\r\n$GPV"))
+	writer.Write([]byte("TG*00\r\n"))
+
+	want := tagBlock("a") + "$GPGGA,1*00\r\n" + tagBlock("a") + "$GPRMC,2*00\r\n" + tagBlock("a") + "$GPVTG*00\r\n"
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestFleetValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*FleetConfig)
+	}{
+		{"No vehicles", func(c *FleetConfig) { c.Vehicles = nil }},
+		{"Unknown tagging", func(c *FleetConfig) { c.Tagging = "prefix" }},
+		{"ID count mismatch", func(c *FleetConfig) { c.IDs = []string{"a"} }},
+		{"Duplicate IDs", func(c *FleetConfig) { c.IDs = []string{"a", "a"} }},
+		{"Unsafe ID", func(c *FleetConfig) { c.IDs = []string{"a*b", "c"} }},
+		{"Invalid vehicle", func(c *FleetConfig) { c.Vehicles[1].Satellites = 0 }},
+		{"Shared talker ID", func(c *FleetConfig) {
+			c.Tagging = FleetTagTalker
+			c.Vehicles[0].TalkerID = "GP"
+			c.Vehicles[1].TalkerID = "GP"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createFleetConfig(2)
+			tt.configure(&config)
+			if _, err := NewFleet(config, &syncBuffer{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFleetServer(t *testing.T) {
+	base := createFleetConfig(1).Vehicles[0]
+	server := NewFleetServer(base, &syncBuffer{})
+	defer server.Stop()
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/fleet", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 before a fleet is created, got %d", recorder.Code)
+	}
+
+	body := `[{"Latitude": 51.5, "Longitude": -0.1}, {"Speed": 20}]`
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/fleet", strings.NewReader(body)))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var status []VehicleStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Invalid status JSON: %v", err)
+	}
+	if len(status) != 2 || status[0].ID != "vehicle-1" || status[0].Status.Latitude != 51.5 || status[1].Status.Latitude != base.Latitude {
+		t.Errorf("Expected two vehicles merged over the base config, got %+v", status)
+	}
+
+	body = `{"vehicles": [{}], "ids": ["solo"], "tagging": "talker"}`
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/fleet", strings.NewReader(body)))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/fleet", nil))
+	status = nil
+	json.Unmarshal(recorder.Body.Bytes(), &status)
+	if len(status) != 1 || status[0].ID != "solo" || status[0].TalkerID != "VA" {
+		t.Errorf("Expected the replacement fleet's status, got %+v", status)
+	}
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/fleet", strings.NewReader(`[{"Satellites": 0}]`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid vehicle, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package gps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// sentenceCounter counts the sentences written through it and remembers the
+// first write error so generation can stop early
+type sentenceCounter struct {
+	w     io.Writer
+	count int
+	err   error
+}
+
+func (c *sentenceCounter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.err = err
+		return n, err
+	}
+	c.count += bytes.Count(p[:n], []byte("\n"))
+	return n, nil
+}
+
+// GenerateTo steps the simulation in virtual time at OutputRate intervals for
+// the given duration as fast as possible, writing every sentence to w with
+// properly spaced simulated timestamps. Motion is integrated at UpdateRate in
+// between when set. Lock acquisition, GPX output and replay all follow the
+// virtual clock. Generation stops early when a
+// non-looping replay or route finishes. Like Run, it closes the simulator
+// when done. It returns the number of sentences written.
+func (s *GPSSimulator) GenerateTo(w io.Writer, duration time.Duration) (int, error) {
+	if duration <= 0 {
+		return 0, errors.New("generation duration must be positive")
+	}
+	if s.Config.OutputRate <= 0 {
+		return 0, errors.New("output rate must be positive")
+	}
+
+	// Ensure GPX writer is closed when generation ends
+	defer s.Close()
+
+	clock := &steppedClock{now: s.now()}
+	s.SetClock(clock)
+
+	counter := &sentenceCounter{w: w}
+	s.mu.Lock()
+	s.nmeaWriter = counter
+	s.mu.Unlock()
+
+	steps := int(duration / s.Config.OutputRate)
+	progressEvery := steps / 10
+	for step := 1; step <= steps; step++ {
+		s.advanceCycle(clock)
+
+		if counter.err != nil {
+			return counter.count, fmt.Errorf("failed to write sentences: %v", counter.err)
+		}
+
+		if !s.Config.Quiet && progressEvery > 0 && step%progressEvery == 0 {
+			fmt.Fprintf(os.Stderr, "Generated %v of %v (%d sentences)\n",
+				time.Duration(step)*s.Config.OutputRate, duration, counter.count)
+		}
+
+		// Stop once a non-looping replay or route has finished
+		if message, done := s.completion(); done {
+			if !s.Config.Quiet {
+				fmt.Fprintf(os.Stderr, "%s\n", message)
+			}
+			break
+		}
+	}
+
+	return counter.count, nil
+}
+
+// advanceCycle moves clock forward by one OutputRate, integrating at
+// UpdateRate on the way when set, and runs the output cycle
+func (s *GPSSimulator) advanceCycle(clock *steppedClock) {
+	elapsed := time.Duration(0)
+	if s.integratesBetweenOutputs() {
+		for ; elapsed+s.Config.UpdateRate < s.Config.OutputRate; elapsed += s.Config.UpdateRate {
+			clock.Advance(s.Config.UpdateRate)
+			s.step()
+		}
+	}
+	clock.Advance(s.Config.OutputRate - elapsed)
+	s.tick()
+}
+
+// Step advances simulated time by one OutputRate and runs a single output
+// cycle, as one tick of Run would, returning the cycle's NMEAData. Position,
+// satellites and replay all advance exactly once. The first call moves the
+// simulator onto a virtual clock that only Step advances, replacing the real
+// clock or one set with SetClock, so tests can drive the simulator
+// deterministically (with Config.Seed) without Run or tickers. Sentences are
+// still written to the output as usual. Step must not be used while Run or
+// GenerateTo is active. A paused simulator produces no sentences.
+func (s *GPSSimulator) Step() NMEAData {
+	s.mu.RLock()
+	clock, ok := s.clock.(*steppedClock)
+	s.mu.RUnlock()
+	if !ok {
+		clock = &steppedClock{now: s.now()}
+		s.SetClock(clock)
+	}
+
+	// Capture the cycle's sentences on their way to the output
+	block := &bytes.Buffer{}
+	s.mu.Lock()
+	writer := s.nmeaWriter
+	if writer == nil {
+		s.nmeaWriter = block
+	} else {
+		s.nmeaWriter = io.MultiWriter(writer, block)
+	}
+	s.mu.Unlock()
+
+	s.advanceCycle(clock)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nmeaWriter = writer
+	return s.nmeaData(s.outputEpoch(clock.Now()), block.String())
+}
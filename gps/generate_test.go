@@ -0,0 +1,319 @@
+package gps
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateTo(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 10 * time.Second
+	config.OutputRate = 2 * time.Second
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	count, err := sim.GenerateTo(buffer, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\r\n")
+	if count != len(lines) {
+		t.Errorf("Expected count %d to match %d written lines", count, len(lines))
+	}
+
+	// 10 minutes at 2s intervals is 300 cycles, each with one GGA
+	var ggaTimes []string
+	noFix := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "$GPGGA") {
+			fields := strings.Split(line, ",")
+			ggaTimes = append(ggaTimes, fields[1])
+			if fields[6] == "0" {
+				noFix++
+			}
+		}
+	}
+	if len(ggaTimes) != 300 {
+		t.Errorf("Expected 300 GGA sentences, got %d", len(ggaTimes))
+	}
+
+	// Lock is acquired after TimeToLock of virtual time
+	if noFix < 4 || noFix > 5 {
+		t.Errorf("Expected 4-5 no-fix GGA sentences before lock, got %d", noFix)
+	}
+
+	// Timestamps advance monotonically by the output rate
+	for i := 1; i < len(ggaTimes); i++ {
+		prev, _ := time.Parse("150405", ggaTimes[i-1])
+		cur, _ := time.Parse("150405", ggaTimes[i])
+		if diff := cur.Sub(prev); diff != 2*time.Second && diff != 2*time.Second-24*time.Hour {
+			t.Fatalf("Expected GGA timestamps 2s apart, got %s then %s", ggaTimes[i-1], ggaTimes[i])
+		}
+	}
+}
+
+func TestGenerateToWritesGPX(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.GPXEnabled = true
+	config.GPXFile = filepath.Join(t.TempDir(), "generated.gpx")
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	if _, err := sim.GenerateTo(&bytes.Buffer{}, time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	points, err := ReadGPXFile(config.GPXFile)
+	if err != nil {
+		t.Fatalf("Failed to read generated GPX: %v", err)
+	}
+	if len(points) != 60 {
+		t.Fatalf("Expected 60 GPX points, got %d", len(points))
+	}
+	if span := points[len(points)-1].Time.Sub(points[0].Time); span != 59*time.Second {
+		t.Errorf("Expected GPX timestamps to span 59s, got %v", span)
+	}
+}
+
+func TestGenerateToStopsAfterReplay(t *testing.T) {
+	sim := createReplaySimulator(seekTestPoints(), false)
+	sim.Config.Quiet = true
+	sim.replayStartTime = time.Now()
+
+	buffer := &bytes.Buffer{}
+	if _, err := sim.GenerateTo(buffer, time.Hour); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The 40s track ends long before the hour is up
+	if gga := strings.Count(buffer.String(), "$GPGGA"); gga > 45 {
+		t.Errorf("Expected generation to stop after the replay, got %d GGA sentences", gga)
+	}
+	if !sim.replayCompleted {
+		t.Error("Expected replay to be completed")
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestGenerateToErrors(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Quiet = true
+
+	if _, err := sim.GenerateTo(&bytes.Buffer{}, 0); err == nil {
+		t.Error("Expected error for zero duration")
+	}
+
+	if _, err := sim.GenerateTo(failingWriter{}, time.Minute); err == nil {
+		t.Error("Expected error when the writer fails")
+	}
+}
+
+func TestGenerateToUpdateRate(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.OutputRate = time.Second
+	config.UpdateRate = 100 * time.Millisecond
+	config.Speed = 20
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	pattern := &countingPattern{}
+	sim.pattern = pattern
+
+	buffer := &bytes.Buffer{}
+	if _, err := sim.GenerateTo(buffer, time.Minute); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Emission follows OutputRate while integration runs at UpdateRate
+	if gga := strings.Count(buffer.String(), "$GPGGA"); gga != 60 {
+		t.Errorf("Expected 60 GGA sentences for 1m at 1s, got %d", gga)
+	}
+	if pattern.steps != 600 {
+		t.Errorf("Expected 600 integration steps for 1m at 100ms, got %d", pattern.steps)
+	}
+}
+
+// countingPattern holds its position and counts the integration steps
+// moving it
+type countingPattern struct {
+	steps int
+}
+
+func (p *countingPattern) NextPosition(current MovementState, dt time.Duration) (float64, float64, float64, float64) {
+	p.steps++
+	return current.Lat, current.Lon, current.Course, current.Speed
+}
+
+func TestGenerateToUpdateRateKeepsJitter(t *testing.T) {
+	generate := func(updateRate time.Duration) string {
+		config := createTestConfig()
+		config.TimeToLock = 0
+		config.OutputRate = time.Second
+		config.UpdateRate = updateRate
+		config.Speed = 0
+		config.Jitter = 0.5
+		config.AltitudeJitter = 0.5
+		config.Seed = 1
+		config.Quiet = true
+		sim, err := NewGPSSimulator(config, nil)
+		if err != nil {
+			t.Fatalf("Failed to create GPS simulator: %v", err)
+		}
+		buffer := &bytes.Buffer{}
+		if _, err := sim.GenerateTo(buffer, 30*time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return buffer.String()
+	}
+
+	// Jitter is drawn once per output cycle, so a stationary receiver
+	// wanders the same whatever the update rate
+	if once, stepped := generate(0), generate(100*time.Millisecond); once != stepped {
+		t.Error("Expected the same jitter with a 100ms update rate as with one step per output")
+	}
+}
+
+func TestGenerateToUpdateRateIgnoredWhenSlower(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.OutputRate = 100 * time.Millisecond
+	config.UpdateRate = time.Second
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	pattern := &countingPattern{}
+	sim.pattern = pattern
+
+	if _, err := sim.GenerateTo(&bytes.Buffer{}, 10*time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pattern.steps != 100 {
+		t.Errorf("Expected one integration step per output cycle, got %d", pattern.steps)
+	}
+}
+
+func BenchmarkGenerateTo10Hz(b *testing.B) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.OutputRate = 100 * time.Millisecond
+	config.UpdateRate = 20 * time.Millisecond
+	config.Speed = 20
+	config.Quiet = true
+
+	for i := 0; i < b.N; i++ {
+		sim, err := NewGPSSimulator(config, nil)
+		if err != nil {
+			b.Fatalf("Failed to create GPS simulator: %v", err)
+		}
+		if _, err := sim.GenerateTo(io.Discard, time.Minute); err != nil {
+			b.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestStepAdvancesAtConfiguredSpeed(t *testing.T) {
+	config := createTestConfig()
+	config.Jitter = 0
+	config.Radius = 1000
+	config.Speed = 10
+	config.Course = 90
+	config.TimeToLock = 2 * time.Second
+	config.Seed = 1
+	config.Quiet = true
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	// Steps before lock carry no fix
+	var data NMEAData
+	for i := 0; i < 3; i++ {
+		data = sim.Step()
+	}
+	if data.FixQuality == 0 {
+		t.Fatalf("Expected a fix after 3 steps past a 2s lock time, got %+v", data)
+	}
+
+	start := data
+	for i := 0; i < 10; i++ {
+		data = sim.Step()
+	}
+
+	if elapsed := data.Timestamp.Sub(start.Timestamp); elapsed != 10*time.Second {
+		t.Errorf("Expected 10 steps to advance 10s, got %v", elapsed)
+	}
+	// 10 knots for 10 seconds is about 51.4 meters due east
+	distance := sim.calculateDistance(start.Latitude, start.Longitude, data.Latitude, data.Longitude)
+	if math.Abs(distance-51.44) > 0.5 {
+		t.Errorf("Expected about 51.4m travelled, got %.2fm", distance)
+	}
+	if data.Longitude <= start.Longitude {
+		t.Errorf("Expected travel east, longitude went from %.6f to %.6f", start.Longitude, data.Longitude)
+	}
+
+	if !strings.Contains(data.Sentences, "$GPGGA") || strings.Count(data.Sentences, "$GPGGA") != 1 {
+		t.Errorf("Expected the step's sentences to hold one GGA, got %q", data.Sentences)
+	}
+	if !strings.HasSuffix(buffer.String(), data.Sentences) {
+		t.Error("Expected the step's sentences to be written to the output")
+	}
+}
+
+func TestStepIsDeterministicWithSeed(t *testing.T) {
+	run := func() string {
+		config := createTestConfig()
+		config.Seed = 42
+		config.Quiet = true
+		config.TimeToLock = time.Second
+		sim, err := NewGPSSimulator(config, io.Discard)
+		if err != nil {
+			t.Fatalf("Failed to create GPS simulator: %v", err)
+		}
+		defer sim.Close()
+		sim.SetClock(newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+		var sentences strings.Builder
+		for i := 0; i < 5; i++ {
+			sentences.WriteString(sim.Step().Sentences)
+		}
+		return sentences.String()
+	}
+
+	if first, second := run(), run(); first != second {
+		t.Errorf("Expected identical output from seeded steps, got:\n%s\nand:\n%s", first, second)
+	}
+}
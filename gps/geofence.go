@@ -0,0 +1,184 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Geofence event types, also used as Event types for event callbacks
+const (
+	EventGeofenceEnter = "geofence_enter" // The position entered a geofence
+	EventGeofenceExit  = "geofence_exit"  // The position left a geofence
+)
+
+// DefaultGeofenceDwell is the number of consecutive updates the position
+// must stay inside or outside a geofence before it counts as entered or left
+const DefaultGeofenceDwell = 3
+
+// Geofence is a circular region reported on as the position enters and
+// leaves it
+type Geofence struct {
+	Name   string  `json:"name"`
+	Lat    float64 `json:"lat"`    // Center latitude in decimal degrees
+	Lon    float64 `json:"lon"`    // Center longitude in decimal degrees
+	Radius float64 `json:"radius"` // Meters
+}
+
+// GeofenceEvent reports the position entering or leaving a geofence. The
+// JSON field names are stable.
+type GeofenceEvent struct {
+	Name      string    `json:"name"`      // Name of the geofence
+	Type      string    `json:"type"`      // EventGeofenceEnter or EventGeofenceExit
+	Lat       float64   `json:"lat"`       // Position that confirmed the crossing
+	Lon       float64   `json:"lon"`       // Position that confirmed the crossing
+	Timestamp time.Time `json:"timestamp"` // Simulated time of the crossing
+}
+
+// geofenceState tracks whether the position is inside a geofence and how
+// many consecutive updates have disagreed with that
+type geofenceState struct {
+	inside  bool
+	pending int
+}
+
+// validateGeofences checks the geofences and the dwell count
+func (c Config) validateGeofences() error {
+	if c.GeofenceDwell < 0 {
+		return errors.New("Geofence dwell must be a non-negative number of updates")
+	}
+	names := make(map[string]bool)
+	for i, fence := range c.Geofences {
+		if err := fence.validate(); err != nil {
+			return fmt.Errorf("Invalid geofence %d: %v", i+1, err)
+		}
+		if names[fence.Name] {
+			return fmt.Errorf("Duplicate geofence name %q", fence.Name)
+		}
+		names[fence.Name] = true
+	}
+	return nil
+}
+
+// validate checks a geofence's name, center and radius
+func (f Geofence) validate() error {
+	if f.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if f.Lat < -90 || f.Lat > 90 || f.Lon < -180 || f.Lon > 180 {
+		return fmt.Errorf("center (%.6f, %.6f) is out of range", f.Lat, f.Lon)
+	}
+	if !(f.Radius > 0) {
+		return errors.New("radius must be positive")
+	}
+	return nil
+}
+
+// AddGeofence adds a circular geofence of radius meters around the center,
+// reported on from the next update. It returns an error for an invalid
+// geofence or a name already in use. It is safe to call while Run is
+// active.
+func (s *GPSSimulator) AddGeofence(name string, lat, lon, radius float64) error {
+	fence := Geofence{Name: name, Lat: lat, Lon: lon, Radius: radius}
+	if err := fence.validate(); err != nil {
+		return fmt.Errorf("invalid geofence: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.Config.Geofences {
+		if existing.Name == name {
+			return fmt.Errorf("geofence %q already exists", name)
+		}
+	}
+	// Copy so the caller's config slice is never written through
+	s.Config.Geofences = append(append([]Geofence(nil), s.Config.Geofences...), fence)
+	return nil
+}
+
+// OnGeofence registers fn to receive a GeofenceEvent each time the position
+// enters or leaves a geofence. The crossings are also passed to event
+// callbacks as EventGeofenceEnter and EventGeofenceExit events. It is safe
+// to call while Run is active. Like AddEventCallback callbacks, fn runs
+// while the simulator is locked, so it must return quickly and must not
+// call back into the simulator.
+func (s *GPSSimulator) OnGeofence(fn func(GeofenceEvent)) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	s.events.geofence = append(s.events.geofence, fn)
+}
+
+// geofenceDwell returns the number of consecutive updates confirming a
+// crossing
+func (s *GPSSimulator) geofenceDwell() int {
+	if s.Config.GeofenceDwell > 0 {
+		return s.Config.GeofenceDwell
+	}
+	return DefaultGeofenceDwell
+}
+
+// checkGeofences compares the position with every geofence while locked.
+// A crossing is reported once the position has been on the new side for
+// geofenceDwell consecutive output cycles, so jitter near a boundary does not
+// report a storm of crossings. The position starts outside every geofence.
+func (s *GPSSimulator) checkGeofences(now time.Time) {
+	if len(s.Config.Geofences) == 0 && len(s.geofences) == 0 {
+		return
+	}
+	if s.geofences == nil {
+		s.geofences = make(map[string]*geofenceState)
+	}
+
+	// Forget removed geofences so one added again starts afresh
+	current := make(map[string]bool, len(s.Config.Geofences))
+	for _, fence := range s.Config.Geofences {
+		current[fence.Name] = true
+	}
+	for name := range s.geofences {
+		if !current[name] {
+			delete(s.geofences, name)
+		}
+	}
+
+	if !s.isLocked {
+		return
+	}
+	for _, fence := range s.Config.Geofences {
+		state, ok := s.geofences[fence.Name]
+		if !ok {
+			state = &geofenceState{}
+			s.geofences[fence.Name] = state
+		}
+
+		inside := s.calculateDistance(s.currentLat, s.currentLon, fence.Lat, fence.Lon) <= fence.Radius
+		if inside == state.inside {
+			state.pending = 0
+			continue
+		}
+		state.pending++
+		if state.pending < s.geofenceDwell() {
+			continue
+		}
+		state.inside = inside
+		state.pending = 0
+
+		event := GeofenceEvent{Name: fence.Name, Type: EventGeofenceExit, Lat: s.currentLat, Lon: s.currentLon, Timestamp: s.fixTime(now)}
+		if inside {
+			event.Type = EventGeofenceEnter
+		}
+		s.notifyGeofence(event)
+	}
+}
+
+// notifyGeofence passes event to every geofence callback and, as an Event,
+// to every event callback
+func (s *GPSSimulator) notifyGeofence(event GeofenceEvent) {
+	s.events.mu.Lock()
+	callbacks := s.events.geofence
+	s.events.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(event)
+	}
+	s.dispatchEvent(Event{Type: event.Type, Timestamp: event.Timestamp, Geofence: event.Name})
+}
@@ -0,0 +1,199 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// createGeofenceSimulator returns a simulator driving due east at 20 knots
+// (about 10.3 m/s) without jitter
+func createGeofenceSimulator(t *testing.T) *GPSSimulator {
+	t.Helper()
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Jitter = 0
+	config.Radius = 2000
+	config.Speed = 20
+	config.Course = 90
+	config.TimeToLock = time.Second
+	config.Seed = 1
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	t.Cleanup(sim.Close)
+	return sim
+}
+
+func TestGeofenceStraightPath(t *testing.T) {
+	sim := createGeofenceSimulator(t)
+
+	// A 40m fence 150m east of the start is crossed in about 8 seconds
+	lat, lon := sim.calculateDestination(sim.Config.Latitude, sim.Config.Longitude, 90, 150)
+	if err := sim.AddGeofence("depot", lat, lon, 40); err != nil {
+		t.Fatalf("AddGeofence failed: %v", err)
+	}
+
+	var fenceEvents []GeofenceEvent
+	sim.OnGeofence(func(event GeofenceEvent) {
+		fenceEvents = append(fenceEvents, event)
+	})
+	var events []Event
+	sim.AddEventCallback(func(event Event) {
+		if event.Geofence != "" {
+			events = append(events, event)
+		}
+	})
+
+	for i := 0; i < 40; i++ {
+		sim.Step()
+	}
+
+	if len(fenceEvents) != 2 || fenceEvents[0].Type != EventGeofenceEnter || fenceEvents[1].Type != EventGeofenceExit {
+		t.Fatalf("Expected one enter and one exit, got %+v", fenceEvents)
+	}
+	for _, event := range fenceEvents {
+		if event.Name != "depot" || event.Timestamp.IsZero() {
+			t.Errorf("Expected a timestamped depot event, got %+v", event)
+		}
+	}
+	// The crossings are confirmed DefaultGeofenceDwell updates after them
+	if distance := sim.calculateDistance(fenceEvents[0].Lat, fenceEvents[0].Lon, lat, lon); distance > 40 || distance < 40-10.3*DefaultGeofenceDwell {
+		t.Errorf("Expected enter reported inside the fence near its edge, got %.1fm from the center", distance)
+	}
+	if distance := sim.calculateDistance(fenceEvents[1].Lat, fenceEvents[1].Lon, lat, lon); distance <= 40 {
+		t.Errorf("Expected exit reported outside the fence, got %.1fm from the center", distance)
+	}
+
+	if len(events) != 2 || events[0].Type != EventGeofenceEnter || events[1].Type != EventGeofenceExit || events[0].Geofence != "depot" {
+		t.Errorf("Expected the crossings as events too, got %+v", events)
+	}
+}
+
+func TestGeofenceHysteresis(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Geofences = []Geofence{{Name: "edge", Lat: sim.currentLat, Lon: sim.currentLon, Radius: 10}}
+
+	var events []GeofenceEvent
+	sim.OnGeofence(func(event GeofenceEvent) {
+		events = append(events, event)
+	})
+
+	inside := sim.currentLat
+	outside, _ := sim.calculateDestination(sim.currentLat, sim.currentLon, 0, 11)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Jitter across the boundary never stays inside long enough
+	for i := 0; i < 20; i++ {
+		sim.currentLat = inside
+		if i%3 == 2 {
+			sim.currentLat = outside
+		}
+		sim.checkGeofences(now)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no events while jittering across the boundary, got %+v", events)
+	}
+
+	sim.currentLat = inside
+	for i := 0; i < DefaultGeofenceDwell; i++ {
+		sim.checkGeofences(now)
+	}
+	if len(events) != 1 || events[0].Type != EventGeofenceEnter {
+		t.Errorf("Expected an enter after %d updates inside, got %+v", DefaultGeofenceDwell, events)
+	}
+}
+
+func TestGeofenceDwellCountsOutputCycles(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Speed = 0
+	sim.Config.Jitter = 0
+	sim.Config.UpdateRate = 100 * time.Millisecond
+	sim.Config.Geofences = []Geofence{{Name: "yard", Lat: sim.currentLat, Lon: sim.currentLon, Radius: 1000}}
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	var events []GeofenceEvent
+	sim.OnGeofence(func(event GeofenceEvent) {
+		events = append(events, event)
+	})
+
+	// Integration steps between outputs do not count towards the dwell
+	for i := 0; i < 10*DefaultGeofenceDwell; i++ {
+		clock.Advance(100 * time.Millisecond)
+		sim.step()
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no events from integration steps, got %+v", events)
+	}
+
+	for i := 0; i < DefaultGeofenceDwell; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+	if len(events) != 1 || events[0].Type != EventGeofenceEnter {
+		t.Errorf("Expected an enter after %d output cycles inside, got %+v", DefaultGeofenceDwell, events)
+	}
+}
+
+func TestGeofenceFromControlMessage(t *testing.T) {
+	sim := createGeofenceSimulator(t)
+
+	lat, lon := sim.calculateDestination(sim.Config.Latitude, sim.Config.Longitude, 90, 60)
+	fences, _ := json.Marshal([]Geofence{{Name: "gate", Lat: lat, Lon: lon, Radius: 20}})
+	message := []byte(`{"type": "config", "data": {"Geofences": ` + string(fences) + `, "GeofenceDwell": 1}}`)
+	if err := sim.HandleControlMessage(message); err != nil {
+		t.Fatalf("Config message failed: %v", err)
+	}
+
+	var events []GeofenceEvent
+	sim.OnGeofence(func(event GeofenceEvent) {
+		events = append(events, event)
+	})
+	for i := 0; i < 15; i++ {
+		sim.Step()
+	}
+	if len(events) != 2 || events[0].Name != "gate" {
+		t.Errorf("Expected an enter and exit of the gate geofence, got %+v", events)
+	}
+}
+
+func TestGeofenceValidation(t *testing.T) {
+	sim := createTestSimulator()
+	if err := sim.AddGeofence("a", 37.7, -122.4, 50); err != nil {
+		t.Errorf("Expected a valid geofence, got: %v", err)
+	}
+	if err := sim.AddGeofence("a", 37.7, -122.4, 50); err == nil {
+		t.Error("Expected error for a duplicate geofence name")
+	}
+	if err := sim.AddGeofence("b", 37.7, -122.4, 0); err == nil {
+		t.Error("Expected error for a zero radius")
+	}
+	if err := sim.AddGeofence("", 37.7, -122.4, 50); err == nil {
+		t.Error("Expected error for an empty name")
+	}
+
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Geofences = []Geofence{{Name: "a", Lat: 37.7, Lon: -122.4, Radius: 50}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid geofence config, got: %v", err)
+	}
+
+	config.Geofences = append(config.Geofences, Geofence{Name: "b", Lat: 91, Lon: 0, Radius: 50})
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for a geofence center out of range")
+	}
+
+	config.Geofences = config.Geofences[:1]
+	config.GeofenceDwell = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for a negative geofence dwell")
+	}
+}
@@ -0,0 +1,48 @@
+package gps
+
+import "math"
+
+// geoidGridStep is the spacing in degrees of geoidGrid
+const geoidGridStep = 30.0
+
+// geoidGrid is a coarse approximation of EGM96 geoid heights above the WGS84
+// ellipsoid in meters, sampled every 30° from 90°N to 90°S (rows) and 180°W
+// to 180°E (columns). Interpolated values are within a few tens of meters of
+// the full model, enough for the separation to vary plausibly with location.
+var geoidGrid = [7][13]float64{
+	{14, 14, 14, 14, 14, 14, 14, 14, 14, 14, 14, 14, 14},              // 90°N
+	{5, 0, -15, -35, -10, 55, 50, 17, -5, -30, -15, 5, 5},             // 60°N
+	{0, -5, -35, -25, -35, 50, 45, 15, -30, -30, 10, 25, 0},           // 30°N
+	{20, 5, -10, -5, -20, 10, 17, -15, -60, -65, 50, 50, 20},          // Equator
+	{20, 0, -10, 0, 15, 10, 20, 30, -20, -40, -20, 25, 20},            // 30°S
+	{-40, -40, -30, -20, 10, 10, 10, 20, 0, -20, -20, -30, -40},       // 60°S
+	{-30, -30, -30, -30, -30, -30, -30, -30, -30, -30, -30, -30, -30}, // 90°S
+}
+
+// approximateGeoidSeparation returns the geoid height above the ellipsoid in
+// meters at lat/lon, bilinearly interpolated from geoidGrid
+func approximateGeoidSeparation(lat, lon float64) float64 {
+	lat = math.Max(-90, math.Min(90, lat))
+	lon = math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+
+	row := (90 - lat) / geoidGridStep
+	col := (lon + 180) / geoidGridStep
+	r0 := int(math.Min(row, float64(len(geoidGrid)-2)))
+	c0 := int(math.Min(col, float64(len(geoidGrid[0])-2)))
+	fr := row - float64(r0)
+	fc := col - float64(c0)
+
+	top := geoidGrid[r0][c0]*(1-fc) + geoidGrid[r0][c0+1]*fc
+	bottom := geoidGrid[r0+1][c0]*(1-fc) + geoidGrid[r0+1][c0+1]*fc
+	return top*(1-fr) + bottom*fr
+}
+
+// geoidSeparation returns the geoidal separation reported in GGA and GNS:
+// approximated at the current position with Config.AutoGeoidSeparation,
+// otherwise the fixed Config.GeoidSeparation
+func (s *GPSSimulator) geoidSeparation() float64 {
+	if s.Config.AutoGeoidSeparation {
+		return approximateGeoidSeparation(s.currentLat, s.currentLon)
+	}
+	return s.Config.GeoidSeparation
+}
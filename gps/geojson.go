@@ -0,0 +1,204 @@
+package gps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrGeoJSONDisabled is returned for GeoJSON track requests when
+// GeoJSONEnabled is off
+var ErrGeoJSONDisabled = errors.New("GeoJSON output is not enabled")
+
+// geoJSONPoint is a recorded GeoJSON track point
+type geoJSONPoint struct {
+	lat, lon, elevation float64
+	time                time.Time
+	speed               float64 // Meters per second
+}
+
+// GeoJSONFeatureCollection is the root of a GeoJSON document
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a GeoJSON feature with its geometry and properties
+type GeoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// GeoJSONGeometry is a LineString, with an array of [lon, lat, elevation]
+// positions, or a Point, with a single position
+type GeoJSONGeometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// GeoJSONWriter handles writing GPS data to a GeoJSON file. The track is a
+// LineString feature whose per-point time, elevation and speed are either
+// arrays in its coordinateProperties or, with pointFeatures, the properties
+// of a Point feature per track point following the line.
+type GeoJSONWriter struct {
+	filename      string
+	pointFeatures bool
+	points        []geoJSONPoint
+	file          *os.File
+	closed        bool // Set once Close has been called
+}
+
+// NewGeoJSONWriter creates a new GeoJSON writer
+func NewGeoJSONWriter(filename string, pointFeatures bool) (*GeoJSONWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GeoJSON file %s: %v", filename, err)
+	}
+	return &GeoJSONWriter{filename: filename, pointFeatures: pointFeatures, file: file}, nil
+}
+
+// AddTrackPoint adds a new track point with its speed in meters per second
+func (w *GeoJSONWriter) AddTrackPoint(lat, lon, elevation float64, timestamp time.Time, speed float64) {
+	w.points = append(w.points, geoJSONPoint{lat: lat, lon: lon, elevation: elevation, time: timestamp.UTC(), speed: speed})
+}
+
+func (w *GeoJSONWriter) GetTrackPointCount() int {
+	return len(w.points)
+}
+
+// featureCollection builds the GeoJSON document for the points so far
+func (w *GeoJSONWriter) featureCollection() GeoJSONFeatureCollection {
+	coordinates := make([][]float64, len(w.points))
+	times := make([]string, len(w.points))
+	elevations := make([]float64, len(w.points))
+	speeds := make([]float64, len(w.points))
+	for i, p := range w.points {
+		coordinates[i] = []float64{p.lon, p.lat, p.elevation}
+		times[i] = p.time.Format(time.RFC3339)
+		elevations[i] = p.elevation
+		speeds[i] = p.speed
+	}
+
+	track := GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   GeoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+		Properties: map[string]any{"name": "GPS Simulator Track"},
+	}
+	if !w.pointFeatures {
+		track.Properties["coordinateProperties"] = map[string]any{
+			"times":      times,
+			"elevations": elevations,
+			"speeds":     speeds,
+		}
+	}
+
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{track}}
+	if w.pointFeatures {
+		for i, p := range w.points {
+			collection.Features = append(collection.Features, GeoJSONFeature{
+				Type:     "Feature",
+				Geometry: GeoJSONGeometry{Type: "Point", Coordinates: coordinates[i]},
+				Properties: map[string]any{
+					"time":      times[i],
+					"elevation": p.elevation,
+					"speed":     p.speed,
+				},
+			})
+		}
+	}
+	return collection
+}
+
+// Bytes returns the GeoJSON document for the points so far
+func (w *GeoJSONWriter) Bytes() ([]byte, error) {
+	data, err := json.MarshalIndent(w.featureCollection(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GeoJSON data: %v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteToFile writes the current GeoJSON data to the file
+func (w *GeoJSONWriter) WriteToFile() error {
+	data, err := w.Bytes()
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, 0); err != nil {
+		return fmt.Errorf("failed to seek to beginning of file: %v", err)
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %v", err)
+	}
+	if _, err := w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write GeoJSON data: %v", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+	return nil
+}
+
+// Snapshot writes the current GeoJSON data to the file, until it is
+// closed, and returns the same document
+func (w *GeoJSONWriter) Snapshot() ([]byte, error) {
+	if !w.closed {
+		if err := w.WriteToFile(); err != nil {
+			return nil, err
+		}
+	}
+	return w.Bytes()
+}
+
+// Close writes the final data and closes the GeoJSON file
+func (w *GeoJSONWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.WriteToFile()
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// AddFix adds the fix to the track, recording its speed in meters per second
+func (w *GeoJSONWriter) AddFix(fix Fix) {
+	w.AddTrackPoint(fix.Lat, fix.Lon, fix.Altitude, fix.Timestamp, fix.SpeedKnots*0.514444)
+}
+
+// GeoJSONBytes writes out the track points recorded so far and returns the
+// session's GeoJSON document, or ErrGeoJSONDisabled without GeoJSON output
+func (s *GPSSimulator) GeoJSONBytes() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.geoJSONWriter == nil {
+		return nil, ErrGeoJSONDisabled
+	}
+	return s.geoJSONWriter.Snapshot()
+}
+
+// GeoJSONHandler returns an HTTP handler serving the session's GeoJSON
+// track, for mounting at /api/geojson and loading live into web maps. It
+// responds 404 with a JSON error when GeoJSON output is not enabled.
+func (s *GPSSimulator) GeoJSONHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := s.GeoJSONBytes()
+		if err != nil {
+			writeTrackError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filepath.Base(s.Config.GeoJSONFile)))
+		w.Write(data)
+	})
+}
@@ -0,0 +1,118 @@
+package gps
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordGeoJSON records GeoJSON to a temporary file, with a point feature
+// per fix when pointFeatures is set
+func recordGeoJSON(t *testing.T, pointFeatures bool) func(*Config) {
+	file := filepath.Join(t.TempDir(), "session.geojson")
+	return func(c *Config) {
+		c.GeoJSONEnabled = true
+		c.GeoJSONFile = file
+		c.GeoJSONPoints = pointFeatures
+	}
+}
+
+// decodeGeoJSON parses a GeoJSON document
+func decodeGeoJSON(t *testing.T, data []byte) GeoJSONFeatureCollection {
+	t.Helper()
+	var collection GeoJSONFeatureCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("Invalid GeoJSON: %v", err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) == 0 {
+		t.Fatalf("Expected a FeatureCollection with features, got %+v", collection)
+	}
+	return collection
+}
+
+func TestGeoJSONLineString(t *testing.T) {
+	sim := createTrackSimulator(t, recordGeoJSON(t, false), 12)
+	sim.Close()
+
+	data, err := os.ReadFile(sim.Config.GeoJSONFile)
+	if err != nil {
+		t.Fatalf("Failed to read GeoJSON file: %v", err)
+	}
+	collection := decodeGeoJSON(t, data)
+	if len(collection.Features) != 1 {
+		t.Fatalf("Expected a single track feature, got %d", len(collection.Features))
+	}
+
+	track := collection.Features[0]
+	coordinates, _ := track.Geometry.Coordinates.([]any)
+	if track.Geometry.Type != "LineString" || len(coordinates) != 12 {
+		t.Fatalf("Expected a LineString of 12 positions, got %s with %d", track.Geometry.Type, len(coordinates))
+	}
+	first := coordinates[0].([]any)
+	if len(first) != 3 || first[0].(float64) != sim.geoJSONWriter.points[0].lon {
+		t.Errorf("Expected [lon, lat, elevation] positions, got %v", first)
+	}
+
+	props, _ := track.Properties["coordinateProperties"].(map[string]any)
+	for _, name := range []string{"times", "elevations", "speeds"} {
+		if values, _ := props[name].([]any); len(values) != 12 {
+			t.Errorf("Expected 12 %s, got %d", name, len(values))
+		}
+	}
+	if times := props["times"].([]any); times[0] != "2024-01-15T10:00:01Z" {
+		t.Errorf("Expected the first time 2024-01-15T10:00:01Z, got %v", times[0])
+	}
+}
+
+func TestGeoJSONPointFeatures(t *testing.T) {
+	sim := createTrackSimulator(t, recordGeoJSON(t, true), 5)
+	defer sim.Close()
+
+	data, err := sim.GeoJSONBytes()
+	if err != nil {
+		t.Fatalf("GeoJSONBytes() failed: %v", err)
+	}
+	collection := decodeGeoJSON(t, data)
+	if len(collection.Features) != 6 {
+		t.Fatalf("Expected the track and 5 point features, got %d", len(collection.Features))
+	}
+	for _, point := range collection.Features[1:] {
+		if point.Geometry.Type != "Point" {
+			t.Errorf("Expected a Point feature, got %s", point.Geometry.Type)
+		}
+		for _, name := range []string{"time", "elevation", "speed"} {
+			if _, ok := point.Properties[name]; !ok {
+				t.Errorf("Expected point property %s", name)
+			}
+		}
+	}
+}
+
+func TestGeoJSONHandler(t *testing.T) {
+	sim := createTrackSimulator(t, recordGeoJSON(t, false), 3)
+	defer sim.Close()
+
+	rec := httptest.NewRecorder()
+	sim.GeoJSONHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/geojson", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Errorf("Expected application/geo+json, got %q", ct)
+	}
+	collection := decodeGeoJSON(t, rec.Body.Bytes())
+	if coordinates, _ := collection.Features[0].Geometry.Coordinates.([]any); len(coordinates) != 3 {
+		t.Errorf("Expected 3 positions, got %d", len(coordinates))
+	}
+
+	// Without GeoJSON output the endpoint is not found
+	disabled := createTestSimulator()
+	rec = httptest.NewRecorder()
+	disabled.GeoJSONHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/geojson", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without GeoJSON output, got %d", rec.Code)
+	}
+}
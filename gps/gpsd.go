@@ -0,0 +1,222 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// gpsd protocol version advertised in the VERSION banner
+const (
+	gpsdProtoMajor = 3
+	gpsdProtoMinor = 14
+	gpsdRelease    = "gps-simulator"
+	gpsdDevice     = "gps-simulator"
+)
+
+// gpsd TPV fix modes
+const (
+	gpsdModeNoFix = 1
+	gpsdModeFix3D = 3
+)
+
+// GpsdVersion is the gpsd VERSION object sent to clients when they connect
+type GpsdVersion struct {
+	Class      string `json:"class"`
+	Release    string `json:"release"`
+	Rev        string `json:"rev"`
+	ProtoMajor int    `json:"proto_major"`
+	ProtoMinor int    `json:"proto_minor"`
+}
+
+// GpsdTPV is the gpsd time-position-velocity report
+type GpsdTPV struct {
+	Class  string   `json:"class"`
+	Device string   `json:"device"`
+	Mode   int      `json:"mode"`
+	Time   string   `json:"time"`
+	Lat    *float64 `json:"lat,omitempty"`
+	Lon    *float64 `json:"lon,omitempty"`
+	Alt    *float64 `json:"alt,omitempty"`
+	Speed  *float64 `json:"speed,omitempty"` // meters per second
+	Track  *float64 `json:"track,omitempty"` // degrees from true north
+}
+
+// GpsdSatellite describes one satellite in a gpsd SKY report
+type GpsdSatellite struct {
+	PRN    int  `json:"PRN"`
+	El     int  `json:"el"`
+	Az     int  `json:"az"`
+	SS     int  `json:"ss"`
+	Used   bool `json:"used"`
+	GnssID int  `json:"gnssid"`
+}
+
+// GpsdSKY is the gpsd sky view report listing satellites and DOP values
+type GpsdSKY struct {
+	Class      string          `json:"class"`
+	Device     string          `json:"device"`
+	Time       string          `json:"time"`
+	HDOP       float64         `json:"hdop"`
+	VDOP       float64         `json:"vdop"`
+	PDOP       float64         `json:"pdop"`
+	Satellites []GpsdSatellite `json:"satellites"`
+}
+
+// gpsdDeviceInfo describes the simulated device in a gpsd DEVICES report
+type gpsdDeviceInfo struct {
+	Class  string `json:"class"`
+	Path   string `json:"path"`
+	Driver string `json:"driver"`
+}
+
+// gpsdDevices is the gpsd DEVICES report sent in response to ?WATCH
+type gpsdDevices struct {
+	Class   string           `json:"class"`
+	Devices []gpsdDeviceInfo `json:"devices"`
+}
+
+// gpsdWatch is the gpsd WATCH report echoing the active watch policy
+type gpsdWatch struct {
+	Class  string `json:"class"`
+	Enable bool   `json:"enable"`
+	JSON   bool   `json:"json"`
+}
+
+// gpsdError is the gpsd ERROR report answering a command that is not
+// supported
+type gpsdError struct {
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+// gpsdGnssID returns the gpsd/u-blox GNSS identifier for a constellation
+func gpsdGnssID(c Constellation) int {
+	switch c {
+	case ConstellationGalileo:
+		return 2
+	case ConstellationBeiDou:
+		return 3
+	case ConstellationGLONASS:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// formatGpsd encodes a gpsd report as a single CRLF-terminated JSON line
+func formatGpsd(report interface{}) string {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return ""
+	}
+	return string(data) + "\r\n"
+}
+
+// gpsdTime formats a timestamp the way gpsd does (ISO 8601 UTC with milliseconds)
+func gpsdTime(timestamp time.Time) string {
+	return timestamp.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// generateTPV generates a gpsd TPV report from the current position
+func (s *GPSSimulator) generateTPV(timestamp time.Time) string {
+	tpv := GpsdTPV{
+		Class:  "TPV",
+		Device: gpsdDevice,
+		Mode:   gpsdModeNoFix,
+		Time:   gpsdTime(timestamp),
+	}
+
+	if s.isLocked {
+		lat, lon := s.reportedPosition()
+		alt := s.currentAlt
+		speed := s.currentSpeed / 1.94384 // Convert knots to m/s
+		track := s.currentCourse
+
+		tpv.Mode = gpsdModeFix3D
+		tpv.Lat = &lat
+		tpv.Lon = &lon
+		tpv.Alt = &alt
+		tpv.Speed = &speed
+		tpv.Track = &track
+	}
+
+	return formatGpsd(tpv)
+}
+
+// generateSKY generates a gpsd SKY report from the simulated satellites
+func (s *GPSSimulator) generateSKY(timestamp time.Time) string {
+	dop := s.currentDOP()
+	sky := GpsdSKY{
+		Class:      "SKY",
+		Device:     gpsdDevice,
+		Time:       gpsdTime(timestamp),
+		HDOP:       dop.HDOP,
+		VDOP:       dop.VDOP,
+		PDOP:       dop.PDOP,
+		Satellites: make([]GpsdSatellite, 0, len(s.Satellites)),
+	}
+
+	for _, sat := range s.Satellites {
+		sky.Satellites = append(sky.Satellites, GpsdSatellite{
+			PRN:    sat.ID,
+			El:     sat.Elevation,
+			Az:     sat.Azimuth,
+			SS:     s.signalSNR(sat),
+			Used:   s.isLocked,
+			GnssID: gpsdGnssID(sat.Constellation),
+		})
+	}
+
+	return formatGpsd(sky)
+}
+
+// outputGpsd emits gpsd TPV and SKY reports instead of NMEA sentences
+func (s *GPSSimulator) outputGpsd() {
+	timestamp := s.fixTime(s.now())
+
+	s.emit(s.generateTPV(timestamp))
+
+	// Satellites are only known once the receiver has locked
+	if s.isLocked {
+		s.emit(s.generateSKY(timestamp))
+	}
+
+	s.notifyCallbacks(timestamp)
+}
+
+// gpsdProtocol answers gpsd clients connecting to the TCP server
+type gpsdProtocol struct{}
+
+// greeting returns the VERSION banner sent to every new client
+func (gpsdProtocol) greeting() []byte {
+	return []byte(formatGpsd(GpsdVersion{
+		Class:      "VERSION",
+		Release:    gpsdRelease,
+		Rev:        gpsdRelease,
+		ProtoMajor: gpsdProtoMajor,
+		ProtoMinor: gpsdProtoMinor,
+	}))
+}
+
+// respond answers client commands. Reports are streamed to every client, so
+// ?WATCH only acknowledges the watch with the device list. Only this
+// read-only subset is supported, and other commands are answered with an
+// ERROR report.
+func (gpsdProtocol) respond(command string) []byte {
+	command = strings.TrimSpace(command)
+	switch {
+	case strings.HasPrefix(command, "?WATCH"):
+		return []byte(formatGpsd(gpsdDevices{
+			Class:   "DEVICES",
+			Devices: []gpsdDeviceInfo{{Class: "DEVICE", Path: gpsdDevice, Driver: "NMEA0183"}},
+		}) + formatGpsd(gpsdWatch{Class: "WATCH", Enable: true, JSON: true}))
+	case strings.HasPrefix(command, "?VERSION"):
+		return gpsdProtocol{}.greeting()
+	case command == "":
+		return nil
+	default:
+		return []byte(formatGpsd(gpsdError{Class: "ERROR", Message: fmt.Sprintf("Unrecognized request '%s'", strings.TrimSuffix(command, ";"))}))
+	}
+}
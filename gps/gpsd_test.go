@@ -0,0 +1,180 @@
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeGpsdLines decodes each JSON line written to buffer into generic objects
+func decodeGpsdLines(t *testing.T, output string) []map[string]interface{} {
+	t.Helper()
+	var reports []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\r\n") {
+		var report map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &report); err != nil {
+			t.Fatalf("Failed to decode gpsd line %q: %v", line, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func TestGenerateTPVMatchesPosition(t *testing.T) {
+	sim := createTestSimulator()
+	sim.currentAlt = 123.4
+	sim.currentSpeed = 10.0
+	sim.currentCourse = 270.0
+
+	var tpv GpsdTPV
+	if err := json.Unmarshal([]byte(sim.generateTPV(time.Now())), &tpv); err != nil {
+		t.Fatalf("Failed to decode TPV: %v", err)
+	}
+
+	if tpv.Class != "TPV" {
+		t.Errorf("Expected class TPV, got %s", tpv.Class)
+	}
+	if tpv.Mode != gpsdModeFix3D {
+		t.Errorf("Expected mode 3 when locked, got %d", tpv.Mode)
+	}
+	if tpv.Lat == nil || *tpv.Lat != sim.currentLat {
+		t.Errorf("Expected lat %f, got %v", sim.currentLat, tpv.Lat)
+	}
+	if tpv.Lon == nil || *tpv.Lon != sim.currentLon {
+		t.Errorf("Expected lon %f, got %v", sim.currentLon, tpv.Lon)
+	}
+	if tpv.Alt == nil || *tpv.Alt != sim.currentAlt {
+		t.Errorf("Expected alt %f, got %v", sim.currentAlt, tpv.Alt)
+	}
+	if tpv.Speed == nil || math.Abs(*tpv.Speed-10.0/1.94384) > 1e-9 {
+		t.Errorf("Expected speed %f m/s, got %v", 10.0/1.94384, tpv.Speed)
+	}
+	if tpv.Track == nil || *tpv.Track != 270.0 {
+		t.Errorf("Expected track 270, got %v", tpv.Track)
+	}
+	if _, err := time.Parse(time.RFC3339, tpv.Time); err != nil {
+		t.Errorf("Expected ISO 8601 time, got %q", tpv.Time)
+	}
+}
+
+func TestGenerateTPVNoFix(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+
+	report := decodeGpsdLines(t, sim.generateTPV(time.Now()))[0]
+	if report["mode"] != float64(gpsdModeNoFix) {
+		t.Errorf("Expected mode 1 without fix, got %v", report["mode"])
+	}
+	for _, field := range []string{"lat", "lon", "alt", "speed", "track"} {
+		if _, ok := report[field]; ok {
+			t.Errorf("Field %s should be omitted without a fix", field)
+		}
+	}
+}
+
+func TestGenerateSKY(t *testing.T) {
+	sim := createTestSimulator()
+
+	var sky GpsdSKY
+	if err := json.Unmarshal([]byte(sim.generateSKY(time.Now())), &sky); err != nil {
+		t.Fatalf("Failed to decode SKY: %v", err)
+	}
+
+	if sky.Class != "SKY" {
+		t.Errorf("Expected class SKY, got %s", sky.Class)
+	}
+	if len(sky.Satellites) != len(sim.Satellites) {
+		t.Fatalf("Expected %d satellites, got %d", len(sim.Satellites), len(sky.Satellites))
+	}
+	for i, sat := range sim.Satellites {
+		got := sky.Satellites[i]
+		if got.PRN != sat.ID || got.El != sat.Elevation || got.Az != sat.Azimuth || got.SS != sat.SNR {
+			t.Errorf("Satellite %d mismatch: expected %+v, got %+v", i, sat, got)
+		}
+	}
+	if sky.HDOP != sim.calculateDOP().HDOP {
+		t.Errorf("Expected HDOP %f, got %f", sim.calculateDOP().HDOP, sky.HDOP)
+	}
+}
+
+func TestOutputGpsdMode(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.GpsdMode = true
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+
+	sim.output()
+
+	if strings.Contains(buffer.String(), "$") {
+		t.Errorf("gpsd mode should not emit NMEA, got %q", buffer.String())
+	}
+	reports := decodeGpsdLines(t, buffer.String())
+	if len(reports) != 2 || reports[0]["class"] != "TPV" || reports[1]["class"] != "SKY" {
+		t.Errorf("Expected TPV then SKY, got %v", reports)
+	}
+
+	// The NMEA path remains the default
+	sim.Config.GpsdMode = false
+	buffer.Reset()
+	sim.output()
+	if !strings.HasPrefix(buffer.String(), "$GPGGA") {
+		t.Errorf("Expected NMEA output by default, got %q", buffer.String())
+	}
+}
+
+func TestGpsdTCPSession(t *testing.T) {
+	config := createTestConfig()
+	config.TCPListen = "127.0.0.1:0"
+	config.GpsdMode = true
+	config.TimeToLock = 0
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	conn, err := net.Dial("tcp", sim.TCPAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	readClass := func() map[string]interface{} {
+		t.Helper()
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read from gpsd stream: %v", err)
+		}
+		return decodeGpsdLines(t, line)[0]
+	}
+
+	if version := readClass(); version["class"] != "VERSION" {
+		t.Fatalf("Expected VERSION banner first, got %v", version)
+	}
+
+	if _, err := conn.Write([]byte("?WATCH={\"enable\":true,\"json\":true};\n")); err != nil {
+		t.Fatalf("Failed to send WATCH: %v", err)
+	}
+	if devices := readClass(); devices["class"] != "DEVICES" {
+		t.Errorf("Expected DEVICES reply, got %v", devices)
+	}
+	if watch := readClass(); watch["class"] != "WATCH" {
+		t.Errorf("Expected WATCH reply, got %v", watch)
+	}
+
+	sim.update()
+	sim.output()
+
+	tpv := readClass()
+	if tpv["class"] != "TPV" || tpv["lat"] != sim.currentLat || tpv["lon"] != sim.currentLon {
+		t.Errorf("Expected TPV at %f, %f, got %v", sim.currentLat, sim.currentLon, tpv)
+	}
+}
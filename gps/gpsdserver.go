@@ -0,0 +1,38 @@
+package gps
+
+import "net"
+
+// startGpsdServer starts the gpsd server on Config.GpsdListen, serving gpsd
+// clients TPV and SKY reports alongside the NMEA output
+func (s *GPSSimulator) startGpsdServer() error {
+	server, err := newTCPServer(s.Config.GpsdListen, gpsdProtocol{})
+	if err != nil {
+		return err
+	}
+	s.gpsdServer = server
+	return nil
+}
+
+// GpsdAddr returns the address the gpsd server is listening on, or nil
+// when GpsdListen is not set
+func (s *GPSSimulator) GpsdAddr() net.Addr {
+	if s.gpsdServer == nil {
+		return nil
+	}
+	return s.gpsdServer.Addr()
+}
+
+// writeGpsdReports writes the cycle's TPV report, and the SKY report once
+// locked, to the clients of the gpsd server
+func (s *GPSSimulator) writeGpsdReports() {
+	if s.gpsdServer == nil {
+		return
+	}
+	timestamp := s.fixTime(s.now())
+	s.gpsdServer.Write([]byte(s.generateTPV(timestamp)))
+
+	// Satellites are only known once the receiver has locked
+	if s.isLocked {
+		s.gpsdServer.Write([]byte(s.generateSKY(timestamp)))
+	}
+}
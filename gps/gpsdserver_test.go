@@ -0,0 +1,97 @@
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGpsdServer(t *testing.T) {
+	config := createTestConfig()
+	config.GpsdListen = "127.0.0.1:0"
+	config.TimeToLock = 0
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	conn, err := net.Dial("tcp", sim.GpsdAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	readReport := func() map[string]interface{} {
+		t.Helper()
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read from gpsd server: %v", err)
+		}
+		var report map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &report); err != nil {
+			t.Fatalf("Invalid gpsd report %q: %v", line, err)
+		}
+		return report
+	}
+
+	if version := readReport(); version["class"] != "VERSION" {
+		t.Fatalf("Expected VERSION banner first, got %v", version)
+	}
+	if _, err := conn.Write([]byte("?WATCH={\"enable\":true,\"json\":true};\n")); err != nil {
+		t.Fatalf("Failed to send WATCH: %v", err)
+	}
+	if devices := readReport(); devices["class"] != "DEVICES" {
+		t.Errorf("Expected DEVICES reply, got %v", devices)
+	}
+	if watch := readReport(); watch["class"] != "WATCH" {
+		t.Errorf("Expected WATCH reply, got %v", watch)
+	}
+
+	// Unsupported commands get an ERROR report
+	if _, err := conn.Write([]byte("?DEVICE={\"path\":\"/dev/ttyS0\"};\n")); err != nil {
+		t.Fatalf("Failed to send DEVICE: %v", err)
+	}
+	if report := readReport(); report["class"] != "ERROR" {
+		t.Errorf("Expected ERROR reply, got %v", report)
+	}
+
+	sim.tick()
+
+	tpv := readReport()
+	fix := sim.CurrentFix()
+	if tpv["class"] != "TPV" || tpv["mode"] != float64(gpsdModeFix3D) {
+		t.Fatalf("Expected a 3D fix TPV report, got %v", tpv)
+	}
+	if tpv["lat"] != fix.Lat || tpv["lon"] != fix.Lon {
+		t.Errorf("Expected TPV at the simulator fix %f, %f, got %v, %v", fix.Lat, fix.Lon, tpv["lat"], tpv["lon"])
+	}
+	if sky := readReport(); sky["class"] != "SKY" {
+		t.Errorf("Expected SKY report after TPV, got %v", sky)
+	}
+
+	// NMEA output carries on unchanged
+	if !strings.HasPrefix(buffer.String(), "$GPGGA") {
+		t.Errorf("Expected NMEA output alongside the gpsd server, got %q", buffer.String())
+	}
+}
+
+func TestGpsdServerListenError(t *testing.T) {
+	config := createTestConfig()
+	config.GpsdListen = "invalid-address"
+
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for an invalid gpsd listen address")
+	}
+	if sim := createTestSimulator(); sim.GpsdAddr() != nil {
+		t.Error("Expected no gpsd address without GpsdListen")
+	}
+}
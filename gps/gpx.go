@@ -1,20 +1,28 @@
 package gps
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // GPX represents the root GPX document structure
 type GPX struct {
-	XMLName xml.Name `xml:"gpx"`
-	Version string   `xml:"version,attr"`
-	Creator string   `xml:"creator,attr"`
-	Xmlns   string   `xml:"xmlns,attr"`
-	Track   Track    `xml:"trk"`
-	Routes  []Route  `xml:"rte"`
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Xmlns     string     `xml:"xmlns,attr"`
+	Track     Track      `xml:"trk"`
+	Routes    []Route    `xml:"rte"`
+	Waypoints []Waypoint `xml:"wpt"`
 }
 
 // Track represents a GPX track
@@ -30,10 +38,135 @@ type TrackSegment struct {
 
 // TrackPoint represents a single point in a GPX track
 type TrackPoint struct {
-	Lat       float64   `xml:"lat,attr"`
-	Lon       float64   `xml:"lon,attr"`
-	Elevation float64   `xml:"ele"`
-	Time      time.Time `xml:"time"`
+	Lat        float64               `xml:"lat,attr"`
+	Lon        float64               `xml:"lon,attr"`
+	Elevation  float64               `xml:"ele"`
+	Time       time.Time             `xml:"time"`
+	Satellites int                   `xml:"sat,omitempty"`        // Satellites used for the fix
+	HDOP       float64               `xml:"hdop,omitempty"`       // Horizontal dilution of precision
+	Extensions *TrackPointExtensions `xml:"extensions,omitempty"` // Speed and course, when recorded
+	Segment    int                   `xml:"-"`                    // Index of the track segment the point was read from
+}
+
+// TrackPointExtensions holds the GPX extensions of a track point. Speed and
+// course use the Garmin TrackPointExtension v2 namespace.
+type TrackPointExtensions struct {
+	TrackPointExtension *TrackPointExtension `xml:"http://www.garmin.com/xmlschemas/TrackPointExtension/v2 TrackPointExtension"`
+}
+
+// TrackPointExtension is a Garmin TrackPointExtension with the speed and
+// course over ground
+type TrackPointExtension struct {
+	Speed  float64 `xml:"speed"`  // Meters per second
+	Course float64 `xml:"course"` // Degrees from true north
+}
+
+// gpxDocument is the read-side view of a GPX file. Unlike GPX, which the
+// writer uses for its single track, it keeps every track and segment.
+type gpxDocument struct {
+	Tracks    []gpxTrack `xml:"trk"`
+	Routes    []Route    `xml:"rte"`
+	Waypoints []Waypoint `xml:"wpt"`
+}
+
+// gpxTrack is a GPX track with all of its segments
+type gpxTrack struct {
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+// gpxSegment is a track segment as read, before points are validated
+type gpxSegment struct {
+	TrackPoints []gpxTrackPoint `xml:"trkpt"`
+}
+
+// gpxTrackPoint is a track point as read. Coordinates are kept as text so a
+// malformed point can be skipped instead of failing the whole file, and the
+// elevation is a pointer so a missing <ele> can be told apart from 0.
+type gpxTrackPoint struct {
+	Lat        string                `xml:"lat,attr"`
+	Lon        string                `xml:"lon,attr"`
+	Elevation  *float64              `xml:"ele"`
+	Time       time.Time             `xml:"time"`
+	Satellites int                   `xml:"sat"`
+	HDOP       float64               `xml:"hdop"`
+	Extensions *TrackPointExtensions `xml:"extensions"`
+}
+
+// GPXSummary describes what was loaded from a GPX file
+type GPXSummary struct {
+	Points           int // Track points loaded
+	Tracks           int // Tracks with at least one loaded point
+	Segments         int // Segments with at least one loaded point
+	MissingElevation int // Loaded points without <ele>, given an elevation of 0
+	Skipped          int // Points skipped for a missing or invalid latitude or longitude
+}
+
+// String returns a one-line description of the summary
+func (s GPXSummary) String() string {
+	return fmt.Sprintf("%d points from %d tracks in %d segments (%d missing elevation, %d skipped)",
+		s.Points, s.Tracks, s.Segments, s.MissingElevation, s.Skipped)
+}
+
+// trackPoint validates a read point, reporting false when its coordinates
+// are missing, malformed or out of range
+func (p gpxTrackPoint) trackPoint() (TrackPoint, bool) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(p.Lat), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return TrackPoint{}, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(p.Lon), 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return TrackPoint{}, false
+	}
+
+	tp := TrackPoint{
+		Lat:        lat,
+		Lon:        lon,
+		Time:       p.Time,
+		Satellites: p.Satellites,
+		HDOP:       p.HDOP,
+		Extensions: p.Extensions,
+	}
+	if p.Elevation != nil {
+		tp.Elevation = *p.Elevation
+	}
+	return tp, true
+}
+
+// trackPoints flattens every segment of every track into one ordered slice,
+// numbering each point's Segment across the whole document. Points with
+// invalid coordinates are skipped and counted in the summary.
+func (d *gpxDocument) trackPoints() ([]TrackPoint, GPXSummary) {
+	var points []TrackPoint
+	var summary GPXSummary
+	for _, track := range d.Tracks {
+		trackLoaded := false
+		for _, trkseg := range track.Segments {
+			segmentLoaded := false
+			for _, p := range trkseg.TrackPoints {
+				tp, ok := p.trackPoint()
+				if !ok {
+					summary.Skipped++
+					continue
+				}
+				if p.Elevation == nil {
+					summary.MissingElevation++
+				}
+				tp.Segment = summary.Segments
+				points = append(points, tp)
+				segmentLoaded = true
+			}
+			if segmentLoaded {
+				summary.Segments++
+				trackLoaded = true
+			}
+		}
+		if trackLoaded {
+			summary.Tracks++
+		}
+	}
+	summary.Points = len(points)
+	return points, summary
 }
 
 // Route represents a GPX route
@@ -50,11 +183,21 @@ type RoutePoint struct {
 	Time      time.Time `xml:"time"`
 }
 
+// Waypoint represents a standalone GPX waypoint
+type Waypoint struct {
+	Lat       float64 `xml:"lat,attr"`
+	Lon       float64 `xml:"lon,attr"`
+	Elevation float64 `xml:"ele"`
+	Name      string  `xml:"name"`
+}
+
 // GPXWriter handles writing GPS data to a GPX file
 type GPXWriter struct {
-	filename string
-	gpx      *GPX
-	file     *os.File
+	filename   string
+	gpx        *GPX
+	file       *os.File
+	closed     bool // Set once Close has been called
+	extensions bool // Record speed, course, satellites and HDOP from AddFix
 }
 
 // NewGPXWriter creates a new GPX writer
@@ -97,6 +240,37 @@ func (w *GPXWriter) AddTrackPoint(lat, lon, elevation float64, timestamp time.Ti
 	w.gpx.Track.TrackSegment.TrackPoints = append(w.gpx.Track.TrackSegment.TrackPoints, trackPoint)
 }
 
+// AddTrackPointExt adds a track point that also records the speed in meters
+// per second and course in Garmin TrackPointExtension elements, and the
+// satellites used and HDOP in the standard GPX 1.1 sat and hdop elements
+func (w *GPXWriter) AddTrackPointExt(lat, lon, elevation float64, timestamp time.Time, speed, course float64, satellites int, hdop float64) {
+	trackPoint := TrackPoint{
+		Lat:        lat,
+		Lon:        lon,
+		Elevation:  elevation,
+		Time:       timestamp.UTC(),
+		Satellites: satellites,
+		HDOP:       hdop,
+		Extensions: &TrackPointExtensions{
+			TrackPointExtension: &TrackPointExtension{Speed: speed, Course: course},
+		},
+	}
+
+	w.gpx.Track.TrackSegment.TrackPoints = append(w.gpx.Track.TrackSegment.TrackPoints, trackPoint)
+}
+
+// AddFix adds the fix to the track, with its motion and signal details when
+// the writer records extensions
+func (w *GPXWriter) AddFix(fix Fix) {
+	if w.extensions {
+		// GPX records speed in meters per second
+		w.AddTrackPointExt(fix.Lat, fix.Lon, fix.Altitude, fix.Timestamp,
+			fix.SpeedKnots*0.514444, fix.CourseDeg, fix.SatellitesUsed, fix.HDOP)
+		return
+	}
+	w.AddTrackPoint(fix.Lat, fix.Lon, fix.Altitude, fix.Timestamp)
+}
+
 // WriteToFile writes the current GPX data to the file
 func (w *GPXWriter) WriteToFile() error {
 	// Seek to the beginning of the file
@@ -111,32 +285,58 @@ func (w *GPXWriter) WriteToFile() error {
 		return fmt.Errorf("failed to truncate file: %v", err)
 	}
 
+	err = w.encode(w.file)
+	if err != nil {
+		return err
+	}
+
+	// Flush to ensure data is written
+	err = w.file.Sync()
+	if err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+
+	return nil
+}
+
+// encode writes the XML header and the GPX document to out
+func (w *GPXWriter) encode(out io.Writer) error {
 	// Write XML header
-	_, err = w.file.WriteString(xml.Header)
+	_, err := io.WriteString(out, xml.Header)
 	if err != nil {
 		return fmt.Errorf("failed to write XML header: %v", err)
 	}
 
 	// Marshal and write the GPX data
-	encoder := xml.NewEncoder(w.file)
+	encoder := xml.NewEncoder(out)
 	encoder.Indent("", "  ")
 	err = encoder.Encode(w.gpx)
 	if err != nil {
 		return fmt.Errorf("failed to encode GPX data: %v", err)
 	}
+	return nil
+}
 
-	// Flush to ensure data is written
-	err = w.file.Sync()
-	if err != nil {
-		return fmt.Errorf("failed to sync file: %v", err)
+// Snapshot writes the current GPX data to the file, until it is closed,
+// and returns the same document
+func (w *GPXWriter) Snapshot() ([]byte, error) {
+	if !w.closed {
+		if err := w.WriteToFile(); err != nil {
+			return nil, err
+		}
 	}
-
-	return nil
+	var buf bytes.Buffer
+	if err := w.encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 // Close closes the GPX file
 func (w *GPXWriter) Close() error {
 	if w.file != nil {
+		w.closed = true
+
 		// Write final data before closing
 		err := w.WriteToFile()
 		if err != nil {
@@ -153,27 +353,50 @@ func (w *GPXWriter) GetTrackPointCount() int {
 	return len(w.gpx.Track.TrackSegment.TrackPoints)
 }
 
-// ReadGPXFile reads and parses a GPX file, returning the track points
+// decompressed returns r, transparently decompressed when it starts with the
+// gzip magic bytes so archived .gz tracks can be read like plain ones
+func decompressed(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+// ReadGPXFile reads and parses a GPX file, returning the track points of
+// every track and segment in document order. A gzip-compressed file is
+// decompressed first.
 func ReadGPXFile(filename string) ([]TrackPoint, error) {
+	points, _, err := ReadGPXFileSummary(filename)
+	return points, err
+}
+
+// ReadGPXFileSummary reads a GPX file like ReadGPXFile and also returns a
+// summary of the tracks and segments loaded and the points skipped. Each
+// point's Segment marks which segment it came from.
+func ReadGPXFileSummary(filename string) ([]TrackPoint, GPXSummary, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open GPX file %s: %v", filename, err)
+		return nil, GPXSummary{}, fmt.Errorf("failed to open GPX file %s: %v", filename, err)
 	}
 	defer file.Close()
 
-	var gpx GPX
-	decoder := xml.NewDecoder(file)
-	err = decoder.Decode(&gpx)
+	reader, err := decompressed(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse GPX file %s: %v", filename, err)
+		return nil, GPXSummary{}, fmt.Errorf("failed to decompress GPX file %s: %v", filename, err)
 	}
 
-	var points []TrackPoint
+	var gpx gpxDocument
+	decoder := xml.NewDecoder(reader)
+	err = decoder.Decode(&gpx)
+	if err != nil {
+		return nil, GPXSummary{}, fmt.Errorf("failed to parse GPX file %s: %v", filename, err)
+	}
 
-	// Try to get points from tracks first
-	if len(gpx.Track.TrackSegment.TrackPoints) > 0 {
-		points = gpx.Track.TrackSegment.TrackPoints
-	} else if len(gpx.Routes) > 0 && len(gpx.Routes[0].RoutePoints) > 0 {
+	// Try to get points from tracks first, flattening all tracks and segments
+	points, summary := gpx.trackPoints()
+	if len(points) == 0 && len(gpx.Routes) > 0 && len(gpx.Routes[0].RoutePoints) > 0 {
 		// Convert route points to track points
 		routePoints := gpx.Routes[0].RoutePoints
 		points = make([]TrackPoint, len(routePoints))
@@ -185,10 +408,51 @@ func ReadGPXFile(filename string) ([]TrackPoint, error) {
 				Time:      rp.Time,
 			}
 		}
+		summary.Points = len(points)
+	}
+
+	if len(points) == 0 {
+		return nil, summary, fmt.Errorf("no track points or route points found in GPX file %s", filename)
+	}
+
+	return points, summary, nil
+}
+
+// ReadGPXRoute reads a GPX file and returns the waypoints to navigate between.
+// Route points are preferred, then standalone waypoints, then track points.
+func ReadGPXRoute(filename string) ([]TrackPoint, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GPX file %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	var gpx gpxDocument
+	decoder := xml.NewDecoder(file)
+	err = decoder.Decode(&gpx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GPX file %s: %v", filename, err)
+	}
+
+	var points []TrackPoint
+
+	if len(gpx.Routes) > 0 && len(gpx.Routes[0].RoutePoints) > 0 {
+		for _, rp := range gpx.Routes[0].RoutePoints {
+			points = append(points, TrackPoint{Lat: rp.Lat, Lon: rp.Lon, Elevation: rp.Elevation})
+		}
+	} else if len(gpx.Waypoints) > 0 {
+		for _, wpt := range gpx.Waypoints {
+			points = append(points, TrackPoint{Lat: wpt.Lat, Lon: wpt.Lon, Elevation: wpt.Elevation})
+		}
+	} else {
+		trackPoints, _ := gpx.trackPoints()
+		for _, tp := range trackPoints {
+			points = append(points, TrackPoint{Lat: tp.Lat, Lon: tp.Lon, Elevation: tp.Elevation})
+		}
 	}
 
 	if len(points) == 0 {
-		return nil, fmt.Errorf("no track points or route points found in GPX file %s", filename)
+		return nil, fmt.Errorf("no route points or waypoints found in GPX file %s", filename)
 	}
 
 	return points, nil
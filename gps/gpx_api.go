@@ -0,0 +1,92 @@
+package gps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+)
+
+// ErrGPXDisabled is returned for GPX track requests when GPXEnabled is off
+var ErrGPXDisabled = errors.New("GPX output is not enabled")
+
+// GPXInfo describes the GPX track recorded so far
+type GPXInfo struct {
+	Filename string  `json:"filename"`
+	Points   int     `json:"points"`
+	Duration float64 `json:"duration"` // Seconds from the first to the last track point
+}
+
+// GPXBytes writes out the track points recorded so far and returns the
+// session's GPX document, or ErrGPXDisabled without a GPX writer
+func (s *GPSSimulator) GPXBytes() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gpxWriter == nil {
+		return nil, ErrGPXDisabled
+	}
+	return s.gpxWriter.Snapshot()
+}
+
+// GPXInfo returns the point count and duration of the recorded GPX track,
+// or ErrGPXDisabled without a GPX writer
+func (s *GPSSimulator) GPXInfo() (GPXInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.gpxWriter == nil {
+		return GPXInfo{}, ErrGPXDisabled
+	}
+	info := GPXInfo{
+		Filename: filepath.Base(s.Config.GPXFile),
+		Points:   s.gpxWriter.GetTrackPointCount(),
+	}
+	if points := s.gpxWriter.gpx.Track.TrackSegment.TrackPoints; len(points) > 1 {
+		info.Duration = points[len(points)-1].Time.Sub(points[0].Time).Seconds()
+	}
+	return info, nil
+}
+
+// GPXHandler returns an HTTP handler serving the session's GPX track as a
+// download, for mounting at /api/gpx. It responds 404 with a JSON error when
+// GPX output is not enabled, and is safe to use while Run is active.
+func (s *GPSSimulator) GPXHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := s.GPXBytes()
+		if err != nil {
+			writeTrackError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gpx+xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(s.Config.GPXFile)))
+		w.Write(data)
+	})
+}
+
+// GPXInfoHandler returns an HTTP handler serving GPXInfo as JSON, for
+// mounting at /api/gpx/info
+func (s *GPSSimulator) GPXInfoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := s.GPXInfo()
+		if err != nil {
+			writeTrackError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	})
+}
+
+// writeTrackError responds with err as a JSON error, 404 when the track
+// output is not enabled
+func writeTrackError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrGPXDisabled) || errors.Is(err, ErrGeoJSONDisabled) {
+		status = http.StatusNotFound
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
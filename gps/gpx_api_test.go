@@ -0,0 +1,113 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordGPX records GPX to a temporary file
+func recordGPX(t *testing.T) func(*Config) {
+	file := filepath.Join(t.TempDir(), "session.gpx")
+	return func(c *Config) {
+		c.GPXEnabled = true
+		c.GPXFile = file
+	}
+}
+
+func TestGPXHandler(t *testing.T) {
+	// Fewer than the ten points that trigger a periodic write to the file
+	sim := createTrackSimulator(t, recordGPX(t), 5)
+	defer sim.Close()
+
+	rec := httptest.NewRecorder()
+	sim.GPXHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/gpx", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/gpx+xml" {
+		t.Errorf("Expected application/gpx+xml, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="session.gpx"` {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+
+	var doc GPX
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Response is not valid GPX XML: %v", err)
+	}
+	if got := len(doc.Track.TrackSegment.TrackPoints); got != 5 {
+		t.Errorf("Expected 5 track points, got %d", got)
+	}
+
+	// The buffered points were flushed to the file as well
+	written, err := os.ReadFile(sim.Config.GPXFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	if !bytes.Equal(written, rec.Body.Bytes()) {
+		t.Error("Expected the GPX file to match the downloaded track")
+	}
+}
+
+func TestGPXHandlerAfterClose(t *testing.T) {
+	sim := createTrackSimulator(t, recordGPX(t), 3)
+	sim.Close()
+
+	data, err := sim.GPXBytes()
+	if err != nil {
+		t.Fatalf("GPXBytes() after Close failed: %v", err)
+	}
+	var doc GPX
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid GPX XML: %v", err)
+	}
+	if got := len(doc.Track.TrackSegment.TrackPoints); got != 3 {
+		t.Errorf("Expected 3 track points, got %d", got)
+	}
+}
+
+func TestGPXInfoHandler(t *testing.T) {
+	sim := createTrackSimulator(t, recordGPX(t), 11)
+	defer sim.Close()
+
+	rec := httptest.NewRecorder()
+	sim.GPXInfoHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/gpx/info", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var info GPXInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	expected := GPXInfo{Filename: "session.gpx", Points: 11, Duration: 10}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+}
+
+func TestGPXHandlersDisabled(t *testing.T) {
+	sim := createTestSimulator()
+
+	for path, handler := range map[string]http.Handler{
+		"/api/gpx":      sim.GPXHandler(),
+		"/api/gpx/info": sim.GPXInfoHandler(),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, rec.Code)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["error"] != ErrGPXDisabled.Error() {
+			t.Errorf("%s: expected a JSON error, got %v (%v)", path, body, err)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package gps
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -601,7 +605,8 @@ func TestReadGPXFileErrors(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tempFile := "test_error_" + strings.ReplaceAll(tt.name, " ", "_") + ".gpx"
+			tempDir := t.TempDir()
+			tempFile := filepath.Join(tempDir, "test_error_"+strings.ReplaceAll(tt.name, " ", "_")+".gpx")
 
 			if tt.name != "Non-existent file" {
 				err := os.WriteFile(tempFile, []byte(tt.content), 0644)
@@ -609,7 +614,7 @@ func TestReadGPXFileErrors(t *testing.T) {
 					t.Fatalf("Failed to write test file: %v", err)
 				}
 			} else {
-				tempFile = "non_existent_file.gpx"
+				tempFile = filepath.Join(tempDir, "non_existent_file.gpx")
 			}
 
 			_, err := ReadGPXFile(tempFile)
@@ -627,3 +632,366 @@ func TestReadGPXFileErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestReadGPXRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+		errorMsg string
+	}{
+		{
+			name: "Route points",
+			content: `<?xml version="1.0"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <rte>
+    <rtept lat="42.0" lon="-71.0"></rtept>
+    <rtept lat="42.1" lon="-71.1"></rtept>
+    <rtept lat="42.2" lon="-71.2"></rtept>
+  </rte>
+</gpx>`,
+			expected: 3,
+		},
+		{
+			name: "Waypoints",
+			content: `<?xml version="1.0"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="42.0" lon="-71.0"><name>A</name></wpt>
+  <wpt lat="42.1" lon="-71.1"><name>B</name></wpt>
+</gpx>`,
+			expected: 2,
+		},
+		{
+			name: "Track points fallback",
+			content: `<?xml version="1.0"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="42.0" lon="-71.0"><time>2024-01-15T10:00:00Z</time></trkpt>
+    <trkpt lat="42.1" lon="-71.1"><time>2024-01-15T10:00:10Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`,
+			expected: 2,
+		},
+		{
+			name: "Empty file",
+			content: `<?xml version="1.0"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"></gpx>`,
+			errorMsg: "no route points or waypoints found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempFile := filepath.Join(t.TempDir(), "route.gpx")
+			if err := os.WriteFile(tempFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			points, err := ReadGPXRoute(tempFile)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("Expected error containing '%s', got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(points) != tt.expected {
+				t.Errorf("Expected %d points, got %d", tt.expected, len(points))
+			}
+			if !points[0].Time.IsZero() {
+				t.Error("Route points should not carry timestamps")
+			}
+			if points[0].Lat != 42.0 || points[0].Lon != -71.0 {
+				t.Errorf("Unexpected first point %f, %f", points[0].Lat, points[0].Lon)
+			}
+		})
+	}
+}
+
+func TestReadGPXFileMultipleSegments(t *testing.T) {
+	points, err := ReadGPXFile(filepath.Join("testdata", "multi_segment.gpx"))
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+
+	// Two segments in the first track plus one in the second
+	if len(points) != 8 {
+		t.Fatalf("Expected 8 track points across all tracks and segments, got %d", len(points))
+	}
+
+	expectedSegments := []int{0, 0, 0, 1, 1, 1, 2, 2}
+	for i, point := range points {
+		if point.Segment != expectedSegments[i] {
+			t.Errorf("Point %d: expected segment %d, got %d", i, expectedSegments[i], point.Segment)
+		}
+	}
+
+	// Points stay in document order
+	for i := 1; i < len(points); i++ {
+		if !points[i].Time.After(points[i-1].Time) {
+			t.Errorf("Point %d is out of order: %v after %v", i, points[i].Time, points[i-1].Time)
+		}
+	}
+}
+
+func TestReadGPXRouteMultipleSegments(t *testing.T) {
+	points, err := ReadGPXRoute(filepath.Join("testdata", "multi_segment.gpx"))
+	if err != nil {
+		t.Fatalf("Failed to read GPX route: %v", err)
+	}
+	if len(points) != 8 {
+		t.Errorf("Expected 8 waypoints from all track segments, got %d", len(points))
+	}
+}
+
+func TestGPXWithoutExtensionsUnchanged(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "plain.gpx")
+	writer, err := NewGPXWriter(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to create GPX writer: %v", err)
+	}
+	writer.AddTrackPoint(37.7749, -122.4194, 45.0, time.Date(2025, 8, 9, 12, 30, 45, 0, time.UTC))
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close GPX writer: %v", err)
+	}
+
+	content, err := os.ReadFile(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="go-gps-simulator" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>GPS Simulator Track</name>
+    <trkseg>
+      <trkpt lat="37.7749" lon="-122.4194">
+        <ele>45</ele>
+        <time>2025-08-09T12:30:45Z</time>
+      </trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+	if string(content) != expected {
+		t.Errorf("GPX output without extensions changed:\n%s", content)
+	}
+}
+
+func TestGPXExtensionsRoundTrip(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "extensions.gpx")
+	writer, err := NewGPXWriter(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to create GPX writer: %v", err)
+	}
+	testTime := time.Date(2025, 8, 9, 12, 30, 45, 0, time.UTC)
+	writer.AddTrackPointExt(37.7749, -122.4194, 45.0, testTime, 5.5, 271.3, 9, 1.2)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close GPX writer: %v", err)
+	}
+
+	points, err := ReadGPXFile(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("Expected 1 track point, got %d", len(points))
+	}
+
+	point := points[0]
+	if point.Satellites != 9 || point.HDOP != 1.2 {
+		t.Errorf("Expected 9 satellites and HDOP 1.2, got %d and %.1f", point.Satellites, point.HDOP)
+	}
+	if point.Extensions == nil || point.Extensions.TrackPointExtension == nil {
+		t.Fatal("Expected track point extensions to be read back")
+	}
+	if ext := point.Extensions.TrackPointExtension; ext.Speed != 5.5 || ext.Course != 271.3 {
+		t.Errorf("Expected speed 5.5 m/s and course 271.3, got %+v", ext)
+	}
+}
+
+func TestReadGPXFileGarminExtensions(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "garmin.gpx")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="device" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2">
+  <trk><trkseg>
+    <trkpt lat="37.7749" lon="-122.4194">
+      <ele>45</ele>
+      <time>2025-08-09T12:30:45Z</time>
+      <extensions>
+        <gpxtpx:TrackPointExtension>
+          <gpxtpx:speed>3.2</gpxtpx:speed>
+          <gpxtpx:course>88.5</gpxtpx:course>
+        </gpxtpx:TrackPointExtension>
+      </extensions>
+    </trkpt>
+  </trkseg></trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write GPX file: %v", err)
+	}
+
+	points, err := ReadGPXFile(tempFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	ext := points[0].Extensions
+	if ext == nil || ext.TrackPointExtension == nil || ext.TrackPointExtension.Speed != 3.2 || ext.TrackPointExtension.Course != 88.5 {
+		t.Errorf("Expected prefixed Garmin extensions to be read, got %+v", ext)
+	}
+}
+
+func TestSimulatorGPXExtensions(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.OutputRate = time.Second
+	config.Speed = 10
+	config.Quiet = true
+	config.GPXEnabled = true
+	config.GPXExtensions = true
+	config.GPXFile = filepath.Join(t.TempDir(), "track.gpx")
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	if _, err := sim.GenerateTo(io.Discard, 5*time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	points, err := ReadGPXFile(config.GPXFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	for i, point := range points {
+		if point.Satellites != config.Satellites || point.HDOP <= 0 {
+			t.Errorf("Point %d: expected %d satellites and positive HDOP, got %d and %.1f", i, config.Satellites, point.Satellites, point.HDOP)
+		}
+		if point.Extensions == nil || point.Extensions.TrackPointExtension == nil {
+			t.Fatalf("Point %d: expected speed and course extensions", i)
+		}
+		// Speed jitter stays within 30% of 10 knots, recorded in m/s
+		if speed := point.Extensions.TrackPointExtension.Speed; speed < 7*0.514444 || speed > 13*0.514444 {
+			t.Errorf("Point %d: expected speed near %.2f m/s, got %.2f", i, 10*0.514444, speed)
+		}
+	}
+}
+
+func TestReadGPXFileSummary(t *testing.T) {
+	points, summary, err := ReadGPXFileSummary(filepath.Join("testdata", "two_tracks_malformed.gpx"))
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+
+	expected := GPXSummary{Points: 9, Tracks: 2, Segments: 4, MissingElevation: 2, Skipped: 3}
+	if summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, summary)
+	}
+	if len(points) != summary.Points {
+		t.Fatalf("Expected %d points, got %d", summary.Points, len(points))
+	}
+
+	expectedSegments := []int{0, 0, 0, 1, 1, 2, 2, 3, 3}
+	for i, point := range points {
+		if point.Segment != expectedSegments[i] {
+			t.Errorf("Point %d: expected segment %d, got %d", i, expectedSegments[i], point.Segment)
+		}
+		if point.Lat < 36 || point.Lat > 38 || point.Lon != -122.0 && point.Lon != -122.001 {
+			t.Errorf("Point %d: malformed coordinates %f, %f were loaded", i, point.Lat, point.Lon)
+		}
+	}
+
+	// Points without <ele> are loaded at elevation 0
+	if points[1].Elevation != 0 || points[6].Elevation != 0 {
+		t.Errorf("Expected missing elevations to read as 0, got %f and %f", points[1].Elevation, points[6].Elevation)
+	}
+
+	if got := summary.String(); got != "9 points from 2 tracks in 4 segments (2 missing elevation, 3 skipped)" {
+		t.Errorf("Unexpected summary string %q", got)
+	}
+}
+
+func TestReadGPXFileAllPointsInvalid(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "invalid.gpx")
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="" lon="-122.0"><ele>10</ele></trkpt>
+    <trkpt lat="37.0" lon="200.0"><ele>10</ele></trkpt>
+  </trkseg></trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write GPX file: %v", err)
+	}
+
+	_, summary, err := ReadGPXFileSummary(tempFile)
+	if err == nil {
+		t.Error("Expected error when every track point is invalid")
+	}
+	if summary.Skipped != 2 || summary.Segments != 0 || summary.Tracks != 0 {
+		t.Errorf("Expected 2 skipped points and no segments, got %+v", summary)
+	}
+}
+
+// writeGzip writes data gzip-compressed to filename
+func writeGzip(t *testing.T, filename string, data []byte) {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := os.WriteFile(filename, buffer.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", filename, err)
+	}
+}
+
+func TestReadGPXFileGzip(t *testing.T) {
+	plain := filepath.Join("testdata", "multi_segment.gpx")
+	expected, err := ReadGPXFile(plain)
+	if err != nil {
+		t.Fatalf("Failed to read uncompressed GPX: %v", err)
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	compressed := filepath.Join(tempDir, "multi_segment.gpx.gz")
+	writeGzip(t, compressed, data)
+
+	// Detected by the gzip magic bytes, whatever the extension
+	unlabelled := filepath.Join(tempDir, "archived.gpx")
+	writeGzip(t, unlabelled, data)
+
+	for _, file := range []string{compressed, unlabelled} {
+		points, err := ReadTrackFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if !reflect.DeepEqual(points, expected) {
+			t.Errorf("Expected %s to load the same points as the uncompressed file", file)
+		}
+	}
+}
+
+func TestReplayCorruptGzip(t *testing.T) {
+	tempFile := filepath.Join(t.TempDir(), "corrupt.gpx.gz")
+	if err := os.WriteFile(tempFile, []byte{0x1f, 0x8b, 0x00, 0x01}, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 1.0
+	_, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "failed to load replay file") {
+		t.Errorf("Expected failed to load replay file error, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// updateRateOfTurn records the rate of change of course since the previous
+// update in degrees per minute. Turns to starboard (clockwise) are positive
+// and turns to port negative.
+func (s *GPSSimulator) updateRateOfTurn(now time.Time) {
+	if !s.previousCourseTime.IsZero() {
+		if elapsed := now.Sub(s.previousCourseTime).Minutes(); elapsed > 0 {
+			// Take the shortest way round so crossing north is a small turn
+			delta := math.Mod(s.currentCourse-s.previousCourse+540, 360) - 180
+			s.rateOfTurn = delta / elapsed
+		}
+	}
+	s.previousCourse = s.currentCourse
+	s.previousCourseTime = now
+}
+
+// generateHDT generates an HDT (Heading - True) sentence reporting the current
+// course as the heading
+func (s *GPSSimulator) generateHDT() string {
+	sentence := fmt.Sprintf("$%sHDT,%.1f,T", s.talkerID(), s.currentCourse)
+	return formatNMEA(sentence)
+}
+
+// generateROT generates a ROT (Rate Of Turn) sentence in degrees per minute,
+// negative when turning to port
+func (s *GPSSimulator) generateROT() string {
+	sentence := fmt.Sprintf("$%sROT,%.1f,A", s.talkerID(), s.rateOfTurn) // A = Data valid
+	return formatNMEA(sentence)
+}
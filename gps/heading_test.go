@@ -0,0 +1,98 @@
+package gps
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHDT(t *testing.T) {
+	sim := createTestSimulator()
+	sim.currentCourse = 123.45
+
+	result := sim.generateHDT()
+	if !strings.HasPrefix(result, "$GPHDT,123.5,T*") {
+		t.Errorf("Expected HDT with heading 123.5, got: %s", result)
+	}
+	parts := strings.Split(result, "*")
+	if len(parts) != 2 || strings.TrimSuffix(parts[1], "\r\n") != calculateChecksum(parts[0]) {
+		t.Errorf("generateHDT has invalid checksum: %s", result)
+	}
+}
+
+func TestRateOfTurnSign(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from, to float64
+		expected float64 // Degrees per minute over a 10 second update
+	}{
+		{"Starboard", 90, 100, 60},
+		{"Port", 90, 80, -60},
+		{"Starboard across north", 355, 5, 60},
+		{"Port across north", 5, 355, -60},
+		{"Steady", 180, 180, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.currentCourse = tt.from
+			sim.updateRateOfTurn(start)
+			sim.currentCourse = tt.to
+			sim.updateRateOfTurn(start.Add(10 * time.Second))
+
+			if math.Abs(sim.rateOfTurn-tt.expected) > 1e-9 {
+				t.Errorf("Expected rate of turn %.1f, got %.1f", tt.expected, sim.rateOfTurn)
+			}
+
+			fields := strings.Split(strings.Split(sim.generateROT(), "*")[0], ",")
+			rot, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || math.Abs(rot-tt.expected) > 0.05 || fields[2] != "A" {
+				t.Errorf("Expected ROT %.1f,A, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
+
+func TestRateOfTurnConstantCourse(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Jitter = 0
+	sim.Config.Radius = 0
+	sim.Config.Speed = 10
+	sim.Config.Course = 45
+	sim.Config.Sentences = []string{SentenceROT}
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+
+	for i := 0; i < 30; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+
+		types := emittedSentenceTypes(sim)
+		if !types[SentenceROT] {
+			t.Fatal("Expected ROT to be emitted when selected")
+		}
+		if math.Abs(sim.rateOfTurn) > 0.05 {
+			t.Fatalf("Expected ROT near zero on a constant course, got %.2f", sim.rateOfTurn)
+		}
+	}
+}
+
+func TestHeadingSentencesOnlyWhenLocked(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Sentences = []string{"HDT", "ROT"}
+
+	if types := emittedSentenceTypes(sim); !types[SentenceHDT] || !types[SentenceROT] {
+		t.Errorf("Expected HDT and ROT when locked and selected, got %v", types)
+	}
+
+	sim.isLocked = false
+	if types := emittedSentenceTypes(sim); types[SentenceHDT] || types[SentenceROT] {
+		t.Errorf("Expected no HDT or ROT before lock, got %v", types)
+	}
+}
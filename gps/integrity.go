@@ -0,0 +1,123 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// errorPlaces returns the decimal places for error statistics in meters:
+// millimeters for centimeter-level RTK fixes, decimeters otherwise
+func (s *GPSSimulator) errorPlaces() int {
+	if quality := s.fixQuality(); quality == FixQualityRTKFixed || quality == FixQualityRTKFloat {
+		return 3
+	}
+	return 1
+}
+
+// rangeResiduals returns the range residual in meters of each satellite, in
+// the order of s.Satellites. The residuals have zero mean, as after a least
+// squares fix, and their RMS equals the GST range RMS so GRS, GBS and GST
+// agree. They are drawn once per epoch so every sentence in a burst reports
+// the same values.
+func (s *GPSSimulator) rangeResiduals(timestamp time.Time) []float64 {
+	if len(s.residuals) == len(s.Satellites) && s.residualsAt.Equal(timestamp) {
+		return s.residuals
+	}
+
+	residuals := make([]float64, len(s.Satellites))
+	if len(residuals) > 1 {
+		mean := 0.0
+		for i := range residuals {
+			residuals[i] = s.random().NormFloat64()
+			mean += residuals[i] / float64(len(residuals))
+		}
+		sumSquares := 0.0
+		for i := range residuals {
+			residuals[i] -= mean
+			sumSquares += residuals[i] * residuals[i]
+		}
+		if scale := s.calculateErrorStats().RMS / math.Sqrt(sumSquares/float64(len(residuals))); !math.IsInf(scale, 0) && !math.IsNaN(scale) {
+			for i := range residuals {
+				residuals[i] *= scale
+			}
+		}
+	}
+
+	s.residuals = residuals
+	s.residualsAt = timestamp
+	return residuals
+}
+
+// generateGRS generates GRS (GNSS Range Residuals) sentences, one per
+// constellation like GSA, with the residual of each satellite used in the
+// fix in GSA order
+func (s *GPSSimulator) generateGRS(timestamp time.Time) []string {
+	residuals := s.rangeResiduals(timestamp)
+
+	var sentences []string
+	for _, c := range s.constellations() {
+		var fields []string
+		for i, sat := range s.Satellites {
+			if sat.Constellation == c && len(fields) < 12 {
+				fields = append(fields, fmt.Sprintf("%.*f", s.errorPlaces(), residuals[i]))
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		for len(fields) < 12 {
+			fields = append(fields, "")
+		}
+
+		// Mode 1: residuals recomputed after the GGA position was computed
+		sentence := fmt.Sprintf("$%sGRS,%s,1,%s",
+			s.talkerID(), s.timeField(timestamp), strings.Join(fields, ","))
+
+		// NMEA 4.1 adds the GNSS system and signal IDs
+		if s.isNMEA41() {
+			sentence += fmt.Sprintf(",%d,%d", c.SystemID(), c.SignalID())
+		}
+		sentences = append(sentences, formatNMEA(sentence))
+	}
+	return sentences
+}
+
+// generateGBS generates a GBS (GNSS Satellite Fault Detection) sentence with
+// the expected position errors from GST and the satellite with the largest
+// range residual as the most likely failed one, its residual as the bias
+// estimate and the range RMS as the bias standard deviation
+func (s *GPSSimulator) generateGBS(timestamp time.Time) string {
+	stats := s.calculateErrorStats()
+	residuals := s.rangeResiduals(timestamp)
+	places := s.errorPlaces()
+
+	// Satellite ID, bias and NMEA 4.1 system and signal IDs stay empty
+	// without any satellites
+	flagged := -1
+	for i, residual := range residuals {
+		if flagged < 0 || math.Abs(residual) > math.Abs(residuals[flagged]) {
+			flagged = i
+		}
+	}
+	satID, bias, systemID, signalID := "", "", "", ""
+	if flagged >= 0 {
+		sat := s.Satellites[flagged]
+		satID = fmt.Sprintf("%02d", sat.ID)
+		bias = fmt.Sprintf("%.*f", places, residuals[flagged])
+		systemID = fmt.Sprintf("%d", sat.Constellation.SystemID())
+		signalID = fmt.Sprintf("%d", sat.Constellation.SignalID())
+	}
+
+	// The probability of missed detection is left empty, as most receivers do
+	sentence := fmt.Sprintf("$%sGBS,%s,%.*f,%.*f,%.*f,%s,,%s,%.*f",
+		s.talkerID(), s.timeField(timestamp),
+		places, stats.LatSigma, places, stats.LonSigma, places, stats.AltSigma,
+		satID, bias, places, stats.RMS)
+
+	if s.isNMEA41() {
+		sentence += fmt.Sprintf(",%s,%s", systemID, signalID)
+	}
+	return formatNMEA(sentence)
+}
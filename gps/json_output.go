@@ -0,0 +1,99 @@
+package gps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Output formats selecting what each output cycle writes
+const (
+	OutputFormatNMEA = "nmea" // NMEA sentences (the default)
+	OutputFormatJSON = "json" // One JSON FixRecord line instead of NMEA sentences
+	OutputFormatBoth = "both" // NMEA sentences, with FixRecord lines written to Config.JSONWriter
+)
+
+// FixRecord is the line-delimited JSON object written each output cycle by
+// the json output format: the fix, with the same fields and names as Fix,
+// and the satellites in view
+type FixRecord struct {
+	Fix
+	Satellites []SatelliteRecord `json:"satellites"`
+}
+
+// SatelliteRecord describes one satellite in a FixRecord
+type SatelliteRecord struct {
+	ID            int    `json:"id"`
+	Constellation string `json:"constellation"`
+	Elevation     int    `json:"elev"` // Degrees above the horizon
+	Azimuth       int    `json:"az"`   // Degrees from true north
+	SNR           int    `json:"snr"`  // dB-Hz
+}
+
+// validateOutputFormat checks the output format and that the both format
+// has a writer for its JSON lines
+func (c Config) validateOutputFormat() error {
+	switch c.OutputFormat {
+	case "", OutputFormatNMEA:
+		return nil
+	case OutputFormatJSON:
+	case OutputFormatBoth:
+		if c.JSONWriter == nil {
+			return errors.New("Output format both requires a JSON writer")
+		}
+	default:
+		return fmt.Errorf("Output format must be %s, %s or %s, got %q", OutputFormatNMEA, OutputFormatJSON, OutputFormatBoth, c.OutputFormat)
+	}
+	if c.GpsdMode {
+		return errors.New("JSON output format cannot be used with gpsd output")
+	}
+	return nil
+}
+
+// fixRecord builds the FixRecord for an output cycle. Satellites are those
+// in view: all of them once locked, or those acquired so far before lock.
+func (s *GPSSimulator) fixRecord(timestamp time.Time) FixRecord {
+	inView := s.Satellites
+	if !s.isLocked {
+		inView = s.acquiredSatellites()
+	}
+	record := FixRecord{Fix: s.currentFix(timestamp), Satellites: make([]SatelliteRecord, len(inView))}
+	for i, sat := range inView {
+		record.Satellites[i] = SatelliteRecord{
+			ID:            sat.ID,
+			Constellation: sat.Constellation.String(),
+			Elevation:     sat.Elevation,
+			Azimuth:       sat.Azimuth,
+			SNR:           sat.SNR,
+		}
+	}
+	return record
+}
+
+// generateFixRecord encodes the FixRecord for an output cycle as a single
+// newline-terminated JSON line
+func (s *GPSSimulator) generateFixRecord(timestamp time.Time) string {
+	data, err := json.Marshal(s.fixRecord(timestamp))
+	if err != nil {
+		return ""
+	}
+	return string(data) + "\n"
+}
+
+// outputJSON emits the cycle's FixRecord in place of NMEA sentences
+func (s *GPSSimulator) outputJSON() {
+	timestamp := s.outputEpoch(s.now())
+	s.emit(s.generateFixRecord(timestamp))
+	s.notifyCallbacks(timestamp)
+}
+
+// writeJSONRecord writes the cycle's FixRecord to Config.JSONWriter alongside
+// the NMEA output when the output format is both
+func (s *GPSSimulator) writeJSONRecord() {
+	if s.Config.OutputFormat != OutputFormatBoth || s.Config.JSONWriter == nil {
+		return
+	}
+	io.WriteString(s.Config.JSONWriter, s.generateFixRecord(s.outputEpoch(s.now())))
+}
@@ -0,0 +1,139 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeFixRecords decodes every line of line-delimited JSON output
+func decodeFixRecords(t *testing.T, output string) []FixRecord {
+	t.Helper()
+	var records []FixRecord
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		var record FixRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestJSONOutputFormat(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 2 * time.Second
+	config.OutputFormat = OutputFormatJSON
+	config.Quiet = true
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+
+	if strings.Contains(buffer.String(), "$GP") {
+		t.Fatalf("Expected JSON instead of NMEA, got %q", buffer.String())
+	}
+	records := decodeFixRecords(t, buffer.String())
+	if len(records) != 5 {
+		t.Fatalf("Expected one JSON object per tick, got %d", len(records))
+	}
+	for i, record := range records {
+		if record.Timestamp.IsZero() || record.Lat < -90 || record.Lat > 90 || record.Lon < -180 || record.Lon > 180 {
+			t.Errorf("Record %d: invalid timestamp or position %+v", i, record.Fix)
+		}
+		if record.SpeedKnots < 0 || record.CourseDeg < 0 || record.CourseDeg >= 360 {
+			t.Errorf("Record %d: invalid speed or course %+v", i, record.Fix)
+		}
+		if record.Locked != (record.HDOP > 0) {
+			t.Errorf("Record %d: expected HDOP only with a fix, got locked %v HDOP %.1f", i, record.Locked, record.HDOP)
+		}
+		for _, sat := range record.Satellites {
+			if sat.ID <= 0 || sat.Elevation < 0 || sat.Elevation > 90 || sat.Azimuth < 0 || sat.Azimuth >= 360 || sat.SNR < 0 || sat.SNR > 99 {
+				t.Errorf("Record %d: satellite out of range %+v", i, sat)
+			}
+		}
+	}
+
+	last := records[len(records)-1]
+	if !last.Locked || len(last.Satellites) != config.Satellites || last.SatellitesUsed != config.Satellites {
+		t.Errorf("Expected a locked fix with %d satellites, got %+v", config.Satellites, last)
+	}
+}
+
+func TestJSONOutputFormatBoth(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.OutputFormat = OutputFormatBoth
+	jsonBuffer := &bytes.Buffer{}
+	config.JSONWriter = jsonBuffer
+	config.Quiet = true
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+
+	if !strings.Contains(buffer.String(), "GGA") || strings.Contains(buffer.String(), "{") {
+		t.Errorf("Expected only NMEA on the main writer, got %q", buffer.String())
+	}
+	records := decodeFixRecords(t, jsonBuffer.String())
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 JSON objects, got %d", len(records))
+	}
+	if fix := sim.CurrentFix(); records[2].Lat != fix.Lat || records[2].Lon != fix.Lon {
+		t.Errorf("Expected the last JSON object at the current fix %f, %f, got %f, %f", fix.Lat, fix.Lon, records[2].Lat, records[2].Lon)
+	}
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	for _, format := range []string{"", OutputFormatNMEA, OutputFormatJSON} {
+		config.OutputFormat = format
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected output format %q to be valid, got %v", format, err)
+		}
+	}
+
+	config.OutputFormat = "xml"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for an unknown output format")
+	}
+
+	config.OutputFormat = OutputFormatBoth
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for the both format without a JSON writer")
+	}
+	config.JSONWriter = &bytes.Buffer{}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected the both format with a JSON writer to be valid, got %v", err)
+	}
+
+	config.GpsdMode = true
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error combining JSON output with gpsd output")
+	}
+}
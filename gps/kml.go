@@ -0,0 +1,335 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadKMLFile reads a KML file and returns the points of every
+// <Placemark><LineString><coordinates> element in document order. KML
+// coordinates are "lon,lat[,alt]" tuples separated by whitespace; note that
+// longitude comes first, the reverse of the usual lat,lon convention. KML
+// LineStrings carry no timestamps, so the returned points have zero times and
+// replay falls back to index-based progression.
+func ReadKMLFile(filename string) ([]TrackPoint, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open KML file %s: %v", filename, err)
+	}
+	defer file.Close()
+
+	reader, err := decompressed(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress KML file %s: %v", filename, err)
+	}
+
+	var points []TrackPoint
+	var path []string
+	segment := 0
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse KML file %s: %v", filename, err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			// Only coordinates belonging to a LineString describe the track
+			if element.Name.Local == "coordinates" && len(path) > 0 && path[len(path)-1] == "LineString" {
+				var coordinates string
+				if err := decoder.DecodeElement(&coordinates, &element); err != nil {
+					return nil, fmt.Errorf("failed to parse KML file %s: %v", filename, err)
+				}
+				parsed, err := parseKMLCoordinates(coordinates)
+				if err != nil {
+					return nil, fmt.Errorf("invalid coordinates in KML file %s: %v", filename, err)
+				}
+				// Each LineString is its own segment
+				for _, point := range parsed {
+					point.Segment = segment
+					points = append(points, point)
+				}
+				segment++
+				continue
+			}
+			path = append(path, element.Name.Local)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no LineString coordinates found in KML file %s", filename)
+	}
+
+	return points, nil
+}
+
+// parseKMLCoordinates parses a KML coordinates string of whitespace-separated
+// "lon,lat[,alt]" tuples
+func parseKMLCoordinates(coordinates string) ([]TrackPoint, error) {
+	var points []TrackPoint
+	for _, tuple := range strings.Fields(coordinates) {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 || len(values) > 3 {
+			return nil, fmt.Errorf("expected lon,lat[,alt] but got %q", tuple)
+		}
+
+		var parsed [3]float64
+		for i, value := range values {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q in %q", value, tuple)
+			}
+			parsed[i] = number
+		}
+
+		lon, lat, alt := parsed[0], parsed[1], parsed[2]
+		if lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("latitude %f out of range in %q (KML order is lon,lat)", lat, tuple)
+		}
+		if lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("longitude %f out of range in %q", lon, tuple)
+		}
+
+		points = append(points, TrackPoint{Lat: lat, Lon: lon, Elevation: alt})
+	}
+	return points, nil
+}
+
+// ReadTrackFile reads replay track points from a GPX or KML file, choosing
+// the format from the file extension (.kml for KML, anything else as GPX).
+// Gzip-compressed files (e.g. track.gpx.gz or route.kml.gz) are decompressed
+// transparently.
+func ReadTrackFile(filename string) ([]TrackPoint, error) {
+	uncompressed := strings.TrimSuffix(strings.ToLower(filename), ".gz")
+	if filepath.Ext(uncompressed) == ".kml" {
+		return ReadKMLFile(filename)
+	}
+	return ReadGPXFile(filename)
+}
+
+// KML is the root of a KML document
+type KML struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document KMLDocument `xml:"Document"`
+}
+
+// KMLDocument holds the track's styles and placemarks
+type KMLDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []KMLStyle     `xml:"Style"`
+	Placemarks []KMLPlacemark `xml:"Placemark"`
+}
+
+// KMLStyle is a shared style referenced by placemarks through styleUrl
+type KMLStyle struct {
+	ID        string        `xml:"id,attr"`
+	LineStyle *KMLLineStyle `xml:"LineStyle,omitempty"`
+}
+
+// KMLLineStyle sets how a path is drawn. Colors are aabbggrr hex.
+type KMLLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+// KMLPlacemark is either the track's LineString, spanning its recorded
+// times, or a Point marking where and when the track starts or ends
+type KMLPlacemark struct {
+	Name       string         `xml:"name"`
+	StyleURL   string         `xml:"styleUrl,omitempty"`
+	TimeStamp  *KMLTimeStamp  `xml:"TimeStamp,omitempty"`
+	TimeSpan   *KMLTimeSpan   `xml:"TimeSpan,omitempty"`
+	LineString *KMLLineString `xml:"LineString,omitempty"`
+	Point      *KMLPoint      `xml:"Point,omitempty"`
+}
+
+// KMLTimeStamp is the time of a point placemark
+type KMLTimeStamp struct {
+	When string `xml:"when"`
+}
+
+// KMLTimeSpan is the time range of the track placemark
+type KMLTimeSpan struct {
+	Begin string `xml:"begin"`
+	End   string `xml:"end"`
+}
+
+// KMLLineString is a path of whitespace-separated "lon,lat,alt" tuples
+type KMLLineString struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// KMLPoint is a single "lon,lat,alt" position
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlPathStyle is the ID of the style the track's path is drawn with
+const kmlPathStyle = "track-path"
+
+// kmlPoint is a recorded KML track point
+type kmlPoint struct {
+	lat, lon, elevation float64
+	time                time.Time
+}
+
+// coordinates formats the point as a KML "lon,lat,alt" tuple
+func (p kmlPoint) coordinates() string {
+	return strconv.FormatFloat(p.lon, 'f', -1, 64) + "," +
+		strconv.FormatFloat(p.lat, 'f', -1, 64) + "," +
+		strconv.FormatFloat(p.elevation, 'f', -1, 64)
+}
+
+// KMLWriter handles writing GPS data to a KML file for viewing in Google
+// Earth. The track is a styled LineString placemark spanning the recorded
+// times, followed by Start and End point placemarks with their timestamps.
+type KMLWriter struct {
+	filename string
+	name     string
+	points   []kmlPoint
+	file     *os.File
+	closed   bool // Set once Close has been called
+}
+
+// NewKMLWriter creates a new KML writer for a track with the given name
+func NewKMLWriter(filename, name string) (*KMLWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KML file %s: %v", filename, err)
+	}
+	if name == "" {
+		name = "GPS Simulator Track"
+	}
+	return &KMLWriter{filename: filename, name: name, file: file}, nil
+}
+
+// AddTrackPoint adds a new track point to the KML file
+func (w *KMLWriter) AddTrackPoint(lat, lon, elevation float64, timestamp time.Time) {
+	w.points = append(w.points, kmlPoint{lat: lat, lon: lon, elevation: elevation, time: timestamp.UTC()})
+}
+
+// AddFix adds the fix to the track
+func (w *KMLWriter) AddFix(fix Fix) {
+	w.AddTrackPoint(fix.Lat, fix.Lon, fix.Altitude, fix.Timestamp)
+}
+
+func (w *KMLWriter) GetTrackPointCount() int {
+	return len(w.points)
+}
+
+// document builds the KML document for the points so far
+func (w *KMLWriter) document() KML {
+	doc := KML{
+		Xmlns: "http://www.opengis.net/kml/2.2",
+		Document: KMLDocument{
+			Name: w.name,
+			Styles: []KMLStyle{{
+				ID:        kmlPathStyle,
+				LineStyle: &KMLLineStyle{Color: "ff0000ff", Width: 3},
+			}},
+		},
+	}
+
+	coordinates := make([]string, len(w.points))
+	for i, p := range w.points {
+		coordinates[i] = p.coordinates()
+	}
+	track := KMLPlacemark{
+		Name:       w.name,
+		StyleURL:   "#" + kmlPathStyle,
+		LineString: &KMLLineString{AltitudeMode: "absolute", Coordinates: strings.Join(coordinates, " ")},
+	}
+	if len(w.points) == 0 {
+		doc.Document.Placemarks = []KMLPlacemark{track}
+		return doc
+	}
+
+	first, last := w.points[0], w.points[len(w.points)-1]
+	track.TimeSpan = &KMLTimeSpan{Begin: first.time.Format(time.RFC3339), End: last.time.Format(time.RFC3339)}
+	doc.Document.Placemarks = []KMLPlacemark{
+		track,
+		{
+			Name:      "Start",
+			TimeStamp: &KMLTimeStamp{When: first.time.Format(time.RFC3339)},
+			Point:     &KMLPoint{Coordinates: first.coordinates()},
+		},
+		{
+			Name:      "End",
+			TimeStamp: &KMLTimeStamp{When: last.time.Format(time.RFC3339)},
+			Point:     &KMLPoint{Coordinates: last.coordinates()},
+		},
+	}
+	return doc
+}
+
+// encode writes the XML header and the KML document to out. Encoding
+// through encoding/xml escapes the track name.
+func (w *KMLWriter) encode(out io.Writer) error {
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return fmt.Errorf("failed to write XML header: %v", err)
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(w.document()); err != nil {
+		return fmt.Errorf("failed to encode KML data: %v", err)
+	}
+	return nil
+}
+
+// Bytes returns the KML document for the points so far
+func (w *KMLWriter) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.encode(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteToFile writes the current KML data to the file
+func (w *KMLWriter) WriteToFile() error {
+	if _, err := w.file.Seek(0, 0); err != nil {
+		return fmt.Errorf("failed to seek to beginning of file: %v", err)
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate file: %v", err)
+	}
+	if err := w.encode(w.file); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+	return nil
+}
+
+// Close writes the final data and closes the KML file
+func (w *KMLWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	err := w.WriteToFile()
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,282 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+  <Document>
+    <name>Test Route</name>
+    <Placemark>
+      <name>Start</name>
+      <Point>
+        <coordinates>-100.0,10.0,0</coordinates>
+      </Point>
+    </Placemark>
+    <Folder>
+      <Placemark>
+        <name>Track</name>
+        <LineString>
+          <tessellate>1</tessellate>
+          <coordinates>
+            -122.4194,37.7749,10
+            -122.4184,37.7759,12.5
+            -122.4174,37.7769
+          </coordinates>
+        </LineString>
+      </Placemark>
+    </Folder>
+  </Document>
+</kml>`
+
+// writeTestKML writes KML content to a temporary file and returns its path
+func writeTestKML(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test KML file: %v", err)
+	}
+	return path
+}
+
+func TestReadKMLFile(t *testing.T) {
+	points, err := ReadKMLFile(writeTestKML(t, "track.kml", testKML))
+	if err != nil {
+		t.Fatalf("Failed to read KML file: %v", err)
+	}
+
+	// The Point placemark is ignored; only LineString coordinates are used
+	if len(points) != 3 {
+		t.Fatalf("Expected 3 points, got %d", len(points))
+	}
+
+	// KML tuples are lon,lat,alt so the first value is the longitude
+	expected := []TrackPoint{
+		{Lat: 37.7749, Lon: -122.4194, Elevation: 10},
+		{Lat: 37.7759, Lon: -122.4184, Elevation: 12.5},
+		{Lat: 37.7769, Lon: -122.4174, Elevation: 0},
+	}
+	for i, want := range expected {
+		got := points[i]
+		if got.Lat != want.Lat || got.Lon != want.Lon || got.Elevation != want.Elevation {
+			t.Errorf("Point %d: expected %+v, got %+v", i, want, got)
+		}
+		if !got.Time.IsZero() {
+			t.Errorf("Point %d: expected no timestamp, got %v", i, got.Time)
+		}
+	}
+}
+
+func TestParseKMLCoordinates(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		count       int
+		shouldError bool
+	}{
+		{"Tuples on one line", "1,2,3 4,5,6", 2, false},
+		{"Without altitude", "1,2\n\t4,5", 2, false},
+		{"Empty", "  ", 0, false},
+		{"Missing latitude", "1", 0, true},
+		{"Too many values", "1,2,3,4", 0, true},
+		{"Not a number", "east,2", 0, true},
+		{"Latitude out of range", "10,95", 0, true},
+		{"Longitude out of range", "190,10", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := parseKMLCoordinates(tt.input)
+			if (err != nil) != tt.shouldError {
+				t.Fatalf("Expected error: %v, got: %v", tt.shouldError, err)
+			}
+			if len(points) != tt.count {
+				t.Errorf("Expected %d points, got %d", tt.count, len(points))
+			}
+		})
+	}
+}
+
+func TestReadKMLFileErrors(t *testing.T) {
+	if _, err := ReadKMLFile("non_existent_file.kml"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	noLine := `<kml xmlns="http://www.opengis.net/kml/2.2"><Placemark><Point><coordinates>1,2</coordinates></Point></Placemark></kml>`
+	if _, err := ReadKMLFile(writeTestKML(t, "point.kml", noLine)); err == nil {
+		t.Error("Expected error for KML without a LineString")
+	}
+
+	if _, err := ReadKMLFile(writeTestKML(t, "broken.kml", "<kml><Placemark>")); err == nil {
+		t.Error("Expected error for invalid XML")
+	}
+}
+
+func TestNewGPSSimulatorWithKMLReplay(t *testing.T) {
+	config := createTestConfig()
+	config.ReplayFile = writeTestKML(t, "track.KML", testKML)
+	config.ReplaySpeed = 1.0
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator with KML replay: %v", err)
+	}
+
+	if len(sim.replayPoints) != 3 {
+		t.Fatalf("Expected 3 replay points, got %d", len(sim.replayPoints))
+	}
+	if sim.currentLat != 37.7749 || sim.currentLon != -122.4194 {
+		t.Errorf("Expected initial position from first KML point, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+
+	// Without timestamps, replay progresses one point per second
+	if sim.hasSequentialTimestamps() {
+		t.Error("KML tracks should use index-based progression")
+	}
+}
+
+// recordKML records KML named name, GPX and GeoJSON to temporary files
+// while moving at 10 knots
+func recordKML(t *testing.T, name string) func(*Config) {
+	gpx, geoJSON := recordGPX(t), recordGeoJSON(t, false)
+	file := filepath.Join(t.TempDir(), "session.kml")
+	return func(c *Config) {
+		gpx(c)
+		geoJSON(c)
+		c.Speed = 10
+		c.KMLEnabled = true
+		c.KMLFile = file
+		c.KMLName = name
+	}
+}
+
+// decodeKML parses a KML document
+func decodeKML(t *testing.T, data []byte) KML {
+	t.Helper()
+	var doc KML
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid KML: %v", err)
+	}
+	return doc
+}
+
+func TestKMLWriterTrack(t *testing.T) {
+	sim := createTrackSimulator(t, recordKML(t, ""), 12)
+	sim.Close()
+
+	data, err := os.ReadFile(sim.Config.KMLFile)
+	if err != nil {
+		t.Fatalf("Failed to read KML file: %v", err)
+	}
+	doc := decodeKML(t, data)
+	if doc.Document.Name != "GPS Simulator Track" {
+		t.Errorf("Expected default track name, got %q", doc.Document.Name)
+	}
+	if len(doc.Document.Styles) != 1 || doc.Document.Styles[0].LineStyle == nil {
+		t.Fatalf("Expected a path style, got %+v", doc.Document.Styles)
+	}
+
+	placemarks := doc.Document.Placemarks
+	if len(placemarks) != 3 {
+		t.Fatalf("Expected track, start and end placemarks, got %d", len(placemarks))
+	}
+	track := placemarks[0]
+	if track.LineString == nil || track.StyleURL != "#"+doc.Document.Styles[0].ID {
+		t.Fatalf("Expected a styled LineString track, got %+v", track)
+	}
+	coordinates := strings.Fields(track.LineString.Coordinates)
+	if len(coordinates) != 12 {
+		t.Errorf("Expected 12 coordinates, got %d", len(coordinates))
+	}
+
+	start, end := placemarks[1], placemarks[2]
+	if start.Point == nil || start.Point.Coordinates != coordinates[0] {
+		t.Errorf("Expected start placemark at %s, got %+v", coordinates[0], start.Point)
+	}
+	if end.Point == nil || end.Point.Coordinates != coordinates[len(coordinates)-1] {
+		t.Errorf("Expected end placemark at %s, got %+v", coordinates[len(coordinates)-1], end.Point)
+	}
+	if track.TimeSpan == nil || start.TimeStamp == nil || end.TimeStamp == nil ||
+		track.TimeSpan.Begin != start.TimeStamp.When || track.TimeSpan.End != end.TimeStamp.When {
+		t.Errorf("Expected the track to span the start and end timestamps, got %+v, %+v and %+v",
+			track.TimeSpan, start.TimeStamp, end.TimeStamp)
+	}
+}
+
+func TestKMLMatchesGPXTrack(t *testing.T) {
+	sim := createTrackSimulator(t, recordKML(t, ""), 15)
+	sim.Close()
+
+	kmlPoints, err := ReadKMLFile(sim.Config.KMLFile)
+	if err != nil {
+		t.Fatalf("Failed to read back KML file: %v", err)
+	}
+	gpxPoints, err := ReadGPXFile(sim.Config.GPXFile)
+	if err != nil {
+		t.Fatalf("Failed to read GPX file: %v", err)
+	}
+	if len(kmlPoints) != len(gpxPoints) {
+		t.Fatalf("Expected %d KML points like the GPX track, got %d", len(gpxPoints), len(kmlPoints))
+	}
+	for i := range gpxPoints {
+		if kmlPoints[i].Lat != gpxPoints[i].Lat || kmlPoints[i].Lon != gpxPoints[i].Lon {
+			t.Errorf("Point %d: KML %f, %f differs from GPX %f, %f", i,
+				kmlPoints[i].Lat, kmlPoints[i].Lon, gpxPoints[i].Lat, gpxPoints[i].Lon)
+		}
+	}
+	if count := sim.geoJSONWriter.GetTrackPointCount(); count != len(gpxPoints) {
+		t.Errorf("Expected %d GeoJSON points like the GPX track, got %d", len(gpxPoints), count)
+	}
+}
+
+func TestKMLTrackNameEscaped(t *testing.T) {
+	name := `Survey <north> & "east"`
+	sim := createTrackSimulator(t, recordKML(t, name), 2)
+	sim.Close()
+
+	data, err := os.ReadFile(sim.Config.KMLFile)
+	if err != nil {
+		t.Fatalf("Failed to read KML file: %v", err)
+	}
+	if bytes.Contains(data, []byte("<north>")) {
+		t.Error("Expected the track name to be escaped")
+	}
+	doc := decodeKML(t, data)
+	if doc.Document.Name != name || doc.Document.Placemarks[0].Name != name {
+		t.Errorf("Expected track name %q, got %q and %q", name, doc.Document.Name, doc.Document.Placemarks[0].Name)
+	}
+}
+
+func TestKMLWriterEmptyTrack(t *testing.T) {
+	writer, err := NewKMLWriter(filepath.Join(t.TempDir(), "empty.kml"), "Empty")
+	if err != nil {
+		t.Fatalf("Failed to create KML writer: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(writer.filename)
+	if err != nil {
+		t.Fatalf("Failed to read KML file: %v", err)
+	}
+	doc := decodeKML(t, data)
+	if len(doc.Document.Placemarks) != 1 || doc.Document.Placemarks[0].TimeSpan != nil {
+		t.Errorf("Expected only an empty track placemark, got %+v", doc.Document.Placemarks)
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Expected closing twice to be a no-op, got %v", err)
+	}
+}
+
+func TestNewKMLWriterError(t *testing.T) {
+	if _, err := NewKMLWriter(filepath.Join(t.TempDir(), "missing", "track.kml"), ""); err == nil {
+		t.Error("Expected error creating KML file in a missing directory")
+	}
+}
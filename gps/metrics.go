@@ -0,0 +1,157 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// metric is one Prometheus metric in the text exposition format
+type metric struct {
+	name  string
+	kind  string // counter or gauge
+	help  string
+	value float64
+}
+
+// Metrics is a snapshot of the simulator's counters and state, for
+// monitoring long runs. The JSON field names are stable.
+type Metrics struct {
+	Ticks             uint64        `json:"ticks"`             // Output cycles run
+	TickDrift         time.Duration `json:"tickDrift"`         // How far the last cycle was from OutputRate after the one before, in nanoseconds
+	MaxTickDrift      time.Duration `json:"maxTickDrift"`      // Largest TickDrift so far, in nanoseconds
+	SentencesEmitted  uint64        `json:"sentencesEmitted"`  // NMEA sentences (or gpsd reports) emitted
+	SentencesDropped  uint64        `json:"sentencesDropped"`  // Sentences dropped for stream subscribers not keeping up
+	TCPClients        int           `json:"tcpClients"`        // Clients connected to the TCP output
+	StreamSubscribers int           `json:"streamSubscribers"` // Sentence stream subscribers
+	Running           bool          `json:"running"`
+	Paused            bool          `json:"paused"`
+	Locked            bool          `json:"locked"`
+	SatellitesInView  int           `json:"satellitesInView"`
+	ReplayIndex       int           `json:"replayIndex"`    // Index of the current replay track point
+	ReplayLoops       uint64        `json:"replayLoops"`    // Passes completed through the replay track or playlist
+	GPXTrackPoints    int           `json:"gpxTrackPoints"` // Track points recorded in the GPX output
+}
+
+// tickTiming counts output cycles and how closely they keep to OutputRate
+type tickTiming struct {
+	count    uint64
+	last     time.Time // Time of the last cycle (zero after a pause)
+	drift    time.Duration
+	maxDrift time.Duration
+}
+
+// record counts a cycle at now, measuring its drift from rate after the last
+func (t *tickTiming) record(now time.Time, rate time.Duration) {
+	t.count++
+	if !t.last.IsZero() {
+		t.drift = now.Sub(t.last) - rate
+		if t.drift < 0 {
+			t.drift = -t.drift
+		}
+		if t.drift > t.maxDrift {
+			t.maxDrift = t.drift
+		}
+	}
+	t.last = now
+}
+
+// Metrics returns the current simulator metrics. It is safe to call while
+// Run is active.
+func (s *GPSSimulator) Metrics() Metrics {
+	s.stream.mu.Lock()
+	emitted := s.stream.emitted
+	dropped := s.stream.dropped
+	subscribers := len(s.stream.subscribers)
+	s.stream.mu.Unlock()
+
+	running := s.IsRunning()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inView := len(s.Satellites)
+	if !s.isLocked {
+		inView = len(s.acquiredSatellites())
+	}
+	tcpClients := 0
+	if s.tcpServer != nil {
+		tcpClients = s.tcpServer.clientCount()
+	}
+	trackPoints := 0
+	if s.gpxWriter != nil {
+		trackPoints = s.gpxWriter.GetTrackPointCount()
+	}
+
+	return Metrics{
+		Ticks:             s.ticks.count,
+		TickDrift:         s.ticks.drift,
+		MaxTickDrift:      s.ticks.maxDrift,
+		SentencesEmitted:  emitted,
+		SentencesDropped:  dropped,
+		TCPClients:        tcpClients,
+		StreamSubscribers: subscribers,
+		Running:           running,
+		Paused:            s.paused,
+		Locked:            s.isLocked,
+		SatellitesInView:  inView,
+		ReplayIndex:       s.replayIndex,
+		ReplayLoops:       s.replayLoops,
+		GPXTrackPoints:    trackPoints,
+	}
+}
+
+// metrics returns the current simulator metrics in Prometheus form
+func (s *GPSSimulator) metrics() []metric {
+	m := s.Metrics()
+	return []metric{
+		{"gps_simulator_ticks_total", "counter", "Output cycles run.", float64(m.Ticks)},
+		{"gps_simulator_tick_drift_seconds", "gauge", "How far the last output cycle was from the output rate after the one before.", m.TickDrift.Seconds()},
+		{"gps_simulator_max_tick_drift_seconds", "gauge", "Largest output cycle drift from the output rate so far.", m.MaxTickDrift.Seconds()},
+		{"gps_simulator_sentences_emitted_total", "counter", "NMEA sentences (or gpsd reports) emitted.", float64(m.SentencesEmitted)},
+		{"gps_simulator_sentences_dropped_total", "counter", "Sentences dropped for stream subscribers not keeping up.", float64(m.SentencesDropped)},
+		{"gps_simulator_tcp_clients", "gauge", "Clients connected to the TCP output.", float64(m.TCPClients)},
+		{"gps_simulator_stream_subscribers", "gauge", "Sentence stream subscribers.", float64(m.StreamSubscribers)},
+		{"gps_simulator_running", "gauge", "Whether the simulation is running (1) or not (0).", boolMetric(m.Running)},
+		{"gps_simulator_paused", "gauge", "Whether the simulation is paused (1) or not (0).", boolMetric(m.Paused)},
+		{"gps_simulator_locked", "gauge", "Whether the receiver has a fix (1) or not (0).", boolMetric(m.Locked)},
+		{"gps_simulator_satellites_in_view", "gauge", "Satellites reported in view.", float64(m.SatellitesInView)},
+		{"gps_simulator_replay_index", "gauge", "Index of the current replay track point.", float64(m.ReplayIndex)},
+		{"gps_simulator_replay_loops_total", "counter", "Passes completed through the replay track or playlist.", float64(m.ReplayLoops)},
+		{"gps_simulator_gpx_track_points", "gauge", "Track points recorded in the GPX output.", float64(m.GPXTrackPoints)},
+	}
+}
+
+// boolMetric converts a flag to a 0 or 1 gauge value
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// MetricsHandler returns an HTTP handler serving the simulator's metrics in
+// the Prometheus text format, for mounting at /metrics. It is safe to use
+// while Run is active.
+func (s *GPSSimulator) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		for _, m := range s.metrics() {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		fmt.Fprint(w, b.String())
+	})
+}
+
+// MetricsJSONHandler returns an HTTP handler serving the simulator's
+// Metrics as JSON, for mounting at /api/metrics. It is safe to use while
+// Run is active.
+func (s *GPSSimulator) MetricsJSONHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Metrics())
+	})
+}
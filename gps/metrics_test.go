@@ -0,0 +1,194 @@
+package gps
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Quiet = true
+	config.GPXEnabled = true
+	config.GPXFile = filepath.Join(t.TempDir(), "metrics.gpx")
+	config.Sentences = []string{"GGA", "RMC"}
+
+	sim, err := NewGPSSimulator(config, io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	_, cancel := sim.SubscribeSentences(10)
+	defer cancel()
+
+	// Three cycles of GGA and RMC
+	sim.isLocked = true
+	for i := 0; i < 3; i++ {
+		sim.tick()
+	}
+
+	server := httptest.NewServer(sim.MetricsHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Expected a text/plain content type, got %q", contentType)
+	}
+
+	for _, line := range []string{
+		"# TYPE gps_simulator_sentences_emitted_total counter",
+		"gps_simulator_sentences_emitted_total 6",
+		"gps_simulator_tcp_clients 0",
+		"gps_simulator_stream_subscribers 1",
+		"gps_simulator_running 0",
+		"gps_simulator_paused 0",
+		"gps_simulator_locked 1",
+		"gps_simulator_satellites_in_view 8",
+		"gps_simulator_replay_index 0",
+		"gps_simulator_gpx_track_points 3",
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsMatchOutput(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Quiet = true
+	sim, err := NewGPSSimulator(config, io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	var output strings.Builder
+	count, err := sim.GenerateTo(&output, 10*time.Second)
+	if err != nil {
+		t.Fatalf("GenerateTo failed: %v", err)
+	}
+
+	m := sim.Metrics()
+	if m.Ticks != 10 {
+		t.Errorf("Expected 10 ticks, got %d", m.Ticks)
+	}
+	if lines := strings.Count(output.String(), "\n"); m.SentencesEmitted != uint64(lines) || lines != count {
+		t.Errorf("Expected %d sentences emitted to match the %d written, got %d", count, lines, m.SentencesEmitted)
+	}
+	if bursts := strings.Count(output.String(), "$GPGGA"); uint64(bursts) != m.Ticks {
+		t.Errorf("Expected a GGA per tick, got %d for %d ticks", bursts, m.Ticks)
+	}
+	if m.TickDrift != 0 || m.MaxTickDrift != 0 {
+		t.Errorf("Expected no drift in virtual time, got %v (max %v)", m.TickDrift, m.MaxTickDrift)
+	}
+}
+
+func TestMetricsTickDrift(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Quiet = true
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.startTime = clock.Now()
+
+	sim.tick()
+	clock.Advance(1200 * time.Millisecond)
+	sim.tick()
+	clock.Advance(time.Second)
+	sim.tick()
+
+	m := sim.Metrics()
+	if m.Ticks != 3 {
+		t.Errorf("Expected 3 ticks, got %d", m.Ticks)
+	}
+	if m.TickDrift != 0 || m.MaxTickDrift != 200*time.Millisecond {
+		t.Errorf("Expected drift 0 (max 200ms), got %v (max %v)", m.TickDrift, m.MaxTickDrift)
+	}
+
+	// Time spent paused is not drift
+	sim.Pause()
+	clock.Advance(time.Minute)
+	sim.tick()
+	sim.Resume()
+	sim.tick()
+	clock.Advance(time.Second)
+	sim.tick()
+	if m := sim.Metrics(); m.Ticks != 5 || m.MaxTickDrift != 200*time.Millisecond {
+		t.Errorf("Expected 5 ticks with max drift 200ms, got %d ticks and %v", m.Ticks, m.MaxTickDrift)
+	}
+}
+
+func TestMetricsSentencesDropped(t *testing.T) {
+	sim := createTestSimulator()
+	_, cancel := sim.SubscribeSentences(1)
+	defer cancel()
+
+	sim.emit("$GPGGA\r\n")
+	sim.emit("$GPRMC\r\n")
+	sim.emit("$GPVTG\r\n")
+
+	if m := sim.Metrics(); m.SentencesEmitted != 3 || m.SentencesDropped != 2 {
+		t.Errorf("Expected 3 emitted and 2 dropped, got %d and %d", m.SentencesEmitted, m.SentencesDropped)
+	}
+}
+
+func TestMetricsReplayLoops(t *testing.T) {
+	points := []TrackPoint{
+		{Lat: 37.7749, Lon: -122.4194, Time: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{Lat: 37.7750, Lon: -122.4193, Time: time.Date(2024, 1, 15, 12, 0, 1, 0, time.UTC)},
+		{Lat: 37.7751, Lon: -122.4192, Time: time.Date(2024, 1, 15, 12, 0, 2, 0, time.UTC)},
+	}
+	sim := createReplaySimulator(points, false)
+	sim.Config.ReplayLoop = true
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Second)
+		sim.updateReplayPosition()
+	}
+	if loops := sim.Metrics().ReplayLoops; loops < 2 {
+		t.Errorf("Expected at least 2 replay loops after 10s of a 2s track, got %d", loops)
+	}
+}
+
+func TestMetricsJSONHandler(t *testing.T) {
+	sim := createTestSimulator()
+	sim.tick()
+
+	server := httptest.NewServer(sim.MetricsJSONHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/metrics")
+	if err != nil {
+		t.Fatalf("Failed to get metrics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected a JSON content type, got %q", contentType)
+	}
+	var m Metrics
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		t.Fatalf("Failed to decode metrics: %v", err)
+	}
+	if m.Ticks != 1 || m.SentencesEmitted == 0 || !m.Locked {
+		t.Errorf("Expected one locked tick with sentences, got %+v", m)
+	}
+}
@@ -3,10 +3,20 @@ package gps
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// NMEA output versions
+const (
+	NMEAVersion23 = "2.3"
+	NMEAVersion41 = "4.1"
+)
+
+// gnsSystems is the order of the per-constellation mode characters in GNS
+var gnsSystems = []Constellation{ConstellationGPS, ConstellationGLONASS, ConstellationGalileo, ConstellationBeiDou}
+
 // calculateChecksum calculates the NMEA checksum for a sentence
 func calculateChecksum(sentence string) string {
 	var checksum byte
@@ -18,42 +28,127 @@ func calculateChecksum(sentence string) string {
 
 // formatNMEA formats a complete NMEA sentence with checksum
 func formatNMEA(sentence string) string {
-	checksum := calculateChecksum(sentence)
-	return fmt.Sprintf("%s*%s\r\n", sentence, checksum)
+	var checksum byte
+	for i := 1; i < len(sentence); i++ { // Skip the '$' character
+		checksum ^= sentence[i]
+	}
+
+	// Built directly rather than with fmt, as this runs for every sentence
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	b.Grow(len(sentence) + 5)
+	b.WriteString(sentence)
+	b.WriteByte('*')
+	b.WriteByte(hexDigits[checksum>>4])
+	b.WriteByte(hexDigits[checksum&0x0F])
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// talkerID returns the NMEA talker ID for position sentences. An explicit
+// Config.TalkerID wins; otherwise GN is used when several constellations are
+// enabled and the single constellation's own talker (GP by default) otherwise.
+func (s *GPSSimulator) talkerID() string {
+	if s.Config.TalkerID != "" {
+		return s.Config.TalkerID
+	}
+	constellations := s.constellations()
+	if len(constellations) > 1 {
+		return MultiConstellationTalkerID
+	}
+	return constellations[0].TalkerID()
+}
+
+// isNMEA41 reports whether NMEA 4.1 output (GNS sentences and GSA/GSV system
+// and signal IDs) is enabled rather than the default 2.3 output
+func (s *GPSSimulator) isNMEA41() bool {
+	return s.Config.NMEAVersion == NMEAVersion41
+}
+
+// timeField formats the UTC time of day as HHMMSS with the decimal places of
+// seconds from timePlaces, the same in every sentence
+func (s *GPSSimulator) timeField(timestamp time.Time) string {
+	return formatNMEATime(timestamp, s.timePlaces())
+}
+
+// timePlaces returns the decimal places of seconds in time fields:
+// Config.TimePrecision, or when it is 0 whole seconds, rising to hundredths
+// for output faster than 1 Hz so successive bursts have distinct times
+func (s *GPSSimulator) timePlaces() int {
+	if s.Config.TimePrecision > 0 {
+		return s.Config.TimePrecision
+	}
+	if s.Config.OutputRate > 0 && s.Config.OutputRate < time.Second {
+		return 2
+	}
+	return 0
+}
+
+// formatNMEATime formats the UTC time of day as HHMMSS with places (0-9)
+// decimal places of seconds. The fraction is truncated and zero-padded, so it
+// reflects the emission time rather than rounding up into the next second.
+func formatNMEATime(timestamp time.Time, places int) string {
+	utcTime := timestamp.UTC()
+	timeStr := utcTime.Format("150405")
+	if places > 0 {
+		fraction := utcTime.Nanosecond() / int(math.Pow10(9-places))
+		timeStr += fmt.Sprintf(".%0*d", places, fraction)
+	}
+	return timeStr
+}
+
+// outputEpoch returns the fix time of an output cycle truncated to the finest
+// time resolution emitted, so every sentence and callback in the burst carries
+// the same time even when the cycle straddles a second boundary
+func (s *GPSSimulator) outputEpoch(now time.Time) time.Time {
+	return s.fixTime(now).Truncate(time.Duration(math.Pow10(9 - s.timePlaces())))
 }
 
 // generateGGA generates a GGA (Global Positioning System Fix Data) sentence
 func (s *GPSSimulator) generateGGA(timestamp time.Time) string {
-	timeStr := timestamp.UTC().Format("150405") // HHMMSS
+	// Quality indicator from the configured fix quality (1 = GPS fix by default)
+	quality := fmt.Sprintf("%d", s.fixQuality())
+	return s.formatGGA(timestamp, quality, len(s.Satellites), s.currentDOP().HDOP)
+}
+
+// generateEstimatedGGA generates a GGA sentence with an estimated fix
+// (quality 6) from the satellites acquired so far while converging on lock
+func (s *GPSSimulator) generateEstimatedGGA(timestamp time.Time) string {
+	acquired := s.acquiredSatellites()
+	return s.formatGGA(timestamp, "6", len(acquired), s.estimatedHDOP(acquired))
+}
+
+// formatGGA formats a GGA sentence at the current position with the given
+// quality indicator, satellite count and HDOP
+func (s *GPSSimulator) formatGGA(timestamp time.Time, quality string, satellites int, horizontalDOP float64) string {
+	timeStr := s.timeField(timestamp)
 
 	// Convert coordinates to NMEA format (DDMM.MMMMM)
-	latDeg := int(math.Abs(s.currentLat))
-	latMin := (math.Abs(s.currentLat) - float64(latDeg)) * 60
+	lat, lon := s.reportedPosition()
+	latDeg := int(math.Abs(lat))
+	latMin := (math.Abs(lat) - float64(latDeg)) * 60
 	latHem := "N"
-	if s.currentLat < 0 {
+	if lat < 0 {
 		latHem = "S"
 	}
 
-	lonDeg := int(math.Abs(s.currentLon))
-	lonMin := (math.Abs(s.currentLon) - float64(lonDeg)) * 60
+	lonDeg := int(math.Abs(lon))
+	lonMin := (math.Abs(lon) - float64(lonDeg)) * 60
 	lonHem := "E"
-	if s.currentLon < 0 {
+	if lon < 0 {
 		lonHem = "W"
 	}
 
-	// Quality indicator: 1 = GPS fix
-	quality := "1"
-	numSats := fmt.Sprintf("%02d", len(s.Satellites))
-	hdop := "1.2"                                 // Horizontal dilution of precision
+	numSats := fmt.Sprintf("%02d", satellites)
+	hdop := fmt.Sprintf("%.1f", horizontalDOP)    // Horizontal dilution of precision
 	altitude := fmt.Sprintf("%.1f", s.currentAlt) // Current altitude above mean sea level
 	altUnit := "M"
-	geoidSep := "0.0" // Geoidal separation
+	geoidSep := fmt.Sprintf("%.1f", s.geoidSeparation()) // Geoidal separation (geoid minus ellipsoid)
 	sepUnit := "M"
-	dgpsAge := "" // Age of DGPS data
-	dgpsID := ""  // DGPS station ID
+	dgpsAge, dgpsID := s.differentialFields() // Age of DGPS data and station ID
 
-	sentence := fmt.Sprintf("$GPGGA,%s,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s",
-		timeStr,
+	sentence := fmt.Sprintf("$%sGGA,%s,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s",
+		s.talkerID(), timeStr,
 		latDeg, latMin, latHem,
 		lonDeg, lonMin, lonHem,
 		quality, numSats, hdop,
@@ -66,41 +161,103 @@ func (s *GPSSimulator) generateGGA(timestamp time.Time) string {
 
 // generateNoFixGGA generates a GGA sentence when there's no GPS fix
 func (s *GPSSimulator) generateNoFixGGA(timestamp time.Time) string {
-	timeStr := timestamp.UTC().Format("150405")
+	timeStr := s.timeField(timestamp)
 
-	sentence := fmt.Sprintf("$GPGGA,%s,,,,,0,00,,,,,,,,,", timeStr)
+	numSats := len(s.acquiredSatellites()) // Satellites acquired so far
+
+	sentence := fmt.Sprintf("$%sGGA,%s,,,,,0,%02d,,,,,,,,,", s.talkerID(), timeStr, numSats)
+	return formatNMEA(sentence)
+}
+
+// generateGNS generates a GNS (GNSS Fix Data) sentence with one mode
+// character per GNSS system: GPS, GLONASS, Galileo and BeiDou
+func (s *GPSSimulator) generateGNS(timestamp time.Time) string {
+	timeStr := s.timeField(timestamp)
+
+	// Convert coordinates to NMEA format (DDMM.MMMMM)
+	lat, lon := s.reportedPosition()
+	latDeg := int(math.Abs(lat))
+	latMin := (math.Abs(lat) - float64(latDeg)) * 60
+	latHem := "N"
+	if lat < 0 {
+		latHem = "S"
+	}
+
+	lonDeg := int(math.Abs(lon))
+	lonMin := (math.Abs(lon) - float64(lonDeg)) * 60
+	lonHem := "E"
+	if lon < 0 {
+		lonHem = "W"
+	}
+
+	// Enabled systems report the fix mode, the others N = No fix
+	var modes strings.Builder
+	for _, c := range gnsSystems {
+		mode := "N"
+		if len(s.satellitesByConstellation(c)) > 0 {
+			mode = s.modeIndicator()
+		}
+		modes.WriteString(mode)
+	}
+
+	numSats := fmt.Sprintf("%02d", len(s.Satellites))
+	hdop := fmt.Sprintf("%.1f", s.currentDOP().HDOP)     // Horizontal dilution of precision
+	altitude := fmt.Sprintf("%.1f", s.currentAlt)        // Current altitude above mean sea level
+	geoidSep := fmt.Sprintf("%.1f", s.geoidSeparation()) // Geoidal separation (geoid minus ellipsoid)
+	dgpsAge, dgpsID := s.differentialFields()            // Age of differential data and reference station ID
+	navStatus := "V"                                     // Navigational status: V = Not valid for navigation safety
+
+	sentence := fmt.Sprintf("$%sGNS,%s,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s,%s,%s,%s,%s,%s",
+		s.talkerID(), timeStr,
+		latDeg, latMin, latHem,
+		lonDeg, lonMin, lonHem,
+		modes.String(), numSats, hdop,
+		altitude, geoidSep,
+		dgpsAge, dgpsID, navStatus)
+
+	return formatNMEA(sentence)
+}
+
+// generateNoFixGNS generates a GNS sentence when there's no GPS fix
+func (s *GPSSimulator) generateNoFixGNS(timestamp time.Time) string {
+	timeStr := s.timeField(timestamp)
+
+	modes := strings.Repeat("N", len(gnsSystems)) // N = No fix for every system
+	numSats := len(s.acquiredSatellites())        // Satellites acquired so far
+
+	sentence := fmt.Sprintf("$%sGNS,%s,,,,,%s,%02d,,,,,,V", s.talkerID(), timeStr, modes, numSats)
 	return formatNMEA(sentence)
 }
 
 // generateRMC generates an RMC (Recommended Minimum) sentence
 func (s *GPSSimulator) generateRMC(timestamp time.Time) string {
-	timeStr := timestamp.UTC().Format("150405") // HHMMSS
+	timeStr := s.timeField(timestamp)
 	dateStr := timestamp.UTC().Format("020106") // DDMMYY
 
 	// Convert coordinates to NMEA format
-	latDeg := int(math.Abs(s.currentLat))
-	latMin := (math.Abs(s.currentLat) - float64(latDeg)) * 60
+	lat, lon := s.reportedPosition()
+	latDeg := int(math.Abs(lat))
+	latMin := (math.Abs(lat) - float64(latDeg)) * 60
 	latHem := "N"
-	if s.currentLat < 0 {
+	if lat < 0 {
 		latHem = "S"
 	}
 
-	lonDeg := int(math.Abs(s.currentLon))
-	lonMin := (math.Abs(s.currentLon) - float64(lonDeg)) * 60
+	lonDeg := int(math.Abs(lon))
+	lonMin := (math.Abs(lon) - float64(lonDeg)) * 60
 	lonHem := "E"
-	if s.currentLon < 0 {
+	if lon < 0 {
 		lonHem = "W"
 	}
 
-	status := "A"                                  // A = Active, V = Void
-	speed := fmt.Sprintf("%.1f", s.currentSpeed)   // Speed over ground in knots (with jitter applied)
-	course := fmt.Sprintf("%.1f", s.currentCourse) // Course over ground in degrees (with jitter applied)
-	magVar := ""                                   // Magnetic variation
-	magVarDir := ""                                // Direction of magnetic variation
-	mode := "A"                                    // A = Autonomous, D = DGPS, E = DR
+	status := "A"                                // A = Active, V = Void
+	speed := fmt.Sprintf("%.1f", s.currentSpeed) // Speed over ground in knots (with jitter applied)
+	course := s.courseField()                    // Course over ground in degrees (with jitter applied)
+	magVar, magVarDir := s.magneticVariation()   // Magnetic variation and direction (E/W)
+	mode := s.modeIndicator()                    // A = Autonomous, D = DGPS, E = DR, R/F = RTK
 
-	sentence := fmt.Sprintf("$GPRMC,%s,%s,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s,%s,%s,%s",
-		timeStr, status,
+	sentence := fmt.Sprintf("$%sRMC,%s,%s,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s,%s,%s,%s",
+		s.talkerID(), timeStr, status,
 		latDeg, latMin, latHem,
 		lonDeg, lonMin, lonHem,
 		speed, course, dateStr,
@@ -111,50 +268,177 @@ func (s *GPSSimulator) generateRMC(timestamp time.Time) string {
 
 // generateNoFixRMC generates an RMC sentence when there's no GPS fix
 func (s *GPSSimulator) generateNoFixRMC(timestamp time.Time) string {
-	timeStr := timestamp.UTC().Format("150405")
+	timeStr := s.timeField(timestamp)
 	dateStr := timestamp.UTC().Format("020106")
 
-	sentence := fmt.Sprintf("$GPRMC,%s,V,,,,,,,,%s,,,N", timeStr, dateStr)
+	sentence := fmt.Sprintf("$%sRMC,%s,V,,,,,,,,%s,,,N", s.talkerID(), timeStr, dateStr)
 	return formatNMEA(sentence)
 }
 
-// generateGSA generates a GSA (GPS DOP and active satellites) sentence
-func (s *GPSSimulator) generateGSA() string {
+// generateGSA generates GSA (GPS DOP and active satellites) sentences. With
+// several constellations enabled, one GSA is emitted per constellation listing
+// only that constellation's satellites.
+func (s *GPSSimulator) generateGSA() []string {
+	if !s.isMultiConstellation() {
+		return []string{s.generateGSAGroup(s.constellations()[0], s.Satellites)}
+	}
+
+	var sentences []string
+	for _, c := range s.constellations() {
+		sats := s.satellitesByConstellation(c)
+		if len(sats) == 0 {
+			continue
+		}
+		sentences = append(sentences, s.generateGSAGroup(c, sats))
+	}
+	return sentences
+}
+
+// generateGSAGroup generates a GSA sentence listing the given satellites of
+// constellation c as active
+func (s *GPSSimulator) generateGSAGroup(c Constellation, sats []Satellite) string {
 	mode1 := "A" // A = Automatic, M = Manual
 	mode2 := "3" // 1 = No fix, 2 = 2D fix, 3 = 3D fix
 
-	// List up to 12 satellite IDs being used for fix
-	var satIDs []string
-	for i, sat := range s.Satellites {
-		if i < 12 {
-			satIDs = append(satIDs, fmt.Sprintf("%02d", sat.ID))
+	var b strings.Builder
+	b.Grow(80)
+	b.WriteString("$" + s.talkerID() + "GSA," + mode1 + "," + mode2)
+
+	// List up to 12 satellite IDs being used for fix, padded with empty
+	// fields to make 12 total
+	for i := 0; i < 12; i++ {
+		b.WriteByte(',')
+		if i < len(sats) {
+			writeZeroPadded(&b, sats[i].ID, 2)
 		}
 	}
 
-	// Pad with empty fields to make 12 total
-	for len(satIDs) < 12 {
-		satIDs = append(satIDs, "")
+	// Dilution of precision from the geometry of all satellites used in the fix
+	dop := s.currentDOP()
+	for _, value := range []float64{dop.PDOP, dop.HDOP, dop.VDOP} {
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(value, 'f', 1, 64))
+	}
+
+	// NMEA 4.1 adds the GNSS system ID of the listed satellites
+	if s.isNMEA41() {
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(c.SystemID()))
 	}
 
-	pdop := "2.1" // Position dilution of precision
-	hdop := "1.2" // Horizontal dilution of precision
-	vdop := "1.8" // Vertical dilution of precision
+	return formatNMEA(b.String())
+}
+
+// generateNoFixGSA generates a GSA sentence when there's no 3D fix, listing
+// the satellites acquired so far. Three acquired satellites report a 2D fix.
+func (s *GPSSimulator) generateNoFixGSA() string {
+	acquired := s.acquiredSatellites()
+	mode2 := "1" // 1 = No fix, 2 = 2D fix
+	if len(acquired) >= 3 {
+		mode2 = "2"
+	}
 
-	sentence := fmt.Sprintf("$GPGSA,%s,%s,%s,%s,%s,%s",
-		mode1, mode2,
-		strings.Join(satIDs, ","),
-		pdop, hdop, vdop)
+	satIDs := make([]string, 12)
+	for i, sat := range acquired {
+		if i < 12 {
+			satIDs[i] = fmt.Sprintf("%02d", sat.ID)
+		}
+	}
+
+	sentence := fmt.Sprintf("$%sGSA,A,%s,%s,,,", s.talkerID(), mode2, strings.Join(satIDs, ","))
+
+	// NMEA 4.1 adds the GNSS system ID, left empty when the acquired
+	// satellites may come from several constellations
+	if s.isNMEA41() {
+		systemID := ""
+		if !s.isMultiConstellation() {
+			systemID = fmt.Sprintf("%d", s.constellations()[0].SystemID())
+		}
+		sentence += "," + systemID
+	}
 
 	return formatNMEA(sentence)
 }
 
-// generateGSV generates GSV (GPS Satellites in view) sentences
+// generateGSV generates GSV (GPS Satellites in view) sentences. With several
+// constellations enabled, a separate GSV group is emitted per constellation
+// using that constellation's talker ID.
 func (s *GPSSimulator) generateGSV() []string {
+	return s.generateGSVFor(s.Satellites)
+}
+
+// generateGSVFor generates GSV sentences describing the given satellites,
+// reusing the previous sentences when nothing they report has changed
+func (s *GPSSimulator) generateGSVFor(sats []Satellite) []string {
+	if s.gsv.matches(s, sats) {
+		return s.gsv.sentences
+	}
+
 	var sentences []string
+	if !s.isMultiConstellation() {
+		sentences = s.generateGSVGroup(s.talkerID(), s.constellations()[0], sats)
+	} else {
+		for _, c := range s.constellations() {
+			sentences = append(sentences, s.generateGSVGroup(c.TalkerID(), c, filterConstellation(sats, c))...)
+		}
+	}
 
-	totalSats := len(s.Satellites)
+	s.gsv.store(s, sats, sentences)
+	return sentences
+}
+
+// gsvCache holds the last GSV sentences generated and everything they
+// depend on, as satellites often report the same values on successive cycles
+type gsvCache struct {
+	sats           []Satellite // Satellites reported, with SNR as reported
+	talkerID       string
+	nmeaVersion    string
+	constellations []string
+	sentences      []string
+}
+
+// matches reports whether the cached sentences describe sats as s would now
+// report them
+func (g *gsvCache) matches(s *GPSSimulator, sats []Satellite) bool {
+	if g.sentences == nil || len(g.sats) != len(sats) || g.talkerID != s.Config.TalkerID ||
+		g.nmeaVersion != s.Config.NMEAVersion || len(g.constellations) != len(s.Config.Constellations) {
+		return false
+	}
+	for i, name := range s.Config.Constellations {
+		if g.constellations[i] != name {
+			return false
+		}
+	}
+	for i, sat := range sats {
+		sat.SNR = s.signalSNR(sat)
+		if g.sats[i] != sat {
+			return false
+		}
+	}
+	return true
+}
+
+// store caches sentences generated for sats
+func (g *gsvCache) store(s *GPSSimulator, sats []Satellite, sentences []string) {
+	g.sats = g.sats[:0]
+	for _, sat := range sats {
+		sat.SNR = s.signalSNR(sat)
+		g.sats = append(g.sats, sat)
+	}
+	g.talkerID = s.Config.TalkerID
+	g.nmeaVersion = s.Config.NMEAVersion
+	g.constellations = append(g.constellations[:0], s.Config.Constellations...)
+	g.sentences = sentences
+}
+
+// generateGSVGroup generates the GSV sentences describing one group of
+// satellites from constellation c
+func (s *GPSSimulator) generateGSVGroup(talker string, c Constellation, sats []Satellite) []string {
+	totalSats := len(sats)
 	totalSentences := (totalSats + 3) / 4 // Round up to nearest 4
+	sentences := make([]string, 0, totalSentences)
 
+	var b strings.Builder
 	for sentenceNum := 1; sentenceNum <= totalSentences; sentenceNum++ {
 		startIdx := (sentenceNum - 1) * 4
 		endIdx := startIdx + 4
@@ -162,36 +446,105 @@ func (s *GPSSimulator) generateGSV() []string {
 			endIdx = totalSats
 		}
 
-		sentence := fmt.Sprintf("$GPGSV,%d,%d,%02d",
-			totalSentences, sentenceNum, totalSats)
+		b.Reset()
+		b.Grow(80)
+		b.WriteString("$" + talker + "GSV,")
+		b.WriteString(strconv.Itoa(totalSentences))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(sentenceNum))
+		b.WriteByte(',')
+		writeZeroPadded(&b, totalSats, 2)
 
 		// Add satellite data (up to 4 satellites per sentence)
 		for i := startIdx; i < endIdx; i++ {
-			sat := s.Satellites[i]
-			sentence += fmt.Sprintf(",%02d,%02d,%03d,%02d",
-				sat.ID, sat.Elevation, sat.Azimuth, sat.SNR)
+			sat := sats[i]
+			b.WriteByte(',')
+			writeZeroPadded(&b, sat.ID, 2)
+			b.WriteByte(',')
+			writeZeroPadded(&b, sat.Elevation, 2)
+			b.WriteByte(',')
+			writeZeroPadded(&b, sat.Azimuth, 3)
+			b.WriteByte(',')
+			writeZeroPadded(&b, s.signalSNR(sat), 2)
 		}
 
 		// Pad with empty fields if less than 4 satellites in this sentence
 		fieldsToAdd := 4 - (endIdx - startIdx)
 		for i := 0; i < fieldsToAdd; i++ {
-			sentence += ",,,,"
+			b.WriteString(",,,,")
 		}
 
-		sentences = append(sentences, formatNMEA(sentence))
+		// NMEA 4.1 adds the signal ID of the reported signal strengths
+		if s.isNMEA41() {
+			b.WriteByte(',')
+			b.WriteString(strconv.Itoa(c.SignalID()))
+		}
+
+		sentences = append(sentences, formatNMEA(b.String()))
 	}
 
 	return sentences
 }
 
+// writeZeroPadded writes n zero-padded to at least width digits, like %0*d
+func writeZeroPadded(b *strings.Builder, n, width int) {
+	digits := strconv.Itoa(n)
+	if n < 0 {
+		b.WriteByte('-')
+		digits = digits[1:]
+		width--
+	}
+	for i := len(digits); i < width; i++ {
+		b.WriteByte('0')
+	}
+	b.WriteString(digits)
+}
+
+// courseValid reports whether the receiver is moving fast enough for its
+// course to be meaningful, per Config.MinSpeedForCourse
+func (s *GPSSimulator) courseValid() bool {
+	return s.Config.MinSpeedForCourse <= 0 || s.currentSpeed >= s.Config.MinSpeedForCourse
+}
+
+// courseField formats the course over ground for RMC and VTG, empty while
+// the course is not valid
+func (s *GPSSimulator) courseField() string {
+	if !s.courseValid() {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", s.currentCourse)
+}
+
+// magneticVariationEnabled reports whether magnetic variation fields are
+// populated. A zero declination leaves them empty unless explicitly requested.
+func (s *GPSSimulator) magneticVariationEnabled() bool {
+	return s.Config.MagneticDeclination != 0 || s.Config.EmitMagneticVariation
+}
+
+// magneticVariation returns the RMC magnetic variation value and E/W
+// direction, or empty fields when magnetic variation is not emitted
+func (s *GPSSimulator) magneticVariation() (string, string) {
+	if !s.magneticVariationEnabled() {
+		return "", ""
+	}
+	direction := "E"
+	if s.Config.MagneticDeclination < 0 {
+		direction = "W"
+	}
+	return fmt.Sprintf("%.1f", math.Abs(s.Config.MagneticDeclination)), direction
+}
+
 // generateVTG generates a VTG (Track Made Good and Ground Speed) sentence
 func (s *GPSSimulator) generateVTG() string {
 	// Course over ground (true)
-	courseTrue := fmt.Sprintf("%.1f", s.currentCourse)
+	courseTrue := s.courseField()
 	courseTrueRef := "T" // T = True
 
-	// Course over ground (magnetic) - we'll leave this empty as we don't simulate magnetic variation
+	// Course over ground (magnetic), empty unless magnetic variation is emitted
 	courseMagnetic := ""
+	if s.magneticVariationEnabled() && s.courseValid() {
+		courseMagnetic = fmt.Sprintf("%.1f", math.Mod(s.currentCourse-s.Config.MagneticDeclination+360, 360))
+	}
 	courseMagneticRef := "M" // M = Magnetic
 
 	// Speed over ground in knots
@@ -203,10 +556,10 @@ func (s *GPSSimulator) generateVTG() string {
 	speedKmh := fmt.Sprintf("%.1f", s.currentSpeed*1.852)
 	speedKmhUnit := "K" // K = Kilometers per hour
 
-	mode := "A" // A = Autonomous, D = DGPS, E = DR
+	mode := s.modeIndicator() // A = Autonomous, D = DGPS, E = DR, R/F = RTK
 
-	sentence := fmt.Sprintf("$GPVTG,%s,%s,%s,%s,%s,%s,%s,%s,%s",
-		courseTrue, courseTrueRef,
+	sentence := fmt.Sprintf("$%sVTG,%s,%s,%s,%s,%s,%s,%s,%s,%s",
+		s.talkerID(), courseTrue, courseTrueRef,
 		courseMagnetic, courseMagneticRef,
 		speedKnots, speedKnotsUnit,
 		speedKmh, speedKmhUnit,
@@ -217,36 +570,35 @@ func (s *GPSSimulator) generateVTG() string {
 
 // generateNoFixVTG generates a VTG sentence when there's no GPS fix
 func (s *GPSSimulator) generateNoFixVTG() string {
-	sentence := "$GPVTG,,,,,,,,,N" // N = Not valid
+	sentence := fmt.Sprintf("$%sVTG,,,,,,,,,N", s.talkerID()) // N = Not valid
 	return formatNMEA(sentence)
 }
 
 // generateGLL generates a GLL (Geographic Position - Latitude/Longitude) sentence
 func (s *GPSSimulator) generateGLL(timestamp time.Time) string {
-	utcTime := timestamp.UTC()
-	timeStr := fmt.Sprintf("%02d%02d%02d.%02d",
-		utcTime.Hour(), utcTime.Minute(), utcTime.Second(), utcTime.Nanosecond()/10000000) // HHMMSS.SS
+	timeStr := s.timeField(timestamp)
 
 	// Convert coordinates to NMEA format (DDMM.MMMMM)
-	latDeg := int(math.Abs(s.currentLat))
-	latMin := (math.Abs(s.currentLat) - float64(latDeg)) * 60
+	lat, lon := s.reportedPosition()
+	latDeg := int(math.Abs(lat))
+	latMin := (math.Abs(lat) - float64(latDeg)) * 60
 	latHem := "N"
-	if s.currentLat < 0 {
+	if lat < 0 {
 		latHem = "S"
 	}
 
-	lonDeg := int(math.Abs(s.currentLon))
-	lonMin := (math.Abs(s.currentLon) - float64(lonDeg)) * 60
+	lonDeg := int(math.Abs(lon))
+	lonMin := (math.Abs(lon) - float64(lonDeg)) * 60
 	lonHem := "E"
-	if s.currentLon < 0 {
+	if lon < 0 {
 		lonHem = "W"
 	}
 
-	status := "A" // A = Data valid, V = Data invalid
-	mode := "A"   // A = Autonomous, D = DGPS, E = DR
+	status := "A"             // A = Data valid, V = Data invalid
+	mode := s.modeIndicator() // A = Autonomous, D = DGPS, E = DR, R/F = RTK
 
-	sentence := fmt.Sprintf("$GPGLL,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s",
-		latDeg, latMin, latHem,
+	sentence := fmt.Sprintf("$%sGLL,%02d%07.4f,%s,%03d%07.4f,%s,%s,%s,%s",
+		s.talkerID(), latDeg, latMin, latHem,
 		lonDeg, lonMin, lonHem,
 		timeStr, status, mode)
 
@@ -255,11 +607,9 @@ func (s *GPSSimulator) generateGLL(timestamp time.Time) string {
 
 // generateNoFixGLL generates a GLL sentence when there's no GPS fix
 func (s *GPSSimulator) generateNoFixGLL(timestamp time.Time) string {
-	utcTime := timestamp.UTC()
-	timeStr := fmt.Sprintf("%02d%02d%02d.%02d",
-		utcTime.Hour(), utcTime.Minute(), utcTime.Second(), utcTime.Nanosecond()/10000000) // HHMMSS.SS
+	timeStr := s.timeField(timestamp)
 
-	sentence := fmt.Sprintf("$GPGLL,,,,,%s,V,N", timeStr) // V = Invalid, N = Not valid
+	sentence := fmt.Sprintf("$%sGLL,,,,,%s,V,N", s.talkerID(), timeStr) // V = Invalid, N = Not valid
 	return formatNMEA(sentence)
 }
 
@@ -267,8 +617,7 @@ func (s *GPSSimulator) generateNoFixGLL(timestamp time.Time) string {
 func (s *GPSSimulator) generateZDA(timestamp time.Time) string {
 	utcTime := timestamp.UTC()
 
-	timeStr := fmt.Sprintf("%02d%02d%02d.%02d",
-		utcTime.Hour(), utcTime.Minute(), utcTime.Second(), utcTime.Nanosecond()/10000000) // HHMMSS.SS
+	timeStr := s.timeField(timestamp)
 	day := fmt.Sprintf("%02d", utcTime.Day())
 	month := fmt.Sprintf("%02d", utcTime.Month())
 	year := fmt.Sprintf("%04d", utcTime.Year())
@@ -277,8 +626,75 @@ func (s *GPSSimulator) generateZDA(timestamp time.Time) string {
 	localZoneHours := "00"
 	localZoneMinutes := "00"
 
-	sentence := fmt.Sprintf("$GPZDA,%s,%s,%s,%s,%s,%s",
-		timeStr, day, month, year, localZoneHours, localZoneMinutes)
+	sentence := fmt.Sprintf("$%sZDA,%s,%s,%s,%s,%s,%s",
+		s.talkerID(), timeStr, day, month, year, localZoneHours, localZoneMinutes)
+
+	return formatNMEA(sentence)
+}
+
+// positionErrorStats holds the simulated position error statistics reported by GST
+type positionErrorStats struct {
+	RMS         float64 // RMS value of the pseudorange residuals (meters)
+	SemiMajor   float64 // Standard deviation of the error ellipse semi-major axis (meters)
+	SemiMinor   float64 // Standard deviation of the error ellipse semi-minor axis (meters)
+	Orientation float64 // Orientation of the semi-major axis (degrees from true north)
+	LatSigma    float64 // Standard deviation of latitude error (meters)
+	LonSigma    float64 // Standard deviation of longitude error (meters)
+	AltSigma    float64 // Standard deviation of altitude error (meters)
+}
+
+// calculateErrorStats derives position error statistics from the jitter and
+// altitude jitter settings and the number of satellites in view, or from the
+// fix quality for DGPS and RTK fixes. The result is deterministic so that the
+// same configuration always reports the same statistics.
+func (s *GPSSimulator) calculateErrorStats() positionErrorStats {
+	// Range error grows from sub-meter (no jitter) to ~10m (full jitter),
+	// matching the jitter distances applied in updatePosition
+	rangeError := 0.5 + 9.5*s.Config.Jitter
+	altitudeError := 10.0 * s.Config.AltitudeJitter
+
+	// Differential corrections bring errors down to the fix quality's
+	// precision, centimeters for RTK
+	if s.isDifferential() {
+		rangeError = fixQualityNoise[s.fixQuality()]
+		altitudeError = 0
+	}
+
+	// Fewer satellites means weaker geometry and larger position errors
+	geometry := 1.0
+	if numSats := len(s.Satellites); numSats > 0 {
+		geometry = math.Sqrt(8.0 / float64(numSats))
+	}
+
+	horizontal := rangeError * geometry
+
+	// Vertical error is weaker than horizontal and grows with altitude jitter,
+	// matching the altitude changes applied in updateAltitude
+	vertical := (1.5*rangeError + altitudeError) * geometry
+
+	return positionErrorStats{
+		RMS:         rangeError,
+		SemiMajor:   horizontal,
+		SemiMinor:   horizontal * 0.8,
+		Orientation: 0.0,
+		LatSigma:    horizontal,
+		LonSigma:    horizontal * 0.8,
+		AltSigma:    vertical,
+	}
+}
+
+// generateGST generates a GST (GNSS Pseudorange Error Statistics) sentence
+func (s *GPSSimulator) generateGST(timestamp time.Time) string {
+	timeStr := s.timeField(timestamp)
+
+	stats := s.calculateErrorStats()
+	places := s.errorPlaces() // Centimeter-level RTK errors need millimeter resolution
+
+	sentence := fmt.Sprintf("$%sGST,%s,%.*f,%.*f,%.*f,%.1f,%.*f,%.*f,%.*f",
+		s.talkerID(), timeStr,
+		places, stats.RMS,
+		places, stats.SemiMajor, places, stats.SemiMinor, stats.Orientation,
+		places, stats.LatSigma, places, stats.LonSigma, places, stats.AltSigma)
 
 	return formatNMEA(sentence)
 }
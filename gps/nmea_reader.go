@@ -0,0 +1,418 @@
+package gps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmeaEpoch is one output cycle of a recorded NMEA log: the sentences that
+// share a timestamp and the fix they report
+type nmeaEpoch struct {
+	offset    time.Duration // Time since the log's first epoch
+	sentences []string      // Sentences as recorded, without line endings
+	fix       bool          // Whether GGA or RMC reported a fix status
+	locked    bool          // Whether the reported fix was valid
+	lat       float64       // Decimal degrees (valid when locked)
+	lon       float64       // Decimal degrees (valid when locked)
+	alt       float64       // Meters above mean sea level (from GGA)
+	hasAlt    bool
+	speed     float64 // Knots (from RMC)
+	course    float64 // Degrees from true north (from RMC)
+	hasMotion bool
+}
+
+// NMEASummary describes what was loaded from a recorded NMEA log
+type NMEASummary struct {
+	Sentences int           // Sentences loaded, including any passed through with a bad checksum
+	Epochs    int           // Distinct timestamps the sentences were grouped by
+	Duration  time.Duration // Time from the first epoch to the last
+	Skipped   int           // Lines dropped: non-sentences, and sentences with a missing or bad checksum when strict
+	Corrupt   int           // Sentences with a missing or bad checksum passed through unchanged when not strict
+}
+
+// String returns a one-line description of the summary
+func (s NMEASummary) String() string {
+	return fmt.Sprintf("%d sentences in %d epochs over %v (%d skipped, %d passed through with bad checksums)",
+		s.Sentences, s.Epochs, s.Duration, s.Skipped, s.Corrupt)
+}
+
+// ReadNMEAFileSummary reads a recorded NMEA log and describes what a replay
+// of it would load, dropping sentences with a bad checksum when strict
+func ReadNMEAFileSummary(filename string, strict bool) (NMEASummary, error) {
+	_, summary, err := readNMEAFile(filename, strict)
+	return summary, err
+}
+
+// readNMEAFile reads a recorded NMEA log, one sentence per line, grouping
+// the sentences into epochs by the timestamps in RMC, GGA, GNS, GLL, ZDA and
+// GST. Sentences without a timestamp join the epoch they follow. Each
+// sentence's checksum is verified: when strict, sentences with a missing or
+// bad checksum are dropped and counted, and otherwise they join the current
+// epoch to be replayed unchanged, without affecting its time or fix. Lines
+// that are not sentences at all are always dropped.
+func readNMEAFile(filename string, strict bool) ([]nmeaEpoch, NMEASummary, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, NMEASummary{}, err
+	}
+	defer file.Close()
+
+	var epochs []nmeaEpoch
+	var summary NMEASummary
+	var current nmeaEpoch
+	var currentTime time.Duration
+	timed := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields, ok := nmeaSentenceFields(line)
+		if !ok {
+			if strict || !strings.HasPrefix(line, "$") {
+				summary.Skipped++
+				continue
+			}
+			current.sentences = append(current.sentences, line)
+			summary.Sentences++
+			summary.Corrupt++
+			continue
+		}
+
+		// A new timestamp starts the next epoch
+		if timeOfDay, ok := nmeaSentenceTime(fields); ok {
+			if timed && timeOfDay != currentTime {
+				epochs = append(epochs, current)
+				current = nmeaEpoch{offset: current.offset + nmeaTimeStep(currentTime, timeOfDay)}
+			}
+			currentTime = timeOfDay
+			timed = true
+		}
+
+		current.sentences = append(current.sentences, line)
+		current.applyFix(fields)
+		summary.Sentences++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NMEASummary{}, err
+	}
+	if !timed {
+		return nil, NMEASummary{}, fmt.Errorf("no timestamped RMC, GGA, GNS, GLL, ZDA or GST sentences in %s", filename)
+	}
+	epochs = append(epochs, current)
+
+	summary.Epochs = len(epochs)
+	summary.Duration = epochs[len(epochs)-1].offset
+	return epochs, summary, nil
+}
+
+// nmeaSentenceFields splits a sentence into its comma-separated fields,
+// starting with the address (e.g. GPGGA), reporting false when the line is
+// not a sentence or its checksum does not match
+func nmeaSentenceFields(line string) ([]string, bool) {
+	star := strings.LastIndexByte(line, '*')
+	if !strings.HasPrefix(line, "$") || star < 0 || len(line)-star != 3 {
+		return nil, false
+	}
+	if !strings.EqualFold(line[star+1:], calculateChecksum(line[:star])) {
+		return nil, false
+	}
+	return strings.Split(line[1:star], ","), true
+}
+
+// nmeaSentenceType returns the sentence type of an address without its
+// two-letter talker ID (e.g. GGA for GPGGA)
+func nmeaSentenceType(fields []string) string {
+	if len(fields[0]) < 5 {
+		return ""
+	}
+	return fields[0][2:]
+}
+
+// nmeaTimeField returns the index of the UTC time field in a sentence type,
+// or -1 when the type carries no time
+func nmeaTimeField(sentenceType string) int {
+	switch sentenceType {
+	case "RMC", "GGA", "GNS", "ZDA", "GST":
+		return 1
+	case "GLL":
+		return 5
+	}
+	return -1
+}
+
+// nmeaSentenceTime returns the time of day in a sentence's hhmmss.ss field
+func nmeaSentenceTime(fields []string) (time.Duration, bool) {
+	index := nmeaTimeField(nmeaSentenceType(fields))
+	if index < 0 || index >= len(fields) {
+		return 0, false
+	}
+	return parseNMEATime(fields[index])
+}
+
+// parseNMEATime parses an hhmmss or hhmmss.ss time field into a time of day
+func parseNMEATime(field string) (time.Duration, bool) {
+	if len(field) < 6 {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(field[0:2])
+	minutes, err2 := strconv.Atoi(field[2:4])
+	seconds, err3 := strconv.ParseFloat(field[4:], 64)
+	if err1 != nil || err2 != nil || err3 != nil || hours > 23 || minutes > 59 || seconds >= 61 {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), true
+}
+
+// nmeaTimeStep returns the time from one epoch's time of day to the next,
+// wrapping past midnight. A time that goes backwards plays immediately.
+func nmeaTimeStep(from, to time.Duration) time.Duration {
+	step := to - from
+	if step < -12*time.Hour {
+		step += 24 * time.Hour
+	}
+	if step < 0 {
+		return 0
+	}
+	return step
+}
+
+// parseNMEALatLon converts a ddmm.mmmm or dddmm.mmmm value and its
+// hemisphere to decimal degrees
+func parseNMEALatLon(value, hemisphere string) (float64, bool) {
+	dot := strings.IndexByte(value, '.')
+	if dot < 0 {
+		dot = len(value)
+	}
+	if dot < 3 {
+		return 0, false
+	}
+	degrees, err1 := strconv.ParseFloat(value[:dot-2], 64)
+	minutes, err2 := strconv.ParseFloat(value[dot-2:], 64)
+	if err1 != nil || err2 != nil || minutes >= 60 {
+		return 0, false
+	}
+	coordinate := degrees + minutes/60
+	switch hemisphere {
+	case "N", "E":
+	case "S", "W":
+		coordinate = -coordinate
+	default:
+		return 0, false
+	}
+	return coordinate, true
+}
+
+// applyFix records the fix reported by a GGA or RMC sentence
+func (e *nmeaEpoch) applyFix(fields []string) {
+	switch nmeaSentenceType(fields) {
+	case "GGA":
+		if len(fields) < 10 {
+			return
+		}
+		e.fix = true
+		e.locked = fields[6] != "" && fields[6] != "0" && e.setPosition(fields[2:6])
+		if alt, err := strconv.ParseFloat(fields[9], 64); err == nil && e.locked {
+			e.alt = alt
+			e.hasAlt = true
+		}
+	case "RMC":
+		if len(fields) < 9 {
+			return
+		}
+		e.fix = true
+		e.locked = fields[2] == "A" && e.setPosition(fields[3:7])
+		speed, err1 := strconv.ParseFloat(fields[7], 64)
+		course, err2 := strconv.ParseFloat(fields[8], 64)
+		if err1 == nil && e.locked {
+			e.speed = speed
+			e.hasMotion = true
+			if err2 == nil {
+				e.course = course
+			}
+		}
+	}
+}
+
+// setPosition parses latitude, N/S, longitude, E/W fields into the epoch,
+// reporting false when they are empty or malformed
+func (e *nmeaEpoch) setPosition(fields []string) bool {
+	lat, ok1 := parseNMEALatLon(fields[0], fields[1])
+	lon, ok2 := parseNMEALatLon(fields[2], fields[3])
+	if !ok1 || !ok2 || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return false
+	}
+	e.lat = lat
+	e.lon = lon
+	return true
+}
+
+// rewriteNMEATime replaces the time (and date) fields of a recorded
+// sentence with t, keeping the recorded number of decimal places, and
+// recalculates the checksum. Sentences without a time are returned as
+// recorded. The result ends with CRLF.
+func rewriteNMEATime(sentence string, t time.Time) string {
+	fields, ok := nmeaSentenceFields(sentence)
+	if !ok {
+		return sentence + "\r\n"
+	}
+	sentenceType := nmeaSentenceType(fields)
+	index := nmeaTimeField(sentenceType)
+	if index < 0 || index >= len(fields) || fields[index] == "" {
+		return sentence + "\r\n"
+	}
+
+	t = t.UTC()
+	fields[index] = formatNMEATimeLike(fields[index], t)
+	switch sentenceType {
+	case "RMC":
+		if len(fields) > 9 && fields[9] != "" {
+			fields[9] = t.Format("020106")
+		}
+	case "ZDA":
+		if len(fields) > 4 {
+			fields[2] = fmt.Sprintf("%02d", t.Day())
+			fields[3] = fmt.Sprintf("%02d", int(t.Month()))
+			fields[4] = fmt.Sprintf("%04d", t.Year())
+		}
+	}
+	return formatNMEA("$" + strings.Join(fields, ","))
+}
+
+// formatNMEATimeLike formats t as hhmmss with as many decimal places of
+// seconds as the recorded field
+func formatNMEATimeLike(recorded string, t time.Time) string {
+	places := 0
+	if dot := strings.IndexByte(recorded, '.'); dot >= 0 {
+		places = len(recorded) - dot - 1
+	}
+	if places > 9 {
+		return formatNMEATime(t, 9) + strings.Repeat("0", places-9)
+	}
+	return formatNMEATime(t, places)
+}
+
+// isNMEAReplay reports whether the simulator replays a recorded NMEA log
+func (s *GPSSimulator) isNMEAReplay() bool {
+	return s.Config.ReplayNMEAFile != ""
+}
+
+// updateNMEAReplay queues the sentences of every epoch due by now, paced by
+// the log's timestamps at ReplaySpeed or one epoch per output cycle with
+// ReplayNMEAFixedRate, and takes the receiver's fix from them. A looping
+// replay starts again one epoch interval after the last.
+func (s *GPSSimulator) updateNMEAReplay(now time.Time) {
+	if len(s.nmeaEpochs) == 0 {
+		return
+	}
+	speed := s.Config.ReplaySpeed
+	if speed <= 0 || s.Config.ReplayNMEAFixedRate {
+		speed = 1.0
+	}
+
+	for {
+		if s.replayIndex >= len(s.nmeaEpochs) {
+			if !s.replayCompleted || s.Config.ReplayLoop {
+				s.replayLoops++
+			}
+			s.replayCompleted = true
+			if !s.Config.ReplayLoop {
+				return
+			}
+			s.replayStartTime = s.replayStartTime.Add(time.Duration(float64(s.nmeaLoopPeriod()) / speed))
+			s.replayIndex = 0
+		}
+
+		epoch := s.nmeaEpochs[s.replayIndex]
+		elapsed := time.Duration(float64(now.Sub(s.replayStartTime)) * speed)
+		if s.nmeaEpochOffset(s.replayIndex) > elapsed {
+			return
+		}
+		s.nmeaPending = append(s.nmeaPending, epoch.sentences...)
+		s.applyNMEAEpoch(epoch)
+		s.replayIndex++
+	}
+}
+
+// nmeaEpochOffset returns when the epoch at index is due after the replay
+// starts: its recorded offset, or one output cycle per epoch with
+// ReplayNMEAFixedRate
+func (s *GPSSimulator) nmeaEpochOffset(index int) time.Duration {
+	if s.Config.ReplayNMEAFixedRate {
+		return time.Duration(index) * s.Config.OutputRate
+	}
+	return s.nmeaEpochs[index].offset
+}
+
+// nmeaLoopPeriod returns the time from the start of the log to the start of
+// its next pass: its duration plus the mean interval between epochs, or one
+// second when that is unknown
+func (s *GPSSimulator) nmeaLoopPeriod() time.Duration {
+	if s.Config.ReplayNMEAFixedRate {
+		return time.Duration(len(s.nmeaEpochs)) * s.Config.OutputRate
+	}
+	last := s.nmeaEpochs[len(s.nmeaEpochs)-1].offset
+	interval := time.Second
+	if len(s.nmeaEpochs) > 1 && last > 0 {
+		interval = last / time.Duration(len(s.nmeaEpochs)-1)
+	}
+	return last + interval
+}
+
+// applyNMEAEpoch takes the receiver's lock, position and motion from a
+// replayed epoch so snapshots, callbacks and GPX output follow the log
+func (s *GPSSimulator) applyNMEAEpoch(epoch nmeaEpoch) {
+	if !epoch.fix {
+		return
+	}
+	s.isLocked = epoch.locked
+	if !epoch.locked {
+		return
+	}
+	s.currentLat = epoch.lat
+	s.currentLon = epoch.lon
+	if epoch.hasAlt {
+		s.currentAlt = epoch.alt
+	}
+	if epoch.hasMotion {
+		s.currentSpeed = epoch.speed
+		s.currentCourse = epoch.course
+	}
+}
+
+// replayedSentenceEnabled reports whether a replayed sentence passes
+// Config.Sentences. Every sentence in the log passes when it is empty.
+func (s *GPSSimulator) replayedSentenceEnabled(sentence string) bool {
+	if len(s.Config.Sentences) == 0 {
+		return true
+	}
+	fields, ok := nmeaSentenceFields(sentence)
+	return ok && s.sentenceEnabled(nmeaSentenceType(fields))
+}
+
+// outputNMEAReplay emits the replayed sentences queued since the last
+// output cycle that pass Config.Sentences, with their times rewritten to
+// now and checksums recalculated when ReplayRewriteTime is set. Sentences
+// passed through with a bad checksum are never rewritten.
+func (s *GPSSimulator) outputNMEAReplay() {
+	timestamp := s.fixTime(s.now())
+	for _, sentence := range s.nmeaPending {
+		if !s.replayedSentenceEnabled(sentence) {
+			continue
+		}
+		if s.Config.ReplayRewriteTime {
+			s.emit(rewriteNMEATime(sentence, timestamp))
+		} else {
+			s.emit(sentence + "\r\n")
+		}
+	}
+	s.nmeaPending = nil
+	s.notifyCallbacks(timestamp)
+}
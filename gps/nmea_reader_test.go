@@ -0,0 +1,367 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const nmeaTestLog = "testdata/drive.nmea"
+
+// nmeaTestLines returns the sentences of the test log with valid checksums,
+// in order
+func nmeaTestLines(t *testing.T) []string {
+	t.Helper()
+	content, err := os.ReadFile(nmeaTestLog)
+	if err != nil {
+		t.Fatalf("Failed to read test log: %v", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(content), "\r\n") {
+		if _, ok := nmeaSentenceFields(line); ok {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// createNMEAReplaySimulator returns a simulator strictly replaying the test
+// log on a fake clock, with the replay starting now
+func createNMEAReplaySimulator(t *testing.T, configure func(*Config)) (*GPSSimulator, *bytes.Buffer, *fakeClock) {
+	t.Helper()
+	config := createTestConfig()
+	config.Quiet = true
+	config.ReplaySpeed = 1.0
+	config.ReplayNMEAFile = nmeaTestLog
+	config.NMEAStrict = true
+	if configure != nil {
+		configure(&config)
+	}
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	t.Cleanup(sim.Close)
+
+	clock := newFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.replayStartTime = clock.Now()
+	return sim, buffer, clock
+}
+
+func TestReadNMEAFile(t *testing.T) {
+	epochs, summary, err := readNMEAFile(nmeaTestLog, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := NMEASummary{Sentences: 13, Epochs: 4, Duration: 3 * time.Second, Skipped: 2}
+	if summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, summary)
+	}
+
+	// Offsets continue across midnight; untimed sentences join their epoch
+	sentenceCounts := []int{4, 2, 3, 4}
+	for i, epoch := range epochs {
+		if epoch.offset != time.Duration(i)*time.Second {
+			t.Errorf("Epoch %d: expected offset %v, got %v", i, time.Duration(i)*time.Second, epoch.offset)
+		}
+		if len(epoch.sentences) != sentenceCounts[i] {
+			t.Errorf("Epoch %d: expected %d sentences, got %d", i, sentenceCounts[i], len(epoch.sentences))
+		}
+		if !epoch.locked || !epoch.hasAlt {
+			t.Errorf("Epoch %d: expected a locked fix with altitude", i)
+		}
+		// The second epoch's RMC has a bad checksum
+		if epoch.hasMotion != (i != 1) {
+			t.Errorf("Epoch %d: expected motion only from a valid RMC", i)
+		}
+	}
+
+	first := epochs[0]
+	if math.Abs(first.lat-(37+46.4940/60)) > 1e-9 || math.Abs(first.lon-(-122-25.1640/60)) > 1e-9 {
+		t.Errorf("Expected first position 37.774900, -122.419400, got %f, %f", first.lat, first.lon)
+	}
+	if first.alt != 45.0 || first.speed != 11.7 {
+		t.Errorf("Expected altitude 45.0 and speed 11.7, got %f and %f", first.alt, first.speed)
+	}
+}
+
+func TestReadNMEAFilePassesThroughCorrupt(t *testing.T) {
+	epochs, summary, err := readNMEAFile(nmeaTestLog, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The garbage line is still dropped, but the corrupt RMC is kept
+	expected := NMEASummary{Sentences: 14, Epochs: 4, Duration: 3 * time.Second, Skipped: 1, Corrupt: 1}
+	if summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, summary)
+	}
+	if len(epochs[1].sentences) != 3 || epochs[1].hasMotion {
+		t.Errorf("Expected the corrupt RMC in the second epoch without its motion, got %d sentences", len(epochs[1].sentences))
+	}
+}
+
+func TestReadNMEAFileWithoutTimestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "untimed.nmea")
+	if err := os.WriteFile(path, []byte(formatNMEA("$GPVTG,0.0,T,,M,11.7,N,21.7,K,A")), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	if _, _, err := readNMEAFile(path, true); err == nil {
+		t.Error("Expected error for a log without timestamps")
+	}
+
+	// GST times alone are enough to pace the log
+	gst := formatNMEA("$GPGST,120000.00,1.2,0.9,0.6,45.0,0.9,0.6,1.5") + formatNMEA("$GPGST,120001.00,1.2,0.9,0.6,45.0,0.9,0.6,1.5")
+	if err := os.WriteFile(path, []byte(gst), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	if epochs, _, err := readNMEAFile(path, true); err != nil || len(epochs) != 2 {
+		t.Errorf("Expected two epochs timed by GST, got %d (%v)", len(epochs), err)
+	}
+	if _, _, err := readNMEAFile(filepath.Join(t.TempDir(), "missing.nmea"), true); err == nil {
+		t.Error("Expected error for a missing log")
+	}
+}
+
+func TestNMEAReplayPacing(t *testing.T) {
+	sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+		config.ReplaySpeed = 2.0
+	})
+	lines := nmeaTestLines(t)
+
+	// Half a second at 2x reaches the second epoch
+	clock.Advance(500 * time.Millisecond)
+	sim.tick()
+	if expected := strings.Join(lines[:6], "\r\n") + "\r\n"; buffer.String() != expected {
+		t.Errorf("Expected the first two epochs verbatim:\n%q\ngot:\n%q", expected, buffer.String())
+	}
+	if !sim.isLocked || math.Abs(sim.currentLat-(37+46.5/60)) > 1e-9 {
+		t.Errorf("Expected a fix at the second epoch, got locked %v lat %f", sim.isLocked, sim.currentLat)
+	}
+
+	// Nothing more is due until the next epoch
+	buffer.Reset()
+	clock.Advance(100 * time.Millisecond)
+	sim.tick()
+	if buffer.Len() != 0 {
+		t.Errorf("Expected no output before the next epoch, got %q", buffer.String())
+	}
+
+	clock.Advance(time.Second)
+	sim.tick()
+	if expected := strings.Join(lines[6:], "\r\n") + "\r\n"; buffer.String() != expected {
+		t.Errorf("Expected the remaining epochs verbatim:\n%q\ngot:\n%q", expected, buffer.String())
+	}
+	if message, done := sim.completion(); !done || message != "NMEA replay completed" {
+		t.Errorf("Expected the replay to complete, got %q, %v", message, done)
+	}
+}
+
+func TestNMEAReplayFixedRate(t *testing.T) {
+	sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+		config.ReplayNMEAFixedRate = true
+		config.ReplaySpeed = 4.0
+		config.OutputRate = 250 * time.Millisecond
+	})
+	lines := nmeaTestLines(t)
+
+	// One epoch per output cycle, whatever the recorded times and speed
+	sentenceCounts := []int{4, 2, 3, 4}
+	emitted := 0
+	for i, count := range sentenceCounts {
+		buffer.Reset()
+		sim.tick()
+		clock.Advance(250 * time.Millisecond)
+
+		expected := strings.Join(lines[emitted:emitted+count], "\r\n") + "\r\n"
+		if buffer.String() != expected {
+			t.Errorf("Cycle %d: expected epoch %d verbatim:\n%q\ngot:\n%q", i, i, expected, buffer.String())
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n") {
+			if _, ok := nmeaSentenceFields(line); !ok {
+				t.Errorf("Cycle %d: expected a valid checksum, got %q", i, line)
+			}
+		}
+		emitted += count
+	}
+	if message, done := sim.completion(); !done || message != "NMEA replay completed" {
+		t.Errorf("Expected the replay to complete, got %q, %v", message, done)
+	}
+}
+
+func TestNMEAReplayCorruptSentence(t *testing.T) {
+	corrupt := "$GPRMC,235959.00,A,3746.5000,N,12225.1640,W,11.7,0.0,311224,,,A*00"
+
+	tests := []struct {
+		name   string
+		strict bool
+	}{
+		{"Strict", true},
+		{"Lenient", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+				config.NMEAStrict = tt.strict
+			})
+			clock.Advance(3 * time.Second)
+			sim.tick()
+
+			if contains := strings.Contains(buffer.String(), corrupt+"\r\n"); contains == tt.strict {
+				t.Errorf("Expected the corrupt sentence emitted %v, got output:\n%q", !tt.strict, buffer.String())
+			}
+			expectedDropped := 1
+			if tt.strict {
+				expectedDropped = 2
+			}
+			if dropped := sim.Snapshot().NMEADropped; dropped != expectedDropped {
+				t.Errorf("Expected %d dropped lines, got %d", expectedDropped, dropped)
+			}
+		})
+	}
+}
+
+func TestNMEAReplayRewriteKeepsCorrupt(t *testing.T) {
+	sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+		config.NMEAStrict = false
+		config.ReplayRewriteTime = true
+	})
+	clock.Advance(3 * time.Second)
+	sim.tick()
+
+	// Rewritten sentences get a recalculated checksum; the corrupt one is
+	// passed through untouched rather than repaired
+	corrupt := 0
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n") {
+		if _, ok := nmeaSentenceFields(line); !ok {
+			corrupt++
+			if !strings.Contains(line, ",235959.00,") {
+				t.Errorf("Expected the corrupt sentence unchanged, got %q", line)
+			}
+		}
+	}
+	if corrupt != 1 {
+		t.Errorf("Expected one corrupt sentence in the output, got %d", corrupt)
+	}
+}
+
+func TestNMEAReplayLoop(t *testing.T) {
+	sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+		config.ReplayLoop = true
+	})
+	lines := nmeaTestLines(t)
+
+	clock.Advance(3 * time.Second)
+	sim.tick()
+	buffer.Reset()
+
+	// The next pass starts one epoch interval after the last epoch
+	clock.Advance(500 * time.Millisecond)
+	sim.tick()
+	if buffer.Len() != 0 {
+		t.Errorf("Expected a pause before looping, got %q", buffer.String())
+	}
+	clock.Advance(500 * time.Millisecond)
+	sim.tick()
+	if expected := strings.Join(lines[:4], "\r\n") + "\r\n"; buffer.String() != expected {
+		t.Errorf("Expected the first epoch again:\n%q\ngot:\n%q", expected, buffer.String())
+	}
+	if _, done := sim.completion(); done {
+		t.Error("A looping replay should not complete")
+	}
+}
+
+func TestNMEAReplayRewriteTime(t *testing.T) {
+	sim, buffer, _ := createNMEAReplaySimulator(t, func(config *Config) {
+		config.ReplayRewriteTime = true
+	})
+
+	sim.tick()
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n") {
+		fields, ok := nmeaSentenceFields(line)
+		if !ok {
+			t.Fatalf("Expected a valid checksum after rewriting, got %q", line)
+		}
+		if index := nmeaTimeField(nmeaSentenceType(fields)); index > 0 && fields[index] != "120000.00" {
+			t.Errorf("Expected the time rewritten to 120000.00, got %q", line)
+		}
+	}
+	if !strings.Contains(buffer.String(), ",010625,") {
+		t.Errorf("Expected the RMC date rewritten to 010625, got %q", buffer.String())
+	}
+}
+
+func TestRewriteNMEATime(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 890000000, time.UTC)
+
+	tests := []struct {
+		name     string
+		sentence string
+		expected string
+	}{
+		{"GGA keeps whole seconds", "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,", "$GPGGA,050607,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"},
+		{"RMC time and date", "$GPRMC,123519.00,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W", "$GPRMC,050607.89,A,4807.038,N,01131.000,E,022.4,084.4,040326,003.1,W"},
+		{"ZDA time and date", "$GPZDA,201530.000,04,07,2002,00,00", "$GPZDA,050607.890,04,03,2026,00,00"},
+		{"GLL time", "$GPGLL,4916.45,N,12311.12,W,225444.0,A", "$GPGLL,4916.45,N,12311.12,W,050607.8,A"},
+		{"VTG unchanged", "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K", "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorded := strings.TrimSuffix(formatNMEA(tt.sentence), "\r\n")
+			if got := rewriteNMEATime(recorded, now); got != formatNMEA(tt.expected) {
+				t.Errorf("Expected %q, got %q", formatNMEA(tt.expected), got)
+			}
+		})
+	}
+}
+
+func TestNMEAReplayValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+	}{
+		{"With GPX replay", func(c *Config) { c.ReplayFile = "track.gpx" }},
+		{"With route", func(c *Config) { c.RouteFile = "route.gpx" }},
+		{"With waypoints", func(c *Config) { c.Waypoints = []Coordinate{{Lat: 37, Lon: -122}} }},
+		{"With gpsd output", func(c *Config) { c.GpsdMode = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			config.ReplayNMEAFile = nmeaTestLog
+			tt.configure(&config)
+			if err := config.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestNMEAReplaySentenceFilter(t *testing.T) {
+	sim, buffer, clock := createNMEAReplaySimulator(t, func(config *Config) {
+		config.Sentences = []string{"GGA", "RMC"}
+	})
+
+	clock.Advance(3 * time.Second)
+	sim.tick()
+
+	var expected []string
+	for _, line := range nmeaTestLines(t) {
+		if strings.HasPrefix(line, "$GPGGA") || strings.HasPrefix(line, "$GPRMC") {
+			expected = append(expected, line)
+		}
+	}
+	if want := strings.Join(expected, "\r\n") + "\r\n"; buffer.String() != want {
+		t.Errorf("Expected only the replayed GGA and RMC sentences:\n%q\ngot:\n%q", want, buffer.String())
+	}
+}
@@ -2,6 +2,9 @@ package gps
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -389,7 +392,11 @@ func TestUpdateSpeedAndCourse(t *testing.T) {
 func TestGenerateGSA(t *testing.T) {
 	sim := createTestSimulator()
 
-	result := sim.generateGSA()
+	results := sim.generateGSA()
+	if len(results) != 1 {
+		t.Fatalf("generateGSA should return 1 sentence for a single constellation, got %d", len(results))
+	}
+	result := results[0]
 
 	// Check basic format
 	if !strings.HasPrefix(result, "$GPGSA,") {
@@ -427,6 +434,32 @@ func TestGenerateGSA(t *testing.T) {
 	}
 }
 
+func TestGenerateNoFixGSA(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Minute)
+
+	result := sim.generateNoFixGSA()
+
+	if !strings.HasPrefix(result, "$GPGSA,") {
+		t.Errorf("generateNoFixGSA should start with '$GPGSA,', got: %s", result)
+	}
+
+	// GSA has mode, fix type, 12 satellite IDs and 3 DOP values
+	parts := strings.Split(strings.Split(result, "*")[0], ",")
+	if len(parts) != 18 {
+		t.Fatalf("generateNoFixGSA should have 18 fields, got %d: %s", len(parts), result)
+	}
+	if parts[2] != "1" {
+		t.Errorf("generateNoFixGSA fix type should be '1', got: %s", parts[2])
+	}
+	for i := 3; i < len(parts); i++ {
+		if parts[i] != "" {
+			t.Errorf("generateNoFixGSA field %d should be empty, got: %s", i, parts[i])
+		}
+	}
+}
+
 func TestGenerateGSV(t *testing.T) {
 	sim := createTestSimulator()
 
@@ -630,6 +663,62 @@ func TestGenerateVTG(t *testing.T) {
 	}
 }
 
+func TestMagneticVariation(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		declination float64
+		emit        bool
+		course      float64
+		magVar      string
+		magVarDir   string
+		magCourse   string
+	}{
+		{"Disabled", 0, false, 90, "", "", ""},
+		{"Zero emitted", 0, true, 90, "0.0", "E", "90.0"},
+		{"East", 13.5, false, 90, "13.5", "E", "76.5"},
+		{"West", -13.5, false, 90, "13.5", "W", "103.5"},
+		{"East wraps below north", 10, false, 5, "10.0", "E", "355.0"},
+		{"West wraps past north", -10, false, 355, "10.0", "W", "5.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.MagneticDeclination = tt.declination
+			sim.Config.EmitMagneticVariation = tt.emit
+			sim.currentCourse = tt.course
+
+			rmc := strings.Split(strings.Split(sim.generateRMC(testTime), "*")[0], ",")
+			if rmc[10] != tt.magVar || rmc[11] != tt.magVarDir {
+				t.Errorf("Expected RMC magnetic variation %q,%q, got %q,%q", tt.magVar, tt.magVarDir, rmc[10], rmc[11])
+			}
+
+			vtg := strings.Split(strings.Split(sim.generateVTG(), "*")[0], ",")
+			if vtg[3] != tt.magCourse || vtg[4] != "M" {
+				t.Errorf("Expected VTG magnetic course %q,M, got %q,%s", tt.magCourse, vtg[3], vtg[4])
+			}
+		})
+	}
+}
+
+func TestMagneticDeclinationValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.MagneticDeclination = -15.2
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid declination, got: %v", err)
+	}
+
+	config.MagneticDeclination = 181
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for declination out of range")
+	}
+}
+
 func TestGenerateNoFixVTG(t *testing.T) {
 	sim := createTestSimulator()
 
@@ -715,10 +804,12 @@ func TestNMEAChecksumValidation(t *testing.T) {
 	sentences := []string{
 		sim.generateGGA(testTime),
 		sim.generateRMC(testTime),
-		sim.generateGSA(),
 		sim.generateVTG(),
 	}
 
+	// Add GSA sentences
+	sentences = append(sentences, sim.generateGSA()...)
+
 	// Add GSV sentences
 	gsv := sim.generateGSV()
 	sentences = append(sentences, gsv...)
@@ -746,6 +837,7 @@ func TestNMEAChecksumValidation(t *testing.T) {
 
 func TestGenerateGLL(t *testing.T) {
 	sim := createTestSimulator()
+	sim.Config.TimePrecision = 2
 	testTime := time.Date(2024, 1, 15, 12, 34, 56, 123000000, time.UTC) // With milliseconds
 
 	result := sim.generateGLL(testTime)
@@ -843,6 +935,7 @@ func TestGenerateNoFixGLL(t *testing.T) {
 
 func TestGenerateZDA(t *testing.T) {
 	sim := createTestSimulator()
+	sim.Config.TimePrecision = 2
 	testTime := time.Date(2024, 1, 15, 12, 34, 56, 123000000, time.UTC)
 
 	result := sim.generateZDA(testTime)
@@ -965,6 +1058,7 @@ func TestGLLCoordinateFormats(t *testing.T) {
 
 func TestZDADifferentTimes(t *testing.T) {
 	sim := createTestSimulator()
+	sim.Config.TimePrecision = 2
 
 	tests := []struct {
 		name          string
@@ -1026,3 +1120,659 @@ func TestZDADifferentTimes(t *testing.T) {
 		})
 	}
 }
+
+func TestTalkerID(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		talkerID string
+		expected string
+	}{
+		{"Default talker", "", "GP"},
+		{"GPS talker", "GP", "GP"},
+		{"GLONASS talker", "GL", "GL"},
+		{"Galileo talker", "GA", "GA"},
+		{"BeiDou talker", "GB", "GB"},
+		{"Combined talker", "GN", "GN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.TalkerID = tt.talkerID
+
+			sentences := []string{
+				sim.generateGGA(testTime),
+				sim.generateNoFixGGA(testTime),
+				sim.generateRMC(testTime),
+				sim.generateNoFixRMC(testTime),
+				sim.generateVTG(),
+				sim.generateNoFixVTG(),
+				sim.generateGLL(testTime),
+				sim.generateNoFixGLL(testTime),
+				sim.generateZDA(testTime),
+			}
+			sentences = append(sentences, sim.generateGSA()...)
+			sentences = append(sentences, sim.generateGSV()...)
+
+			for _, sentence := range sentences {
+				if !strings.HasPrefix(sentence, "$"+tt.expected) {
+					t.Errorf("Expected sentence to start with '$%s', got: %s", tt.expected, sentence)
+				}
+
+				// Checksum must still cover everything after the '$'
+				parts := strings.Split(strings.TrimSuffix(sentence, "\r\n"), "*")
+				if len(parts) != 2 {
+					t.Fatalf("Sentence should contain exactly one '*', got: %s", sentence)
+				}
+				if calculateChecksum(parts[0]) != parts[1] {
+					t.Errorf("Invalid checksum for sentence: %s", sentence)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateGST(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.TimePrecision = 2
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 120000000, time.UTC)
+
+	result := sim.generateGST(testTime)
+
+	if !strings.HasPrefix(result, "$GPGST,") {
+		t.Errorf("generateGST should start with '$GPGST,', got: %s", result)
+	}
+	if !strings.HasSuffix(result, "\r\n") {
+		t.Errorf("generateGST should end with \\r\\n, got: %s", result)
+	}
+
+	parts := strings.Split(strings.TrimSuffix(result, "\r\n"), "*")
+	if len(parts) != 2 {
+		t.Fatalf("generateGST should contain exactly one '*', got: %s", result)
+	}
+	if calculateChecksum(parts[0]) != parts[1] {
+		t.Errorf("generateGST has invalid checksum: %s", result)
+	}
+
+	// Sentence ID, time, RMS, semi-major, semi-minor, orientation, lat, lon, alt
+	fields := strings.Split(parts[0], ",")
+	if len(fields) != 9 {
+		t.Fatalf("generateGST should have 9 comma-separated fields, got %d: %s", len(fields), result)
+	}
+	if fields[1] != "123456.12" {
+		t.Errorf("generateGST should contain time '123456.12', got: %s", fields[1])
+	}
+	for i := 2; i < 9; i++ {
+		if fields[i] == "" {
+			t.Errorf("generateGST field %d should not be empty", i)
+		}
+	}
+}
+
+func TestGSTScalesWithJitter(t *testing.T) {
+	sim := createTestSimulator()
+
+	var previous positionErrorStats
+	for i, jitter := range []float64{0.0, 0.25, 0.5, 0.75, 1.0} {
+		sim.Config.Jitter = jitter
+		stats := sim.calculateErrorStats()
+
+		// Same jitter must give identical statistics
+		if again := sim.calculateErrorStats(); again != stats {
+			t.Errorf("Error stats should be deterministic for jitter %.2f", jitter)
+		}
+
+		if i > 0 && (stats.LatSigma <= previous.LatSigma || stats.AltSigma <= previous.AltSigma || stats.RMS <= previous.RMS) {
+			t.Errorf("Error stats should grow with jitter: %.2f gave %+v, previous %+v", jitter, stats, previous)
+		}
+		if stats.SemiMinor > stats.SemiMajor {
+			t.Errorf("Semi-minor axis %.2f should not exceed semi-major %.2f", stats.SemiMinor, stats.SemiMajor)
+		}
+		previous = stats
+	}
+
+	// Zero jitter stays sub-meter, full jitter stays within ~10m range error
+	sim.Config.Jitter = 0.0
+	if stats := sim.calculateErrorStats(); stats.RMS < 0.1 || stats.RMS > 1.0 {
+		t.Errorf("Expected sub-meter RMS at zero jitter, got %.2f", stats.RMS)
+	}
+	sim.Config.Jitter = 1.0
+	if stats := sim.calculateErrorStats(); stats.RMS < 5.0 || stats.RMS > 15.0 {
+		t.Errorf("Expected RMS around 10m at full jitter, got %.2f", stats.RMS)
+	}
+}
+
+func TestGSTAltitudeSigmaScalesWithAltitudeJitter(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Jitter = 0.2
+
+	var previous positionErrorStats
+	for i, altitudeJitter := range []float64{0.0, 0.5, 1.0} {
+		sim.Config.AltitudeJitter = altitudeJitter
+		stats := sim.calculateErrorStats()
+
+		if i > 0 {
+			if stats.AltSigma <= previous.AltSigma {
+				t.Errorf("Altitude sigma should grow with altitude jitter: %.2f gave %.2f, previous %.2f",
+					altitudeJitter, stats.AltSigma, previous.AltSigma)
+			}
+			if stats.LatSigma != previous.LatSigma || stats.RMS != previous.RMS {
+				t.Errorf("Altitude jitter should only affect altitude sigma, got %+v after %+v", stats, previous)
+			}
+		}
+		previous = stats
+	}
+
+	gst := strings.Split(strings.Split(sim.generateGST(time.Now()), "*")[0], ",")
+	if gst[8] != strconv.FormatFloat(previous.AltSigma, 'f', 1, 64) {
+		t.Errorf("Expected GST altitude sigma %.1f, got %s", previous.AltSigma, gst[8])
+	}
+}
+
+func TestGSTOnlyWhenLocked(t *testing.T) {
+	sim := createTestSimulator()
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+
+	sim.isLocked = false
+	sim.outputNMEA()
+	if strings.Contains(buffer.String(), "GST,") {
+		t.Error("GST should not be emitted before lock")
+	}
+
+	buffer.Reset()
+	sim.isLocked = true
+	sim.outputNMEA()
+	if !strings.Contains(buffer.String(), "$GPGST,") {
+		t.Error("GST should be emitted when locked")
+	}
+}
+
+func TestNMEAVersionFieldCounts(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	tests := []struct {
+		version   string
+		gsaFields int
+		gsvFields int
+	}{
+		{"", 18, 20},
+		{NMEAVersion23, 18, 20},
+		{NMEAVersion41, 19, 21},
+	}
+
+	for _, tt := range tests {
+		t.Run("Version "+tt.version, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.NMEAVersion = tt.version
+
+			gsa := sentenceFields(sim.generateGSA()[0])
+			if len(gsa) != tt.gsaFields {
+				t.Errorf("Expected %d GSA fields, got %d: %v", tt.gsaFields, len(gsa), gsa)
+			}
+			noFixGSA := sentenceFields(sim.generateNoFixGSA())
+			if len(noFixGSA) != tt.gsaFields {
+				t.Errorf("Expected %d no-fix GSA fields, got %d: %v", tt.gsaFields, len(noFixGSA), noFixGSA)
+			}
+			for _, sentence := range sim.generateGSV() {
+				if fields := sentenceFields(sentence); len(fields) != tt.gsvFields {
+					t.Errorf("Expected %d GSV fields, got %d: %v", tt.gsvFields, len(fields), fields)
+				}
+			}
+
+			if tt.version == NMEAVersion41 {
+				if gsa[18] != "1" {
+					t.Errorf("Expected GPS system ID 1 in GSA, got %q", gsa[18])
+				}
+				if gsv := sentenceFields(sim.generateGSV()[0]); gsv[20] != "1" {
+					t.Errorf("Expected GPS L1 C/A signal ID 1 in GSV, got %q", gsv[20])
+				}
+			}
+
+			// GGA is unchanged between versions
+			if fields := sentenceFields(sim.generateGGA(testTime)); len(fields) != 15 {
+				t.Errorf("Expected 15 GGA fields, got %d", len(fields))
+			}
+		})
+	}
+}
+
+func TestGenerateGNS(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.NMEAVersion = NMEAVersion41
+	sim.Config.FixQuality = FixQualityDGPS
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	fields := sentenceFields(sim.generateGNS(testTime))
+	if len(fields) != 14 {
+		t.Fatalf("Expected 14 GNS fields, got %d: %v", len(fields), fields)
+	}
+	if fields[0] != "$GPGNS" || fields[1] != "123456" {
+		t.Errorf("Unexpected GNS header or time: %v", fields[:2])
+	}
+	if fields[6] != "DNNN" {
+		t.Errorf("Expected GPS-only DGPS mode DNNN, got %q", fields[6])
+	}
+	if fields[7] != "04" || fields[13] != "V" {
+		t.Errorf("Expected 04 satellites and navigational status V, got %q and %q", fields[7], fields[13])
+	}
+
+	noFix := sentenceFields(sim.generateNoFixGNS(testTime))
+	if len(noFix) != 14 || noFix[6] != "NNNN" {
+		t.Errorf("Expected 14 no-fix GNS fields with mode NNNN, got %v", noFix)
+	}
+}
+
+func TestGNSMultiConstellationModes(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.NMEAVersion = NMEAVersion41
+	sim.Config.Constellations = []string{"GPS", "Galileo"}
+	sim.Satellites = append(sim.Satellites, Satellite{ID: 5, Elevation: 50, Azimuth: 120, SNR: 38, Constellation: ConstellationGalileo})
+
+	fields := sentenceFields(sim.generateGNS(time.Now()))
+	if fields[0] != "$GNGNS" || fields[6] != "ANAN" {
+		t.Errorf("Expected $GNGNS with modes ANAN, got %s %s", fields[0], fields[6])
+	}
+
+	gsa := sim.generateGSA()
+	if len(gsa) != 2 || sentenceFields(gsa[1])[18] != "3" {
+		t.Errorf("Expected a second GSA with Galileo system ID 3, got %v", gsa)
+	}
+}
+
+func TestNMEAVersionSentenceSelection(t *testing.T) {
+	sim := createTestSimulator()
+	if types := emittedSentenceTypes(sim); types[SentenceGNS] {
+		t.Error("Expected no GNS with default NMEA 2.3 output")
+	}
+
+	// Sentence settings are parsed once, so clear them after each change
+	sim.Config.NMEAVersion = NMEAVersion41
+	sim.sentences = nil
+	if types := emittedSentenceTypes(sim); !types[SentenceGNS] || !types[SentenceGGA] {
+		t.Errorf("Expected GNS alongside GGA with NMEA 4.1 output, got %v", types)
+	}
+
+	sim.Config.Sentences = []string{"RMC"}
+	sim.sentences = nil
+	if types := emittedSentenceTypes(sim); types[SentenceGNS] {
+		t.Error("Expected GNS to respect the sentence selection")
+	}
+}
+
+func TestNMEAVersionValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	for _, version := range []string{"", NMEAVersion23, NMEAVersion41} {
+		config.NMEAVersion = version
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected NMEA version %q to be valid, got: %v", version, err)
+		}
+	}
+
+	config.NMEAVersion = "3.0"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for NMEA version 3.0")
+	}
+}
+
+func TestGGAGeoidSeparation(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+
+	sim := createTestSimulator()
+	if fields := sentenceFields(sim.generateGGA(testTime)); fields[11] != "0.0" || fields[12] != "M" {
+		t.Errorf("Expected default geoid separation 0.0,M, got %s,%s", fields[11], fields[12])
+	}
+
+	sim.Config.GeoidSeparation = -32.5
+	result := sim.generateGGA(testTime)
+	fields := sentenceFields(result)
+	if fields[11] != "-32.5" || fields[12] != "M" {
+		t.Errorf("Expected geoid separation -32.5,M, got %s,%s", fields[11], fields[12])
+	}
+	parts := strings.Split(result, "*")
+	if strings.TrimSuffix(parts[1], "\r\n") != calculateChecksum(parts[0]) {
+		t.Errorf("Expected checksum recalculated with the geoid separation, got: %s", result)
+	}
+}
+
+func TestAutoGeoidSeparation(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected float64 // Approximate EGM96 geoid height in meters
+	}{
+		{"San Francisco", 37.7749, -122.4194, -32},
+		{"London", 51.5074, -0.1278, 47},
+		{"Indian Ocean low", 5, 80, -100},
+		{"New Guinea high", -5, 145, 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.AutoGeoidSeparation = true
+			sim.Config.GeoidSeparation = 99 // Ignored with auto separation
+			sim.currentLat, sim.currentLon = tt.lat, tt.lon
+
+			separation := sim.geoidSeparation()
+			if math.Abs(separation-tt.expected) > 50 {
+				t.Errorf("Expected geoid separation near %.0fm, got %.1fm", tt.expected, separation)
+			}
+
+			field := sentenceFields(sim.generateGGA(time.Now()))[11]
+			if field != strconv.FormatFloat(separation, 'f', 1, 64) {
+				t.Errorf("Expected GGA separation %.1f, got %s", separation, field)
+			}
+		})
+	}
+
+	// Wrapping longitude and clamping latitude stay within the grid
+	for _, point := range [][2]float64{{90, 180}, {-90, -180}, {0, 540}, {95, -200}} {
+		if separation := approximateGeoidSeparation(point[0], point[1]); math.IsNaN(separation) || math.Abs(separation) > 120 {
+			t.Errorf("Unexpected separation %.1f at %v", separation, point)
+		}
+	}
+}
+
+func TestTimePrecision(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 789000000, time.UTC)
+
+	tests := []struct {
+		precision int
+		gga, gll  string
+	}{
+		{0, "123456", "123456"},
+		{1, "123456.7", "123456.7"},
+		{2, "123456.78", "123456.78"},
+		{3, "123456.789", "123456.789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.precision), func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.TimePrecision = tt.precision
+
+			if field := sentenceFields(sim.generateGGA(testTime))[1]; field != tt.gga {
+				t.Errorf("Expected GGA time %s, got %s", tt.gga, field)
+			}
+			if field := sentenceFields(sim.generateRMC(testTime))[1]; field != tt.gga {
+				t.Errorf("Expected RMC time %s, got %s", tt.gga, field)
+			}
+			if field := sentenceFields(sim.generateGLL(testTime))[5]; field != tt.gll {
+				t.Errorf("Expected GLL time %s, got %s", tt.gll, field)
+			}
+			if field := sentenceFields(sim.generateZDA(testTime))[1]; field != tt.gll {
+				t.Errorf("Expected ZDA time %s, got %s", tt.gll, field)
+			}
+		})
+	}
+}
+
+func TestTimePrecisionAt10Hz(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.TimePrecision = 1
+	sim.Config.Sentences = []string{SentenceGGA}
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC))
+	sim.SetClock(clock)
+
+	var previous string
+	for i := 0; i < 20; i++ {
+		buffer := &bytes.Buffer{}
+		sim.nmeaWriter = buffer
+		sim.outputNMEA()
+
+		field := sentenceFields(buffer.String())[1]
+		if field == previous {
+			t.Fatalf("Expected consecutive 10Hz GGA timestamps to differ, got %s twice", field)
+		}
+		if expected := clock.Now().Format("150405.0"); field != expected {
+			t.Errorf("Expected GGA time %s from the emission time, got %s", expected, field)
+		}
+		previous = field
+		clock.Advance(100 * time.Millisecond)
+	}
+}
+
+func TestFormatNMEATime(t *testing.T) {
+	tests := []struct {
+		nanos  int
+		places int
+		want   string
+	}{
+		{0, 0, "123456"},
+		{0, 2, "123456.00"},
+		{5000000, 2, "123456.00"},   // 5ms truncates below a centisecond
+		{50000000, 2, "123456.05"},  // 5 centiseconds keeps its leading zero
+		{500000000, 2, "123456.50"}, // Half a second is 50 centiseconds
+		{500000000, 1, "123456.5"},
+		{999999999, 0, "123456"},    // Never rounds up into the next second
+		{999999999, 2, "123456.99"}, // Never rounds up into the next second
+		{9999999, 2, "123456.00"},   // Just under a centisecond
+		{10000000, 2, "123456.01"},  // Exactly a centisecond
+		{1000000, 3, "123456.001"},  // One millisecond
+		{999999, 3, "123456.000"},   // Just under a millisecond
+		{123456789, 9, "123456.123456789"},
+	}
+
+	for _, tt := range tests {
+		timestamp := time.Date(2024, 1, 15, 12, 34, 56, tt.nanos, time.UTC)
+		if got := formatNMEATime(timestamp, tt.places); got != tt.want {
+			t.Errorf("formatNMEATime(%dns, %d) = %s, want %s", tt.nanos, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestHighRateDefaultTimePrecision(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 250000000, time.UTC)
+
+	tests := []struct {
+		rate      time.Duration
+		precision int
+		gga       string
+	}{
+		{time.Second, 0, "123456"},
+		{200 * time.Millisecond, 0, "123456.25"},
+		{200 * time.Millisecond, 2, "123456.25"},
+		{200 * time.Millisecond, 1, "123456.2"},
+	}
+
+	for _, tt := range tests {
+		sim := createTestSimulator()
+		sim.Config.OutputRate = tt.rate
+		sim.Config.TimePrecision = tt.precision
+
+		if field := sentenceFields(sim.generateGGA(testTime))[1]; field != tt.gga {
+			t.Errorf("Rate %v precision %d: expected GGA time %s, got %s", tt.rate, tt.precision, tt.gga, field)
+		}
+		if field := sentenceFields(sim.generateRMC(testTime))[1]; field != tt.gga {
+			t.Errorf("Rate %v precision %d: expected RMC time %s, got %s", tt.rate, tt.precision, tt.gga, field)
+		}
+		if field := sentenceFields(sim.generateGLL(testTime))[5]; field != tt.gga {
+			t.Errorf("Rate %v precision %d: expected GLL time %s, got %s", tt.rate, tt.precision, tt.gga, field)
+		}
+	}
+}
+
+func TestTimePrecisionValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	for _, precision := range []int{-1, 4} {
+		config.TimePrecision = precision
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for time precision %d", precision)
+		}
+	}
+}
+
+func TestOutputBurstSharesEpoch(t *testing.T) {
+	// A cycle 5ms before the second boundary must not round any sentence up
+	// to the next second
+	for _, locked := range []bool{true, false} {
+		sim := createTestSimulator()
+		sim.isLocked = locked
+		sim.Config.NMEAVersion = NMEAVersion41
+		clock := newFakeClock(time.Date(2024, 1, 15, 12, 34, 56, 995000000, time.UTC))
+		sim.clock = clock
+		sim.startTime = clock.Now()
+
+		buffer := &bytes.Buffer{}
+		sim.nmeaWriter = buffer
+		sim.outputNMEA()
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\r\n")
+		if !strings.Contains(lines[0], "GGA,") {
+			t.Errorf("Locked %v: expected the burst to start with GGA, got %s", locked, lines[0])
+		}
+
+		timeFields := map[string]int{"GGA": 1, "GNS": 1, "RMC": 1, "GLL": 5, "ZDA": 1, "GST": 1}
+		seen := 0
+		for _, line := range lines {
+			fields := sentenceFields(line)
+			index, ok := timeFields[fields[0][3:]]
+			if !ok || len(fields) <= index {
+				continue
+			}
+			seen++
+			if !strings.HasPrefix(fields[index], "123456") {
+				t.Errorf("Locked %v: expected time 123456 in %s, got %s", locked, fields[0], fields[index])
+			}
+			if strings.Contains(fields[index], ".") && fields[index] != "123456.99" {
+				t.Errorf("Locked %v: expected truncated time 123456.99 in %s, got %s", locked, fields[0], fields[index])
+			}
+		}
+		if seen < 4 {
+			t.Errorf("Locked %v: expected at least 4 timed sentences, got %d", locked, seen)
+		}
+	}
+}
+
+// validateChecksum reports whether a generated sentence ends with the
+// checksum of its contents
+func validateChecksum(sentence string) bool {
+	parts := strings.Split(strings.TrimSuffix(sentence, "\r\n"), "*")
+	return len(parts) == 2 && calculateChecksum(parts[0]) == parts[1]
+}
+
+func TestGenerateGRS(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+	sim := createTestSimulator()
+
+	sentences := sim.generateGRS(testTime)
+	if len(sentences) != 1 {
+		t.Fatalf("Expected one GRS sentence, got %d", len(sentences))
+	}
+	grs := sentences[0]
+	if !validateChecksum(grs) {
+		t.Errorf("Invalid GRS checksum: %s", grs)
+	}
+
+	fields := sentenceFields(grs)
+	if fields[0] != "$GPGRS" || len(fields) != 15 {
+		t.Fatalf("Expected $GPGRS with 15 fields, got %s with %d", fields[0], len(fields))
+	}
+	if fields[1] != "123456" || fields[2] != "1" {
+		t.Errorf("Expected time 123456 and mode 1, got %s and %s", fields[1], fields[2])
+	}
+
+	// One residual per satellite, with zero mean and the GST range RMS
+	var sum, sumSquares float64
+	for i, field := range fields[3:] {
+		if i >= len(sim.Satellites) {
+			if field != "" {
+				t.Errorf("Expected empty residual %d, got %s", i+1, field)
+			}
+			continue
+		}
+		residual, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			t.Fatalf("Invalid residual %q: %v", field, err)
+		}
+		sum += residual
+		sumSquares += residual * residual
+	}
+	n := float64(len(sim.Satellites))
+	rms := sim.calculateErrorStats().RMS
+	if math.Abs(sum/n) > 0.1 {
+		t.Errorf("Expected residuals with zero mean, got mean %.2f", sum/n)
+	}
+	if math.Abs(math.Sqrt(sumSquares/n)-rms) > 0.1 {
+		t.Errorf("Expected residual RMS %.2f matching GST, got %.2f", rms, math.Sqrt(sumSquares/n))
+	}
+
+	// NMEA 4.1 adds the system and signal IDs
+	sim.Config.NMEAVersion = NMEAVersion41
+	if fields := sentenceFields(sim.generateGRS(testTime)[0]); len(fields) != 17 || fields[15] != "1" || fields[16] != "1" {
+		t.Errorf("Expected NMEA 4.1 GRS to end with system and signal IDs 1,1, got %v", fields[15:])
+	}
+}
+
+func TestGenerateGBS(t *testing.T) {
+	testTime := time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC)
+	sim := createTestSimulator()
+
+	gbs := sim.generateGBS(testTime)
+	if !validateChecksum(gbs) {
+		t.Errorf("Invalid GBS checksum: %s", gbs)
+	}
+	fields := sentenceFields(gbs)
+	if fields[0] != "$GPGBS" || len(fields) != 9 {
+		t.Fatalf("Expected $GPGBS with 9 fields, got %s with %d", fields[0], len(fields))
+	}
+
+	// Expected errors and bias standard deviation match GST
+	gst := sentenceFields(sim.generateGST(testTime))
+	if fields[2] != gst[6] || fields[3] != gst[7] || fields[4] != gst[8] {
+		t.Errorf("Expected lat/lon/alt errors %s/%s/%s from GST, got %s/%s/%s", gst[6], gst[7], gst[8], fields[2], fields[3], fields[4])
+	}
+	if fields[8] != gst[2] {
+		t.Errorf("Expected bias standard deviation %s from the GST RMS, got %s", gst[2], fields[8])
+	}
+
+	// The flagged satellite has the largest residual reported in GRS
+	residuals := sentenceFields(sim.generateGRS(testTime)[0])[3:]
+	largest := 0
+	for i := range sim.Satellites {
+		a, _ := strconv.ParseFloat(residuals[i], 64)
+		b, _ := strconv.ParseFloat(residuals[largest], 64)
+		if math.Abs(a) > math.Abs(b) {
+			largest = i
+		}
+	}
+	if want := fmt.Sprintf("%02d", sim.Satellites[largest].ID); fields[5] != want {
+		t.Errorf("Expected flagged satellite %s, got %s", want, fields[5])
+	}
+	if fields[7] != residuals[largest] {
+		t.Errorf("Expected bias %s from the flagged satellite's residual, got %s", residuals[largest], fields[7])
+	}
+}
+
+func TestIntegritySentencesOutput(t *testing.T) {
+	sim := createTestSimulator()
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 34, 56, 0, time.UTC))
+	sim.clock = clock
+
+	if sentences := emittedFields(sim); sentences["GRS"] != nil || sentences["GBS"] != nil {
+		t.Error("Expected no integrity sentences by default")
+	}
+
+	// Sentence settings are parsed once, so clear them after the change
+	sim.Config.EmitIntegritySentences = true
+	sim.sentences = nil
+	sentences := emittedFields(sim)
+	if sentences["GRS"] == nil || sentences["GBS"] == nil {
+		t.Error("Expected GRS and GBS with EmitIntegritySentences")
+	}
+
+	// Not reported without a fix
+	sim.isLocked = false
+	if sentences := emittedFields(sim); sentences["GRS"] != nil || sentences["GBS"] != nil {
+		t.Error("Expected no integrity sentences without a fix")
+	}
+}
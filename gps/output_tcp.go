@@ -0,0 +1,192 @@
+package gps
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"time"
+)
+
+// tcpWriteTimeout bounds how long a client may take to accept a single write
+const tcpWriteTimeout = 500 * time.Millisecond
+
+// tcpClientQueue is the number of pending writes buffered per client before
+// it is considered too slow and dropped
+const tcpClientQueue = 256
+
+// tcpProtocol lets an output format greet new TCP clients and answer their commands
+type tcpProtocol interface {
+	greeting() []byte              // Sent to each client on connect (nil for none)
+	respond(command string) []byte // Reply to a line received from a client (nil for none)
+}
+
+// tcpClient is a connected client with its own queue of pending writes, so a
+// slow client never stalls the simulation loop or other clients
+type tcpClient struct {
+	conn  net.Conn
+	queue chan []byte
+}
+
+// tcpServer is an io.Writer that accepts TCP clients on a listener and writes
+// every sentence to all of them. Clients that disconnect, fail a write or fall
+// too far behind are dropped.
+type tcpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	clients  map[net.Conn]*tcpClient
+	closed   bool
+	done     chan struct{}
+	protocol tcpProtocol // Optional client greeting and command handling
+}
+
+// newTCPServer starts listening on addr (e.g. ":10110") and accepts clients in
+// the background. protocol may be nil when clients only receive the stream.
+func newTCPServer(addr string, protocol tcpProtocol) (*tcpServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &tcpServer{
+		listener: listener,
+		clients:  make(map[net.Conn]*tcpClient),
+		done:     make(chan struct{}),
+		protocol: protocol,
+	}
+	go t.acceptLoop()
+
+	return t, nil
+}
+
+// acceptLoop registers incoming connections until the listener is closed
+func (t *tcpServer) acceptLoop() {
+	defer close(t.done)
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		t.addClient(conn)
+	}
+}
+
+// addClient starts streaming to conn. The protocol greeting, if any, is queued
+// before the client joins the stream so it is always received first.
+func (t *tcpServer) addClient(conn net.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		conn.Close()
+		return
+	}
+
+	client := &tcpClient{conn: conn, queue: make(chan []byte, tcpClientQueue)}
+	if t.protocol != nil {
+		if greeting := t.protocol.greeting(); greeting != nil {
+			client.queue <- greeting
+		}
+		go t.readCommands(client)
+	}
+	t.clients[conn] = client
+	go t.writeLoop(client)
+}
+
+// writeLoop delivers queued writes to a client until it is dropped
+func (t *tcpServer) writeLoop(client *tcpClient) {
+	for p := range client.queue {
+		client.conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+		if _, err := client.conn.Write(p); err != nil {
+			t.mu.Lock()
+			t.dropClient(client)
+			t.mu.Unlock()
+			return
+		}
+	}
+}
+
+// readCommands answers each line a client sends and drops it once it hangs up
+func (t *tcpServer) readCommands(client *tcpClient) {
+	scanner := bufio.NewScanner(client.conn)
+	for scanner.Scan() {
+		if reply := t.protocol.respond(scanner.Text()); reply != nil {
+			t.mu.Lock()
+			t.enqueue(client, reply)
+			t.mu.Unlock()
+		}
+	}
+
+	t.mu.Lock()
+	t.dropClient(client)
+	t.mu.Unlock()
+}
+
+// enqueue queues p for a client, dropping the client if its queue is full.
+// The caller must hold t.mu.
+func (t *tcpServer) enqueue(client *tcpClient, p []byte) {
+	if _, ok := t.clients[client.conn]; !ok {
+		return
+	}
+	select {
+	case client.queue <- p:
+	default:
+		// Client is not keeping up; drop it rather than block the others
+		t.dropClient(client)
+	}
+}
+
+// dropClient disconnects a client and stops its writer. It is safe to call
+// more than once. The caller must hold t.mu.
+func (t *tcpServer) dropClient(client *tcpClient) {
+	if _, ok := t.clients[client.conn]; !ok {
+		return
+	}
+	delete(t.clients, client.conn)
+	close(client.queue)
+	client.conn.Close()
+}
+
+// Write queues p for every connected client. It never blocks on the network
+// and never fails: clients that disconnect or fall behind are dropped instead.
+func (t *tcpServer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Copy once since callers may reuse p after Write returns
+	data := append([]byte(nil), p...)
+	for _, client := range t.clients {
+		t.enqueue(client, data)
+	}
+
+	return len(p), nil
+}
+
+// Addr returns the address the server is listening on
+func (t *tcpServer) Addr() net.Addr {
+	return t.listener.Addr()
+}
+
+// clientCount returns the number of currently connected clients
+func (t *tcpServer) clientCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.clients)
+}
+
+// Close stops accepting clients and disconnects all connected ones
+func (t *tcpServer) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	err := t.listener.Close()
+	for _, client := range t.clients {
+		t.dropClient(client)
+	}
+	t.mu.Unlock()
+
+	<-t.done
+	return err
+}
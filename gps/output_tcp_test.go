@@ -0,0 +1,253 @@
+package gps
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForClients polls until the server has accepted the expected number of clients
+func waitForClients(t *testing.T, server *tcpServer, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for server.clientCount() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connected clients, got %d", expected, server.clientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTCPServerBroadcastsToAllClients(t *testing.T) {
+	server, err := newTCPServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	defer server.Close()
+
+	var readers []*bufio.Reader
+	for i := 0; i < 3; i++ {
+		conn, err := net.Dial("tcp", server.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to connect client %d: %v", i, err)
+		}
+		defer conn.Close()
+		readers = append(readers, bufio.NewReader(conn))
+	}
+	waitForClients(t, server, 3)
+
+	sentence := "$GPGGA,123456,,,,,0,00,,,,,,,,,*66\r\n"
+	if n, err := server.Write([]byte(sentence)); err != nil || n != len(sentence) {
+		t.Fatalf("Write returned %d, %v", n, err)
+	}
+
+	for i, reader := range readers {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Client %d failed to read: %v", i, err)
+		}
+		if line != sentence {
+			t.Errorf("Client %d expected %q, got %q", i, sentence, line)
+		}
+	}
+}
+
+func TestTCPServerRemovesDisconnectedClients(t *testing.T) {
+	server, err := newTCPServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	defer server.Close()
+
+	gone, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	alive, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer alive.Close()
+	waitForClients(t, server, 2)
+
+	gone.Close()
+
+	// Writes to a closed peer eventually fail; keep writing until it is dropped
+	deadline := time.Now().Add(2 * time.Second)
+	for server.clientCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Disconnected client was not removed, %d clients remain", server.clientCount())
+		}
+		if _, err := server.Write([]byte("$GPZDA*00\r\n")); err != nil {
+			t.Fatalf("Write should not fail when a client disconnects: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	alive.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := bufio.NewReader(alive).ReadString('\n'); err != nil {
+		t.Errorf("Remaining client should still receive data: %v", err)
+	}
+}
+
+func TestSimulatorTCPListen(t *testing.T) {
+	config := createTestConfig()
+	config.TCPListen = "127.0.0.1:0"
+	config.TimeToLock = 0
+	buffer := &bytes.Buffer{}
+
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	if sim.TCPAddr() == nil {
+		t.Fatal("Expected TCP address when TCPListen is set")
+	}
+
+	conn, err := net.Dial("tcp", sim.TCPAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, sim.tcpServer, 1)
+
+	sim.update()
+	sim.outputNMEA()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read from TCP stream: %v", err)
+	}
+	if !strings.HasPrefix(line, "$GPGGA") {
+		t.Errorf("Expected GGA sentence over TCP, got %q", line)
+	}
+
+	// The original writer still receives output alongside the TCP clients
+	if !strings.HasPrefix(buffer.String(), line) {
+		t.Errorf("Expected writer output to match TCP stream, got %q", buffer.String())
+	}
+}
+
+func TestSimulatorTCPListenInvalidAddress(t *testing.T) {
+	config := createTestConfig()
+	config.TCPListen = "invalid-address"
+
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for invalid TCP listen address")
+	}
+}
+
+func TestSimulatorWithoutTCPListen(t *testing.T) {
+	sim, err := NewGPSSimulator(createTestConfig(), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	if sim.TCPAddr() != nil {
+		t.Errorf("Expected no TCP address, got %v", sim.TCPAddr())
+	}
+}
+
+func TestTCPServerDropsSlowClient(t *testing.T) {
+	server, err := newTCPServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	defer server.Close()
+
+	// net.Pipe is unbuffered, so a peer that never reads blocks every write
+	slowServer, slowClient := net.Pipe()
+	defer slowClient.Close()
+	fastServer, fastClient := net.Pipe()
+	defer fastClient.Close()
+
+	server.addClient(slowServer)
+	server.addClient(fastServer)
+
+	sentence := []byte("$GPZDA,123456.00,15,01,2024,00,00*6C\r\n")
+	total := tcpClientQueue * 2
+
+	received := make(chan int)
+	go func() {
+		count := 0
+		reader := bufio.NewReader(fastClient)
+		for count < total {
+			if _, err := reader.ReadString('\n'); err != nil {
+				break
+			}
+			count++
+		}
+		received <- count
+	}()
+
+	// Pace writes so the fast client keeps up while the slow one falls behind
+	for i := 0; i < total; i++ {
+		start := time.Now()
+		server.Write(sentence)
+		if elapsed := time.Since(start); elapsed > tcpWriteTimeout/2 {
+			t.Fatalf("Write should not block on a slow client, took %v", elapsed)
+		}
+		time.Sleep(200 * time.Microsecond)
+	}
+
+	select {
+	case count := <-received:
+		if count != total {
+			t.Errorf("Fast client expected %d sentences, got %d", total, count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fast client did not receive all sentences")
+	}
+
+	waitForClients(t, server, 1)
+	server.mu.Lock()
+	_, slowConnected := server.clients[slowServer]
+	server.mu.Unlock()
+	if slowConnected {
+		t.Error("Slow client should have been dropped")
+	}
+}
+
+func TestTCPServerNewClientReceivesImmediately(t *testing.T) {
+	server, err := newTCPServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	defer server.Close()
+
+	serverSide, clientSide := net.Pipe()
+	defer clientSide.Close()
+	server.addClient(serverSide)
+
+	server.Write([]byte("$GPGGA*00\r\n"))
+
+	clientSide.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(clientSide).ReadString('\n')
+	if err != nil || line != "$GPGGA*00\r\n" {
+		t.Errorf("Expected the next sentence after connecting, got %q, %v", line, err)
+	}
+}
+
+func TestTCPServerRejectsClientsAfterClose(t *testing.T) {
+	server, err := newTCPServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	server.Close()
+
+	serverSide, clientSide := net.Pipe()
+	defer clientSide.Close()
+	server.addClient(serverSide)
+
+	if server.clientCount() != 0 {
+		t.Error("Closed server should not accept clients")
+	}
+	if _, err := server.Write([]byte("$GPGGA*00\r\n")); err != nil {
+		t.Errorf("Write after close should not fail: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package gps
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+)
+
+// udpWriter is an io.Writer that sends each NMEA sentence to a UDP target
+// (unicast or broadcast) as its own datagram
+type udpWriter struct {
+	conn *net.UDPConn
+}
+
+// newUDPWriter resolves target (e.g. "192.168.1.10:10110" or
+// "255.255.255.255:10110") once and prepares a socket for sending to it
+func newUDPWriter(target string) (*udpWriter, error) {
+	addr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UDP target %q: %v", target, err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open UDP socket for %s: %v", target, err)
+	}
+
+	return &udpWriter{conn: conn}, nil
+}
+
+// Write sends every CRLF-terminated sentence in p as a separate datagram, as
+// NMEA-over-UDP consumers expect. Send errors are logged rather than returned
+// so a transient network problem never stops the simulation.
+func (u *udpWriter) Write(p []byte) (int, error) {
+	remaining := p
+	for len(remaining) > 0 {
+		end := bytes.Index(remaining, []byte("\r\n"))
+		var sentence []byte
+		if end < 0 {
+			sentence, remaining = remaining, nil
+		} else {
+			sentence, remaining = remaining[:end+2], remaining[end+2:]
+		}
+
+		if _, err := u.conn.Write(sentence); err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending UDP datagram: %v\n", err)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Addr returns the resolved UDP target
+func (u *udpWriter) Addr() net.Addr {
+	return u.conn.RemoteAddr()
+}
+
+// Close releases the UDP socket
+func (u *udpWriter) Close() error {
+	return u.conn.Close()
+}
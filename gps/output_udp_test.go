@@ -0,0 +1,130 @@
+package gps
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUDP opens a local UDP listener for receiving test datagrams
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen for UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readDatagrams reads count datagrams from conn
+func readDatagrams(t *testing.T, conn *net.UDPConn, count int) []string {
+	t.Helper()
+	var datagrams []string
+	buffer := make([]byte, 2048)
+	for len(datagrams) < count {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			t.Fatalf("Failed to read datagram %d: %v", len(datagrams), err)
+		}
+		datagrams = append(datagrams, string(buffer[:n]))
+	}
+	return datagrams
+}
+
+func TestUDPWriterOneSentencePerDatagram(t *testing.T) {
+	listener := listenUDP(t)
+
+	writer, err := newUDPWriter(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to create UDP writer: %v", err)
+	}
+	defer writer.Close()
+
+	// Several sentences in a single write are still sent separately
+	sentences := []string{
+		formatNMEA("$GPGGA,123456,,,,,0,00,,,,,,,,,"),
+		formatNMEA("$GPRMC,123456,V,,,,,,,150124,,,N"),
+	}
+	payload := strings.Join(sentences, "")
+	if n, err := writer.Write([]byte(payload)); err != nil || n != len(payload) {
+		t.Fatalf("Write returned %d, %v", n, err)
+	}
+
+	datagrams := readDatagrams(t, listener, 2)
+	for i, datagram := range datagrams {
+		if datagram != sentences[i] {
+			t.Errorf("Datagram %d: expected %q, got %q", i, sentences[i], datagram)
+		}
+	}
+}
+
+func TestUDPWriterInvalidTarget(t *testing.T) {
+	for _, target := range []string{"no-port", "localhost:notaport"} {
+		if _, err := newUDPWriter(target); err == nil {
+			t.Errorf("Expected error for UDP target %q", target)
+		}
+	}
+}
+
+func TestUDPWriterSendErrorDoesNotFail(t *testing.T) {
+	listener := listenUDP(t)
+	writer, err := newUDPWriter(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to create UDP writer: %v", err)
+	}
+	writer.Close()
+
+	// Sending on a closed socket is logged rather than returned
+	if _, err := writer.Write([]byte("$GPZDA*00\r\n")); err != nil {
+		t.Errorf("Write should not return send errors: %v", err)
+	}
+}
+
+func TestSimulatorUDPTarget(t *testing.T) {
+	listener := listenUDP(t)
+
+	config := createTestConfig()
+	config.UDPTarget = listener.LocalAddr().String()
+	config.TimeToLock = 0
+	config.Quiet = true
+
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	sim.update()
+	sim.outputNMEA()
+
+	// Every sentence written to the buffer arrives as its own datagram
+	expected := strings.SplitAfter(buffer.String(), "\r\n")
+	expected = expected[:len(expected)-1]
+	datagrams := readDatagrams(t, listener, len(expected))
+	for i, datagram := range datagrams {
+		if datagram != expected[i] {
+			t.Errorf("Datagram %d: expected %q, got %q", i, expected[i], datagram)
+		}
+
+		// Checksums survive the trip intact
+		body := strings.TrimSuffix(datagram, "\r\n")
+		star := strings.LastIndex(body, "*")
+		if star < 0 || calculateChecksum(body[:star]) != body[star+1:] {
+			t.Errorf("Datagram %d has an invalid checksum: %q", i, datagram)
+		}
+	}
+}
+
+func TestSimulatorUDPTargetInvalid(t *testing.T) {
+	config := createTestConfig()
+	config.UDPTarget = "not a valid target"
+
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for invalid UDP target")
+	}
+}
@@ -0,0 +1,117 @@
+package gps
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pacedWrite is one queued write to a pacingWriter, or the start of a new
+// output burst
+type pacedWrite struct {
+	data     []byte
+	burst    bool
+	interval time.Duration // Wall time until the next burst, for burst starts
+}
+
+// pacingWriter spaces writes to w by their transmission time at a serial
+// baud rate, so each burst is spread across the output interval the way a
+// receiver's UART sends it instead of arriving all at once. Writes are
+// queued and made on a separate goroutine so pacing never holds up the
+// simulation, and a burst never runs past the start of the next one.
+type pacingWriter struct {
+	w        io.Writer
+	byteTime time.Duration // Time to send one byte with 8N1 framing
+	queue    chan pacedWrite
+	done     chan struct{}
+	closing  atomic.Bool // Set by Close to write out the queue without pacing
+
+	mu     sync.Mutex // Guards sends on queue against Close closing it
+	closed bool
+}
+
+// newPacingWriter returns a writer pacing writes to w at baudRate
+func newPacingWriter(w io.Writer, baudRate int) *pacingWriter {
+	p := &pacingWriter{
+		w:        w,
+		byteTime: time.Second * serialBitsPerByte / time.Duration(baudRate),
+		queue:    make(chan pacedWrite, 256),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Write queues a copy of b to be written in its turn. Errors from the
+// underlying writer are not reported, and writes after Close return
+// io.ErrClosedPipe.
+func (p *pacingWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(b) > 0 {
+		p.queue <- pacedWrite{data: append([]byte(nil), b...)}
+	}
+	return len(b), nil
+}
+
+// startBurst marks the start of an output burst, due to be followed by the
+// next one after interval of wall time. It does nothing after Close.
+func (p *pacingWriter) startBurst(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.queue <- pacedWrite{burst: true, interval: interval}
+	}
+}
+
+// Close writes out anything still queued without pacing and stops the
+// writer. It is safe to call more than once.
+func (p *pacingWriter) Close() {
+	// Stop pacing first so a write blocked on a full queue finishes quickly
+	p.closing.Store(true)
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	<-p.done
+}
+
+// run writes queued data, each write starting once the one before it would
+// have finished sending, and none later than the end of its burst
+func (p *pacingWriter) run() {
+	defer close(p.done)
+	var next, burstEnd time.Time
+	for write := range p.queue {
+		now := time.Now()
+		if write.burst {
+			next = now
+			burstEnd = now.Add(write.interval)
+			continue
+		}
+		if wait := next.Sub(now); wait > 0 && !p.closing.Load() {
+			time.Sleep(wait)
+		} else {
+			next = now
+		}
+		p.w.Write(write.data)
+
+		next = next.Add(time.Duration(len(write.data)) * p.byteTime)
+		if next.After(burstEnd) {
+			next = burstEnd
+		}
+	}
+}
+
+// outputInterval returns the wall time between output cycles
+func (c Config) outputInterval() time.Duration {
+	if c.TimeScale > 0 {
+		return time.Duration(float64(c.OutputRate) / c.TimeScale)
+	}
+	return c.OutputRate
+}
@@ -0,0 +1,177 @@
+package gps
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingWriter records when each write arrives
+type recordingWriter struct {
+	mu     sync.Mutex
+	writes []string
+	times  []time.Time
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(b))
+	w.times = append(w.times, time.Now())
+	return len(b), nil
+}
+
+// gaps returns the time between each write and the first
+func (w *recordingWriter) gaps() []time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	gaps := make([]time.Duration, len(w.times))
+	for i, t := range w.times {
+		gaps[i] = t.Sub(w.times[0])
+	}
+	return gaps
+}
+
+func TestPacingWriterSpacesByBaud(t *testing.T) {
+	recorder := &recordingWriter{}
+	// 96 bytes take 100ms at 9600 baud
+	pacer := newPacingWriter(recorder, 9600)
+	sentence := strings.Repeat("x", 94) + "\r\n"
+
+	pacer.startBurst(time.Second)
+	for i := 0; i < 3; i++ {
+		pacer.Write([]byte(sentence))
+	}
+	time.Sleep(300 * time.Millisecond)
+	pacer.Close()
+
+	gaps := recorder.gaps()
+	if len(gaps) != 3 {
+		t.Fatalf("Expected 3 writes, got %d", len(gaps))
+	}
+	for i, gap := range gaps {
+		expected := time.Duration(i) * 100 * time.Millisecond
+		if gap < expected-10*time.Millisecond || gap > expected+50*time.Millisecond {
+			t.Errorf("Write %d: expected about %v after the first, got %v", i+1, expected, gap)
+		}
+	}
+}
+
+func TestPacingWriterNeverPassesNextBurst(t *testing.T) {
+	recorder := &recordingWriter{}
+	pacer := newPacingWriter(recorder, 9600)
+	sentence := strings.Repeat("x", 94) + "\r\n"
+
+	// Five 100ms sentences don't fit in a 150ms interval
+	pacer.startBurst(150 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		pacer.Write([]byte(sentence))
+	}
+	time.Sleep(200 * time.Millisecond)
+	pacer.Close()
+
+	gaps := recorder.gaps()
+	if len(gaps) != 5 {
+		t.Fatalf("Expected 5 writes, got %d", len(gaps))
+	}
+	if last := gaps[len(gaps)-1]; last > 190*time.Millisecond {
+		t.Errorf("Expected the burst to finish by the end of its interval, last write after %v", last)
+	}
+}
+
+func TestPacingWriterCloseFlushes(t *testing.T) {
+	recorder := &recordingWriter{}
+	// Each 96-byte sentence takes a second at 960 baud
+	pacer := newPacingWriter(recorder, 960)
+	sentence := strings.Repeat("x", 94) + "\r\n"
+
+	pacer.startBurst(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		pacer.Write([]byte(sentence))
+	}
+	start := time.Now()
+	pacer.Close()
+	pacer.Close()
+
+	// Writes after Close are refused instead of panicking
+	if _, err := pacer.Write([]byte(sentence)); err != io.ErrClosedPipe {
+		t.Errorf("Expected io.ErrClosedPipe writing after Close, got %v", err)
+	}
+	pacer.startBurst(time.Second)
+
+	if len(recorder.gaps()) != 3 {
+		t.Errorf("Expected Close to write out all 3 sentences, got %d", len(recorder.gaps()))
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Errorf("Expected Close to skip pacing, took %v", elapsed)
+	}
+}
+
+func TestPaceOutput(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Quiet = true
+	config.BaudRate = 4800
+	buffer := &bytes.Buffer{}
+
+	// Unpaced output is written straight to the writer
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	if sim.pacer != nil || sim.nmeaWriter != buffer {
+		t.Error("Expected output not to be paced unless PaceOutput is set")
+	}
+	sim.Close()
+
+	config.PaceOutput = true
+	recorder := &recordingWriter{}
+	sim, err = NewGPSSimulator(config, recorder)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+	sim.tick()
+	time.Sleep(300 * time.Millisecond)
+	sim.Close()
+
+	// At 4800 baud each sentence arrives after the one before has been sent
+	recorder.mu.Lock()
+	writes, times := recorder.writes, recorder.times
+	recorder.mu.Unlock()
+	if len(writes) < 2 || !strings.HasPrefix(writes[0], "$GPGGA") {
+		t.Fatalf("Expected a paced burst starting with GGA, got %q", writes)
+	}
+	sendTime := time.Duration(len(writes[0])) * time.Second * serialBitsPerByte / 4800
+	if gap := times[1].Sub(times[0]); gap < sendTime-10*time.Millisecond {
+		t.Errorf("Expected the second sentence about %v after the first, got %v", sendTime, gap)
+	}
+}
+
+func TestPaceOutputAfterClose(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Quiet = true
+	config.PaceOutput = true
+
+	// Pacing needs a baud rate, and NewGPSSimulator does not call Validate
+	config.BaudRate = 0
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for paced output without a baud rate")
+	}
+
+	config.BaudRate = 9600
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+	sim.Close()
+
+	// Neither a tick nor a step after Close sends on the closed queue
+	sim.tick()
+	sim.Step()
+}
@@ -0,0 +1,300 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Movement patterns describing the path the receiver follows around the
+// center when not replaying or following a route
+const (
+	PatternWander      = "wander"       // Default movement selected by RouteMode
+	PatternCircle      = "circle"       // Clockwise circle of Radius around the center
+	PatternFigureEight = "figure-eight" // Two circles of half Radius meeting at the center
+	PatternGrid        = "grid"         // Lawnmower survey of the square within Radius of the center
+	PatternRandomWalk  = "random-walk"  // Randomly turning walk within Radius of the center
+)
+
+// Patterns lists the supported movement patterns
+var Patterns = []string{PatternWander, PatternCircle, PatternFigureEight, PatternGrid, PatternRandomWalk}
+
+// MovementState is the receiver's ideal position and motion before jitter
+type MovementState struct {
+	Lat    float64 // Decimal degrees
+	Lon    float64 // Decimal degrees
+	Course float64 // Degrees from true north
+	Speed  float64 // Knots
+}
+
+// MovementPattern moves the receiver along a path. NextPosition returns the
+// ideal position, course and speed dt after current; jitter is applied on
+// top by the simulator.
+type MovementPattern interface {
+	NextPosition(current MovementState, dt time.Duration) (lat, lon, course, speed float64)
+}
+
+// ParsePattern converts a movement pattern name (case-insensitive) to its
+// canonical form. An empty name is wander.
+func ParsePattern(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return PatternWander, nil
+	}
+	for _, pattern := range Patterns {
+		if normalized == pattern {
+			return pattern, nil
+		}
+	}
+	return "", fmt.Errorf("unknown movement pattern %q (valid: %s)", name, strings.Join(Patterns, ", "))
+}
+
+// pathPattern moves at the current speed along a path given by its east and
+// north offsets in meters from a center at each distance traveled. It first
+// drives straight from wherever the receiver is to the start of the path.
+type pathPattern struct {
+	center   Coordinate
+	joined   bool    // Whether the receiver has reached the start of the path
+	traveled float64 // Meters traveled along the path
+	path     func(distance float64) (east, north float64)
+}
+
+// NextPosition advances along the path, heading along its direction of travel
+func (p *pathPattern) NextPosition(current MovementState, dt time.Duration) (float64, float64, float64, float64) {
+	// 1 knot = 0.514444 m/s
+	distance := current.Speed * 0.514444 * dt.Seconds()
+
+	if !p.joined {
+		east, north := centerOffset(p.center, current.Lat, current.Lon)
+		startEast, startNorth := p.path(0)
+		gap := math.Hypot(startEast-east, startNorth-north)
+		if distance < gap {
+			// Still on the way to the start of the path
+			fraction := distance / gap
+			course := math.Mod(math.Atan2(startEast-east, startNorth-north)*180/math.Pi+360, 360)
+			lat, lon := offsetCoordinate(p.center, east+(startEast-east)*fraction, north+(startNorth-north)*fraction)
+			return lat, lon, course, current.Speed
+		}
+		p.joined = true
+		distance -= gap
+	}
+	p.traveled += distance
+
+	east, north := p.path(p.traveled)
+	aheadEast, aheadNorth := p.path(p.traveled + 0.1)
+	course := math.Mod(math.Atan2(aheadEast-east, aheadNorth-north)*180/math.Pi+360, 360)
+
+	lat, lon := offsetCoordinate(p.center, east, north)
+	return lat, lon, course, current.Speed
+}
+
+// offsetCoordinate returns the position east and north meters from center,
+// using the same flat-earth approximation as wandering
+func offsetCoordinate(center Coordinate, east, north float64) (float64, float64) {
+	return normalizeCoordinate(center.Lat+north/111320.0, center.Lon+east/metersPerLonDegree(center.Lat))
+}
+
+// NewCirclePattern returns a pattern driving clockwise around a circle of
+// radius meters, joining it due north of the center. At speed v knots a
+// revolution takes 2πr / (v × 0.514444) seconds.
+func NewCirclePattern(center Coordinate, radius float64) MovementPattern {
+	return &pathPattern{
+		center: center,
+		path: func(distance float64) (float64, float64) {
+			angle := distance / radius
+			return radius * math.Sin(angle), radius * math.Cos(angle)
+		},
+	}
+}
+
+// NewFigureEightPattern returns a pattern driving a figure-eight of two
+// circles of radius/2 that meet at the center: clockwise around the northern
+// loop, then anticlockwise around the southern one
+func NewFigureEightPattern(center Coordinate, radius float64) MovementPattern {
+	loop := radius / 2
+	circumference := 2 * math.Pi * loop
+	return &pathPattern{
+		center: center,
+		path: func(distance float64) (float64, float64) {
+			distance = math.Mod(distance, 2*circumference)
+			if distance < circumference {
+				// Clockwise from the bottom of the northern loop
+				angle := math.Pi + distance/loop
+				return loop * math.Sin(angle), loop + loop*math.Cos(angle)
+			}
+			// Anticlockwise from the top of the southern loop
+			angle := -(distance - circumference) / loop
+			return loop * math.Sin(angle), -loop + loop*math.Cos(angle)
+		},
+	}
+}
+
+// NewGridPattern returns a lawnmower survey of the square within radius
+// meters of the center: north-south lines spacing meters apart, worked from
+// the west edge to the east and then back again
+func NewGridPattern(center Coordinate, radius, spacing float64) MovementPattern {
+	side := 2 * radius
+	lines := int(side/spacing) + 1
+	sweep := float64(lines)*side + float64(lines-1)*spacing
+	return &pathPattern{
+		center: center,
+		path: func(distance float64) (float64, float64) {
+			// Retrace the survey backwards after finishing it
+			distance = math.Mod(distance, 2*sweep)
+			if distance > sweep {
+				distance = 2*sweep - distance
+			}
+
+			// Each line is followed by a step east to the next one
+			leg := side + spacing
+			line := int(distance / leg)
+			if line >= lines {
+				line = lines - 1
+			}
+			along := distance - float64(line)*leg
+			east := -radius + float64(line)*spacing
+			if along > side {
+				east += along - side
+				along = side
+			}
+			if line%2 == 0 {
+				return east, -radius + along
+			}
+			return east, radius - along
+		},
+	}
+}
+
+// gridSpacing returns the distance between grid survey lines in meters
+func (c Config) gridSpacing() float64 {
+	if c.GridSpacing > 0 {
+		return c.GridSpacing
+	}
+	return c.Radius / 5
+}
+
+// centerOffset returns the east and north offsets in meters of a position
+// from center, the inverse of offsetCoordinate
+func centerOffset(center Coordinate, lat, lon float64) (float64, float64) {
+	east := math.Remainder(lon-center.Lon, 360) * metersPerLonDegree(center.Lat)
+	return east, (lat - center.Lat) * 111320.0
+}
+
+// randomWalkTurn is the standard deviation in degrees of the random walk's
+// change of course over one second
+const randomWalkTurn = 30.0
+
+// randomWalkPattern walks at the current speed, turning by a random amount
+// each step and heading back towards the center whenever it reaches radius
+type randomWalkPattern struct {
+	center Coordinate
+	radius float64
+	rng    *rand.Rand
+}
+
+// NewRandomWalkPattern returns a pattern walking randomly within radius
+// meters of the center. Its turns are drawn from seed and scale with the
+// square root of the step, so the spread of headings over time does not
+// depend on the update rate.
+func NewRandomWalkPattern(center Coordinate, radius float64, seed int64) MovementPattern {
+	return &randomWalkPattern{
+		center: center,
+		radius: radius,
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// NextPosition turns by a random amount and moves along the new course
+func (p *randomWalkPattern) NextPosition(current MovementState, dt time.Duration) (float64, float64, float64, float64) {
+	east, north := centerOffset(p.center, current.Lat, current.Lon)
+
+	course := current.Course + p.rng.NormFloat64()*randomWalkTurn*math.Sqrt(dt.Seconds())
+	if math.Hypot(east, north) >= p.radius {
+		// Head back inside rather than straying further out
+		course = math.Atan2(-east, -north) * 180 / math.Pi
+	}
+	course = math.Mod(math.Mod(course, 360)+360, 360)
+
+	// 1 knot = 0.514444 m/s
+	distance := current.Speed * 0.514444 * dt.Seconds()
+	east += distance * math.Sin(course*math.Pi/180)
+	north += distance * math.Cos(course*math.Pi/180)
+
+	lat, lon := offsetCoordinate(p.center, east, north)
+	return lat, lon, course, current.Speed
+}
+
+// wanderPattern is the default movement: drifting along Course at Speed, both
+// varied by the simulator's jitter, and bouncing off Radius around the center.
+// Its path already includes the jitter, so none is added on top.
+type wanderPattern struct {
+	sim *GPSSimulator
+}
+
+// NextPosition draws this output cycle's speed and course and moves along them
+func (p *wanderPattern) NextPosition(current MovementState, dt time.Duration) (float64, float64, float64, float64) {
+	p.sim.updateSpeedAndCourse()
+	lat, lon := p.sim.wanderPosition(current.Lat, current.Lon, dt.Seconds())
+	return lat, lon, p.sim.currentCourse, p.sim.currentSpeed
+}
+
+// newMovementPattern returns the configured movement pattern around the
+// center
+func (s *GPSSimulator) newMovementPattern() MovementPattern {
+	center := Coordinate{Lat: s.Config.Latitude, Lon: s.Config.Longitude}
+	pattern, _ := ParsePattern(s.Config.Pattern)
+	switch pattern {
+	case PatternCircle:
+		return NewCirclePattern(center, s.Config.Radius)
+	case PatternFigureEight:
+		return NewFigureEightPattern(center, s.Config.Radius)
+	case PatternGrid:
+		return NewGridPattern(center, s.Config.Radius, s.Config.gridSpacing())
+	case PatternRandomWalk:
+		return NewRandomWalkPattern(center, s.Config.Radius, s.random().Int63())
+	}
+	return &wanderPattern{sim: s}
+}
+
+// movementPattern returns the movement pattern being followed, building it
+// from the receiver's current position on first use
+func (s *GPSSimulator) movementPattern() MovementPattern {
+	if s.pattern == nil {
+		s.pattern = s.newMovementPattern()
+		s.patternPosition = Coordinate{Lat: s.currentLat, Lon: s.currentLon}
+	}
+	return s.pattern
+}
+
+// updatePatternPosition moves along the movement pattern at Speed, with
+// jitter applied around the ideal position
+func (s *GPSSimulator) updatePatternPosition() {
+	pattern := s.movementPattern()
+
+	now := s.now()
+	dt := now.Sub(s.lastUpdateTime)
+	s.lastUpdateTime = now
+
+	// If no time has passed, don't update position
+	if dt <= 0 {
+		return
+	}
+
+	current := MovementState{
+		Lat:    s.patternPosition.Lat,
+		Lon:    s.patternPosition.Lon,
+		Course: s.currentCourse,
+		Speed:  s.Config.Speed,
+	}
+	lat, lon, course, speed := pattern.NextPosition(current, dt)
+	s.patternPosition = Coordinate{Lat: lat, Lon: lon}
+	s.currentCourse = course
+	s.currentSpeed = speed
+	if _, wander := pattern.(*wanderPattern); wander {
+		s.currentLat, s.currentLon = lat, lon
+		return
+	}
+	s.currentLat, s.currentLon = s.jitterPosition(lat, lon)
+}
@@ -0,0 +1,252 @@
+package gps
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// patternOffset returns the flat-earth east and north offsets in meters of
+// a position from center
+func patternOffset(center Coordinate, lat, lon float64) (float64, float64) {
+	north := (lat - center.Lat) * 111320.0
+	east := (lon - center.Lon) * 111320.0 * math.Cos(center.Lat*math.Pi/180.0)
+	return east, north
+}
+
+func TestCirclePatternRevolution(t *testing.T) {
+	center := Coordinate{Lat: 37.7749, Lon: -122.4194}
+	pattern := NewCirclePattern(center, 100)
+	lat, lon := offsetCoordinate(center, 0, 100)
+	state := MovementState{Lat: lat, Lon: lon, Speed: 10}
+
+	// One revolution of a 100m circle at 10 knots
+	seconds := 2 * math.Pi * 100 / (10 * 0.514444)
+	period := time.Duration(seconds * float64(time.Second))
+
+	tests := []struct {
+		east, north float64
+		course      float64
+	}{
+		{100, 0, 180},
+		{0, -100, 270},
+		{-100, 0, 0},
+		{0, 100, 90},
+	}
+	for i, tt := range tests {
+		lat, lon, course, speed := pattern.NextPosition(state, period/4)
+		east, north := patternOffset(center, lat, lon)
+		if math.Abs(east-tt.east) > 0.01 || math.Abs(north-tt.north) > 0.01 {
+			t.Errorf("Quarter %d: expected offset (%.0f, %.0f), got (%.2f, %.2f)", i+1, tt.east, tt.north, east, north)
+		}
+		if diff := math.Abs(math.Mod(course-tt.course+540, 360) - 180); diff > 0.1 {
+			t.Errorf("Quarter %d: expected course %.0f, got %.2f", i+1, tt.course, course)
+		}
+		if speed != 10 {
+			t.Errorf("Expected speed 10, got %f", speed)
+		}
+	}
+}
+
+func TestCirclePatternStaysWithinRadius(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Pattern = PatternCircle
+	sim.Config.Radius = 100
+	sim.Config.Speed = 10
+	sim.Config.Jitter = 0.3
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+	center := Coordinate{Lat: sim.Config.Latitude, Lon: sim.Config.Longitude}
+	sim.currentLat, sim.currentLon = offsetCoordinate(center, 0, 100)
+
+	// Jitter of 0.3 moves the receiver up to 3m off the circle
+	maxJitter := 10.0 * sim.Config.Jitter
+	for i := 0; i < 150; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+		distance := sim.distanceFromCenter(sim.currentLat, sim.currentLon)
+		if math.Abs(distance-100) > maxJitter+0.5 {
+			t.Fatalf("Step %d: expected to stay within 100m ± %.0fm of the center, got %.2fm", i, maxJitter, distance)
+		}
+	}
+}
+
+func TestCirclePatternRampsIn(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Pattern = PatternCircle
+	sim.Config.Radius = 100
+	sim.Config.Speed = 10
+	sim.Config.Jitter = 0
+	sim.Config.FixQuality = FixQualityRTKFixed
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+
+	// Starting from the center, the receiver drives out to the circle at
+	// 10 knots rather than jumping onto it
+	step := 10 * 0.514444
+	previous := 0.0
+	for i := 0; i < 25; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+		distance := sim.distanceFromCenter(sim.currentLat, sim.currentLon)
+		if distance-previous > step+0.5 {
+			t.Fatalf("Step %d: expected to move at most %.1fm from the center, jumped from %.2fm to %.2fm", i, step, previous, distance)
+		}
+		previous = distance
+	}
+	if math.Abs(previous-100) > 0.5 {
+		t.Errorf("Expected to reach the circle, got %.2fm from the center", previous)
+	}
+}
+
+func TestRandomWalkPattern(t *testing.T) {
+	center := Coordinate{Lat: 37.7749, Lon: -122.4194}
+	state := MovementState{Lat: center.Lat, Lon: center.Lon, Speed: 10}
+	walk := NewRandomWalkPattern(center, 100, 1)
+	again := NewRandomWalkPattern(center, 100, 1)
+	step := 10 * 0.514444
+
+	courses := map[int]bool{}
+	for i := 0; i < 1000; i++ {
+		lat, lon, course, speed := walk.NextPosition(state, time.Second)
+		if lat2, lon2, course2, _ := again.NextPosition(state, time.Second); lat2 != lat || lon2 != lon || course2 != course {
+			t.Fatalf("Step %d: expected the same walk from the same seed", i)
+		}
+		if speed != 10 {
+			t.Fatalf("Step %d: expected speed 10, got %f", i, speed)
+		}
+
+		// Each step covers the distance at speed, never straying more than
+		// one step outside the radius
+		east, north := patternOffset(center, lat, lon)
+		fromEast, fromNorth := patternOffset(center, state.Lat, state.Lon)
+		if moved := math.Hypot(east-fromEast, north-fromNorth); math.Abs(moved-step) > 0.01 {
+			t.Fatalf("Step %d: expected to move %.2fm, moved %.2fm", i, step, moved)
+		}
+		if distance := math.Hypot(east, north); distance > 100+step+0.01 {
+			t.Fatalf("Step %d: expected to stay within 100m, got %.2fm", i, distance)
+		}
+
+		courses[int(course/90)] = true
+		state = MovementState{Lat: lat, Lon: lon, Course: course, Speed: 10}
+	}
+	if len(courses) != 4 {
+		t.Errorf("Expected the walk to head in every direction, got quadrants %v", courses)
+	}
+}
+
+func TestFigureEightPattern(t *testing.T) {
+	center := Coordinate{Lat: 37.7749, Lon: -122.4194}
+	pattern := NewFigureEightPattern(center, 100)
+	state := MovementState{Lat: center.Lat, Lon: center.Lon, Speed: 10}
+	loop := 2 * math.Pi * 50 // Length of each loop in meters
+	step := time.Duration(loop / 8 / (10 * 0.514444) * float64(time.Second))
+
+	for i := 1; i <= 16; i++ {
+		lat, lon, _, _ := pattern.NextPosition(state, step)
+		east, north := patternOffset(center, lat, lon)
+		if math.Hypot(east, north) > 100.01 {
+			t.Errorf("Step %d: expected to stay within 100m, got (%.2f, %.2f)", i, east, north)
+		}
+
+		switch i {
+		case 4:
+			// Top of the northern loop
+			if math.Abs(east) > 0.01 || math.Abs(north-100) > 0.01 {
+				t.Errorf("Expected the top of the northern loop, got (%.2f, %.2f)", east, north)
+			}
+		case 8, 16:
+			// Back through the center
+			if math.Hypot(east, north) > 0.01 {
+				t.Errorf("Step %d: expected to pass through the center, got (%.2f, %.2f)", i, east, north)
+			}
+		case 12:
+			// Bottom of the southern loop
+			if math.Abs(east) > 0.01 || math.Abs(north+100) > 0.01 {
+				t.Errorf("Expected the bottom of the southern loop, got (%.2f, %.2f)", east, north)
+			}
+		}
+	}
+}
+
+func TestGridPattern(t *testing.T) {
+	center := Coordinate{Lat: 37.7749, Lon: -122.4194}
+	pattern := NewGridPattern(center, 100, 50)
+	lat, lon := offsetCoordinate(center, -100, -100)
+	state := MovementState{Lat: lat, Lon: lon, Speed: 10}
+	speed := 10 * 0.514444 // Meters per second
+	meter := time.Duration(float64(time.Second) / speed)
+
+	tests := []struct {
+		distance    float64 // Meters from the start
+		east, north float64
+		course      float64
+	}{
+		{100, -100, 0, 0},   // Halfway up the first line
+		{225, -75, 100, 90}, // Stepping east to the second line
+		{350, -50, 0, 180},  // Halfway down the second line
+		{1200, 100, 100, 0}, // End of the fifth and last line
+		{1300, 100, 0, 180}, // Retracing the last line
+	}
+	traveled := 0.0
+	for _, tt := range tests {
+		lat, lon, course, _ := pattern.NextPosition(state, time.Duration(tt.distance-traveled)*meter)
+		traveled = tt.distance
+		east, north := patternOffset(center, lat, lon)
+		if math.Abs(east-tt.east) > 0.01 || math.Abs(north-tt.north) > 0.01 {
+			t.Errorf("After %.0fm: expected (%.0f, %.0f), got (%.2f, %.2f)", tt.distance, tt.east, tt.north, east, north)
+		}
+		if tt.distance != 1200 && math.Abs(course-tt.course) > 0.1 {
+			t.Errorf("After %.0fm: expected course %.0f, got %.2f", tt.distance, tt.course, course)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		valid    bool
+	}{
+		{"", PatternWander, true},
+		{"Circle", PatternCircle, true},
+		{" figure-eight ", PatternFigureEight, true},
+		{"GRID", PatternGrid, true},
+		{"random-walk", PatternRandomWalk, true},
+		{"spiral", "", false},
+	}
+	for _, tt := range tests {
+		pattern, err := ParsePattern(tt.name)
+		if (err == nil) != tt.valid || pattern != tt.expected {
+			t.Errorf("ParsePattern(%q) = %q, %v; expected %q (valid %v)", tt.name, pattern, err, tt.expected, tt.valid)
+		}
+	}
+}
+
+func TestPatternValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		valid     bool
+	}{
+		{"Circle", func(c *Config) { c.Pattern = PatternCircle }, true},
+		{"Unknown pattern", func(c *Config) { c.Pattern = "spiral" }, false},
+		{"Circle without radius", func(c *Config) { c.Pattern = PatternCircle; c.Radius = 0 }, false},
+		{"Random walk without radius", func(c *Config) { c.Pattern = PatternRandomWalk; c.Radius = 0 }, false},
+		{"With road-like mode", func(c *Config) { c.Pattern = PatternGrid; c.RouteMode = RouteModeRoadlike }, false},
+		{"Negative grid spacing", func(c *Config) { c.Pattern = PatternGrid; c.GridSpacing = -1 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			tt.configure(&config)
+			if err := config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
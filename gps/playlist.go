@@ -0,0 +1,178 @@
+package gps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Replay gap behaviors describing how a playlist moves from the end of one
+// file to the start of the next
+const (
+	ReplayGapJump     = "jump"     // Move straight to the next file's first point
+	ReplayGapSimulate = "simulate" // Drive from the end of one file to the start of the next at Speed
+)
+
+// ReplayGapBehaviors lists the supported replay gap behaviors
+var ReplayGapBehaviors = []string{ReplayGapJump, ReplayGapSimulate}
+
+// ParseReplayGapBehavior converts a replay gap behavior name
+// (case-insensitive) to its canonical form. An empty name is jump.
+func ParseReplayGapBehavior(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return ReplayGapJump, nil
+	}
+	for _, behavior := range ReplayGapBehaviors {
+		if normalized == behavior {
+			return behavior, nil
+		}
+	}
+	return "", fmt.Errorf("unknown replay gap behavior %q (valid: %s)", name, strings.Join(ReplayGapBehaviors, ", "))
+}
+
+// ParseReplayFiles parses a comma-separated list of replay files into a
+// playlist. Each entry may be a glob pattern, expanded in sorted order; a
+// pattern matching nothing is kept as written so loading it reports the
+// missing file.
+func ParseReplayFiles(spec string) ([]string, error) {
+	var files []string
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			return nil, fmt.Errorf("empty replay file in %q", spec)
+		}
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replay file pattern %q: %v", entry, err)
+		}
+		if len(matches) == 0 {
+			matches = []string{entry}
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// replayPlaylist returns the files to replay in order: ReplayFiles, or
+// ReplayFile on its own. A reversed replay plays the files in reverse too.
+func (c Config) replayPlaylist() []string {
+	files := c.ReplayFiles
+	if len(files) == 0 && c.ReplayFile != "" {
+		files = []string{c.ReplayFile}
+	}
+	if !c.ReplayReverse {
+		return files
+	}
+	reversed := make([]string, len(files))
+	for i, file := range files {
+		reversed[len(files)-1-i] = file
+	}
+	return reversed
+}
+
+// replayTracks returns the number of tracks replayed in turn: the playlist
+// files, or one for ReplayPoints
+func (c Config) replayTracks() int {
+	if len(c.ReplayPoints) > 0 {
+		return 1
+	}
+	return len(c.replayPlaylist())
+}
+
+// isReplayMode reports whether the simulator replays recorded tracks
+func (s *GPSSimulator) isReplayMode() bool {
+	return s.Config.ReplayFile != "" || len(s.Config.ReplayFiles) > 0 || len(s.Config.ReplayPoints) > 0
+}
+
+// checkReplayFiles reports a playlist file that does not exist, so a bad
+// playlist fails when the simulator is created rather than part way through
+func checkReplayFiles(files []string) error {
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("replay file %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// loadReplayFile reads playlist file i and makes it the active replay track,
+// starting from its first point
+func (s *GPSSimulator) loadReplayFile(i int) error {
+	file := s.Config.replayPlaylist()[i]
+	points, err := ReadTrackFile(file)
+	if err != nil {
+		return err
+	}
+	s.loadReplayPoints(points, i)
+	return nil
+}
+
+// loadReplayPoints makes points the active replay track, as track i of the
+// playlist, starting from its first point
+func (s *GPSSimulator) loadReplayPoints(points []TrackPoint, i int) {
+	if !s.Config.ReplaySegmentGaps {
+		points = collapseSegmentGaps(points)
+	}
+	if s.Config.ReplayReverse {
+		points = reverseTrackPoints(points)
+	}
+
+	s.replayPoints = points
+	s.replayStats = s.trackStats(points)
+	s.replayFileIndex = i
+	s.replayIndex = 0
+	if len(points) > 0 {
+		s.replaySegment = points[0].Segment
+	}
+}
+
+// startReplayFile moves replay on to playlist file i. With the simulate gap
+// behavior the receiver first drives to the file's first point, and the
+// file's replay clock starts on arrival.
+func (s *GPSSimulator) startReplayFile(i int, now time.Time) {
+	if err := s.loadReplayFile(i); err != nil {
+		// End the replay rather than retrying the file on every update
+		fmt.Fprintf(os.Stderr, "Warning: failed to load replay file: %v\n", err)
+		s.replayPoints = nil
+		s.replayCompleted = true
+		s.Config.ReplayLoop = false
+		return
+	}
+
+	first := s.replayPoints[0]
+	s.replayStartTime = now
+	s.lastUpdateTime = now
+	s.currentAlt = first.Elevation
+
+	if behavior, _ := ParseReplayGapBehavior(s.Config.ReplayGapBehavior); behavior == ReplayGapSimulate {
+		s.startTransition(Coordinate{Lat: first.Lat, Lon: first.Lon})
+		return
+	}
+	s.currentLat = first.Lat
+	s.currentLon = first.Lon
+}
+
+// updateReplayGap drives toward the next playlist file, starting its replay
+// clock once the receiver arrives
+func (s *GPSSimulator) updateReplayGap(now time.Time) {
+	s.updateTransition()
+	if s.transition == nil {
+		s.replayStartTime = now
+	}
+}
+
+// replayFileName returns the playlist file being replayed, empty when
+// replaying ReplayPoints
+func (s *GPSSimulator) replayFileName() string {
+	if len(s.Config.ReplayPoints) > 0 {
+		return ""
+	}
+	playlist := s.Config.replayPlaylist()
+	if s.replayFileIndex < len(playlist) {
+		return playlist[s.replayFileIndex]
+	}
+	return ""
+}
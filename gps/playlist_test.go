@@ -0,0 +1,226 @@
+package gps
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// playlistFiles are two consecutive legs, the second starting about 900 m
+// east of where the first ends
+var playlistFiles = []string{
+	filepath.Join("testdata", "leg1.gpx"),
+	filepath.Join("testdata", "leg2.gpx"),
+}
+
+// newPlaylistSimulator returns a locked simulator replaying playlistFiles on a fake clock
+func newPlaylistSimulator(t *testing.T, configure func(*Config)) (*GPSSimulator, *fakeClock) {
+	config := createTestConfig()
+	config.ReplayFiles = playlistFiles
+	config.ReplaySpeed = 1.0
+	config.TimeToLock = 0
+	config.Quiet = true
+	if configure != nil {
+		configure(&config)
+	}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.isLocked = true
+	sim.replayStartTime = clock.Now()
+	sim.lastUpdateTime = clock.Now()
+	return sim, clock
+}
+
+// replayVisit is a replay track point reached during a playlist
+type replayVisit struct {
+	File  int
+	Index int
+}
+
+// playlistVisits steps sim one second at a time for the given number of
+// seconds, recording each distinct track point reached
+func playlistVisits(sim *GPSSimulator, clock *fakeClock, seconds int) []replayVisit {
+	var visits []replayVisit
+	for i := 0; i < seconds; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+
+		snapshot := sim.Snapshot()
+		if snapshot.ReplayIndex >= len(sim.replayPoints) || sim.transition != nil {
+			continue
+		}
+		visit := replayVisit{snapshot.ReplayFileIndex, snapshot.ReplayIndex}
+		if len(visits) == 0 || visits[len(visits)-1] != visit {
+			visits = append(visits, visit)
+		}
+	}
+	return visits
+}
+
+func TestReplayPlaylistOrder(t *testing.T) {
+	sim, clock := newPlaylistSimulator(t, nil)
+
+	if snapshot := sim.Snapshot(); snapshot.ReplayFile != playlistFiles[0] || snapshot.ReplayFileIndex != 0 {
+		t.Errorf("Expected to start on %s, got %s (%d)", playlistFiles[0], snapshot.ReplayFile, snapshot.ReplayFileIndex)
+	}
+
+	visits := playlistVisits(sim, clock, 60)
+	expected := []replayVisit{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("Expected track points %v, got %v", expected, visits)
+	}
+
+	snapshot := sim.Snapshot()
+	if snapshot.ReplayFile != playlistFiles[1] || !snapshot.ReplayCompleted {
+		t.Errorf("Expected the playlist to complete on %s, got %s (completed=%v)", playlistFiles[1], snapshot.ReplayFile, snapshot.ReplayCompleted)
+	}
+	if _, done := sim.completion(); !done {
+		t.Error("Expected the replay to be complete after the last file")
+	}
+}
+
+func TestReplayPlaylistJump(t *testing.T) {
+	sim, clock := newPlaylistSimulator(t, nil)
+
+	// The first update past the end of the first file jumps to the second
+	clock.Advance(21 * time.Second)
+	sim.update()
+
+	first := sim.replayPoints[0]
+	if sim.replayFileIndex != 1 || sim.currentLat != first.Lat || sim.currentLon != first.Lon || sim.currentAlt != first.Elevation {
+		t.Errorf("Expected to jump to the start of the second file, got file %d at %f, %f, %.1fm",
+			sim.replayFileIndex, sim.currentLat, sim.currentLon, sim.currentAlt)
+	}
+}
+
+func TestReplayPlaylistSimulatedGap(t *testing.T) {
+	sim, clock := newPlaylistSimulator(t, func(config *Config) {
+		config.ReplayGapBehavior = ReplayGapSimulate
+		config.Speed = 100 // Knots, about 51 m/s
+	})
+
+	clock.Advance(21 * time.Second)
+	sim.update()
+	if !sim.IsTransitioning() {
+		t.Fatal("Expected to drive between files")
+	}
+
+	// Drive the ~900 m gap east at Speed without starting the next file
+	start := sim.replayPoints[0]
+	previousLon := sim.currentLon
+	for sim.transition != nil {
+		clock.Advance(time.Second)
+		sim.update()
+		if sim.currentLon < previousLon {
+			t.Fatalf("Expected to head east toward the next file, went from %f to %f", previousLon, sim.currentLon)
+		}
+		previousLon = sim.currentLon
+		if sim.transition != nil && (sim.currentSpeed < 99 || sim.currentSpeed > 101) {
+			t.Errorf("Expected to drive at 100 knots, got %.1f", sim.currentSpeed)
+		}
+	}
+	if sim.currentLat != start.Lat || sim.currentLon != start.Lon {
+		t.Errorf("Expected to arrive at the start of the next file, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+
+	// The next file plays from its first point once there
+	visits := playlistVisits(sim, clock, 25)
+	expected := []replayVisit{{1, 0}, {1, 1}, {1, 2}}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("Expected track points %v after the gap, got %v", expected, visits)
+	}
+}
+
+func TestReplayPlaylistLoop(t *testing.T) {
+	sim, clock := newPlaylistSimulator(t, func(config *Config) {
+		config.ReplayLoop = true
+	})
+
+	visits := playlistVisits(sim, clock, 70)
+	if len(visits) < 8 || visits[6] != (replayVisit{0, 0}) || visits[7] != (replayVisit{0, 1}) {
+		t.Errorf("Expected the whole playlist to loop back to the first file, got %v", visits)
+	}
+	if !sim.replayCompleted {
+		t.Error("Expected the first pass to be marked complete")
+	}
+	if _, done := sim.completion(); done {
+		t.Error("Expected a looping playlist not to complete")
+	}
+}
+
+func TestReplayPlaylistReverse(t *testing.T) {
+	sim, clock := newPlaylistSimulator(t, func(config *Config) {
+		config.ReplayReverse = true
+	})
+
+	if sim.Snapshot().ReplayFile != playlistFiles[1] {
+		t.Errorf("Expected a reversed playlist to start with %s, got %s", playlistFiles[1], sim.Snapshot().ReplayFile)
+	}
+	visits := playlistVisits(sim, clock, 60)
+	expected := []replayVisit{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}}
+	if !reflect.DeepEqual(visits, expected) {
+		t.Errorf("Expected track points %v, got %v", expected, visits)
+	}
+	if sim.currentLat != 37.0 || sim.currentLon != -122.0 {
+		t.Errorf("Expected a reversed playlist to end at the start of the first leg, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+}
+
+func TestParseReplayFiles(t *testing.T) {
+	files, err := ParseReplayFiles(filepath.Join("testdata", "leg*.gpx"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, playlistFiles) {
+		t.Errorf("Expected glob to expand to %v, got %v", playlistFiles, files)
+	}
+
+	files, err = ParseReplayFiles("b.gpx, a.gpx")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{"b.gpx", "a.gpx"}) {
+		t.Errorf("Expected the listed order to be kept, got %v", files)
+	}
+
+	for _, spec := range []string{"", "a.gpx,", "[.gpx"} {
+		if _, err := ParseReplayFiles(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestReplayPlaylistValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.ReplayFiles = playlistFiles
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid playlist, got: %v", err)
+	}
+
+	config.ReplayFile = playlistFiles[0]
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for both a replay file and replay files")
+	}
+
+	config.ReplayFile = ""
+	config.ReplayGapBehavior = "teleport"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown replay gap behavior")
+	}
+
+	config.ReplayGapBehavior = ""
+	config.ReplayFiles = []string{playlistFiles[0], filepath.Join("testdata", "missing.gpx")}
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for a missing playlist file")
+	}
+}
@@ -0,0 +1,110 @@
+package gps
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentAccessWhileRunning exercises every entry point that may be
+// called from other goroutines while Run is active. Run it with -race to
+// check the shared state is guarded.
+func TestConcurrentAccessWhileRunning(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.OutputRate = 2 * time.Millisecond
+	config.TimeToLock = 10 * time.Millisecond
+	config.Duration = 200 * time.Millisecond
+	config.TCPListen = "127.0.0.1:0"
+	config.Quiet = true
+	sim, err := NewGPSSimulator(config, &lockedBuffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+
+	running := func() bool {
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	var wg sync.WaitGroup
+	hammer := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; running(); i++ {
+				fn(i)
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+
+	// Status reads
+	hammer(func(int) {
+		sim.Snapshot()
+		sim.IsPaused()
+		sim.IsSignalLost()
+		sim.IsTransitioning()
+	})
+
+	// Live configuration changes
+	hammer(func(i int) {
+		update := config
+		update.Speed = float64(i % 30)
+		update.Course = float64(i % 360)
+		update.Satellites = 4 + i%8
+		if err := sim.UpdateConfig(update); err != nil {
+			t.Errorf("UpdateConfig failed: %v", err)
+		}
+	})
+
+	// Pausing and resuming
+	hammer(func(i int) {
+		if i%2 == 0 {
+			sim.Pause()
+		} else {
+			sim.Resume()
+		}
+	})
+
+	// Callbacks registered while running
+	hammer(func(i int) {
+		if i < 10 {
+			sim.AddCallback(func(NMEAData) {})
+		}
+	})
+
+	// Sentence subscribers connecting and disconnecting
+	hammer(func(int) {
+		ch, cancel := sim.SubscribeSentences(4)
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Millisecond):
+		}
+		cancel()
+	})
+
+	// TCP clients connecting and disconnecting
+	hammer(func(int) {
+		conn, err := net.Dial("tcp", sim.TCPAddr().String())
+		if err != nil {
+			return // The server closes when Run finishes
+		}
+		conn.Close()
+	})
+
+	wg.Wait()
+	<-done
+}
@@ -0,0 +1,179 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// maxReplayUpload limits the size of an uploaded replay file
+const maxReplayUpload = 32 << 20
+
+// ReplayServer starts replays of GPX files uploaded over HTTP, one at a
+// time. Each upload stops the replay before it and starts a new simulator
+// from the base configuration, writing NMEA to the same writer.
+type ReplayServer struct {
+	base   Config
+	writer io.Writer
+
+	mu  sync.Mutex
+	sim *GPSSimulator
+}
+
+// ReplayBounds is the box enclosing a replay track, in decimal degrees
+type ReplayBounds struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// NewReplayServer returns a server starting replays from the base
+// configuration, for mounting at /api/replay
+func NewReplayServer(base Config, writer io.Writer) *ReplayServer {
+	return &ReplayServer{base: base, writer: writer}
+}
+
+// Simulator returns the simulator running the latest uploaded replay, or
+// nil before the first upload
+func (rs *ReplayServer) Simulator() *GPSSimulator {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.sim
+}
+
+// Stop stops the running replay, if any, and waits for it to finish
+func (rs *ReplayServer) Stop() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.stopLocked()
+}
+
+// stopLocked stops the running replay with rs.mu held
+func (rs *ReplayServer) stopLocked() {
+	if rs.sim == nil {
+		return
+	}
+	rs.sim.Stop()
+	<-rs.sim.Done()
+}
+
+// ServeHTTP accepts a multipart POST with the GPX track in the "file" field
+// and optional "speed" and "loop" fields setting ReplaySpeed and ReplayLoop.
+// It starts the replay and responds with its ReplayStats as JSON. The uploaded file is removed when the replay stops.
+func (rs *ReplayServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxReplayUpload)
+	if err := r.ParseMultipartForm(maxReplayUpload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing GPX file in the file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	suffix, ok := gpxUploadSuffix(header.Filename)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not a GPX file", header.Filename), http.StatusBadRequest)
+		return
+	}
+
+	config := rs.base
+	config.ReplayFiles = nil
+	config.ReplayPoints = nil
+	if speed := r.FormValue("speed"); speed != "" {
+		if config.ReplaySpeed, err = strconv.ParseFloat(speed, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid speed %q", speed), http.StatusBadRequest)
+			return
+		}
+	}
+	if loop := r.FormValue("loop"); loop != "" {
+		if config.ReplayLoop, err = strconv.ParseBool(loop); err != nil {
+			http.Error(w, fmt.Sprintf("invalid loop %q", loop), http.StatusBadRequest)
+			return
+		}
+	}
+
+	path, err := saveUpload(file, "", "replay-*"+suffix)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to save upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	config.ReplayFile = path
+
+	_, err = ReadGPXFile(path)
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Stop the previous replay first so it releases any network outputs
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.stopLocked()
+
+	sim, err := NewGPSSimulator(config, rs.writer)
+	if err != nil {
+		os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sim.OnComplete(func(string) { os.Remove(path) })
+	rs.sim = sim
+	go sim.Run()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sim.ReplayStats())
+}
+
+// saveUpload copies an uploaded file to a new temporary file in dir (the
+// default temporary directory when empty) matching pattern and returns its
+// path
+func saveUpload(src io.Reader, dir, pattern string) (string, error) {
+	dst, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// trackBounds returns the box enclosing the points
+func trackBounds(points []TrackPoint) ReplayBounds {
+	if len(points) == 0 {
+		return ReplayBounds{}
+	}
+	bounds := ReplayBounds{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+	for _, p := range points {
+		bounds.MinLat = math.Min(bounds.MinLat, p.Lat)
+		bounds.MinLon = math.Min(bounds.MinLon, p.Lon)
+		bounds.MaxLat = math.Max(bounds.MaxLat, p.Lat)
+		bounds.MaxLon = math.Max(bounds.MaxLon, p.Lon)
+	}
+	return bounds
+}
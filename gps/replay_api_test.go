@@ -0,0 +1,127 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const uploadGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test">
+  <trk><trkseg>
+    <trkpt lat="42.0" lon="-71.1"><ele>10</ele><time>2024-01-15T10:00:00Z</time></trkpt>
+    <trkpt lat="42.1" lon="-71.0"><ele>12</ele><time>2024-01-15T10:00:10Z</time></trkpt>
+    <trkpt lat="42.2" lon="-71.2"><ele>14</ele><time>2024-01-15T10:00:20Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+// replayUpload builds a multipart replay upload of a file with the given
+// name and contents and extra form fields
+func replayUpload(t *testing.T, filename, contents string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	io.WriteString(part, contents)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/replay", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestReplayServerUpload(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	server := NewReplayServer(config, io.Discard)
+	defer server.Stop()
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, replayUpload(t, "track.gpx", uploadGPX, map[string]string{"speed": "4", "loop": "true"}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var started ReplayStats
+	if err := json.NewDecoder(rec.Body).Decode(&started); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if started.Points != 3 || started.Duration != 20 || started.Distance <= 0 {
+		t.Errorf("Expected 3 points over 20 seconds with a distance, got %+v", started)
+	}
+	expected := ReplayBounds{MinLat: 42.0, MinLon: -71.2, MaxLat: 42.2, MaxLon: -71.0}
+	if started.Bounds != expected {
+		t.Errorf("Expected bounds %+v, got %+v", expected, started.Bounds)
+	}
+
+	sim := server.Simulator()
+	if sim == nil {
+		t.Fatal("Expected a running replay simulator")
+	}
+	if sim.Config.ReplaySpeed != 4 || !sim.Config.ReplayLoop {
+		t.Errorf("Expected replay speed 4 with loop, got %v and %v", sim.Config.ReplaySpeed, sim.Config.ReplayLoop)
+	}
+	path := sim.Config.ReplayFile
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected uploaded file at %s: %v", path, err)
+	}
+
+	// Stopping the replay removes the upload
+	server.Stop()
+	select {
+	case <-sim.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Replay did not stop")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected uploaded file to be removed, got %v", err)
+	}
+}
+
+func TestReplayServerRejects(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	server := NewReplayServer(config, io.Discard)
+	defer server.Stop()
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"GET", httptest.NewRequest(http.MethodGet, "/api/replay", nil), http.StatusMethodNotAllowed},
+		{"Not GPX", replayUpload(t, "track.txt", uploadGPX, nil), http.StatusBadRequest},
+		{"Invalid XML", replayUpload(t, "track.gpx", "<gpx", nil), http.StatusBadRequest},
+		{"Invalid speed", replayUpload(t, "track.gpx", uploadGPX, map[string]string{"speed": "fast"}), http.StatusBadRequest},
+		{"Negative speed", replayUpload(t, "track.gpx", uploadGPX, map[string]string{"speed": "-1"}), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.code {
+				t.Errorf("Expected status %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	if server.Simulator() != nil {
+		t.Error("Expected no replay to start from rejected uploads")
+	}
+}
@@ -0,0 +1,187 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const sequentialReplayGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="37.774900" lon="-122.419400"><ele>50.0</ele><time>2024-01-15T10:00:00Z</time></trkpt>
+    <trkpt lat="37.775000" lon="-122.419300"><ele>52.0</ele><time>2024-01-15T10:00:10Z</time></trkpt>
+    <trkpt lat="37.775200" lon="-122.419100"><ele>55.0</ele><time>2024-01-15T10:00:20Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+const indexedReplayGPX = `<?xml version="1.0"?>
+<gpx version="1.0" creator="test" xmlns="http://www.topografix.com/GPX/1/0">
+  <rte>
+    <rtept lat="42.430950" lon="-71.107628"><ele>23.5</ele><time>2001-11-28T21:05:28Z</time></rtept>
+    <rtept lat="42.431240" lon="-71.109236"><ele>26.6</ele><time>2001-06-02T03:26:55Z</time></rtept>
+    <rtept lat="42.432000" lon="-71.110000"><ele>30.0</ele><time>2001-12-01T12:00:00Z</time></rtept>
+  </rte>
+</gpx>`
+
+// createReplayPair returns two simulators replaying the same GPX track on
+// fake clocks, one from the file and one from its points in memory
+func createReplayPair(t *testing.T, gpx string, configure func(*Config)) (fromFile, fromMemory *GPSSimulator, clocks [2]*fakeClock) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "track.gpx")
+	if err := os.WriteFile(path, []byte(gpx), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+	points, err := ReadTrackFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read test GPX file: %v", err)
+	}
+
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	sims := make([]*GPSSimulator, 2)
+	for i := range sims {
+		config := createTestConfig()
+		config.BaudRate = 9600
+		config.ReplaySpeed = 1.0
+		configure(&config)
+		if i == 0 {
+			config.ReplayFile = path
+		} else {
+			config.ReplayPoints = points
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Validate() failed: %v", err)
+		}
+
+		sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("Failed to create GPS simulator: %v", err)
+		}
+		clocks[i] = newFakeClock(start)
+		sim.SetClock(clocks[i])
+		sim.replayStartTime = start
+		sim.lastUpdateTime = start
+		sims[i] = sim
+	}
+	return sims[0], sims[1], clocks
+}
+
+func TestReplayPointsParity(t *testing.T) {
+	tests := []struct {
+		name      string
+		gpx       string
+		configure func(*Config)
+		steps     int
+	}{
+		{"Sequential timestamps", sequentialReplayGPX, func(c *Config) {}, 25},
+		{"Sequential timestamps at 2x", sequentialReplayGPX, func(c *Config) { c.ReplaySpeed = 2 }, 15},
+		{"Interpolated", sequentialReplayGPX, func(c *Config) { c.ReplayInterpolate = true }, 25},
+		{"Looping", sequentialReplayGPX, func(c *Config) { c.ReplayLoop = true }, 50},
+		{"Index based", indexedReplayGPX, func(c *Config) {}, 5},
+		{"Index based looping at 2x", indexedReplayGPX, func(c *Config) {
+			c.ReplayLoop = true
+			c.ReplaySpeed = 2
+		}, 10},
+		{"Reversed", sequentialReplayGPX, func(c *Config) { c.ReplayReverse = true }, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromFile, fromMemory, clocks := createReplayPair(t, tt.gpx, tt.configure)
+
+			if fromFile.hasSequentialTimestamps() != fromMemory.hasSequentialTimestamps() {
+				t.Fatal("Sequential timestamp detection differs between file and memory")
+			}
+			for step := 0; step <= tt.steps; step++ {
+				for i, sim := range []*GPSSimulator{fromFile, fromMemory} {
+					if step > 0 {
+						clocks[i].Advance(time.Second)
+					}
+					sim.updateReplayPosition()
+				}
+
+				if math.Abs(fromFile.currentLat-fromMemory.currentLat) > 1e-12 ||
+					math.Abs(fromFile.currentLon-fromMemory.currentLon) > 1e-12 ||
+					fromFile.currentAlt != fromMemory.currentAlt {
+					t.Fatalf("Step %d: file at %f, %f, %f but memory at %f, %f, %f", step,
+						fromFile.currentLat, fromFile.currentLon, fromFile.currentAlt,
+						fromMemory.currentLat, fromMemory.currentLon, fromMemory.currentAlt)
+				}
+				if fromFile.replayIndex != fromMemory.replayIndex || fromFile.replayLoops != fromMemory.replayLoops {
+					t.Fatalf("Step %d: file at index %d loop %d but memory at index %d loop %d", step,
+						fromFile.replayIndex, fromFile.replayLoops, fromMemory.replayIndex, fromMemory.replayLoops)
+				}
+
+				fileMessage, fileDone := fromFile.completion()
+				memoryMessage, memoryDone := fromMemory.completion()
+				if fileDone != memoryDone || fileMessage != memoryMessage {
+					t.Fatalf("Step %d: file completion %q %v but memory completion %q %v", step,
+						fileMessage, fileDone, memoryMessage, memoryDone)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayPointsCompletion(t *testing.T) {
+	_, sim, clocks := createReplayPair(t, sequentialReplayGPX, func(c *Config) {})
+
+	clocks[1].Advance(25 * time.Second)
+	sim.updateReplayPosition()
+	if message, done := sim.completion(); !done || message != "GPX replay completed" {
+		t.Errorf("Expected the in-memory replay to complete, got %q %v", message, done)
+	}
+	if sim.replayFileName() != "" {
+		t.Errorf("Expected no replay file name, got %q", sim.replayFileName())
+	}
+}
+
+func TestReplayPointsPrecedence(t *testing.T) {
+	config := createTestConfig()
+	config.ReplayFile = "missing.gpx"
+	config.ReplayPoints = []TrackPoint{{Lat: 10, Lon: 20}, {Lat: 10.001, Lon: 20.001}}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Expected replay points to take precedence over the replay file, got: %v", err)
+	}
+	if sim.currentLat != 10 || sim.currentLon != 20 {
+		t.Errorf("Expected to start at the first replay point, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+
+	// The simulator keeps its own copy of the points
+	config.ReplayPoints[0].Lat = 50
+	if sim.replayPoints[0].Lat != 10 {
+		t.Error("Expected changes to the caller's points not to affect the replay")
+	}
+}
+
+func TestReplayPointsValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  []TrackPoint
+		wantErr bool
+	}{
+		{"Unset", nil, false},
+		{"Valid", []TrackPoint{{Lat: 10, Lon: 20}}, false},
+		{"Empty", []TrackPoint{}, true},
+		{"NaN latitude", []TrackPoint{{Lat: 10, Lon: 20}, {Lat: math.NaN(), Lon: 20}}, true},
+		{"NaN longitude", []TrackPoint{{Lat: 10, Lon: math.NaN()}}, true},
+		{"Out of range", []TrackPoint{{Lat: 95, Lon: 20}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			config.ReplayPoints = tt.points
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package gps
+
+// ReplayStats describes the replay track loaded, for fitting a map to it
+type ReplayStats struct {
+	Points   int          `json:"points"`
+	Distance float64      `json:"distance"` // Meters along the track, summed between consecutive points
+	Duration float64      `json:"duration"` // Seconds from the first to the last track point (0 without timestamps)
+	Bounds   ReplayBounds `json:"bounds"`
+}
+
+// ReplayStats returns the point count, distance, duration and bounds of the
+// replay track loaded, computed once as it is loaded. In a playlist they
+// describe the file being replayed. The stats are zero outside replay mode.
+func (s *GPSSimulator) ReplayStats() ReplayStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.replayStats
+}
+
+// trackStats computes the stats of a replay track
+func (s *GPSSimulator) trackStats(points []TrackPoint) ReplayStats {
+	stats := ReplayStats{Points: len(points), Bounds: trackBounds(points)}
+	for i := 1; i < len(points); i++ {
+		stats.Distance += s.calculateDistance(points[i-1].Lat, points[i-1].Lon, points[i].Lat, points[i].Lon)
+	}
+	if len(points) > 1 {
+		first, last := points[0].Time, points[len(points)-1].Time
+		if !first.IsZero() && last.After(first) {
+			stats.Duration = last.Sub(first).Seconds()
+		}
+	}
+	return stats
+}
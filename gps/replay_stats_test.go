@@ -0,0 +1,55 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+// loadReplayStats returns the stats of a simulator loading points for replay
+func loadReplayStats(t *testing.T, points []TrackPoint) ReplayStats {
+	t.Helper()
+	config := createTestConfig()
+	config.ReplayPoints = points
+	config.ReplaySpeed = 1.0
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+	return sim.ReplayStats()
+}
+
+func TestReplayStats(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	// East along the equator, then north along a meridian, 0.01 degrees each
+	points := []TrackPoint{
+		{Lat: 0, Lon: 0, Time: start},
+		{Lat: 0, Lon: 0.01, Time: start.Add(30 * time.Second)},
+		{Lat: 0.01, Lon: 0.01, Time: start.Add(90 * time.Second)},
+	}
+	stats := loadReplayStats(t, points)
+	leg := 6371000 * 0.01 * math.Pi / 180 // 1111.95 m
+	if math.Abs(stats.Distance-2*leg) > 1e-6 {
+		t.Errorf("Expected distance %.6f m, got %.6f m", 2*leg, stats.Distance)
+	}
+	if stats.Points != 3 || stats.Duration != 90 {
+		t.Errorf("Expected 3 points over 90 seconds, got %d over %v", stats.Points, stats.Duration)
+	}
+	expected := ReplayBounds{MinLat: 0, MinLon: 0, MaxLat: 0.01, MaxLon: 0.01}
+	if stats.Bounds != expected {
+		t.Errorf("Expected bounds %+v, got %+v", expected, stats.Bounds)
+	}
+}
+
+func TestReplayStatsWithoutTimestamps(t *testing.T) {
+	points := []TrackPoint{{Lat: 37.7749, Lon: -122.4194}, {Lat: 37.7759, Lon: -122.4194}}
+	if stats := loadReplayStats(t, points); stats.Duration != 0 || stats.Distance <= 0 {
+		t.Errorf("Expected a distance without a duration, got %+v", stats)
+	}
+
+	if stats := createTestSimulator().ReplayStats(); stats != (ReplayStats{}) {
+		t.Errorf("Expected zero stats outside replay mode, got %+v", stats)
+	}
+}
@@ -0,0 +1,178 @@
+package gps
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// maxReplayUploads is the number of uploads kept before the oldest is removed
+const maxReplayUploads = 16
+
+// ReplayUploads stores GPX tracks uploaded over HTTP in a temporary
+// directory so a later request can start a replay of one by its ID. Only the
+// most recent uploads are kept.
+type ReplayUploads struct {
+	dir string
+
+	mu      sync.Mutex
+	uploads map[string]storedUpload
+	order   []string // Upload IDs, oldest first
+}
+
+// storedUpload is a stored upload and its parsed track
+type storedUpload struct {
+	path   string
+	points []TrackPoint
+}
+
+// ReplayUploadSummary is the response to a replay upload
+type ReplayUploadSummary struct {
+	ReplayID string       `json:"replay_id"`
+	Points   int          `json:"points"`
+	Duration float64      `json:"duration"` // Seconds from the first to the last track point
+	Bounds   ReplayBounds `json:"bounds"`
+}
+
+// NewReplayUploads returns an upload store in a new temporary directory,
+// for mounting at /api/replay/upload
+func NewReplayUploads() (*ReplayUploads, error) {
+	dir, err := os.MkdirTemp("", "gps-replay-uploads-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	return &ReplayUploads{dir: dir, uploads: make(map[string]storedUpload)}, nil
+}
+
+// Points returns the track points of the upload with the given ID
+func (u *ReplayUploads) Points(id string) ([]TrackPoint, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	upload, ok := u.uploads[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown replay ID %q", id)
+	}
+	return upload.points, nil
+}
+
+// Config returns base set up to replay the upload with the given ID from
+// its parsed points, without reading the file again
+func (u *ReplayUploads) Config(base Config, id string) (Config, error) {
+	points, err := u.Points(id)
+	if err != nil {
+		return Config{}, err
+	}
+	base.ReplayFile = ""
+	base.ReplayFiles = nil
+	base.ReplayPoints = points
+	return base, nil
+}
+
+// Close removes the upload directory and every stored upload
+func (u *ReplayUploads) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.uploads = make(map[string]storedUpload)
+	u.order = nil
+	return os.RemoveAll(u.dir)
+}
+
+// ServeHTTP accepts a multipart POST with the GPX track in the "file" field,
+// stores it and responds with its replay ID and a summary of the track as
+// JSON
+func (u *ReplayUploads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxReplayUpload)
+	if err := r.ParseMultipartForm(maxReplayUpload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing GPX file in the file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	suffix, ok := gpxUploadSuffix(header.Filename)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not a GPX file", header.Filename), http.StatusBadRequest)
+		return
+	}
+	id, err := newReplayID()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to create replay ID: %v", err), http.StatusInternalServerError)
+		return
+	}
+	path, err := saveUpload(file, u.dir, id+"-*"+suffix)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to save upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	points, err := ReadGPXFile(path)
+	if err != nil {
+		os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u.add(id, storedUpload{path: path, points: points})
+
+	summary := ReplayUploadSummary{ReplayID: id, Points: len(points), Bounds: trackBounds(points)}
+	if len(points) > 1 {
+		summary.Duration = points[len(points)-1].Time.Sub(points[0].Time).Seconds()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// add stores an upload, removing the oldest once more than
+// maxReplayUploads are kept
+func (u *ReplayUploads) add(id string, upload storedUpload) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.uploads[id] = upload
+	u.order = append(u.order, id)
+	for len(u.order) > maxReplayUploads {
+		oldest := u.order[0]
+		u.order = u.order[1:]
+		os.Remove(u.uploads[oldest].path)
+		delete(u.uploads, oldest)
+	}
+}
+
+// newReplayID returns a random upload ID
+func newReplayID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// gpxUploadSuffix returns the file suffix to store an upload with, keeping
+// .gz so compressed uploads are still read, and reports whether the name is
+// a GPX file at all
+func gpxUploadSuffix(filename string) (string, bool) {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".gpx.gz"):
+		return ".gpx.gz", true
+	case strings.HasSuffix(name, ".gpx"):
+		return ".gpx", true
+	}
+	return "", false
+}
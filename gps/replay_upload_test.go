@@ -0,0 +1,141 @@
+package gps
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// createReplayUploads returns an upload store removed when the test ends
+func createReplayUploads(t *testing.T) *ReplayUploads {
+	uploads, err := NewReplayUploads()
+	if err != nil {
+		t.Fatalf("Failed to create replay uploads: %v", err)
+	}
+	t.Cleanup(func() { uploads.Close() })
+	return uploads
+}
+
+// uploadReplay uploads uploadGPX and returns the decoded summary
+func uploadReplay(t *testing.T, uploads *ReplayUploads) ReplayUploadSummary {
+	rec := httptest.NewRecorder()
+	uploads.ServeHTTP(rec, replayUpload(t, "track.gpx", uploadGPX, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var summary ReplayUploadSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summary); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return summary
+}
+
+func TestReplayUploadSummary(t *testing.T) {
+	uploads := createReplayUploads(t)
+	summary := uploadReplay(t, uploads)
+
+	if summary.ReplayID == "" {
+		t.Error("Expected a replay ID")
+	}
+	if summary.Points != 3 {
+		t.Errorf("Expected 3 points, got %d", summary.Points)
+	}
+	if summary.Duration != 20 {
+		t.Errorf("Expected a duration of 20 seconds, got %v", summary.Duration)
+	}
+	expected := ReplayBounds{MinLat: 42.0, MinLon: -71.2, MaxLat: 42.2, MaxLon: -71.0}
+	if summary.Bounds != expected {
+		t.Errorf("Expected bounds %+v, got %+v", expected, summary.Bounds)
+	}
+}
+
+func TestReplayUploadConfig(t *testing.T) {
+	uploads := createReplayUploads(t)
+	summary := uploadReplay(t, uploads)
+
+	// The simulator replays the parsed points without reading the file again
+	os.RemoveAll(uploads.dir)
+
+	base := createTestConfig()
+	base.BaudRate = 9600
+	base.ReplaySpeed = 1.0
+	base.Quiet = true
+	config, err := uploads.Config(base, summary.ReplayID)
+	if err != nil {
+		t.Fatalf("Config() failed: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	if !sim.isReplayMode() {
+		t.Fatal("Expected replay mode")
+	}
+	if len(sim.replayPoints) != 3 {
+		t.Errorf("Expected 3 replay points, got %d", len(sim.replayPoints))
+	}
+	if sim.currentLat != 42.0 || sim.currentLon != -71.1 {
+		t.Errorf("Expected to start at the first point, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+
+	if _, err := uploads.Config(base, "missing"); err == nil {
+		t.Error("Expected an error for an unknown replay ID")
+	}
+}
+
+func TestReplayUploadRejects(t *testing.T) {
+	uploads := createReplayUploads(t)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"GET", httptest.NewRequest(http.MethodGet, "/api/replay/upload", nil), http.StatusMethodNotAllowed},
+		{"Not GPX", replayUpload(t, "track.txt", uploadGPX, nil), http.StatusBadRequest},
+		{"Invalid XML", replayUpload(t, "track.gpx", "<gpx", nil), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			uploads.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.code {
+				t.Errorf("Expected status %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	entries, _ := os.ReadDir(uploads.dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected rejected uploads to be removed, found %d files", len(entries))
+	}
+}
+
+func TestReplayUploadCleanup(t *testing.T) {
+	uploads := createReplayUploads(t)
+
+	first := uploadReplay(t, uploads)
+	for i := 0; i < maxReplayUploads; i++ {
+		uploadReplay(t, uploads)
+	}
+
+	if _, err := uploads.Points(first.ReplayID); err == nil {
+		t.Error("Expected the oldest upload to be removed")
+	}
+	entries, _ := os.ReadDir(uploads.dir)
+	if len(entries) != maxReplayUploads {
+		t.Errorf("Expected %d stored uploads, found %d", maxReplayUploads, len(entries))
+	}
+
+	uploads.Close()
+	if _, err := os.Stat(uploads.dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the upload directory to be removed, got %v", err)
+	}
+}
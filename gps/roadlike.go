@@ -0,0 +1,147 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Route modes describing how the receiver moves when not replaying or
+// following a route
+const (
+	RouteModeWander   = "wander"   // Random jitter around Course and Speed, bouncing off the radius
+	RouteModeRoadlike = "roadlike" // Straight legs joined by smooth turns, speeding up and slowing down gradually
+)
+
+// RouteModes lists the supported route modes
+var RouteModes = []string{RouteModeWander, RouteModeRoadlike}
+
+// DefaultMaxTurnRate is the road-like turn rate in degrees per second used
+// when Config.MaxTurnRate is zero, about a 90° junction turn in 6 seconds
+const DefaultMaxTurnRate = 15.0
+
+// roadAcceleration is how quickly road-like driving speeds up or brakes, in
+// meters per second squared
+const roadAcceleration = 1.5
+
+// roadReturnFraction is the fraction of Radius beyond which road-like
+// driving turns back toward the center
+const roadReturnFraction = 0.8
+
+// roadLeg is the road-like driving the receiver is steering toward until
+// the leg ends
+type roadLeg struct {
+	course float64   // Course to turn onto in degrees
+	speed  float64   // Speed to settle at in knots
+	ends   time.Time // When to choose the next leg (zero = choose now)
+}
+
+// ParseRouteMode converts a route mode name (case-insensitive) to its
+// canonical form. An empty name is wander.
+func ParseRouteMode(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return RouteModeWander, nil
+	}
+	for _, mode := range RouteModes {
+		if normalized == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown route mode %q (valid: %s)", name, strings.Join(RouteModes, ", "))
+}
+
+// isRoadlike reports whether the receiver drives a road-like path
+func (s *GPSSimulator) isRoadlike() bool {
+	mode, err := ParseRouteMode(s.Config.RouteMode)
+	return err == nil && mode == RouteModeRoadlike
+}
+
+// maxTurnRate returns the road-like turn rate limit in degrees per second
+func (s *GPSSimulator) maxTurnRate() float64 {
+	if s.Config.MaxTurnRate > 0 {
+		return s.Config.MaxTurnRate
+	}
+	return DefaultMaxTurnRate
+}
+
+// nextRoadLeg chooses the next leg: usually carrying on with a gentle bend,
+// sometimes a junction turn or a stop
+func (s *GPSSimulator) nextRoadLeg(now time.Time) {
+	rng := s.random()
+
+	// Speed varies more with jitter
+	variation := 0.2 + 0.5*s.Config.Jitter
+	leg := roadLeg{
+		course: s.currentCourse + (rng.Float64()-0.5)*30, // Gentle bend of up to ±15°
+		speed:  s.Config.Speed * (1 + (rng.Float64()-0.5)*2*variation),
+		ends:   now.Add(time.Duration(10+rng.Intn(21)) * time.Second),
+	}
+
+	switch roll := rng.Float64(); {
+	case roll < 0.1:
+		// Stop at a junction for a short while
+		leg.course = s.currentCourse
+		leg.speed = 0
+		leg.ends = now.Add(time.Duration(5+rng.Intn(11)) * time.Second)
+	case roll < 0.4:
+		// Turn left or right at a junction
+		turn := 90.0
+		if rng.Intn(2) == 0 {
+			turn = -90.0
+		}
+		leg.course = s.currentCourse + turn
+	}
+
+	leg.course = math.Mod(leg.course+360, 360)
+	leg.speed = math.Max(leg.speed, 0)
+	s.road = leg
+}
+
+// updateRoadlikePosition drives along the current road-like leg, turning
+// no faster than the maximum turn rate and changing speed no faster than
+// roadAcceleration, while keeping within Radius
+func (s *GPSSimulator) updateRoadlikePosition() {
+	now := s.now()
+	deltaTime := now.Sub(s.lastUpdateTime).Seconds()
+	s.lastUpdateTime = now
+
+	// If no time has passed, don't update position
+	if deltaTime <= 0 {
+		return
+	}
+
+	if s.road.ends.IsZero() || !now.Before(s.road.ends) {
+		s.nextRoadLeg(now)
+	}
+
+	// Head back toward the center while near the edge of the radius
+	if s.Config.Radius > 0 && s.distanceFromCenter(s.currentLat, s.currentLon) > s.Config.Radius*roadReturnFraction {
+		s.road.course = s.calculateBearing(s.currentLat, s.currentLon, s.Config.Latitude, s.Config.Longitude)
+		s.road.speed = math.Max(s.road.speed, s.Config.Speed)
+	}
+
+	// Turn through the smaller angle toward the leg's course
+	delta := math.Mod(s.road.course-s.currentCourse+540, 360) - 180
+	maxTurn := s.maxTurnRate() * deltaTime
+	delta = math.Max(-maxTurn, math.Min(maxTurn, delta))
+	s.currentCourse = math.Mod(s.currentCourse+delta+360, 360)
+
+	// Accelerate or brake toward the leg's speed (1 knot = 0.514444 m/s)
+	maxSpeedChange := roadAcceleration / 0.514444 * deltaTime
+	speedDelta := math.Max(-maxSpeedChange, math.Min(maxSpeedChange, s.road.speed-s.currentSpeed))
+	s.currentSpeed = math.Max(s.currentSpeed+speedDelta, 0)
+
+	distance := s.currentSpeed * 0.514444 * deltaTime
+	newLat, newLon := s.calculateDestination(s.currentLat, s.currentLon, s.currentCourse, distance)
+
+	// Hold at the radius boundary if the turn back has not finished in time
+	if s.Config.Radius > 0 && s.distanceFromCenter(newLat, newLon) > s.Config.Radius {
+		bearing := s.calculateBearing(s.Config.Latitude, s.Config.Longitude, newLat, newLon)
+		newLat, newLon = s.calculateDestination(s.Config.Latitude, s.Config.Longitude, bearing, s.Config.Radius)
+	}
+
+	s.currentLat = newLat
+	s.currentLon = newLon
+}
@@ -0,0 +1,118 @@
+package gps
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// newRoadlikeSimulator returns a locked road-like simulator on a fake clock
+func newRoadlikeSimulator(t *testing.T, maxTurnRate float64) (*GPSSimulator, *fakeClock) {
+	config := createTestConfig()
+	config.RouteMode = RouteModeRoadlike
+	config.MaxTurnRate = maxTurnRate
+	config.Radius = 500
+	config.Speed = 20
+	config.TimeToLock = 0
+	config.Quiet = true
+	config.Seed = 7
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.lastUpdateTime = clock.Now()
+	return sim, clock
+}
+
+func TestRoadlikeTurnRate(t *testing.T) {
+	for _, maxTurnRate := range []float64{5, DefaultMaxTurnRate} {
+		sim, clock := newRoadlikeSimulator(t, maxTurnRate)
+
+		step := 500 * time.Millisecond
+		maxDelta := maxTurnRate * step.Seconds()
+		turned := 0.0
+		previous := sim.currentCourse
+		for i := 0; i < 1200; i++ {
+			clock.Advance(step)
+			sim.update()
+
+			delta := math.Abs(math.Mod(sim.currentCourse-previous+540, 360) - 180)
+			if delta > maxDelta+1e-9 {
+				t.Fatalf("Max turn rate %v: course changed %.2f° in %v at step %d, limit %.2f°",
+					maxTurnRate, delta, step, i, maxDelta)
+			}
+			turned += delta
+			previous = sim.currentCourse
+		}
+
+		// The path should actually turn rather than drive straight
+		if turned < 90 {
+			t.Errorf("Max turn rate %v: expected the route to turn, turned %.1f° in total", maxTurnRate, turned)
+		}
+	}
+}
+
+func TestRoadlikeSpeedChangesGradually(t *testing.T) {
+	sim, clock := newRoadlikeSimulator(t, 0)
+
+	maxChange := roadAcceleration / 0.514444 // Knots per second
+	previous := sim.currentSpeed
+	for i := 0; i < 600; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+
+		if change := math.Abs(sim.currentSpeed - previous); change > maxChange+1e-9 {
+			t.Fatalf("Speed changed %.2f knots in one second at step %d, limit %.2f", change, i, maxChange)
+		}
+		if sim.currentSpeed < 0 {
+			t.Fatalf("Speed went negative: %f", sim.currentSpeed)
+		}
+		previous = sim.currentSpeed
+	}
+}
+
+func TestRoadlikeStaysWithinRadius(t *testing.T) {
+	sim, clock := newRoadlikeSimulator(t, 0)
+
+	for i := 0; i < 1800; i++ {
+		clock.Advance(time.Second)
+		sim.update()
+
+		if distance := sim.distanceFromCenter(sim.currentLat, sim.currentLon); distance > sim.Config.Radius+0.01 {
+			t.Fatalf("Drove %.1f m from center at step %d, radius %.1f m", distance, i, sim.Config.Radius)
+		}
+	}
+}
+
+func TestRouteModeValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	for _, mode := range []string{"", "wander", "RoadLike"} {
+		config.RouteMode = mode
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected route mode %q to be valid, got: %v", mode, err)
+		}
+	}
+
+	config.RouteMode = "offroad"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown route mode")
+	}
+
+	config.RouteMode = RouteModeRoadlike
+	config.MaxTurnRate = -1
+	if err := config.Validate(); err == nil || err.Error() != "Max turn rate must be non-negative" {
+		t.Errorf("Expected a non-negative max turn rate error, got %v", err)
+	}
+
+	// 0 selects DefaultMaxTurnRate
+	config.MaxTurnRate = 0
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected a zero max turn rate to be valid, got %v", err)
+	}
+}
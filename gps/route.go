@@ -0,0 +1,173 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// routeArrivalRadius is the distance in meters at which a waypoint counts as reached
+const routeArrivalRadius = 1.0
+
+// Coordinate is a latitude/longitude pair in decimal degrees
+type Coordinate struct {
+	Lat float64
+	Lon float64
+}
+
+// ParseWaypoints parses a semicolon-separated list of "lat,lon" pairs such as
+// "37.7749,-122.4194;37.8080,-122.4177" into coordinates
+func ParseWaypoints(spec string) ([]Coordinate, error) {
+	var waypoints []Coordinate
+	for _, entry := range strings.Split(spec, ";") {
+		lat, lon, ok := strings.Cut(entry, ",")
+		if !ok {
+			return nil, fmt.Errorf("invalid waypoint %q (expected LAT,LON)", entry)
+		}
+		latValue, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude in waypoint %q: %v", entry, err)
+		}
+		lonValue, err := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude in waypoint %q: %v", entry, err)
+		}
+		waypoints = append(waypoints, Coordinate{Lat: latValue, Lon: lonValue})
+	}
+	return waypoints, nil
+}
+
+// waypointRoute converts configured waypoints to route points at the given altitude
+func waypointRoute(waypoints []Coordinate, altitude float64) []TrackPoint {
+	points := make([]TrackPoint, len(waypoints))
+	for i, wp := range waypoints {
+		points[i] = TrackPoint{Lat: wp.Lat, Lon: wp.Lon, Elevation: altitude}
+	}
+	return points
+}
+
+// updateRoutePosition advances the receiver along the great-circle segments
+// between route waypoints at the current speed, steering toward the active
+// waypoint and applying jitter on top of the ideal path.
+func (s *GPSSimulator) updateRoutePosition() {
+	now := s.now()
+	deltaTime := now.Sub(s.lastUpdateTime).Seconds()
+	s.lastUpdateTime = now
+
+	if len(s.routePoints) == 0 || s.routeCompleted {
+		s.currentSpeed = 0
+		return
+	}
+
+	// Convert speed from knots to meters per second and get the distance to cover
+	remaining := s.currentSpeed * 0.514444 * math.Max(deltaTime, 0)
+
+	// Bound the number of waypoints passed per update so a route made of
+	// coincident points cannot loop forever
+	for hops := 0; hops <= 2*len(s.routePoints); hops++ {
+		target := s.routePoints[s.routeIndex]
+		s.currentCourse = s.calculateBearing(s.routeLat, s.routeLon, target.Lat, target.Lon)
+		distanceToTarget := s.calculateDistance(s.routeLat, s.routeLon, target.Lat, target.Lon)
+
+		if remaining < distanceToTarget && distanceToTarget > routeArrivalRadius {
+			s.routeLat, s.routeLon = s.calculateDestination(s.routeLat, s.routeLon, s.currentCourse, remaining)
+			break
+		}
+
+		// Arrived at the active waypoint: snap to it and move on to the next segment
+		remaining -= distanceToTarget
+		s.routeLat = target.Lat
+		s.routeLon = target.Lon
+		s.routeIndex++
+
+		if s.routeIndex >= len(s.routePoints) {
+			if !s.routeLoops() {
+				s.routeIndex = len(s.routePoints) - 1
+				s.routeCompleted = true
+				s.currentSpeed = 0
+				break
+			}
+			s.routeIndex = 0
+		}
+
+		if remaining <= 0 {
+			// Point the course at the new segment so output reflects the turn
+			next := s.routePoints[s.routeIndex]
+			s.currentCourse = s.calculateBearing(s.routeLat, s.routeLon, next.Lat, next.Lon)
+			break
+		}
+	}
+
+	s.currentLat, s.currentLon = s.jitterPosition(s.routeLat, s.routeLon)
+}
+
+// jitterPosition applies GPS jitter noise of up to 10 meters at full jitter
+// around an ideal position, falling back to the noise implied by the fix
+// quality without a jitter factor
+func (s *GPSSimulator) jitterPosition(lat, lon float64) (float64, float64) {
+	maxJitterDistance := s.qualityNoiseDistance()
+	if s.Config.Jitter > 0 {
+		maxJitterDistance = 10.0 * s.Config.Jitter
+	}
+	if maxJitterDistance <= 0 {
+		return lat, lon
+	}
+	jitterBearing := s.random().Float64() * 360.0
+	jitterDistance := s.random().Float64() * maxJitterDistance
+	return s.calculateDestination(lat, lon, jitterBearing, jitterDistance)
+}
+
+// loadRoute loads the route waypoints and positions the receiver at the first one
+func (s *GPSSimulator) loadRoute(points []TrackPoint) {
+	s.routePoints = points
+	s.routeIndex = 0
+	s.routeCompleted = false
+
+	if len(points) == 0 {
+		return
+	}
+
+	s.routeLat = points[0].Lat
+	s.routeLon = points[0].Lon
+	s.currentLat = points[0].Lat
+	s.currentLon = points[0].Lon
+	s.currentAlt = points[0].Elevation
+
+	if len(points) > 1 {
+		s.routeIndex = 1
+		s.currentCourse = s.calculateBearing(points[0].Lat, points[0].Lon, points[1].Lat, points[1].Lon)
+	} else {
+		s.routeCompleted = true
+	}
+}
+
+// routeLoops reports whether the route should restart at its first waypoint
+func (s *GPSSimulator) routeLoops() bool {
+	return s.Config.RouteLoop || s.Config.WaypointLoop
+}
+
+// isRouteMode reports whether the simulator is navigating a route
+func (s *GPSSimulator) isRouteMode() bool {
+	return len(s.routePoints) > 0
+}
+
+// calculateDestination calculates the point reached by travelling distance meters
+// from lat/lon along the given initial bearing on a great circle, with the
+// longitude wrapped into [-180, 180) across the antimeridian
+func (s *GPSSimulator) calculateDestination(lat, lon, bearing, distance float64) (float64, float64) {
+	const R = 6371000 // Earth's radius in meters
+
+	lat1Rad := lat * math.Pi / 180
+	lon1Rad := lon * math.Pi / 180
+	bearingRad := bearing * math.Pi / 180
+	angularDistance := distance / R
+
+	lat2Rad := math.Asin(math.Sin(lat1Rad)*math.Cos(angularDistance) +
+		math.Cos(lat1Rad)*math.Sin(angularDistance)*math.Cos(bearingRad))
+	lon2Rad := lon1Rad + math.Atan2(
+		math.Sin(bearingRad)*math.Sin(angularDistance)*math.Cos(lat1Rad),
+		math.Cos(angularDistance)-math.Sin(lat1Rad)*math.Sin(lat2Rad))
+
+	return normalizeCoordinate(lat2Rad*180/math.Pi, lon2Rad*180/math.Pi)
+}
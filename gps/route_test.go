@@ -0,0 +1,344 @@
+package gps
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// createRouteSimulator creates a locked simulator navigating the given waypoints without jitter
+func createRouteSimulator(points []TrackPoint, loop bool) *GPSSimulator {
+	sim := createTestSimulator()
+	sim.Config.Jitter = 0
+	sim.Config.RouteLoop = loop
+	sim.loadRoute(points)
+	return sim
+}
+
+// advanceRoute moves the simulator along its route as if seconds had elapsed at speed knots
+func advanceRoute(sim *GPSSimulator, speed float64, seconds float64) {
+	sim.currentSpeed = speed
+	sim.lastUpdateTime = time.Now().Add(-time.Duration(seconds * float64(time.Second)))
+	sim.updateRoutePosition()
+}
+
+// testRoute returns an L-shaped route: ~111m north, then ~88m east
+func testRoute() []TrackPoint {
+	return []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Elevation: 10},
+		{Lat: 37.0010, Lon: -122.0000, Elevation: 20},
+		{Lat: 37.0010, Lon: -121.9990, Elevation: 30},
+	}
+}
+
+func TestCalculateDestination(t *testing.T) {
+	sim := createTestSimulator()
+
+	for _, bearing := range []float64{0, 45, 90, 180, 270} {
+		lat, lon := sim.calculateDestination(37.0, -122.0, bearing, 1000)
+
+		distance := sim.calculateDistance(37.0, -122.0, lat, lon)
+		if math.Abs(distance-1000) > 0.5 {
+			t.Errorf("Bearing %.0f: expected 1000m, got %.2fm", bearing, distance)
+		}
+
+		computed := sim.calculateBearing(37.0, -122.0, lat, lon)
+		diff := math.Abs(computed - bearing)
+		if diff > 180 {
+			diff = 360 - diff
+		}
+		if diff > 0.1 {
+			t.Errorf("Bearing %.0f: computed bearing %.2f", bearing, computed)
+		}
+	}
+}
+
+func TestLoadRoute(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), false)
+
+	if sim.currentLat != 37.0 || sim.currentLon != -122.0 {
+		t.Errorf("Expected start at first waypoint, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+	if sim.currentAlt != 10 {
+		t.Errorf("Expected start altitude 10, got %f", sim.currentAlt)
+	}
+	if sim.routeIndex != 1 {
+		t.Errorf("Expected to navigate toward waypoint 1, got %d", sim.routeIndex)
+	}
+	if math.Abs(sim.currentCourse) > 0.1 {
+		t.Errorf("Expected initial course north, got %f", sim.currentCourse)
+	}
+}
+
+func TestUpdateRoutePositionAlongSegment(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), false)
+
+	// 10 knots for 10 seconds ≈ 51m along the first (northbound) segment
+	advanceRoute(sim, 10, 10)
+
+	if sim.routeIndex != 1 {
+		t.Errorf("Should still be on the first segment, got index %d", sim.routeIndex)
+	}
+	travelled := sim.calculateDistance(37.0, -122.0, sim.currentLat, sim.currentLon)
+	if math.Abs(travelled-51.44) > 1.0 {
+		t.Errorf("Expected ~51.4m travelled, got %.2fm", travelled)
+	}
+	if math.Abs(sim.currentLon-(-122.0)) > 1e-9 {
+		t.Errorf("Northbound segment should not change longitude, got %f", sim.currentLon)
+	}
+	if math.Abs(sim.currentCourse) > 0.1 {
+		t.Errorf("Expected course 0 on northbound segment, got %f", sim.currentCourse)
+	}
+}
+
+func TestUpdateRoutePositionSegmentTransition(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), false)
+
+	// ≈154m: past the first waypoint (111m) and ~43m into the eastbound segment
+	advanceRoute(sim, 10, 30)
+
+	if sim.routeIndex != 2 {
+		t.Fatalf("Expected to navigate toward waypoint 2, got %d", sim.routeIndex)
+	}
+	if math.Abs(sim.currentCourse-90) > 0.5 {
+		t.Errorf("Expected course ~90 on eastbound segment, got %f", sim.currentCourse)
+	}
+	if sim.currentLon <= -122.0 {
+		t.Errorf("Expected to have moved east of the corner, got lon %f", sim.currentLon)
+	}
+	fromCorner := sim.calculateDistance(37.0010, -122.0, sim.currentLat, sim.currentLon)
+	if math.Abs(fromCorner-(154.33-111.19)) > 1.5 {
+		t.Errorf("Expected ~43m past the corner, got %.2fm", fromCorner)
+	}
+}
+
+func TestUpdateRoutePositionArrival(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), false)
+
+	// Far more distance than the route length
+	advanceRoute(sim, 10, 120)
+
+	if !sim.routeCompleted {
+		t.Error("Route should be completed after passing the last waypoint")
+	}
+	last := testRoute()[2]
+	if sim.currentLat != last.Lat || sim.currentLon != last.Lon {
+		t.Errorf("Expected to stop at last waypoint, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+	if sim.currentSpeed != 0 {
+		t.Errorf("Expected zero speed after arrival, got %f", sim.currentSpeed)
+	}
+
+	// Further updates do not move the receiver
+	advanceRoute(sim, 10, 10)
+	if sim.currentLat != last.Lat || sim.currentLon != last.Lon {
+		t.Error("Receiver should stay at the last waypoint once the route is completed")
+	}
+}
+
+func TestUpdateRoutePositionLoop(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), true)
+
+	// Route is ~199m plus ~142m back to the start; 10 knots for 45s ≈ 231m
+	advanceRoute(sim, 10, 45)
+
+	if sim.routeCompleted {
+		t.Error("Looping route should never complete")
+	}
+	if sim.routeIndex != 0 {
+		t.Errorf("Expected to be heading back to the first waypoint, got index %d", sim.routeIndex)
+	}
+
+	// Course should point from the last waypoint back toward the first (south-west)
+	if sim.currentCourse < 180 || sim.currentCourse > 270 {
+		t.Errorf("Expected south-westerly course back to start, got %f", sim.currentCourse)
+	}
+}
+
+func TestUpdateRoutePositionJitter(t *testing.T) {
+	sim := createRouteSimulator(testRoute(), false)
+	sim.Config.Jitter = 0.5
+
+	for i := 0; i < 50; i++ {
+		advanceRoute(sim, 5, 1)
+		offset := sim.calculateDistance(sim.routeLat, sim.routeLon, sim.currentLat, sim.currentLon)
+		if offset > 5.0+0.01 {
+			t.Fatalf("Jitter offset %.2fm exceeds maximum 5m", offset)
+		}
+	}
+}
+
+func TestNewGPSSimulatorWithRoute(t *testing.T) {
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, "route.gpx")
+
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="37.0000" lon="-122.0000"><ele>10</ele></wpt>
+  <wpt lat="37.0010" lon="-122.0000"><ele>20</ele></wpt>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write route file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.RouteFile = tempFile
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator with route: %v", err)
+	}
+
+	if len(sim.routePoints) != 2 {
+		t.Errorf("Expected 2 route points, got %d", len(sim.routePoints))
+	}
+	if sim.currentLat != 37.0 || sim.currentLon != -122.0 {
+		t.Errorf("Expected initial position at first waypoint, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+
+	config.RouteFile = filepath.Join(tempDir, "missing.gpx")
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for missing route file")
+	}
+}
+
+func TestRunStopsWhenRouteCompleted(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.Speed = 100
+	config.Jitter = 0
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.loadRoute([]TrackPoint{
+		{Lat: 37.0, Lon: -122.0},
+		{Lat: 37.0001, Lon: -122.0},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run should return once a non-looping route is completed")
+	}
+}
+
+func TestWaypointNavigationReachesEachWaypointInOrder(t *testing.T) {
+	config := createTestConfig()
+	config.Jitter = 0
+	config.Waypoints = []Coordinate{
+		{Lat: 37.0000, Lon: -122.0000},
+		{Lat: 37.0010, Lon: -122.0000},
+		{Lat: 37.0010, Lon: -121.9990},
+		{Lat: 37.0000, Lon: -121.9990},
+	}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator with waypoints: %v", err)
+	}
+	if sim.currentLat != 37.0 || sim.currentLon != -122.0 || sim.currentAlt != config.Altitude {
+		t.Errorf("Expected to start at first waypoint at configured altitude, got %f, %f, %f",
+			sim.currentLat, sim.currentLon, sim.currentAlt)
+	}
+
+	// Step at 10 knots (~5.1 m/s) one second at a time and record each arrival
+	var reached []int
+	for step := 0; step < 200 && !sim.routeCompleted; step++ {
+		index := sim.routeIndex
+		advanceRoute(sim, 10, 1)
+		if sim.routeIndex != index || sim.routeCompleted {
+			reached = append(reached, index)
+		}
+	}
+
+	if !sim.routeCompleted {
+		t.Fatal("Expected waypoint route to complete")
+	}
+	if len(reached) != 3 || reached[0] != 1 || reached[1] != 2 || reached[2] != 3 {
+		t.Errorf("Expected waypoints reached in order [1 2 3], got %v", reached)
+	}
+	last := config.Waypoints[3]
+	if sim.currentLat != last.Lat || sim.currentLon != last.Lon {
+		t.Errorf("Expected to stop at last waypoint, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+}
+
+func TestWaypointNavigationLoop(t *testing.T) {
+	config := createTestConfig()
+	config.Jitter = 0
+	config.WaypointLoop = true
+	config.Waypoints = []Coordinate{
+		{Lat: 37.0000, Lon: -122.0000},
+		{Lat: 37.0010, Lon: -122.0000},
+	}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator with waypoints: %v", err)
+	}
+
+	// ~111m at 10 knots takes ~22s; after 30s the receiver is heading back south
+	advanceRoute(sim, 10, 30)
+	if sim.routeCompleted {
+		t.Fatal("Looping waypoint route should never complete")
+	}
+	if sim.routeIndex != 0 {
+		t.Errorf("Expected to be heading back to waypoint 0, got index %d", sim.routeIndex)
+	}
+	if math.Abs(sim.currentCourse-180) > 0.1 {
+		t.Errorf("Expected course ~180 after looping, got %.2f", sim.currentCourse)
+	}
+}
+
+func TestValidateWaypoints(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Waypoints = []Coordinate{{Lat: 37.0, Lon: -122.0}, {Lat: 91.0, Lon: -122.0}}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for out of range waypoint")
+	}
+
+	config.Waypoints = []Coordinate{{Lat: 37.0, Lon: -122.0}}
+	config.RouteFile = "route.gpx"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error when combining waypoints with a route file")
+	}
+
+	config.RouteFile = ""
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid waypoint config, got %v", err)
+	}
+}
+
+func TestParseWaypoints(t *testing.T) {
+	waypoints, err := ParseWaypoints("37.7749,-122.4194; 37.8080, -122.4177")
+	if err != nil {
+		t.Fatalf("Failed to parse waypoints: %v", err)
+	}
+	if len(waypoints) != 2 {
+		t.Fatalf("Expected 2 waypoints, got %d", len(waypoints))
+	}
+	if waypoints[1].Lat != 37.8080 || waypoints[1].Lon != -122.4177 {
+		t.Errorf("Unexpected second waypoint: %+v", waypoints[1])
+	}
+
+	for _, spec := range []string{"37.7749", "north,-122.4194", "37.7749,west", "37.7749,-122.4194;"} {
+		if _, err := ParseWaypoints(spec); err == nil {
+			t.Errorf("Expected error parsing %q", spec)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Scenario is a recorded simulation session: the configuration it started
+// with, the random seed and every live configuration change made while it
+// ran. Playing it back with the same clock reproduces the session's output.
+type Scenario struct {
+	Config  Config           `json:"config"`
+	Seed    int64            `json:"seed"`
+	Updates []ScenarioUpdate `json:"updates"`
+}
+
+// ScenarioUpdate is a configuration change applied with UpdateConfig
+type ScenarioUpdate struct {
+	Offset time.Duration `json:"offset"` // Simulated time since the session started, in nanoseconds
+	Config Config        `json:"config"`
+}
+
+// LoadScenario reads a scenario file written with Config.RecordScenario
+func LoadScenario(filename string) (Scenario, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario file %s: %v", filename, err)
+	}
+	if err := scenario.Config.Validate(); err != nil {
+		return Scenario{}, fmt.Errorf("invalid scenario config: %v", err)
+	}
+	for i := 1; i < len(scenario.Updates); i++ {
+		if scenario.Updates[i].Offset < scenario.Updates[i-1].Offset {
+			return Scenario{}, fmt.Errorf("scenario update %d is out of order", i+1)
+		}
+	}
+	return scenario, nil
+}
+
+// PlaybackConfig returns the recorded configuration and seed with the
+// output settings of c (Quiet, serial output and pacing, GPX, GeoJSON, KML
+// and CSV files, TCP, UDP and gpsd output, the output format, Duration and
+// the scenario files), so a scenario can be played back to any output
+func (sc Scenario) PlaybackConfig(c Config) Config {
+	config := sc.Config
+	config.Seed = sc.Seed
+	config.Quiet = c.Quiet
+	config.SerialPort = c.SerialPort
+	config.BaudRate = c.BaudRate
+	config.PaceOutput = c.PaceOutput
+	config.GPXEnabled = c.GPXEnabled
+	config.GPXExtensions = c.GPXExtensions
+	config.GPXFile = c.GPXFile
+	config.GeoJSONEnabled = c.GeoJSONEnabled
+	config.GeoJSONFile = c.GeoJSONFile
+	config.GeoJSONPoints = c.GeoJSONPoints
+	config.KMLEnabled = c.KMLEnabled
+	config.KMLFile = c.KMLFile
+	config.KMLName = c.KMLName
+	config.CSVFile = c.CSVFile
+	config.CSVColumns = c.CSVColumns
+	config.TCPListen = c.TCPListen
+	config.UDPTarget = c.UDPTarget
+	config.GpsdMode = c.GpsdMode
+	config.GpsdListen = c.GpsdListen
+	config.OutputFormat = c.OutputFormat
+	config.JSONWriter = c.JSONWriter
+	config.Duration = c.Duration
+	config.ScenarioFile = c.ScenarioFile
+	config.RecordScenario = c.RecordScenario
+	return config
+}
+
+// startScenarioRecording begins recording the session to
+// Config.RecordScenario, writing the file once so an unwritable path fails
+// when the simulator is created
+func (s *GPSSimulator) startScenarioRecording() error {
+	recorded := s.Config
+	recorded.RecordScenario = ""
+	recorded.ScenarioFile = ""
+	s.recording = &Scenario{Config: recorded, Seed: s.Config.Seed, Updates: []ScenarioUpdate{}}
+	return s.writeScenario()
+}
+
+// recordScenarioUpdate saves a configuration change to the scenario being
+// recorded, if any
+func (s *GPSSimulator) recordScenarioUpdate(config Config) {
+	if s.recording == nil {
+		return
+	}
+	config.RecordScenario = ""
+	config.ScenarioFile = ""
+	s.recording.Updates = append(s.recording.Updates, ScenarioUpdate{
+		Offset: s.now().Sub(s.startTime),
+		Config: config,
+	})
+	if err := s.writeScenario(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing scenario: %v\n", err)
+	}
+}
+
+// writeScenario rewrites the scenario file with everything recorded so far
+func (s *GPSSimulator) writeScenario() error {
+	data, err := json.MarshalIndent(s.recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.Config.RecordScenario, data, 0644)
+}
+
+// playScenarioUpdates applies the played back configuration changes due by
+// now, each on the first cycle at or after its recorded time
+func (s *GPSSimulator) playScenarioUpdates(now time.Time) {
+	elapsed := now.Sub(s.startTime)
+	for len(s.scenarioUpdates) > 0 && s.scenarioUpdates[0].Offset <= elapsed {
+		config := s.scenarioUpdates[0].Config
+		s.scenarioUpdates = s.scenarioUpdates[1:]
+		s.applyConfigUpdate(config)
+		s.recordScenarioUpdate(config)
+	}
+}
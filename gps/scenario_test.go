@@ -0,0 +1,219 @@
+package gps
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scenarioOutput ticks the simulator once a second for ten seconds from a
+// fixed start time, calling midway between the fifth and sixth ticks
+func scenarioOutput(t *testing.T, config Config, midway func(*GPSSimulator)) string {
+	t.Helper()
+	buffer := &bytes.Buffer{}
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	defer sim.Close()
+
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.startTime = clock.Now()
+	sim.lastUpdateTime = clock.Now()
+	sim.lockTime = clock.Now().Add(sim.Config.TimeToLock)
+
+	for i := 1; i <= 10; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+		if i == 5 {
+			clock.Advance(500 * time.Millisecond)
+			midway(sim)
+			clock.Advance(-500 * time.Millisecond)
+		}
+	}
+	return buffer.String()
+}
+
+func TestScenarioRecordAndPlayback(t *testing.T) {
+	scenarioFile := filepath.Join(t.TempDir(), "session.json")
+
+	config := createTestConfig()
+	config.TimeToLock = 2 * time.Second
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	config.RecordScenario = scenarioFile
+
+	recorded := scenarioOutput(t, config, func(sim *GPSSimulator) {
+		update := sim.Config
+		update.Speed = 20.0
+		update.Jitter = 0.1
+		if err := sim.UpdateConfig(update); err != nil {
+			t.Fatalf("Failed to update config: %v", err)
+		}
+	})
+
+	scenario, err := LoadScenario(scenarioFile)
+	if err != nil {
+		t.Fatalf("Failed to load scenario: %v", err)
+	}
+	if scenario.Seed == 0 || scenario.Config.Seed != scenario.Seed {
+		t.Errorf("Expected a chosen seed to be recorded, got %d (config %d)", scenario.Seed, scenario.Config.Seed)
+	}
+	if len(scenario.Updates) != 1 || scenario.Updates[0].Offset != 5500*time.Millisecond || scenario.Updates[0].Config.Speed != 20.0 {
+		t.Fatalf("Expected one update to 20 knots at 5.5s, got %+v", scenario.Updates)
+	}
+	if scenario.Config.RecordScenario != "" {
+		t.Error("The recorded config should not record again when played back")
+	}
+
+	// Playback reproduces the session, mid-run change included
+	playback := Config{ScenarioFile: scenarioFile, Quiet: true}
+	replayed := scenarioOutput(t, playback, func(*GPSSimulator) {})
+	if replayed != recorded {
+		t.Errorf("Expected played back output to match the recording:\n%s\ngot:\n%s", recorded, replayed)
+	}
+	if !strings.Contains(recorded, "$GPRMC") {
+		t.Error("Expected NMEA output from the recording")
+	}
+}
+
+func TestScenarioPlaybackKeepsOutputs(t *testing.T) {
+	scenario := Scenario{
+		Config: Config{Quiet: false, TCPListen: ":10110", Speed: 12.0},
+		Seed:   7,
+	}
+	config := scenario.PlaybackConfig(Config{Quiet: true, GPXFile: "out.gpx", ScenarioFile: "session.json"})
+
+	if !config.Quiet || config.TCPListen != "" || config.GPXFile != "out.gpx" {
+		t.Errorf("Expected the caller's output settings, got %+v", config)
+	}
+	if config.Speed != 12.0 || config.Seed != 7 {
+		t.Errorf("Expected the recorded speed and seed, got %f and %d", config.Speed, config.Seed)
+	}
+}
+
+func TestScenarioPlaybackWithAllOutputs(t *testing.T) {
+	dir := t.TempDir()
+	scenarioFile := filepath.Join(dir, "session.json")
+	csvFile := filepath.Join(dir, "track.csv")
+
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.Quiet = true
+	config.RecordScenario = scenarioFile
+	config.PaceOutput = true
+	config.GPXEnabled = true
+	config.GPXFile = filepath.Join(dir, "track.gpx")
+	config.GeoJSONEnabled = true
+	config.GeoJSONFile = filepath.Join(dir, "track.geojson")
+	config.KMLEnabled = true
+	config.KMLFile = filepath.Join(dir, "track.kml")
+	config.CSVFile = csvFile
+	config.GpsdListen = "127.0.0.1:0"
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+	for i := 0; i < 3; i++ {
+		sim.tick()
+	}
+	sim.Close()
+	recordedCSV, err := os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("Failed to read recorded CSV: %v", err)
+	}
+
+	// Playing back without outputs opens none of the recording's
+	playback, err := NewGPSSimulator(Config{ScenarioFile: scenarioFile, Quiet: true}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to play back scenario: %v", err)
+	}
+	if playback.pacer != nil || playback.gpxWriter != nil || playback.geoJSONWriter != nil ||
+		playback.kmlWriter != nil || playback.csvWriter != nil || playback.gpsdServer != nil {
+		t.Error("Expected playback to use only its own outputs")
+	}
+	playback.Close()
+	if data, _ := os.ReadFile(csvFile); !bytes.Equal(data, recordedCSV) {
+		t.Error("Expected playback to leave the recording's CSV file untouched")
+	}
+
+	// The playback run's own outputs are used
+	outputs := Config{
+		ScenarioFile:   scenarioFile,
+		Quiet:          true,
+		BaudRate:       4800,
+		PaceOutput:     true,
+		GeoJSONEnabled: true,
+		GeoJSONFile:    filepath.Join(dir, "playback.geojson"),
+		KMLEnabled:     true,
+		KMLFile:        filepath.Join(dir, "playback.kml"),
+		KMLName:        "Playback",
+		CSVFile:        filepath.Join(dir, "playback.csv"),
+		CSVColumns:     []string{"lat", "lon"},
+		GpsdListen:     "127.0.0.1:0",
+	}
+	playback, err = NewGPSSimulator(outputs, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to play back scenario: %v", err)
+	}
+	defer playback.Close()
+	if playback.pacer == nil || playback.geoJSONWriter == nil || playback.kmlWriter == nil ||
+		playback.csvWriter == nil || playback.gpsdServer == nil || playback.gpxWriter != nil {
+		t.Error("Expected playback to open the outputs it was given")
+	}
+	if playback.Config.CSVFile != outputs.CSVFile || playback.Config.KMLName != "Playback" || playback.Config.BaudRate != 4800 {
+		t.Errorf("Expected the playback output settings, got %+v", playback.Config)
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write scenario: %v", err)
+		}
+		return path
+	}
+
+	valid := `"config": {"Latitude": 37, "Longitude": -122, "Satellites": 8, "BaudRate": 9600, "ReplaySpeed": 1}`
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"Missing file", filepath.Join(dir, "missing.json")},
+		{"Invalid JSON", write("invalid.json", "{")},
+		{"Invalid config", write("config.json", `{"config": {"Latitude": 100, "Satellites": 8}}`)},
+		{"Updates out of order", write("order.json", `{`+valid+`, "updates": [{"offset": 2000000000}, {"offset": 1000000000}]}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadScenario(tt.path); err == nil {
+				t.Error("Expected error loading scenario")
+			}
+		})
+	}
+
+	if _, err := LoadScenario(write("valid.json", `{`+valid+`}`)); err != nil {
+		t.Errorf("Unexpected error loading a valid scenario: %v", err)
+	}
+}
+
+func TestRecordScenarioUnwritable(t *testing.T) {
+	config := createTestConfig()
+	config.Quiet = true
+	config.RecordScenario = filepath.Join(t.TempDir(), "missing", "session.json")
+
+	if _, err := NewGPSSimulator(config, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error recording to an unwritable path")
+	}
+}
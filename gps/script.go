@@ -0,0 +1,129 @@
+package gps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ScriptStep is a timed change to the live-tunable settings during a run.
+// Only the settings that are set are changed; the rest carry on as before.
+type ScriptStep struct {
+	At             time.Duration // Simulated time since the start of the run
+	Speed          *float64      // Knots
+	Course         *float64      // Degrees (0-359)
+	Jitter         *float64      // GPS jitter factor (0.0-1.0)
+	AltitudeJitter *float64      // Altitude jitter factor (0.0-1.0)
+	Satellites     *int          // Satellites to simulate (4-12)
+}
+
+// scriptStepJSON is the file form of a ScriptStep, with At written as a
+// duration string such as "2m30s"
+type scriptStepJSON struct {
+	At             string   `json:"at"`
+	Speed          *float64 `json:"speed,omitempty"`
+	Course         *float64 `json:"course,omitempty"`
+	Jitter         *float64 `json:"jitter,omitempty"`
+	AltitudeJitter *float64 `json:"altitude_jitter,omitempty"`
+	Satellites     *int     `json:"satellites,omitempty"`
+}
+
+// MarshalJSON writes the step with At as a duration string
+func (step ScriptStep) MarshalJSON() ([]byte, error) {
+	return json.Marshal(scriptStepJSON{
+		At:             step.At.String(),
+		Speed:          step.Speed,
+		Course:         step.Course,
+		Jitter:         step.Jitter,
+		AltitudeJitter: step.AltitudeJitter,
+		Satellites:     step.Satellites,
+	})
+}
+
+// UnmarshalJSON reads a step with At as a duration string
+func (step *ScriptStep) UnmarshalJSON(data []byte) error {
+	var raw scriptStepJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	at, err := time.ParseDuration(raw.At)
+	if err != nil {
+		return fmt.Errorf("invalid step time %q: %v", raw.At, err)
+	}
+	*step = ScriptStep{
+		At:             at,
+		Speed:          raw.Speed,
+		Course:         raw.Course,
+		Jitter:         raw.Jitter,
+		AltitudeJitter: raw.AltitudeJitter,
+		Satellites:     raw.Satellites,
+	}
+	return nil
+}
+
+// LoadScript reads a JSON array of script steps, for example
+// [{"at": "0s", "speed": 30, "course": 0}, {"at": "2m", "speed": 0}]
+func LoadScript(filename string) ([]ScriptStep, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var steps []ScriptStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("invalid script file %s: %v", filename, err)
+	}
+	return steps, nil
+}
+
+// apply overrides the settings the step sets
+func (step ScriptStep) apply(c *Config) {
+	if step.Speed != nil {
+		c.Speed = *step.Speed
+	}
+	if step.Course != nil {
+		c.Course = *step.Course
+	}
+	if step.Jitter != nil {
+		c.Jitter = *step.Jitter
+	}
+	if step.AltitudeJitter != nil {
+		c.AltitudeJitter = *step.AltitudeJitter
+	}
+	if step.Satellites != nil {
+		c.Satellites = *step.Satellites
+	}
+}
+
+// validateScript checks that the steps are in time order and that each
+// leaves the configuration valid
+func (c Config) validateScript() error {
+	config := c
+	config.Script = nil
+	for i, step := range c.Script {
+		if step.At < 0 {
+			return fmt.Errorf("Script step %d must not be at a negative time", i+1)
+		}
+		if i > 0 && step.At < c.Script[i-1].At {
+			return errors.New("Script steps must be in time order")
+		}
+		step.apply(&config)
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("Invalid script step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// runScript applies the script steps due by now, each on the first cycle at
+// or after its time
+func (s *GPSSimulator) runScript(now time.Time) {
+	elapsed := now.Sub(s.startTime)
+	for s.scriptIndex < len(s.Config.Script) && s.Config.Script[s.scriptIndex].At <= elapsed {
+		config := s.Config
+		s.Config.Script[s.scriptIndex].apply(&config)
+		s.scriptIndex++
+		s.applyConfigUpdate(config)
+	}
+}
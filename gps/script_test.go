@@ -0,0 +1,128 @@
+package gps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func TestScriptChangesSpeedAndCourse(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.Jitter = 0
+	sim.Config.Radius = 0
+	sim.Config.Script = []ScriptStep{
+		{At: 0, Speed: floatPtr(30), Course: floatPtr(0)},
+		{At: 2 * time.Minute, Speed: floatPtr(0)},
+		{At: 2*time.Minute + 30*time.Second, Speed: floatPtr(30), Course: floatPtr(90)},
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+	sim.startTime = clock.Now()
+	sim.lastUpdateTime = clock.Now()
+
+	tests := []struct {
+		at     time.Duration
+		speed  float64
+		course float64
+	}{
+		{time.Second, 30, 0},
+		{119 * time.Second, 30, 0},
+		{120 * time.Second, 0, 0},
+		{149 * time.Second, 0, 0},
+		{150 * time.Second, 30, 90},
+		{200 * time.Second, 30, 90},
+	}
+	elapsed := time.Duration(0)
+	for _, tt := range tests {
+		// Step a second at a time up to the checked time
+		for elapsed < tt.at {
+			clock.Advance(time.Second)
+			elapsed += time.Second
+			sim.tick()
+		}
+		if sim.currentSpeed != tt.speed || sim.currentCourse != tt.course {
+			t.Errorf("At %v: expected speed %.0f course %.0f, got speed %.1f course %.1f",
+				tt.at, tt.speed, tt.course, sim.currentSpeed, sim.currentCourse)
+		}
+	}
+
+	// Settings the script does not set are left alone
+	if sim.Config.Satellites != 8 {
+		t.Errorf("Expected satellites to stay at 8, got %d", sim.Config.Satellites)
+	}
+}
+
+func TestScriptValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		script []ScriptStep
+		valid  bool
+	}{
+		{"Valid", []ScriptStep{{At: 0, Speed: floatPtr(10)}, {At: time.Minute, Satellites: intPtr(6)}}, true},
+		{"Negative time", []ScriptStep{{At: -time.Second, Speed: floatPtr(10)}}, false},
+		{"Out of order", []ScriptStep{{At: time.Minute}, {At: time.Second}}, false},
+		{"Invalid course", []ScriptStep{{At: 0, Course: floatPtr(400)}}, false},
+		{"Invalid jitter", []ScriptStep{{At: 0, Jitter: floatPtr(2)}}, false},
+		{"Too few satellites", []ScriptStep{{At: 0, Satellites: intPtr(2)}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			config.Script = tt.script
+			if err := config.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.json")
+	content := `[
+		{"at": "0s", "speed": 30, "course": 0},
+		{"at": "2m", "speed": 0},
+		{"at": "2m30s", "speed": 30, "course": 90, "jitter": 0.2, "satellites": 10}
+	]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	steps, err := LoadScript(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []ScriptStep{
+		{At: 0, Speed: floatPtr(30), Course: floatPtr(0)},
+		{At: 2 * time.Minute, Speed: floatPtr(0)},
+		{At: 150 * time.Second, Speed: floatPtr(30), Course: floatPtr(90), Jitter: floatPtr(0.2), Satellites: intPtr(10)},
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, steps)
+	}
+
+	// Steps round-trip through JSON, as in scenario files
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatalf("Failed to marshal script: %v", err)
+	}
+	var roundTrip []ScriptStep
+	if err := json.Unmarshal(data, &roundTrip); err != nil || !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("Expected script to round-trip, got %+v (%v)", roundTrip, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"at": "soon"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	if _, err := LoadScript(path); err == nil {
+		t.Error("Expected error for an invalid step time")
+	}
+}
@@ -0,0 +1,254 @@
+package gps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NMEA sentence types the simulator can emit
+const (
+	SentenceGGA = "GGA"
+	SentenceRMC = "RMC"
+	SentenceGLL = "GLL"
+	SentenceVTG = "VTG"
+	SentenceGSA = "GSA"
+	SentenceGSV = "GSV"
+	SentenceZDA = "ZDA"
+	SentenceGST = "GST"
+	SentenceGNS = "GNS"
+	SentenceHDT = "HDT"
+	SentenceROT = "ROT"
+	SentenceGRS = "GRS"
+	SentenceGBS = "GBS"
+)
+
+// DefaultSentences lists the sentence types emitted when Config.Sentences is
+// empty, in output order
+var DefaultSentences = []string{
+	SentenceGGA,
+	SentenceRMC,
+	SentenceGLL,
+	SentenceVTG,
+	SentenceGSA,
+	SentenceGSV,
+	SentenceZDA,
+	SentenceGST,
+}
+
+// AllSentences lists every supported sentence type. GNS is only emitted with
+// NMEA 4.1 output, heading sentences (HDT, ROT) only when selected in
+// Config.Sentences and integrity sentences (GRS, GBS) when selected or with
+// Config.EmitIntegritySentences.
+var AllSentences = append(append([]string(nil), DefaultSentences...), SentenceGNS, SentenceHDT, SentenceROT, SentenceGRS, SentenceGBS)
+
+// ParseSentence converts a sentence name (case-insensitive) to its canonical form
+func ParseSentence(name string) (string, error) {
+	normalized := sentenceKey(name)
+	for _, sentence := range AllSentences {
+		if normalized == sentence {
+			return sentence, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sentence %q (valid: %s)", name, strings.Join(AllSentences, ", "))
+}
+
+// parseSentences converts sentence names to their canonical forms, rejecting unknown or duplicate names
+func parseSentences(names []string) ([]string, error) {
+	var sentences []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		sentence, err := ParseSentence(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[sentence] {
+			return nil, fmt.Errorf("duplicate sentence %q", name)
+		}
+		seen[sentence] = true
+		sentences = append(sentences, sentence)
+	}
+	return sentences, nil
+}
+
+// ParseSentenceRates parses a compact rate specification such as "GSV=5,GSA=5"
+// into a map of upper-case sentence names to tick divisors, rejecting
+// repeated names
+func ParseSentenceRates(spec string) (map[string]int, error) {
+	rates := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sentence rate %q (expected NAME=DIVISOR)", entry)
+		}
+		divisor, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid divisor for sentence %q: %v", name, err)
+		}
+		key := sentenceKey(name)
+		if _, ok := rates[key]; ok {
+			return nil, fmt.Errorf("duplicate sentence %q", strings.TrimSpace(name))
+		}
+		rates[key] = divisor
+	}
+	return rates, nil
+}
+
+// sentenceKey returns a sentence name trimmed and upper-cased, as
+// ParseSentence compares it
+func sentenceKey(name string) string {
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// parseSentenceRates canonicalizes sentence rate keys, rejecting unknown or
+// duplicate names and divisors below 1
+func parseSentenceRates(rates map[string]int) (map[string]int, error) {
+	parsed := make(map[string]int, len(rates))
+	for name, divisor := range rates {
+		sentence, err := ParseSentence(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := parsed[sentence]; ok {
+			return nil, fmt.Errorf("duplicate sentence %q", name)
+		}
+		if divisor < 1 {
+			return nil, fmt.Errorf("rate divisor for %s must be at least 1, got %d", sentence, divisor)
+		}
+		parsed[sentence] = divisor
+	}
+	return parsed, nil
+}
+
+// ParseSentenceIntervals parses a compact interval specification such as
+// "GSV=5s,GSA=5s" into a map of upper-case sentence names to emit
+// intervals, rejecting repeated names
+func ParseSentenceIntervals(spec string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sentence interval %q (expected NAME=DURATION)", entry)
+		}
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval for sentence %q: %v", name, err)
+		}
+		key := sentenceKey(name)
+		if _, ok := intervals[key]; ok {
+			return nil, fmt.Errorf("duplicate sentence %q", strings.TrimSpace(name))
+		}
+		intervals[key] = interval
+	}
+	return intervals, nil
+}
+
+// parseSentenceIntervals canonicalizes sentence interval keys, rejecting
+// unknown or duplicate names and intervals that aren't positive
+func parseSentenceIntervals(intervals map[string]time.Duration) (map[string]time.Duration, error) {
+	parsed := make(map[string]time.Duration, len(intervals))
+	for name, interval := range intervals {
+		sentence, err := ParseSentence(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := parsed[sentence]; ok {
+			return nil, fmt.Errorf("duplicate sentence %q", name)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval for %s must be positive, got %v", sentence, interval)
+		}
+		parsed[sentence] = interval
+	}
+	return parsed, nil
+}
+
+// sentencePlan is the parsed sentence selection, rates and intervals, which
+// are fixed for the lifetime of a simulator
+type sentencePlan struct {
+	enabled   map[string]bool
+	rates     map[string]int
+	intervals map[string]time.Duration
+}
+
+// newSentencePlan parses the sentence settings of c. The selection defaults
+// to DefaultSentences (plus GNS with NMEA 4.1 output) when Config.Sentences
+// is empty, and invalid settings, which Validate rejects, are ignored.
+func newSentencePlan(c Config) *sentencePlan {
+	sentences, err := parseSentences(c.Sentences)
+	if err != nil || len(sentences) == 0 {
+		sentences = DefaultSentences
+		if c.NMEAVersion == NMEAVersion41 {
+			sentences = append(append([]string(nil), DefaultSentences...), SentenceGNS)
+		}
+	}
+	plan := &sentencePlan{enabled: make(map[string]bool, len(sentences)+2)}
+	for _, sentence := range sentences {
+		plan.enabled[sentence] = true
+	}
+	if c.EmitIntegritySentences {
+		plan.enabled[SentenceGRS] = true
+		plan.enabled[SentenceGBS] = true
+	}
+	if rates, err := parseSentenceRates(c.SentenceRates); err == nil {
+		plan.rates = rates
+	}
+	if intervals, err := parseSentenceIntervals(c.SentenceIntervals); err == nil {
+		plan.intervals = intervals
+	}
+	return plan
+}
+
+// sentencePlan returns the parsed sentence settings, parsing them once on
+// first use so output cycles only look them up
+func (s *GPSSimulator) sentencePlan() *sentencePlan {
+	if s.sentences == nil {
+		s.sentences = newSentencePlan(s.Config)
+	}
+	return s.sentences
+}
+
+// sentenceEnabled reports whether the given sentence type should be emitted
+func (s *GPSSimulator) sentenceEnabled(sentence string) bool {
+	return s.sentencePlan().enabled[sentence]
+}
+
+// sentenceDue reports whether the given sentence type should be emitted on the
+// current output tick, honouring Config.Sentences, Config.SentenceIntervals
+// and Config.SentenceRates. Sentences without a configured interval or rate
+// are emitted on every tick.
+func (s *GPSSimulator) sentenceDue(sentence string) bool {
+	plan := s.sentencePlan()
+	if !plan.enabled[sentence] {
+		return false
+	}
+	if interval, ok := plan.intervals[sentence]; ok {
+		return s.intervalDue(sentence, interval)
+	}
+	if divisor, ok := plan.rates[sentence]; ok {
+		return s.outputTick%divisor == 0
+	}
+	return true
+}
+
+// intervalDue reports whether a sentence emitted every interval is due now,
+// scheduling its next emission when it is. Cycles up to half an output
+// interval early count as on time, so ticker jitter doesn't skip a cycle.
+func (s *GPSSimulator) intervalDue(sentence string, interval time.Duration) bool {
+	now := s.now()
+	next, scheduled := s.sentenceNext[sentence]
+	if scheduled && now.Before(next.Add(-s.Config.OutputRate/2)) {
+		return false
+	}
+
+	// Keep to the schedule, restarting it after a gap such as a pause
+	if !scheduled || now.Sub(next) >= interval {
+		next = now
+	}
+	if s.sentenceNext == nil {
+		s.sentenceNext = make(map[string]time.Time)
+	}
+	s.sentenceNext[sentence] = next.Add(interval)
+	return true
+}
@@ -0,0 +1,298 @@
+package gps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSentence(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		shouldError bool
+	}{
+		{"GGA", "GGA", SentenceGGA, false},
+		{"RMC lowercase", "rmc", SentenceRMC, false},
+		{"GSV with spaces", " gsv ", SentenceGSV, false},
+		{"HDT", "hdt", SentenceHDT, false},
+		{"Unknown", "XTE", "", true},
+		{"Empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseSentence(tt.input)
+			if (err != nil) != tt.shouldError {
+				t.Fatalf("Expected error: %v, got: %v", tt.shouldError, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSentenceValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.Sentences = []string{"GGA", "rmc", "VTG"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid sentences, got: %v", err)
+	}
+
+	config.Sentences = []string{"GGA", "XYZ"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown sentence")
+	}
+
+	config.Sentences = []string{"GGA", "gga"}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for duplicate sentence")
+	}
+}
+
+// emittedSentenceTypes runs one output cycle and returns the sentence types written
+func emittedSentenceTypes(sim *GPSSimulator) map[string]bool {
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+	sim.outputNMEA()
+
+	types := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\r\n") {
+		if len(line) >= 6 {
+			types[line[3:6]] = true
+		}
+	}
+	return types
+}
+
+func TestSentenceSelectionDefaults(t *testing.T) {
+	sim := createTestSimulator()
+
+	types := emittedSentenceTypes(sim)
+	for _, sentence := range DefaultSentences {
+		if !types[sentence] {
+			t.Errorf("Expected %s to be emitted by default", sentence)
+		}
+	}
+	for _, sentence := range []string{SentenceHDT, SentenceROT} {
+		if types[sentence] {
+			t.Errorf("Expected %s to be emitted only when selected", sentence)
+		}
+	}
+}
+
+func TestSentenceSelectionFiltersOutput(t *testing.T) {
+	for _, locked := range []bool{true, false} {
+		sim := createTestSimulator()
+		sim.isLocked = locked
+		sim.Config.Sentences = []string{"GGA", "VTG"}
+
+		types := emittedSentenceTypes(sim)
+		if !types[SentenceGGA] || !types[SentenceVTG] {
+			t.Errorf("Expected GGA and VTG (locked=%v), got %v", locked, types)
+		}
+		for sentence := range types {
+			if sentence != SentenceGGA && sentence != SentenceVTG {
+				t.Errorf("Disabled sentence %s was emitted (locked=%v)", sentence, locked)
+			}
+		}
+	}
+}
+
+func TestSentenceSelectionGGAAndRMC(t *testing.T) {
+	for _, locked := range []bool{true, false} {
+		sim := createTestSimulator()
+		sim.isLocked = locked
+		sim.lockTime = time.Now().Add(-time.Second)
+		sim.Config.Sentences = []string{"GGA", "RMC"}
+
+		types := emittedSentenceTypes(sim)
+		if !types[SentenceGGA] || !types[SentenceRMC] {
+			t.Errorf("Expected GGA and RMC (locked=%v), got %v", locked, types)
+		}
+		for _, sentence := range []string{SentenceGSV, SentenceGSA, SentenceZDA} {
+			if types[sentence] {
+				t.Errorf("Expected no %s (locked=%v)", sentence, locked)
+			}
+		}
+	}
+}
+
+func TestSentenceSelectionNoFixRespectsFilter(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Minute)
+	sim.Config.Sentences = []string{"GSV", "ZDA"}
+
+	// Neither GSV nor ZDA is emitted before any satellite is acquired, so
+	// nothing should be output
+	if types := emittedSentenceTypes(sim); len(types) != 0 {
+		t.Errorf("Expected no sentences before lock, got %v", types)
+	}
+}
+
+func TestParseSentenceRates(t *testing.T) {
+	rates, err := ParseSentenceRates("GSV=5, gsa=3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rates["GSV"] != 5 || rates["GSA"] != 3 {
+		t.Errorf("Expected GSV=5 and GSA=3, got %v", rates)
+	}
+
+	for _, spec := range []string{"GSV", "GSV=fast", "GSV=5,", "GSV=5,GSV=2", "GSV=5, gsv=2"} {
+		if _, err := ParseSentenceRates(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestSentenceRateValidation(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+
+	config.SentenceRates = map[string]int{"GSV": 5, "gsa": 1}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected valid sentence rates, got: %v", err)
+	}
+
+	config.SentenceRates = map[string]int{"GSV": 0}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for divisor below 1")
+	}
+
+	config.SentenceRates = map[string]int{"XYZ": 2}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for unknown sentence")
+	}
+
+	config.SentenceRates = map[string]int{"GSV": 2, "gsv": 3}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for duplicate sentence")
+	}
+}
+
+func TestSentenceRatesOverTicks(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.SentenceRates = map[string]int{"GSV": 5, "GSA": 2}
+
+	counts := make(map[string]int)
+	for tick := 0; tick < 10; tick++ {
+		for sentence := range emittedSentenceTypes(sim) {
+			counts[sentence]++
+		}
+	}
+
+	expected := map[string]int{
+		SentenceGGA: 10,
+		SentenceRMC: 10,
+		SentenceVTG: 10,
+		SentenceGSA: 5,
+		SentenceGSV: 2,
+	}
+	for sentence, want := range expected {
+		if counts[sentence] != want {
+			t.Errorf("Expected %s %d times over 10 ticks, got %d", sentence, want, counts[sentence])
+		}
+	}
+}
+
+func TestSentenceIntervalsOverFakeClock(t *testing.T) {
+	sim := createTestSimulator()
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.startTime = clock.Now()
+	sim.Config.SentenceIntervals = map[string]time.Duration{"GSV": 5 * time.Second, "GGA": time.Second}
+	sim.Config.SentenceRates = map[string]int{"GSV": 2}
+
+	counts := make(map[string]int)
+	for tick := 0; tick < 10; tick++ {
+		for sentence := range emittedSentenceTypes(sim) {
+			counts[sentence]++
+		}
+		clock.Advance(time.Second)
+	}
+
+	// The GSV interval takes precedence over its rate divisor
+	expected := map[string]int{
+		SentenceGGA: 10,
+		SentenceRMC: 10,
+		SentenceGSV: 2,
+	}
+	for sentence, want := range expected {
+		if counts[sentence] != want {
+			t.Errorf("Expected %s %d times over 10s, got %d", sentence, want, counts[sentence])
+		}
+	}
+}
+
+func TestSentenceIntervalsToleratesEarlyTicks(t *testing.T) {
+	sim := createTestSimulator()
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.Config.SentenceIntervals = map[string]time.Duration{"GSV": 2 * time.Second}
+
+	gsv := 0
+	for tick := 0; tick < 6; tick++ {
+		if emittedSentenceTypes(sim)[SentenceGSV] {
+			gsv++
+		}
+		// Ticks arriving slightly early don't push GSV back a cycle
+		clock.Advance(time.Second - time.Millisecond)
+	}
+	if gsv != 3 {
+		t.Errorf("Expected GSV 3 times over 6 early ticks, got %d", gsv)
+	}
+}
+
+func TestParseSentenceIntervals(t *testing.T) {
+	intervals, err := ParseSentenceIntervals("GSV=5s, gsa=500ms")
+	if err != nil {
+		t.Fatalf("ParseSentenceIntervals() failed: %v", err)
+	}
+	if intervals["GSV"] != 5*time.Second || intervals["GSA"] != 500*time.Millisecond {
+		t.Errorf("Unexpected intervals %v", intervals)
+	}
+	for _, spec := range []string{"GSV", "GSV=fast", "GSV=5", "GSV=5s,GSV=2s", "GSV=5s, gsv =2s"} {
+		if _, err := ParseSentenceIntervals(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	for _, intervals := range []map[string]time.Duration{
+		{"GSV": 0},
+		{"XYZ": time.Second},
+		{"GSV": time.Second, "gsv": 2 * time.Second},
+	} {
+		config.SentenceIntervals = intervals
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected error for sentence intervals %v", intervals)
+		}
+	}
+}
+
+func TestSentenceRatesApplyBeforeLock(t *testing.T) {
+	sim := createTestSimulator()
+	sim.isLocked = false
+	sim.Config.SentenceRates = map[string]int{"RMC": 3}
+
+	rmc := 0
+	for tick := 0; tick < 6; tick++ {
+		if emittedSentenceTypes(sim)[SentenceRMC] {
+			rmc++
+		}
+	}
+	if rmc != 2 {
+		t.Errorf("Expected no-fix RMC twice over 6 ticks, got %d", rmc)
+	}
+}
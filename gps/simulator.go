@@ -1,36 +1,353 @@
 package gps
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
+	"net"
 	"os"
+	"sync"
 	"time"
 )
 
 // Config represents the configuration for the GPS simulator
 type Config struct {
-	Latitude       float64
-	Longitude      float64
-	Radius         float64       // in meters
-	Altitude       float64       // starting altitude in meters
-	Jitter         float64       // GPS jitter factor (0.0-1.0)
-	AltitudeJitter float64       // altitude jitter factor (0.0-1.0)
-	Speed          float64       // static speed in knots
-	Course         float64       // static course in degrees (0-359)
-	Satellites     int
-	TimeToLock     time.Duration
-	OutputRate     time.Duration
-	SerialPort     string        // Serial port device (e.g., /dev/ttyUSB0, COM1)
-	BaudRate       int           // Serial baud rate
-	Quiet          bool          // Suppress informational messages
-	GPXEnabled     bool          // Enable GPX file generation with timestamp filename
-	GPXFile        string        // Generated GPX filename (internal use)
-	Duration       time.Duration // How long to run the simulation (0 = run indefinitely)
-	ReplayFile     string        // GPX file to replay (empty = normal simulation mode)
-	ReplaySpeed    float64       // Replay speed multiplier (1.0 = real-time, 2.0 = 2x speed, etc.)
-	ReplayLoop     bool          // Whether to loop the replay (false = stop after one pass, true = loop continuously)
+	Latitude               float64
+	Longitude              float64
+	Radius                 float64 // in meters
+	Altitude               float64 // starting altitude in meters
+	Jitter                 float64 // GPS jitter factor (0.0-1.0)
+	AltitudeJitter         float64 // altitude jitter factor (0.0-1.0)
+	ClimbRate              float64 // Meters per second the altitude ramps at, negative to descend (0 = altitude only jitters)
+	TargetAltitude         float64 // Altitude in meters to level off at when climbing or descending toward it
+	Speed                  float64 // static speed in knots
+	Course                 float64 // static course in degrees (0-359)
+	MinSpeedForCourse      float64 // Speed in knots below which course is held and left empty in RMC and VTG (0 = always report course)
+	Satellites             int
+	SkyCondition           string // Preset satellite elevation and SNR ranges (open, urban, indoor); empty keeps the defaults
+	MinElevation           *int   // Lowest satellite elevation in degrees (nil = from SkyCondition or default)
+	MaxElevation           *int   // Highest satellite elevation in degrees (nil = from SkyCondition or default)
+	MinSNR                 *int   // Weakest satellite signal in dB (nil = from SkyCondition or default)
+	MaxSNR                 *int   // Strongest satellite signal in dB (nil = from SkyCondition or default)
+	SatelliteChurn         bool   // Satellites rise and set over time, keeping the count within 2 of Satellites
+	TimeToLock             time.Duration
+	OutputRate             time.Duration
+	SerialPort             string                   // Serial port device (e.g., /dev/ttyUSB0, COM1)
+	BaudRate               int                      // Serial baud rate
+	PaceOutput             bool                     // Space sentence writes across each output interval by their transmission time at BaudRate
+	Quiet                  bool                     // Suppress informational messages
+	GPXEnabled             bool                     // Enable GPX file generation with timestamp filename
+	GPXExtensions          bool                     // Record speed, course, satellites and HDOP in generated GPX track points
+	GPXFile                string                   // Generated GPX filename (internal use)
+	GeoJSONEnabled         bool                     // Enable GeoJSON track file generation with timestamp filename
+	GeoJSONFile            string                   // Generated GeoJSON filename (internal use)
+	GeoJSONPoints          bool                     // Write a Point feature per GeoJSON track point instead of coordinateProperties arrays
+	KMLEnabled             bool                     // Enable KML track file generation with timestamp filename
+	KMLFile                string                   // Generated KML filename (internal use)
+	KMLName                string                   // Name of the KML document and track (default "GPS Simulator Track")
+	CSVFile                string                   // Record each output cycle as a row in this CSV file (empty = disabled)
+	CSVColumns             []string                 // CSV columns to write, in order (empty = all of CSVColumns)
+	Duration               time.Duration            // How long to run the simulation (0 = run indefinitely)
+	ReplayFile             string                   // GPX or KML (.kml) file to replay (empty = normal simulation mode)
+	ReplayFiles            []string                 // GPX or KML files to replay back to back instead of ReplayFile, each loaded when reached
+	ReplayPoints           []TrackPoint             // Track points to replay from memory, taking precedence over ReplayFile and ReplayFiles
+	ReplayGapBehavior      string                   // How a playlist moves between files (jump, simulate to drive there at Speed); empty is jump
+	ReplaySpeed            float64                  // Replay speed multiplier (1.0 = real-time, 2.0 = 2x speed, etc.)
+	ReplayLoop             bool                     // Whether to loop the replay (false = stop after one pass, true = loop continuously)
+	ReplayInterpolate      bool                     // Interpolate position (along great circles), altitude, speed and course between replay points instead of snapping; the CLI enables it by default
+	ReplayReverse          bool                     // Replay the track backwards from the last point to the first
+	ReplaySegmentGaps      bool                     // Hold position through time gaps between track segments instead of collapsing them
+	ReplayHonorSegments    bool                     // Report no fix briefly at each track segment boundary, as when the recording lost signal
+	ReplayUseOriginalTime  bool                     // Timestamp output with the replayed track's recorded times instead of the clock
+	ReplayNMEAFile         string                   // Recorded NMEA log to re-emit verbatim, paced by its timestamps (empty = disabled)
+	ReplayRewriteTime      bool                     // Rewrite the time and date fields of replayed NMEA sentences to the current time
+	ReplayNMEAFixedRate    bool                     // Replay one NMEA log epoch per output cycle instead of pacing by its timestamps
+	NMEAStrict             bool                     // Drop replayed NMEA sentences with a missing or bad checksum instead of passing them through unchanged
+	TalkerID               string                   // NMEA talker ID prefix (e.g., GP, GL, GA, GB, GN); empty derives it from Constellations
+	Constellations         []string                 // Enabled constellations (GPS, GLONASS, Galileo, BeiDou); empty defaults to GPS only
+	RouteFile              string                   // GPX file with route waypoints to drive between at Speed (empty = disabled)
+	RouteLoop              bool                     // Whether to loop back to the first waypoint after reaching the last
+	Waypoints              []Coordinate             // Waypoints to navigate between at Speed along great circles (empty = disabled)
+	WaypointLoop           bool                     // Whether to loop back to the first of Waypoints after reaching the last
+	Geofences              []Geofence               // Circular regions reported on with geofence events as the position enters and leaves them
+	GeofenceDwell          int                      // Consecutive output cycles inside or outside a geofence confirming a crossing (0 = DefaultGeofenceDwell)
+	Sentences              []string                 // NMEA sentence types to emit (GGA, RMC, GLL, VTG, GSA, GSV, ZDA, GST, HDT, ROT, GRS, GBS); empty emits all but HDT, ROT, GRS and GBS
+	EmitIntegritySentences bool                     // Emit GRS range residuals and GBS fault detection while locked, for RAIM-aware consumers
+	SentenceRates          map[string]int           // Per-sentence divisor of OutputRate (e.g., GSV: 5 emits GSV every 5th tick); unset sentences emit every tick
+	SentenceIntervals      map[string]time.Duration // Per-sentence emit interval (e.g., GSV: 5s), taking precedence over SentenceRates; unset sentences follow SentenceRates
+	TimeScale              float64                  // Simulated seconds per wall-clock second (e.g., 60 = one hour per minute); 0 means real time
+	TimeOffset             time.Duration            // Added to every emitted timestamp, e.g. to simulate GPS-UTC leap second differences
+	SimulatedDate          string                   // RFC3339 date and time output starts at, advancing with simulated time (empty = clock time)
+	TCPListen              string                   // TCP address to serve NMEA to connected clients (e.g., :10110); empty disables
+	UDPTarget              string                   // UDP host:port to send each sentence to as a datagram (e.g., 255.255.255.255:10110); empty disables
+	GpsdMode               bool                     // Emit gpsd JSON (TPV/SKY) reports instead of NMEA sentences
+	GpsdListen             string                   // TCP address to serve gpsd JSON (TPV/SKY) reports on alongside NMEA output (e.g., :2947); empty disables
+	OutputFormat           string                   // What each output cycle writes (nmea, json, both); empty is nmea
+	JSONWriter             io.Writer                `json:"-"` // Where the both output format writes its JSON lines
+	DropoutInterval        time.Duration            // Time locked before the signal is lost again (0 = never lose fix)
+	DropoutDuration        time.Duration            // How long the fix stays lost before re-acquisition starts
+	MagneticDeclination    float64                  // Magnetic declination in degrees, positive east, reported in RMC and VTG
+	BiasMeters             float64                  // Constant position error in meters applied to reported NMEA positions, not to movement or track output (0 = none)
+	BiasBearing            float64                  // Direction of BiasMeters in degrees from true north
+	DOPJitter              float64                  // Random variation applied to reported DOP values (0.0-1.0)
+	FixQuality             int                      // GGA fix quality while locked (1 = GPS, 2 = DGPS, 4 = RTK fixed, 5 = RTK float, ...); 0 defaults to 1
+	DGPSAge                float64                  // Age of differential corrections in seconds reported in GGA for DGPS and RTK fixes (0 = DefaultDGPSAge)
+	DGPSStationID          int                      // Differential reference station ID reported in GGA for DGPS and RTK fixes (0-1023)
+	StartMode              string                   // Receiver start mode (cold, warm, hot) scaling TimeToLock; empty is a cold start
+	AcquisitionProfile     string                   // How satellites are acquired before lock (progressive, instant, gradual); empty is progressive
+	RouteMode              string                   // How the receiver moves around the center (wander, roadlike); empty is wander
+	MaxTurnRate            float64                  // Road-like turn rate limit in degrees per second (0 = DefaultMaxTurnRate)
+	Pattern                string                   // Movement pattern around the center (wander, circle, figure-eight, grid, random-walk); empty is wander
+	GridSpacing            float64                  // Meters between grid pattern survey lines (0 = a fifth of Radius)
+	EmitMagneticVariation  bool                     // Populate magnetic variation fields even when MagneticDeclination is zero
+	GeoidSeparation        float64                  // Geoid height above the WGS84 ellipsoid in meters reported in GGA (default 0)
+	AutoGeoidSeparation    bool                     // Approximate the geoid separation from the current position instead of GeoidSeparation
+	UpdateRate             time.Duration            // Position integration interval between output cycles (e.g., 100ms); 0 or >= OutputRate integrates once per output
+	TimePrecision          int                      // Decimal places of seconds in every sentence time field (0-3); 0 is whole seconds, or hundredths below 1s OutputRate
+	TransitionDuration     time.Duration            // Time to move to a new center set with UpdateConfig (0 = travel at Speed)
+	Seed                   int64                    // Seed for the simulator's random source so runs are reproducible (0 = seeded from the current time)
+	RecordScenario         string                   // JSON file to record the session to: this config, the seed and every UpdateConfig change (empty = disabled)
+	ScenarioFile           string                   // Recorded scenario to play back, replacing all but the output settings (empty = disabled)
+	Script                 []ScriptStep             // Timed changes to speed, course, jitter and satellites applied during the run, in time order
+	NMEAVersion            string                   // NMEA output version ("2.3" or "4.1", adding GNS and GSA/GSV system and signal IDs); empty is 2.3
+}
+
+// DefaultTalkerID is the NMEA talker ID used when Config.TalkerID is empty
+const DefaultTalkerID = "GP"
+
+// Validate checks that the configuration values are within their allowed ranges
+func (c Config) Validate() error {
+	if c.Satellites < 4 || c.Satellites > 12 {
+		return errors.New("Number of satellites must be between 4 and 12")
+	}
+
+	if c.Radius < 0 {
+		return errors.New("Radius must be positive")
+	}
+
+	if c.Jitter < 0.0 || c.Jitter > 1.0 {
+		return errors.New("Jitter must be between 0.0 and 1.0")
+	}
+
+	if c.AltitudeJitter < 0.0 || c.AltitudeJitter > 1.0 {
+		return errors.New("Altitude jitter must be between 0.0 and 1.0")
+	}
+
+	if c.FixQuality < FixQualityInvalid || c.FixQuality > FixQualitySimulated {
+		return errors.New("Fix quality must be between 0 and 8")
+	}
+
+	if c.DGPSAge < 0 || c.DGPSAge > 999.9 {
+		return errors.New("DGPS age must be between 0 and 999.9 seconds")
+	}
+
+	if c.DGPSStationID < 0 || c.DGPSStationID > MaxDGPSStationID {
+		return fmt.Errorf("DGPS station ID must be between 0 and %d", MaxDGPSStationID)
+	}
+
+	if c.DOPJitter < 0.0 || c.DOPJitter > 1.0 {
+		return errors.New("DOP jitter must be between 0.0 and 1.0")
+	}
+
+	if c.BaudRate <= 0 {
+		return errors.New("Baud rate must be positive")
+	}
+
+	if c.Speed < 0.0 {
+		return errors.New("Speed must be non-negative")
+	}
+
+	if c.Course < 0.0 || c.Course >= 360.0 {
+		return errors.New("Course must be between 0.0 and 359.9 degrees")
+	}
+
+	if c.ReplaySpeed <= 0.0 {
+		return errors.New("Replay speed must be positive")
+	}
+
+	if c.TalkerID != "" && !isValidTalkerID(c.TalkerID) {
+		return fmt.Errorf("Talker ID must be exactly two uppercase letters, got %q", c.TalkerID)
+	}
+
+	if _, err := parseConstellations(c.Constellations); err != nil {
+		return fmt.Errorf("Invalid constellations: %v", err)
+	}
+
+	if _, err := parseSentences(c.Sentences); err != nil {
+		return fmt.Errorf("Invalid sentences: %v", err)
+	}
+
+	if _, err := parseSentenceRates(c.SentenceRates); err != nil {
+		return fmt.Errorf("Invalid sentence rates: %v", err)
+	}
+
+	if _, err := parseSentenceIntervals(c.SentenceIntervals); err != nil {
+		return fmt.Errorf("Invalid sentence intervals: %v", err)
+	}
+
+	if c.TimeScale < 0.0 {
+		return errors.New("Time scale must be non-negative")
+	}
+
+	if c.SimulatedDate != "" {
+		if _, err := time.Parse(time.RFC3339, c.SimulatedDate); err != nil {
+			return fmt.Errorf("Simulated date must be RFC3339 (e.g., 2024-01-15T23:59:58Z): %v", err)
+		}
+	}
+
+	if c.ReplayUseOriginalTime && c.SimulatedDate != "" {
+		return errors.New("Replay original time and simulated date cannot be used together")
+	}
+
+	if c.MagneticDeclination < -180.0 || c.MagneticDeclination > 180.0 {
+		return errors.New("Magnetic declination must be between -180.0 and 180.0 degrees")
+	}
+
+	if _, err := ParseStartMode(c.StartMode); err != nil {
+		return fmt.Errorf("Invalid start mode: %v", err)
+	}
+
+	if _, err := ParseAcquisitionProfile(c.AcquisitionProfile); err != nil {
+		return fmt.Errorf("Invalid acquisition profile: %v", err)
+	}
+
+	if _, err := ParseRouteMode(c.RouteMode); err != nil {
+		return fmt.Errorf("Invalid route mode: %v", err)
+	}
+
+	if c.MinSpeedForCourse < 0 {
+		return errors.New("Minimum speed for course must be non-negative")
+	}
+
+	if c.MaxTurnRate < 0 {
+		return errors.New("Max turn rate must be non-negative")
+	}
+
+	pattern, err := ParsePattern(c.Pattern)
+	if err != nil {
+		return fmt.Errorf("Invalid movement pattern: %v", err)
+	}
+	if pattern != PatternWander {
+		if c.Radius <= 0 {
+			return fmt.Errorf("The %s pattern needs a radius greater than 0", pattern)
+		}
+		if mode, _ := ParseRouteMode(c.RouteMode); mode == RouteModeRoadlike {
+			return errors.New("Movement patterns cannot be used with the road-like route mode")
+		}
+	}
+
+	if c.GridSpacing < 0 {
+		return errors.New("Grid spacing must be positive")
+	}
+
+	if err := c.validateSky(); err != nil {
+		return err
+	}
+
+	if err := c.validateCSVColumns(); err != nil {
+		return err
+	}
+
+	if err := c.validateBias(); err != nil {
+		return err
+	}
+
+	if c.NMEAVersion != "" && c.NMEAVersion != NMEAVersion23 && c.NMEAVersion != NMEAVersion41 {
+		return fmt.Errorf("NMEA version must be %s or %s, got %q", NMEAVersion23, NMEAVersion41, c.NMEAVersion)
+	}
+
+	if c.DropoutInterval < 0 || c.DropoutDuration < 0 {
+		return errors.New("Dropout interval and duration must be non-negative")
+	}
+
+	if c.GeoidSeparation < -200.0 || c.GeoidSeparation > 200.0 {
+		return errors.New("Geoid separation must be between -200.0 and 200.0 meters")
+	}
+
+	if c.UpdateRate < 0 {
+		return errors.New("Update rate must be non-negative")
+	}
+
+	if c.TimePrecision < 0 || c.TimePrecision > 3 {
+		return errors.New("Time precision must be between 0 and 3 decimal places")
+	}
+
+	if c.TransitionDuration < 0 {
+		return errors.New("Transition duration must be non-negative")
+	}
+
+	if c.ReplayFile != "" && len(c.ReplayFiles) > 0 {
+		return errors.New("Replay file and replay files cannot be used together")
+	}
+
+	if c.ReplayPoints != nil && len(c.ReplayPoints) == 0 {
+		return errors.New("Replay points must not be empty")
+	}
+
+	for i, p := range c.ReplayPoints {
+		if math.IsNaN(p.Lat) || math.IsNaN(p.Lon) || p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+			return fmt.Errorf("Replay point %d (%f, %f) is out of range", i+1, p.Lat, p.Lon)
+		}
+	}
+
+	if _, err := ParseReplayGapBehavior(c.ReplayGapBehavior); err != nil {
+		return fmt.Errorf("Invalid replay gap behavior: %v", err)
+	}
+
+	if c.RouteFile != "" && (c.ReplayFile != "" || len(c.ReplayFiles) > 0 || len(c.ReplayPoints) > 0) {
+		return errors.New("Route file and replay file cannot be used together")
+	}
+
+	if c.ReplayNMEAFile != "" && (c.ReplayFile != "" || len(c.ReplayFiles) > 0 || len(c.ReplayPoints) > 0 || c.RouteFile != "" || len(c.Waypoints) > 0) {
+		return errors.New("NMEA replay file cannot be used together with a route, waypoints or replay file")
+	}
+
+	if c.ReplayNMEAFile != "" && c.GpsdMode {
+		return errors.New("NMEA replay file cannot be used with gpsd output")
+	}
+
+	if err := c.validateOutputFormat(); err != nil {
+		return err
+	}
+
+	if err := c.validateScript(); err != nil {
+		return err
+	}
+
+	if c.ScenarioFile != "" && c.ScenarioFile == c.RecordScenario {
+		return errors.New("Scenario file and scenario recording must be different files")
+	}
+
+	if len(c.Waypoints) > 0 && (c.RouteFile != "" || c.ReplayFile != "" || len(c.ReplayFiles) > 0 || len(c.ReplayPoints) > 0) {
+		return errors.New("Waypoints cannot be used together with a route or replay file")
+	}
+
+	for i, wp := range c.Waypoints {
+		if wp.Lat < -90 || wp.Lat > 90 || wp.Lon < -180 || wp.Lon > 180 {
+			return fmt.Errorf("Waypoint %d (%.6f, %.6f) is out of range", i+1, wp.Lat, wp.Lon)
+		}
+	}
+
+	if err := c.validateGeofences(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// isValidTalkerID reports whether id is exactly two uppercase ASCII letters
+func isValidTalkerID(id string) bool {
+	if len(id) != 2 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 'A' || id[i] > 'Z' {
+			return false
+		}
+	}
+	return true
 }
 
 type GPSSimulator struct {
@@ -47,22 +364,151 @@ type GPSSimulator struct {
 	Satellites     []Satellite
 	nmeaWriter     io.Writer
 	gpxWriter      *GPXWriter
+	geoJSONWriter  *GeoJSONWriter
+	kmlWriter      *KMLWriter
+	csvWriter      *CSVWriter
 	// Replay mode fields
 	replayPoints    []TrackPoint
 	replayIndex     int
 	replayStartTime time.Time
-	replayCompleted bool // Track if we've completed one full pass through the replay
+	replayStats     ReplayStats
+	replayCompleted bool   // Track if we've completed one full pass through the replay
+	replaySegment   int    // Segment of the active replay point, to spot segment boundaries
+	replayFileIndex int    // Index of the playlist file being replayed
+	replayLoops     uint64 // Passes completed through the replay track or playlist
+	// Recorded NMEA log replay, sharing the replay index and clock above
+	nmeaEpochs  []nmeaEpoch
+	nmeaPending []string // Replayed sentences due at the next output cycle
+	nmeaDropped int      // Lines of the NMEA log dropped when it was loaded
+	// Channel subscribers receiving each emitted sentence
+	stream sentenceStream
+	// Callbacks receiving status events
+	events eventHub
+
+	// Whether the position is inside each geofence, by name
+	geofences map[string]*geofenceState
+	// Spaces out config updates from HandleControlMessage
+	control controlLimiter
+	// Route following fields
+	routePoints    []TrackPoint
+	routeIndex     int     // Index of the waypoint currently being navigated to
+	routeLat       float64 // Ideal position on the route before jitter is applied
+	routeLon       float64
+	routeCompleted bool
+	// Network outputs streaming NMEA to clients
+	tcpServer *tcpServer
+	udpWriter *udpWriter
+	// Serves gpsd JSON reports to clients on GpsdListen
+	gpsdServer *tcpServer
+	// Paces writes to the NMEA writer at BaudRate when PaceOutput is set
+	pacer *pacingWriter
+	// Number of output cycles completed, used for per-sentence rates
+	outputTick int
+	// When each sentence with a SentenceIntervals entry is next due
+	sentenceNext map[string]time.Time
+	// Number of output bursts started, identifying the burst each emitted
+	// sentence belongs to
+	bursts uint64
+	// Output cycle count and timing, for metrics
+	ticks tickTiming
+	// Source of simulated time
+	clock Clock
+	// Guards all mutable state: each simulation cycle and control call takes
+	// the write lock, while read-only accessors take the read lock
+	mu       sync.RWMutex
+	paused   bool
+	pausedAt time.Time
+	// Course at the previous update, used for the rate of turn
+	previousCourse     float64
+	previousCourseTime time.Time
+	rateOfTurn         float64 // Degrees per minute, negative to port
+	// DOP reported in output, updated as satellites move (nil until first update)
+	dop *DOP
+	// Signal dropout state
+	dropoutAt  time.Time // When the signal starts fading (zero = no dropout scheduled)
+	signalFade float64   // Fraction of satellite signal strength lost (0 = clear, 1 = lost)
+	signalLost bool      // Whether the fix was lost to a dropout and has not been re-acquired
+	// Movement toward a new center set with UpdateConfig (nil when not moving)
+	transition *positionTransition
+	// Output rate changes from UpdateConfig, picked up by Run
+	rateChange chan time.Duration
+	// Fix recorded at the end of the most recent update
+	fix Fix
+	// Whether the integration step running is between output cycles, when
+	// random jitter is left for the output cycle
+	betweenOutputs bool
+	// Last GSV sentences generated, reused while satellites are unchanged
+	gsv gsvCache
+	// Bytes emitted in the current output cycle and the serial throughput
+	// check they feed
+	burstBytes int
+	throughput throughputCheck
+	// Range residuals of the satellites for the epoch they were drawn at,
+	// shared by GRS and GBS
+	residuals   []float64
+	residualsAt time.Time
+	// Road-like leg being driven (zero until the first road-like update)
+	road roadLeg
+	// Movement pattern being followed (nil until first use) and its ideal
+	// position before jitter
+	pattern         MovementPattern
+	patternPosition Coordinate
+	// Altitude ramped by Config.ClimbRate before jitter, and when it was last
+	// updated
+	altitudeBase float64
+	altitudeTime time.Time
+	// Whether Run is active, how it finished and its OnComplete callbacks
+	run runState
+	// Parsed sentence selection, rates and intervals (nil until first use)
+	sentences *sentencePlan
+	// Random source for satellites, jitter and wander (nil until first use)
+	rng *rand.Rand
+	// Session being recorded to Config.RecordScenario (nil when not recording)
+	recording *Scenario
+	// Played back configuration changes not yet applied
+	scenarioUpdates []ScenarioUpdate
+	// Index of the next Config.Script step to apply
+	scriptIndex int
 }
 
 type Satellite struct {
-	ID        int
-	Elevation int // degrees above horizon
-	Azimuth   int // degrees from north
-	SNR       int // signal-to-noise ratio
+	ID            int
+	Elevation     int           // degrees above horizon
+	Azimuth       int           // degrees from north
+	SNR           int           // signal-to-noise ratio
+	Constellation Constellation // satellite system the satellite belongs to
+	acquireLead   time.Duration // how long before lock the satellite is acquired
+	setting       bool          // moving toward the horizon, with SatelliteChurn
 }
 
 func NewGPSSimulator(config Config, nmeaWriter io.Writer) (*GPSSimulator, error) {
-	now := time.Now()
+	// Play back a recorded scenario to the outputs configured here
+	var scenarioUpdates []ScenarioUpdate
+	if config.ScenarioFile != "" {
+		scenario, err := LoadScenario(config.ScenarioFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scenario: %v", err)
+		}
+		config = scenario.PlaybackConfig(config)
+		scenarioUpdates = scenario.Updates
+	}
+
+	// Pacing divides by the baud rate
+	if config.PaceOutput && config.BaudRate <= 0 {
+		return nil, fmt.Errorf("invalid baud rate %d for paced output", config.BaudRate)
+	}
+
+	// A recorded session needs a known seed to be reproduced
+	if config.RecordScenario != "" && config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	var clock Clock = realClock{}
+	if config.TimeScale > 0 && config.TimeScale != 1.0 {
+		clock = newScaledClock(clock, config.TimeScale)
+	}
+
+	now := clock.Now()
 	sim := &GPSSimulator{
 		Config:          config,
 		currentLat:      config.Latitude,
@@ -72,30 +518,72 @@ func NewGPSSimulator(config Config, nmeaWriter io.Writer) (*GPSSimulator, error)
 		currentCourse:   config.Course,
 		isLocked:        false,
 		startTime:       now,
-		lockTime:        now.Add(config.TimeToLock),
 		lastUpdateTime:  now,
+		altitudeBase:    config.Altitude,
+		altitudeTime:    now,
 		nmeaWriter:      nmeaWriter,
 		replayIndex:     0,
 		replayStartTime: now,
 		replayCompleted: false,
+		clock:           clock,
+		rateChange:      make(chan time.Duration, 1),
+		rng:             newRand(config.Seed),
+		scenarioUpdates: scenarioUpdates,
 	}
 
-	// Load GPX file for replay mode
-	if config.ReplayFile != "" {
-		points, err := ReadGPXFile(config.ReplayFile)
-		if err != nil {
+	// Load the first GPX or KML file for replay mode, or the given points;
+	// later playlist files are loaded when reached
+	if len(config.ReplayPoints) > 0 {
+		sim.loadReplayPoints(append([]TrackPoint(nil), config.ReplayPoints...), 0)
+	} else if sim.isReplayMode() {
+		if err := checkReplayFiles(config.replayPlaylist()); err != nil {
 			return nil, fmt.Errorf("failed to load replay file: %v", err)
 		}
-		sim.replayPoints = points
+		if err := sim.loadReplayFile(0); err != nil {
+			return nil, fmt.Errorf("failed to load replay file: %v", err)
+		}
+	}
+	if sim.isReplayMode() {
 
-		// Set initial position from first track point
-		if len(points) > 0 {
+		// Set initial position from first track point (the last one when reversed)
+		if points := sim.replayPoints; len(points) > 0 {
 			sim.currentLat = points[0].Lat
 			sim.currentLon = points[0].Lon
 			sim.currentAlt = points[0].Elevation
 		}
 	}
 
+	// Load the recorded NMEA log, starting from its first fix
+	if config.ReplayNMEAFile != "" {
+		epochs, summary, err := readNMEAFile(config.ReplayNMEAFile, config.NMEAStrict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load NMEA replay file: %v", err)
+		}
+		sim.nmeaEpochs = epochs
+		sim.nmeaDropped = summary.Skipped
+		for _, epoch := range epochs {
+			if epoch.locked {
+				sim.applyNMEAEpoch(epoch)
+				sim.isLocked = false
+				break
+			}
+		}
+	}
+
+	// Load GPX route waypoints for route following mode
+	if config.RouteFile != "" {
+		points, err := ReadGPXRoute(config.RouteFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load route file: %v", err)
+		}
+		sim.loadRoute(points)
+	}
+
+	// Navigate between configured waypoints
+	if len(config.Waypoints) > 0 {
+		sim.loadRoute(waypointRoute(config.Waypoints, config.Altitude))
+	}
+
 	// Initialize GPX writer if GPX is enabled
 	if config.GPXEnabled {
 		gpxWriter, err := NewGPXWriter(config.GPXFile)
@@ -103,30 +591,209 @@ func NewGPSSimulator(config Config, nmeaWriter io.Writer) (*GPSSimulator, error)
 			return nil, fmt.Errorf("failed to create GPX writer: %v", err)
 		}
 		sim.gpxWriter = gpxWriter
+		gpxWriter.extensions = config.GPXExtensions
+	}
+
+	// Initialize GeoJSON writer if GeoJSON is enabled
+	if config.GeoJSONEnabled {
+		geoJSONWriter, err := NewGeoJSONWriter(config.GeoJSONFile, config.GeoJSONPoints)
+		if err != nil {
+			sim.abortTracks()
+			return nil, fmt.Errorf("failed to create GeoJSON writer: %v", err)
+		}
+		sim.geoJSONWriter = geoJSONWriter
+	}
+
+	// Initialize KML writer if KML is enabled
+	if config.KMLEnabled {
+		kmlWriter, err := NewKMLWriter(config.KMLFile, config.KMLName)
+		if err != nil {
+			sim.abortTracks()
+			return nil, fmt.Errorf("failed to create KML writer: %v", err)
+		}
+		sim.kmlWriter = kmlWriter
+	}
+
+	// Initialize CSV writer if a CSV file is set
+	if config.CSVFile != "" {
+		csvWriter, err := NewCSVWriter(config.CSVFile, config.CSVColumns)
+		if err != nil {
+			sim.abortTracks()
+			return nil, fmt.Errorf("failed to create CSV writer: %v", err)
+		}
+		sim.csvWriter = csvWriter
+	}
+
+	// Release anything already opened if a network output fails to start
+	abort := func() {
+		sim.closeOutputs()
+		sim.abortTracks()
+	}
+
+	// Fan NMEA output out to network outputs as well as the writer
+	writers := []io.Writer{}
+	if nmeaWriter != nil {
+		if config.PaceOutput {
+			sim.pacer = newPacingWriter(nmeaWriter, config.BaudRate)
+			nmeaWriter = sim.pacer
+		}
+		writers = append(writers, nmeaWriter)
+	}
+
+	// Start the TCP server for connecting clients
+	if config.TCPListen != "" {
+		var protocol tcpProtocol
+		if config.GpsdMode {
+			protocol = gpsdProtocol{}
+		}
+		server, err := newTCPServer(config.TCPListen, protocol)
+		if err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to start TCP server: %v", err)
+		}
+		sim.tcpServer = server
+		writers = append(writers, server)
+	}
+
+	// Resolve the UDP target once so an invalid address fails early
+	if config.UDPTarget != "" {
+		udp, err := newUDPWriter(config.UDPTarget)
+		if err != nil {
+			abort()
+			return nil, err
+		}
+		sim.udpWriter = udp
+		writers = append(writers, udp)
+	}
+
+	// Serve gpsd clients on their own listener alongside the NMEA output
+	if config.GpsdListen != "" {
+		if err := sim.startGpsdServer(); err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to start gpsd server: %v", err)
+		}
+	}
+
+	if len(writers) == 1 {
+		sim.nmeaWriter = writers[0]
+	} else if len(writers) > 1 {
+		sim.nmeaWriter = io.MultiWriter(writers...)
+	}
+
+	if config.RecordScenario != "" {
+		if err := sim.startScenarioRecording(); err != nil {
+			abort()
+			return nil, fmt.Errorf("failed to record scenario: %v", err)
+		}
 	}
 
 	// Initialize satellites
 	sim.initializeSatellites()
+	sim.scheduleAcquisition(now)
 
 	return sim, nil
 }
 
+// newRand returns a random source seeded with seed, or from the current time
+// when seed is 0
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// random returns the simulator's random source, creating one from
+// Config.Seed if the simulator was not built by NewGPSSimulator
+func (s *GPSSimulator) random() *rand.Rand {
+	if s.rng == nil {
+		s.rng = newRand(s.Config.Seed)
+	}
+	return s.rng
+}
+
 func (s *GPSSimulator) initializeSatellites() {
 	s.Satellites = make([]Satellite, s.Config.Satellites)
 
+	// Distribute satellites round-robin across the enabled constellations,
+	// numbering each from the start of its constellation's PRN range
+	constellations := s.constellations()
+	nextPRN := make(map[Constellation]int)
+	for _, c := range constellations {
+		nextPRN[c], _ = c.PRNRange()
+	}
+
 	for i := 0; i < s.Config.Satellites; i++ {
-		s.Satellites[i] = Satellite{
-			ID:        i + 1,
-			Elevation: rand.Intn(70) + 10, // 10-80 degrees
-			Azimuth:   rand.Intn(360),     // 0-359 degrees
-			SNR:       rand.Intn(30) + 20, // 20-50 dB
+		c := constellations[i%len(constellations)]
+		s.Satellites[i] = s.newSatellite(nextPRN[c], c)
+		nextPRN[c]++
+	}
+}
+
+// resizeSatellites adds or removes satellites so n are in view, keeping the
+// existing ones. New satellites continue the round-robin distribution across
+// constellations with the next unused PRN of each.
+func (s *GPSSimulator) resizeSatellites(n int) {
+	if n <= len(s.Satellites) {
+		s.Satellites = s.Satellites[:n]
+		s.updateDOP()
+		return
+	}
+
+	constellations := s.constellations()
+	nextPRN := make(map[Constellation]int)
+	for _, c := range constellations {
+		nextPRN[c], _ = c.PRNRange()
+	}
+	for _, sat := range s.Satellites {
+		if sat.ID >= nextPRN[sat.Constellation] {
+			nextPRN[sat.Constellation] = sat.ID + 1
 		}
 	}
+
+	for i := len(s.Satellites); i < n; i++ {
+		c := constellations[i%len(constellations)]
+		s.Satellites = append(s.Satellites, s.newSatellite(nextPRN[c], c))
+		nextPRN[c]++
+	}
+	s.updateDOP()
 }
 
+// Run emits sentences at OutputRate until a non-looping replay or route
+// finishes, Config.Duration elapses or Stop is called, then closes the
+// outputs, calls the OnComplete callbacks with the reason and closes Done
 func (s *GPSSimulator) Run() {
-	ticker := time.NewTicker(s.Config.OutputRate)
-	defer ticker.Stop()
+	stop := s.startRun()
+	var reason string
+	defer func() { s.finishRun(reason) }()
+
+	s.mu.RLock()
+	ticker := s.newTicker(s.Config.OutputRate)
+	s.mu.RUnlock()
+	defer func() { ticker.Stop() }()
+
+	// Integrate motion between output cycles when a faster update rate is
+	// set, restarting with the output rate
+	var integrateTicker Ticker
+	var integrateC <-chan time.Time
+	startIntegrating := func() {
+		if integrateTicker != nil {
+			integrateTicker.Stop()
+			integrateTicker, integrateC = nil, nil
+		}
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.integratesBetweenOutputs() {
+			integrateTicker = s.newTicker(s.Config.UpdateRate)
+			integrateC = integrateTicker.C()
+		}
+	}
+	startIntegrating()
+	defer func() {
+		if integrateTicker != nil {
+			integrateTicker.Stop()
+		}
+	}()
 
 	// Ensure GPX writer is closed when simulation ends
 	defer s.Close()
@@ -146,15 +813,24 @@ func (s *GPSSimulator) Run() {
 
 	for {
 		select {
-		case <-ticker.C:
-			s.update()
-			s.outputNMEA()
-			s.updateGPX()
-
-			// Check if replay is completed and looping is disabled
-			if s.Config.ReplayFile != "" && !s.Config.ReplayLoop && s.replayCompleted {
+		case rate := <-s.rateChange:
+			// Restart the tickers at the output rate set with UpdateConfig
+			ticker.Stop()
+			ticker = s.newTicker(rate)
+			startIntegrating()
+		case <-integrateC:
+			s.step()
+		case <-ticker.C():
+			s.tick()
+
+			// Stop once a non-looping replay or route has finished
+			if message, done := s.completion(); done {
 				if !s.Config.Quiet {
-					fmt.Fprintf(os.Stderr, "\nGPX replay completed\n")
+					fmt.Fprintf(os.Stderr, "\n%s\n", message)
+				}
+				reason = CompletionRoute
+				if s.isReplayMode() || s.isNMEAReplay() {
+					reason = CompletionReplay
 				}
 				return
 			}
@@ -162,43 +838,98 @@ func (s *GPSSimulator) Run() {
 			if !s.Config.Quiet {
 				fmt.Fprintf(os.Stderr, "\nSimulation completed after %v\n", s.Config.Duration)
 			}
+			reason = CompletionDuration
+			return
+		case <-stop:
+			reason = CompletionStopped
 			return
 		}
 	}
 }
 
-// Close closes any open resources (like GPX writer)
-func (s *GPSSimulator) Close() {
-	if s.gpxWriter != nil {
-		if !s.Config.Quiet {
-			fmt.Fprintf(os.Stderr, "Writing GPX file: %s with %d track points\n",
-				s.Config.GPXFile, s.gpxWriter.GetTrackPointCount())
-		}
-		err := s.gpxWriter.Close()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing GPX file: %v\n", err)
-		}
+// completion reports whether a non-looping GPX replay or route has finished,
+// along with the message describing which one
+func (s *GPSSimulator) completion() (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Check if replay is completed and looping is disabled
+	if s.isReplayMode() && !s.Config.ReplayLoop && s.replayCompleted {
+		return "GPX replay completed", true
+	}
+	if s.isNMEAReplay() && !s.Config.ReplayLoop && s.replayCompleted {
+		return "NMEA replay completed", true
+	}
+
+	// Check if the route is completed and looping is disabled
+	if s.isRouteMode() && s.routeCompleted {
+		return "GPX route completed", true
 	}
+
+	return "", false
 }
 
-// updateGPX adds current position to GPX track if GPX writer is enabled and GPS is locked
-func (s *GPSSimulator) updateGPX() {
-	if s.gpxWriter != nil && s.isLocked {
-		s.gpxWriter.AddTrackPoint(s.currentLat, s.currentLon, s.currentAlt, time.Now())
+// TCPAddr returns the address the TCP server is listening on, or nil when TCPListen is not set
+func (s *GPSSimulator) TCPAddr() net.Addr {
+	if s.tcpServer == nil {
+		return nil
+	}
+	return s.tcpServer.Addr()
+}
 
-		// Write to file periodically to avoid losing data if program is interrupted
-		// Write every 10 points to balance between performance and data safety
-		if s.gpxWriter.GetTrackPointCount()%10 == 0 {
-			err := s.gpxWriter.WriteToFile()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error writing GPX data: %v\n", err)
-			}
-		}
+// closeOutputs shuts down the network outputs and writes out any paced
+// output still queued
+func (s *GPSSimulator) closeOutputs() {
+	if s.pacer != nil {
+		s.pacer.Close()
+	}
+	if s.tcpServer != nil {
+		s.tcpServer.Close()
+	}
+	if s.udpWriter != nil {
+		s.udpWriter.Close()
+	}
+	if s.gpsdServer != nil {
+		s.gpsdServer.Close()
 	}
 }
 
+// Close closes any open resources (like GPX writer and network outputs) and sentence subscriptions
+func (s *GPSSimulator) Close() {
+	s.closeSubscribers()
+	s.closeOutputs()
+
+	s.closeTracks()
+}
+
+// update advances the simulation to the current time and moves the
+// satellites, ready for an output cycle
 func (s *GPSSimulator) update() {
-	now := time.Now()
+	now := s.now()
+	s.integrate(now)
+	s.checkGeofences(now)
+
+	// Update satellites
+	s.updateSatellites()
+
+	// Record the fix so CurrentFix never mixes values from different updates
+	s.fix = s.currentFix(now)
+}
+
+// integrate advances lock acquisition, signal dropouts and the receiver's
+// motion to now. With Config.UpdateRate it also runs between output cycles.
+func (s *GPSSimulator) integrate(now time.Time) {
+	defer s.notifyLockChange(s.isLocked)
+
+	// Apply scripted and played back configuration changes that are due
+	s.runScript(now)
+	s.playScenarioUpdates(now)
+
+	// A recorded NMEA log drives the fix on its own
+	if s.isNMEAReplay() {
+		s.updateNMEAReplay(now)
+		return
+	}
 
 	// Check if GPS should be locked
 	if !s.isLocked && now.After(s.lockTime) {
@@ -206,24 +937,43 @@ func (s *GPSSimulator) update() {
 		if !s.Config.Quiet {
 			fmt.Fprintf(os.Stderr, "GPS LOCKED after %v\n", now.Sub(s.startTime))
 		}
+		s.scheduleDropout(now)
 	}
 
+	// Fade and lose the signal when a dropout is due
+	s.updateDropout(now)
+
 	// Update position if locked
 	if s.isLocked {
-		if s.Config.ReplayFile != "" {
+		if s.isReplayMode() && s.transition != nil {
+			s.updateReplayGap(now)
+		} else if s.isReplayMode() {
 			s.updateReplayPosition()
-		} else {
+		} else if s.isRouteMode() {
 			s.updateSpeedAndCourse()
-			s.updatePosition()
+			s.updateRoutePosition()
+			s.updateAltitude()
+		} else if s.transition != nil {
+			s.updateTransition()
+			s.updateAltitude()
+		} else if s.isRoadlike() {
+			s.updateRoadlikePosition()
+			s.updateAltitude()
+		} else {
+			s.updatePatternPosition()
 			s.updateAltitude()
 		}
+		s.updateRateOfTurn(now)
 	}
-
-	// Update satellites
-	s.updateSatellites()
 }
 
 func (s *GPSSimulator) updateSpeedAndCourse() {
+	// Draw speed and course once per output cycle, so a finer UpdateRate
+	// does not average the variation away
+	if s.betweenOutputs {
+		return
+	}
+
 	// Apply jitter to speed and course based on jitter configuration
 	var speedVariation, courseVariation float64
 
@@ -246,14 +996,20 @@ func (s *GPSSimulator) updateSpeedAndCourse() {
 	}
 
 	// Apply speed variation
-	speedDelta := (rand.Float64() - 0.5) * 2 * s.Config.Speed * speedVariation
+	speedDelta := (s.random().Float64() - 0.5) * 2 * s.Config.Speed * speedVariation
 	s.currentSpeed = s.Config.Speed + speedDelta
 	if s.currentSpeed < 0 {
 		s.currentSpeed = 0 // Speed cannot be negative
 	}
 
+	// Hold the course while nearly stationary, as a receiver cannot tell
+	// which way it is heading
+	if !s.courseValid() {
+		return
+	}
+
 	// Apply course variation
-	courseDelta := (rand.Float64() - 0.5) * 2 * courseVariation
+	courseDelta := (s.random().Float64() - 0.5) * 2 * courseVariation
 	s.currentCourse = s.Config.Course + courseDelta
 
 	// Normalize course to 0-359.9 range
@@ -266,7 +1022,7 @@ func (s *GPSSimulator) updateSpeedAndCourse() {
 }
 
 func (s *GPSSimulator) updatePosition() {
-	now := time.Now()
+	now := s.now()
 	deltaTime := now.Sub(s.lastUpdateTime).Seconds()
 	s.lastUpdateTime = now
 
@@ -275,6 +1031,12 @@ func (s *GPSSimulator) updatePosition() {
 		return
 	}
 
+	s.currentLat, s.currentLon = s.wanderPosition(s.currentLat, s.currentLon, deltaTime)
+}
+
+// wanderPosition moves a position deltaTime seconds along the current speed
+// and course, adding jitter and bouncing off the radius around the center
+func (s *GPSSimulator) wanderPosition(lat, lon, deltaTime float64) (float64, float64) {
 	// Convert speed from knots to meters per second
 	// 1 knot = 0.514444 meters per second
 	speedMPS := s.currentSpeed * 0.514444
@@ -294,8 +1056,9 @@ func (s *GPSSimulator) updatePosition() {
 
 	// Apply GPS jitter noise within the radius constraint
 	// GPS receivers have noise even when stationary due to satellite signal variations
+	// Without a jitter factor, noise follows the configured fix quality
+	maxJitterDistance := s.qualityNoiseDistance()
 	if s.Config.Jitter > 0 {
-		var maxJitterDistance float64
 		if s.Config.Radius > 0 {
 			// Calculate maximum jitter distance as a fraction of radius
 			// Low jitter: up to 10% of radius, High jitter: up to 50% of radius
@@ -305,10 +1068,14 @@ func (s *GPSSimulator) updatePosition() {
 			// Base it on typical GPS accuracy: ~10m max jitter at high jitter settings
 			maxJitterDistance = 10.0 * s.Config.Jitter
 		}
+	}
 
+	// Jitter once per output cycle, as the walk would otherwise grow with
+	// the number of integration steps
+	if maxJitterDistance > 0 && !s.betweenOutputs {
 		// Generate random jitter in meters
-		jitterAngle := rand.Float64() * 2 * math.Pi // Random direction
-		jitterDistance := rand.Float64() * maxJitterDistance // Random distance within max
+		jitterAngle := s.random().Float64() * 2 * math.Pi          // Random direction
+		jitterDistance := s.random().Float64() * maxJitterDistance // Random distance within max
 
 		// Add jitter to movement
 		deltaEast += jitterDistance * math.Cos(jitterAngle)
@@ -319,11 +1086,10 @@ func (s *GPSSimulator) updatePosition() {
 	// At the equator: 1 degree latitude ≈ 111,320 meters
 	// 1 degree longitude varies by latitude: ≈ 111,320 * cos(latitude) meters
 	deltaLatDeg := deltaNorth / 111320.0
-	deltaLonDeg := deltaEast / (111320.0 * math.Cos(s.currentLat*math.Pi/180.0))
+	deltaLonDeg := deltaEast / metersPerLonDegree(lat)
 
-	// Calculate new position
-	newLat := s.currentLat + deltaLatDeg
-	newLon := s.currentLon + deltaLonDeg
+	// Calculate new position, crossing over a pole rather than past it
+	newLat, newLon := normalizeCoordinate(lat+deltaLatDeg, lon+deltaLonDeg)
 
 	// Enforce radius constraint only if radius > 0 (radius = 0 means no constraint)
 	if s.Config.Radius > 0 {
@@ -333,22 +1099,16 @@ func (s *GPSSimulator) updatePosition() {
 		centerLat := s.Config.Latitude
 		centerLon := s.Config.Longitude
 
-		bearing := math.Atan2(
-			(newLon-centerLon)*math.Cos(centerLat*math.Pi/180.0),
-			newLat-centerLat,
-		)
+		bearing := s.calculateBearing(centerLat, centerLon, newLat, newLon)
 
-		// Place new position at radius boundary in that direction
-		radiusDegLat := s.Config.Radius / 111320.0
-		radiusDegLon := s.Config.Radius / (111320.0 * math.Cos(centerLat*math.Pi/180.0))
-
-		newLat = centerLat + radiusDegLat*math.Cos(bearing)
-		newLon = centerLon + radiusDegLon*math.Sin(bearing)/math.Cos(centerLat*math.Pi/180.0)
+		// Place new position at radius boundary in that direction, along a
+		// great circle so the boundary stays finite near the poles
+		newLat, newLon = s.calculateDestination(centerLat, centerLon, bearing, s.Config.Radius)
 
 		// Reverse direction to bounce off the boundary for next update
 		if s.Config.Jitter > 0.3 {
 			// Add random course change when hitting boundary
-			randomCourseChange := (rand.Float64() - 0.5) * 90.0 // ±45° change
+			randomCourseChange := (s.random().Float64() - 0.5) * 90.0 // ±45° change
 			s.currentCourse += randomCourseChange
 
 			// Normalize course
@@ -362,20 +1122,28 @@ func (s *GPSSimulator) updatePosition() {
 		}
 	}
 
-	// Update current position
-	s.currentLat = newLat
-	s.currentLon = newLon
+	return newLat, newLon
 }
 
 func (s *GPSSimulator) updateAltitude() {
-	// Apply altitude jitter based on configuration
-	if s.Config.AltitudeJitter > 0 {
+	now := s.now()
+	dt := now.Sub(s.altitudeTime)
+	s.altitudeTime = now
+
+	// Follow the climb profile, with jitter as noise on top
+	if s.Config.ClimbRate != 0 {
+		s.updateClimb(dt)
+		return
+	}
+
+	// Apply altitude jitter based on configuration, once per output cycle
+	if s.Config.AltitudeJitter > 0 && !s.betweenOutputs {
 		// Calculate maximum altitude change per update
 		// Low jitter = small changes; High jitter = large changes
 		maxChange := 1.0 + (s.Config.AltitudeJitter * 20.0) // 1-21 meters max change
 
 		// Generate random altitude change
-		change := (rand.Float64() - 0.5) * 2 * maxChange // -maxChange to +maxChange
+		change := (s.random().Float64() - 0.5) * 2 * maxChange // -maxChange to +maxChange
 
 		// Apply change
 		newAltitude := s.currentAlt + change
@@ -384,8 +1152,8 @@ func (s *GPSSimulator) updateAltitude() {
 		minAltitude := s.Config.Altitude - 100.0 // Allow 100m below starting altitude
 		maxAltitude := s.Config.Altitude + 500.0 // Allow 500m above starting altitude
 
-		if minAltitude < -50.0 {
-			minAltitude = -50.0 // Don't go too far below sea level
+		if minAltitude < minimumAltitude {
+			minAltitude = minimumAltitude // Don't go too far below sea level
 		}
 
 		if newAltitude < minAltitude {
@@ -402,6 +1170,32 @@ func (s *GPSSimulator) distanceFromCenter(lat, lon float64) float64 {
 	return s.calculateDistance(s.Config.Latitude, s.Config.Longitude, lat, lon)
 }
 
+// minLonScale is the least cosine of latitude used to convert meters east to
+// degrees of longitude, so positions within centimeters of a pole never
+// divide by zero
+const minLonScale = 1e-8
+
+// metersPerLonDegree returns the approximate length in meters of a degree of
+// longitude at lat
+func metersPerLonDegree(lat float64) float64 {
+	return 111320.0 * math.Max(math.Cos(lat*math.Pi/180.0), minLonScale)
+}
+
+// normalizeCoordinate folds a latitude past a pole back over it onto the
+// opposite meridian and wraps longitude into [-180, 180)
+func normalizeCoordinate(lat, lon float64) (float64, float64) {
+	if lat > 90 {
+		lat, lon = 180-lat, lon+180
+	} else if lat < -90 {
+		lat, lon = -180-lat, lon+180
+	}
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lat, lon - 180
+}
+
 // hasSequentialTimestamps checks if the replay points have sequential timestamps
 func (s *GPSSimulator) hasSequentialTimestamps() bool {
 	if len(s.replayPoints) < 2 {
@@ -414,71 +1208,252 @@ func (s *GPSSimulator) hasSequentialTimestamps() bool {
 			return false
 		}
 	}
-	return true
+
+	// Tracks without timestamps (e.g. KML) all share the zero time
+	return s.replayPoints[len(s.replayPoints)-1].Time.After(s.replayPoints[0].Time)
 }
 
 func (s *GPSSimulator) updateSatellites() {
 	// Simulate satellite movement and signal changes
+	_, elevationBounds, _, snrBounds := s.Config.satelliteRanges()
 	for i := range s.Satellites {
 		// Slightly adjust elevation and azimuth
-		s.Satellites[i].Elevation += rand.Intn(3) - 1 // -1, 0, or 1
-		s.Satellites[i].Azimuth = (s.Satellites[i].Azimuth + rand.Intn(3) - 1 + 360) % 360
+		s.Satellites[i].Elevation += s.random().Intn(3) - 1 // -1, 0, or 1
+		if s.Config.SatelliteChurn {
+			s.Satellites[i].Elevation += s.orbitStep(s.Satellites[i])
+		}
+		s.Satellites[i].Azimuth = (s.Satellites[i].Azimuth + s.random().Intn(3) - 1 + 360) % 360
 
 		// Keep elevation within bounds
-		if s.Satellites[i].Elevation < 5 {
-			s.Satellites[i].Elevation = 5
-		}
-		if s.Satellites[i].Elevation > 85 {
-			s.Satellites[i].Elevation = 85
-		}
+		s.Satellites[i].Elevation = elevationBounds.clamp(s.Satellites[i].Elevation)
 
 		// Simulate SNR variations
-		s.Satellites[i].SNR += rand.Intn(6) - 3 // -3 to +3
-		if s.Satellites[i].SNR < 15 {
-			s.Satellites[i].SNR = 15
-		}
-		if s.Satellites[i].SNR > 55 {
-			s.Satellites[i].SNR = 55
-		}
+		s.Satellites[i].SNR += s.random().Intn(6) - 3 // -3 to +3
+		s.Satellites[i].SNR = snrBounds.clamp(s.Satellites[i].SNR)
+	}
+
+	// Let satellites set and rise
+	if s.Config.SatelliteChurn {
+		s.churnSatellites(elevationBounds)
+	}
+
+	// Recompute DOP from the new geometry
+	s.updateDOP()
+}
+
+// output emits one cycle of reports in the configured format
+func (s *GPSSimulator) output() {
+	s.bursts++
+
+	// Start a paced burst, spread across the interval to the next one
+	if s.pacer != nil {
+		s.pacer.startBurst(s.Config.outputInterval())
+	}
+	defer s.writeGpsdReports()
+	defer s.writeJSONRecord()
+	if s.Config.OutputFormat == OutputFormatJSON {
+		s.outputJSON()
+		return
+	}
+	if s.isNMEAReplay() {
+		s.outputNMEAReplay()
+		return
+	}
+	if s.Config.GpsdMode {
+		s.outputGpsd()
+		return
 	}
+	s.outputNMEA()
 }
 
+// outputNMEA emits the sentences due this cycle as one burst sharing a single
+// epoch. The burst starts with GGA whenever it is due, so consumers can group
+// sentences by it.
 func (s *GPSSimulator) outputNMEA() {
-	timestamp := time.Now()
+	timestamp := s.outputEpoch(s.now())
 
 	if s.isLocked {
 		// Output GGA sentence (Global Positioning System Fix Data)
-		fmt.Fprint(s.nmeaWriter, s.generateGGA(timestamp))
+		if s.sentenceDue(SentenceGGA) {
+			s.emit(s.generateGGA(timestamp))
+		}
+
+		// Output GNS sentence (GNSS Fix Data, NMEA 4.1 only)
+		if s.isNMEA41() && s.sentenceDue(SentenceGNS) {
+			s.emit(s.generateGNS(timestamp))
+		}
 
 		// Output RMC sentence (Recommended Minimum)
-		fmt.Fprint(s.nmeaWriter, s.generateRMC(timestamp))
+		if s.sentenceDue(SentenceRMC) {
+			s.emit(s.generateRMC(timestamp))
+		}
 
 		// Output GLL sentence (Geographic Position - Latitude/Longitude)
-		fmt.Fprint(s.nmeaWriter, s.generateGLL(timestamp))
+		if s.sentenceDue(SentenceGLL) {
+			s.emit(s.generateGLL(timestamp))
+		}
 
 		// Output VTG sentence (Track Made Good and Ground Speed)
-		fmt.Fprint(s.nmeaWriter, s.generateVTG())
+		if s.sentenceDue(SentenceVTG) {
+			s.emit(s.generateVTG())
+		}
 
-		// Output GSA sentence (GPS DOP and active satellites)
-		fmt.Fprint(s.nmeaWriter, s.generateGSA())
+		// Output GSA sentences (GPS DOP and active satellites)
+		if s.sentenceDue(SentenceGSA) {
+			for _, sentence := range s.generateGSA() {
+				s.emit(sentence)
+			}
+		}
 
 		// Output GSV sentences (GPS Satellites in view)
-		gsv := s.generateGSV()
-		for _, sentence := range gsv {
-			fmt.Fprint(s.nmeaWriter, sentence)
+		if s.sentenceDue(SentenceGSV) {
+			gsv := s.generateGSV()
+			for _, sentence := range gsv {
+				s.emit(sentence)
+			}
 		}
 
 		// Output ZDA sentence (UTC Date and Time)
-		fmt.Fprint(s.nmeaWriter, s.generateZDA(timestamp))
+		if s.sentenceDue(SentenceZDA) {
+			s.emit(s.generateZDA(timestamp))
+		}
+
+		// Output GST sentence (Pseudorange Error Statistics)
+		if s.sentenceDue(SentenceGST) {
+			s.emit(s.generateGST(timestamp))
+		}
+
+		// Output GRS and GBS sentences (Range Residuals and Satellite Fault Detection)
+		if s.sentenceDue(SentenceGRS) {
+			for _, sentence := range s.generateGRS(timestamp) {
+				s.emit(sentence)
+			}
+		}
+		if s.sentenceDue(SentenceGBS) {
+			s.emit(s.generateGBS(timestamp))
+		}
+
+		// Output HDT sentence (Heading - True)
+		if s.sentenceDue(SentenceHDT) {
+			s.emit(s.generateHDT())
+		}
+
+		// Output ROT sentence (Rate Of Turn)
+		if s.sentenceDue(SentenceROT) {
+			s.emit(s.generateROT())
+		}
 	} else {
 		// Output sentences indicating no fix
-		fmt.Fprint(s.nmeaWriter, s.generateNoFixGGA(timestamp))
-		fmt.Fprint(s.nmeaWriter, s.generateNoFixRMC(timestamp))
-		fmt.Fprint(s.nmeaWriter, s.generateNoFixGLL(timestamp))
-		fmt.Fprint(s.nmeaWriter, s.generateNoFixVTG())
+		if s.sentenceDue(SentenceGGA) {
+			if s.isEstimatedFix() {
+				s.emit(s.generateEstimatedGGA(timestamp))
+			} else {
+				s.emit(s.generateNoFixGGA(timestamp))
+			}
+		}
+		if s.isNMEA41() && s.sentenceDue(SentenceGNS) {
+			s.emit(s.generateNoFixGNS(timestamp))
+		}
+		if s.sentenceDue(SentenceRMC) {
+			s.emit(s.generateNoFixRMC(timestamp))
+		}
+		if s.sentenceDue(SentenceGLL) {
+			s.emit(s.generateNoFixGLL(timestamp))
+		}
+		if s.sentenceDue(SentenceVTG) {
+			s.emit(s.generateNoFixVTG())
+		}
+		if s.sentenceDue(SentenceGSA) {
+			s.emit(s.generateNoFixGSA())
+		}
+
+		// Satellites acquired so far are reported while acquiring a fix
+		if s.sentenceDue(SentenceGSV) {
+			if acquired := s.acquiredSatellites(); len(acquired) > 0 {
+				for _, sentence := range s.generateGSVFor(acquired) {
+					s.emit(sentence)
+				}
+			}
+		}
 	}
 
+	s.outputTick++
+
 	// No extra blank lines - NMEA sentences should be continuous
+
+	s.notifyCallbacks(timestamp)
+}
+
+// collapseSegmentGaps removes the time gaps (usually pauses) between track
+// segments so replay moves continuously from one segment into the next. Each
+// later segment is shifted earlier so it starts one sampling interval after
+// the previous segment ends, using that segment's last interval or one second.
+func collapseSegmentGaps(points []TrackPoint) []TrackPoint {
+	collapsed := make([]TrackPoint, len(points))
+	copy(collapsed, points)
+
+	var shift time.Duration
+	for i := 1; i < len(collapsed); i++ {
+		collapsed[i].Time = points[i].Time.Add(-shift)
+		if points[i].Segment == points[i-1].Segment {
+			continue
+		}
+
+		interval := time.Second
+		if i >= 2 && points[i-2].Segment == points[i-1].Segment {
+			if last := points[i-1].Time.Sub(points[i-2].Time); last > 0 {
+				interval = last
+			}
+		}
+
+		if gap := collapsed[i].Time.Sub(collapsed[i-1].Time); gap > interval {
+			shift += gap - interval
+			collapsed[i].Time = points[i].Time.Add(-shift)
+		}
+	}
+	return collapsed
+}
+
+// isSegmentGap reports whether the replay step from point i to i+1 crosses
+// a segment boundary that should be held rather than travelled
+func (s *GPSSimulator) isSegmentGap(i int) bool {
+	return (s.Config.ReplaySegmentGaps || s.Config.ReplayHonorSegments) &&
+		s.replayPoints[i].Segment != s.replayPoints[i+1].Segment
+}
+
+// segmentOutageDuration is how long the fix is lost at a segment boundary
+// with Config.ReplayHonorSegments
+const segmentOutageDuration = 3 * time.Second
+
+// loseFixAtSegmentBoundary drops the fix for segmentOutageDuration when
+// replay enters a new track segment, since a segment break in a recording
+// usually means the receiver lost signal
+func (s *GPSSimulator) loseFixAtSegmentBoundary(now time.Time) {
+	s.isLocked = false
+	s.lockTime = now.Add(segmentOutageDuration)
+
+	if !s.Config.Quiet {
+		fmt.Fprintf(os.Stderr, "GPS fix lost at track segment boundary after %v\n", now.Sub(s.startTime))
+	}
+}
+
+// reverseTrackPoints returns the points in reverse order with timestamps
+// remapped so the gaps between points are preserved. The first reversed point
+// keeps the original first timestamp, so sequential tracks stay sequential and
+// time-based replay runs backwards at the original pace.
+func reverseTrackPoints(points []TrackPoint) []TrackPoint {
+	if len(points) == 0 {
+		return points
+	}
+
+	first := points[0].Time
+	last := points[len(points)-1].Time
+	reversed := make([]TrackPoint, len(points))
+	for i, point := range points {
+		point.Time = first.Add(last.Sub(point.Time))
+		reversed[len(points)-1-i] = point
+	}
+	return reversed
 }
 
 // updateReplayPosition updates position based on GPX replay data
@@ -494,7 +1469,7 @@ func (s *GPSSimulator) updateReplayPosition() {
 		s.Config.ReplaySpeed = 1.0
 	}
 
-	now := time.Now()
+	now := s.now()
 	elapsedTime := now.Sub(s.replayStartTime)
 
 	// Apply replay speed multiplier
@@ -503,6 +1478,9 @@ func (s *GPSSimulator) updateReplayPosition() {
 	// Check if timestamps are sequential for time-based progression
 	useTimestamps := s.hasSequentialTimestamps()
 
+	// Fraction of the way from the active point to the next one (0.0-1.0)
+	fraction := 0.0
+
 	if useTimestamps {
 		// Time-based progression using GPX timestamps
 		targetTime := s.replayPoints[0].Time.Add(adjustedTime)
@@ -523,58 +1501,142 @@ func (s *GPSSimulator) updateReplayPosition() {
 		}
 
 		s.replayIndex = newIndex
+
+		// Work out how far through the segment to the next point we are
+		if newIndex < len(s.replayPoints)-1 {
+			span := s.replayPoints[newIndex+1].Time.Sub(s.replayPoints[newIndex].Time)
+			if span > 0 {
+				fraction = float64(targetTime.Sub(s.replayPoints[newIndex].Time)) / float64(span)
+			}
+		}
 	} else {
 		// Index-based progression when timestamps are not sequential
 		// Progress through points at a steady rate (1 point per second at 1x speed)
 		pointInterval := time.Duration(float64(time.Second) / s.Config.ReplaySpeed)
 		pointsSinceStart := int(elapsedTime / pointInterval)
+		fraction = float64(elapsedTime%pointInterval) / float64(pointInterval)
 
-		if s.Config.ReplayLoop {
+		if s.Config.ReplayLoop && s.Config.replayTracks() == 1 {
 			s.replayIndex = pointsSinceStart % len(s.replayPoints)
+			s.replayLoops = uint64(pointsSinceStart / len(s.replayPoints))
 		} else {
 			s.replayIndex = pointsSinceStart
 		}
 	}
 
-	// If we've reached the end, handle completion/looping
+	// If we've reached the end, move on to the next file or handle completion/looping
 	if s.replayIndex >= len(s.replayPoints) {
-		s.replayCompleted = true
-		if s.Config.ReplayLoop {
+		tracks := s.Config.replayTracks()
+		last := s.replayFileIndex == tracks-1
+		if last {
+			if !s.replayCompleted || s.Config.ReplayLoop {
+				s.replayLoops++
+			}
+			s.replayCompleted = true
+			if !s.Config.ReplayLoop {
+				return
+			}
+		}
+
+		if tracks > 1 {
+			// Loop the whole playlist, not a single file
+			s.startReplayFile((s.replayFileIndex+1)%tracks, now)
+		} else {
 			// Loop back to start if looping is enabled
 			s.replayIndex = 0
 			s.replayStartTime = now
+			s.replaySegment = s.replayPoints[0].Segment
 		}
 		return
 	}
 
 	// Update current position from track point
 	currentPoint := s.replayPoints[s.replayIndex]
+
+	// Drop the fix on entering a new segment, moving on once it is re-acquired
+	if currentPoint.Segment != s.replaySegment {
+		s.replaySegment = currentPoint.Segment
+		if s.Config.ReplayHonorSegments {
+			s.loseFixAtSegmentBoundary(now)
+			return
+		}
+	}
+
 	s.currentLat = currentPoint.Lat
 	s.currentLon = currentPoint.Lon
 	s.currentAlt = currentPoint.Elevation
 
 	// Calculate speed and course from next point if available
-	if s.replayIndex < len(s.replayPoints)-1 {
-		nextPoint := s.replayPoints[s.replayIndex+1]
+	if speed, course, ok := s.replaySegmentMotion(s.replayIndex, useTimestamps); ok {
+		s.currentSpeed = speed
+		s.currentCourse = course
+	}
 
-		// Calculate distance and time between points
-		distance := s.calculateDistance(s.currentLat, s.currentLon, nextPoint.Lat, nextPoint.Lon)
+	// Smooth motion between sparse points instead of snapping to the active one
+	if s.Config.ReplayInterpolate && fraction > 0 && s.replayIndex < len(s.replayPoints)-1 && !s.isSegmentGap(s.replayIndex) {
+		s.interpolateReplayPosition(math.Min(fraction, 1.0), useTimestamps)
+	}
+}
 
-		var timeDiff float64
-		if useTimestamps {
-			timeDiff = nextPoint.Time.Sub(currentPoint.Time).Seconds()
-		} else {
-			// Use a fixed time interval for non-sequential timestamps
-			timeDiff = 1.0 // 1 second between points
-		}
+// replaySegmentMotion calculates the speed (knots) and course (degrees) of the
+// replay segment from point i to point i+1. It reports false when there is no
+// following point or the segment has no duration.
+func (s *GPSSimulator) replaySegmentMotion(i int, useTimestamps bool) (float64, float64, bool) {
+	if i < 0 || i >= len(s.replayPoints)-1 {
+		return 0, 0, false
+	}
+
+	// Hold still through a preserved gap between segments
+	if s.isSegmentGap(i) {
+		return 0, s.currentCourse, true
+	}
 
-		if timeDiff > 0 {
-			// Convert m/s to knots (1 m/s = 1.94384 knots)
-			s.currentSpeed = (distance / timeDiff) * 1.94384
+	currentPoint := s.replayPoints[i]
+	nextPoint := s.replayPoints[i+1]
 
-			// Calculate course (bearing) to next point
-			s.currentCourse = s.calculateBearing(s.currentLat, s.currentLon, nextPoint.Lat, nextPoint.Lon)
-		}
+	// Calculate distance and time between points
+	distance := s.calculateDistance(currentPoint.Lat, currentPoint.Lon, nextPoint.Lat, nextPoint.Lon)
+
+	var timeDiff float64
+	if useTimestamps {
+		timeDiff = nextPoint.Time.Sub(currentPoint.Time).Seconds()
+	} else {
+		// Use a fixed time interval for non-sequential timestamps
+		timeDiff = 1.0 // 1 second between points
+	}
+
+	if timeDiff <= 0 {
+		return 0, 0, false
+	}
+
+	// Convert m/s to knots (1 m/s = 1.94384 knots)
+	speed := (distance / timeDiff) * 1.94384
+
+	// Calculate course (bearing) to next point
+	course := s.calculateBearing(currentPoint.Lat, currentPoint.Lon, nextPoint.Lat, nextPoint.Lon)
+
+	return speed, course, true
+}
+
+// interpolateReplayPosition moves fraction (0.0-1.0) of the way along the
+// great circle from the active replay point to the next one, blending
+// altitude linearly. Speed and course are blended toward the following
+// segment so turns are smooth.
+func (s *GPSSimulator) interpolateReplayPosition(fraction float64, useTimestamps bool) {
+	currentPoint := s.replayPoints[s.replayIndex]
+	nextPoint := s.replayPoints[s.replayIndex+1]
+
+	distance := s.calculateDistance(currentPoint.Lat, currentPoint.Lon, nextPoint.Lat, nextPoint.Lon)
+	bearing := s.calculateBearing(currentPoint.Lat, currentPoint.Lon, nextPoint.Lat, nextPoint.Lon)
+	s.currentLat, s.currentLon = s.calculateDestination(currentPoint.Lat, currentPoint.Lon, bearing, distance*fraction)
+	s.currentAlt = currentPoint.Elevation + (nextPoint.Elevation-currentPoint.Elevation)*fraction
+
+	if nextSpeed, nextCourse, ok := s.replaySegmentMotion(s.replayIndex+1, useTimestamps); ok {
+		s.currentSpeed += (nextSpeed - s.currentSpeed) * fraction
+
+		// Turn through the smaller angle between the two courses
+		delta := math.Mod(nextCourse-s.currentCourse+540, 360) - 180
+		s.currentCourse = math.Mod(s.currentCourse+delta*fraction+360, 360)
 	}
 }
 
@@ -434,6 +434,8 @@ func TestUpdatePositionEdgeCases(t *testing.T) {
 			t.Fatalf("Failed to create GPS simulator: %v", err)
 		}
 		sim.isLocked = true
+		clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		sim.SetClock(clock)
 
 		// Move close to boundary
 		radiusDeg := config.Radius / 111320.0
@@ -442,7 +444,7 @@ func TestUpdatePositionEdgeCases(t *testing.T) {
 
 		// Update to trigger boundary constraint
 		sim.updateSpeedAndCourse()
-		time.Sleep(20 * time.Millisecond) // Longer time to ensure movement
+		clock.Advance(20 * time.Millisecond)
 		sim.updatePosition()
 
 		// Should be constrained near the boundary
@@ -735,6 +737,8 @@ func TestUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create GPS simulator: %v", err)
 	}
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
 
 	// Initially should not be locked
 	if sim.isLocked {
@@ -747,8 +751,8 @@ func TestUpdate(t *testing.T) {
 		t.Error("GPS should not be locked before lock time")
 	}
 
-	// Wait for lock time and update
-	time.Sleep(config.TimeToLock + 10*time.Millisecond)
+	// Pass the lock time and update
+	clock.Advance(config.TimeToLock + 10*time.Millisecond)
 	sim.update()
 	if !sim.isLocked {
 		t.Error("GPS should be locked after lock time")
@@ -759,6 +763,7 @@ func TestUpdate(t *testing.T) {
 	initialLon := sim.currentLon
 
 	// Update again - position should change now that it's locked
+	clock.Advance(time.Second)
 	sim.update()
 	if sim.currentLat == initialLat && sim.currentLon == initialLon {
 		// Position might not change every update due to randomness, so this is not a hard failure
@@ -792,10 +797,10 @@ func TestOutputNMEA(t *testing.T) {
 	if !strings.Contains(output, "$GPVTG,") {
 		t.Error("Output should contain VTG sentence when not locked")
 	}
-	// Should not contain GSA, GSV, or ZDA when not locked
-	if strings.Contains(output, "$GPGSA,") {
-		t.Error("Output should not contain GSA sentence when not locked")
+	if !strings.Contains(output, "$GPGSA,A,1,") {
+		t.Error("Output should contain GSA sentence with no-fix type when not locked")
 	}
+	// Should not contain GSV or ZDA when not locked
 	if strings.Contains(output, "$GPGSV,") {
 		t.Error("Output should not contain GSV sentence when not locked")
 	}
@@ -1095,7 +1100,9 @@ func BenchmarkOutputNMEA(b *testing.B) {
 		b.Fatalf("Failed to create GPS simulator: %v", err)
 	}
 	sim.isLocked = true
+	sim.updateDOP()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		buffer.Reset()
@@ -1191,8 +1198,8 @@ func TestClose(t *testing.T) {
 
 	// Add some track points
 	sim.isLocked = true
-	sim.updateGPX()
-	sim.updateGPX()
+	sim.updateTracks()
+	sim.updateTracks()
 
 	// Capture stderr for testing output
 	oldStderr := os.Stderr
@@ -1249,7 +1256,7 @@ func TestCloseQuietMode(t *testing.T) {
 
 	// Add some track points
 	sim.isLocked = true
-	sim.updateGPX()
+	sim.updateTracks()
 
 	// Capture stderr for testing output
 	oldStderr := os.Stderr
@@ -1276,7 +1283,7 @@ func TestCloseQuietMode(t *testing.T) {
 }
 
 func TestUpdateGPX(t *testing.T) {
-	// Test updateGPX function with GPX enabled and GPS locked
+	// Test updateTracks function with GPX enabled and GPS locked
 	config := createTestConfig()
 	config.GPXEnabled = true
 	tempDir := t.TempDir()
@@ -1289,21 +1296,21 @@ func TestUpdateGPX(t *testing.T) {
 	}
 
 	// GPS not locked - should not add points
-	sim.updateGPX()
+	sim.updateTracks()
 	if sim.gpxWriter.GetTrackPointCount() != 0 {
 		t.Error("Should not add track points when GPS is not locked")
 	}
 
 	// GPS locked - should add points
 	sim.isLocked = true
-	sim.updateGPX()
+	sim.updateTracks()
 	if sim.gpxWriter.GetTrackPointCount() != 1 {
 		t.Errorf("Expected 1 track point, got %d", sim.gpxWriter.GetTrackPointCount())
 	}
 
 	// Add more points to test periodic writing (every 10 points)
 	for i := 0; i < 12; i++ {
-		sim.updateGPX()
+		sim.updateTracks()
 	}
 
 	if sim.gpxWriter.GetTrackPointCount() != 13 {
@@ -1316,7 +1323,7 @@ func TestUpdateGPX(t *testing.T) {
 }
 
 func TestUpdateGPXWithoutGPXWriter(t *testing.T) {
-	// Test updateGPX function without GPX writer
+	// Test updateTracks function without GPX writer
 	config := createTestConfig()
 	config.GPXEnabled = false
 	buffer := &bytes.Buffer{}
@@ -1327,8 +1334,8 @@ func TestUpdateGPXWithoutGPXWriter(t *testing.T) {
 	}
 
 	sim.isLocked = true
-	// Should not panic when calling updateGPX without GPX writer
-	sim.updateGPX()
+	// Should not panic when calling updateTracks without GPX writer
+	sim.updateTracks()
 }
 
 func TestNewGPSSimulatorWithGPXError(t *testing.T) {
@@ -1453,7 +1460,7 @@ func TestRunWithDurationQuiet(t *testing.T) {
 }
 
 func TestUpdateGPXWriteError(t *testing.T) {
-	// Test updateGPX with WriteToFile error
+	// Test updateTracks with WriteToFile error
 	config := createTestConfig()
 	config.GPXEnabled = true
 	tempDir := t.TempDir()
@@ -1470,7 +1477,7 @@ func TestUpdateGPXWriteError(t *testing.T) {
 
 	// Add 9 track points (won't trigger write)
 	for i := 0; i < 9; i++ {
-		sim.updateGPX()
+		sim.updateTracks()
 	}
 
 	// Close the underlying file to cause WriteToFile error on 10th point
@@ -1484,7 +1491,7 @@ func TestUpdateGPXWriteError(t *testing.T) {
 	os.Stderr = w
 
 	// Add 10th point - should trigger WriteToFile error
-	sim.updateGPX()
+	sim.updateTracks()
 
 	// Restore stderr and read captured output
 	w.Close()
@@ -1515,7 +1522,7 @@ func TestCloseWithGPXError(t *testing.T) {
 
 	// Add some track points
 	sim.isLocked = true
-	sim.updateGPX()
+	sim.updateTracks()
 
 	// Close the underlying GPX file to cause error in Close
 	if sim.gpxWriter.file != nil {
@@ -2007,6 +2014,8 @@ func TestDeterministicBoundaryConditions(t *testing.T) {
 			t.Fatalf("Failed to create GPS simulator: %v", err)
 		}
 		sim.isLocked = true
+		clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		sim.SetClock(clock)
 
 		// Record initial position
 		initialLat := sim.currentLat
@@ -2014,7 +2023,7 @@ func TestDeterministicBoundaryConditions(t *testing.T) {
 
 		// Move for several updates - should keep moving without constraint
 		for i := 0; i < 5; i++ {
-			time.Sleep(100 * time.Millisecond) // Ensure deltaTime > 0
+			clock.Advance(100 * time.Millisecond)
 			sim.updatePosition()
 		}
 
@@ -2672,3 +2681,683 @@ func TestReplaySpeedZeroDefensiveCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigValidate(t *testing.T) {
+	validConfig := func() Config {
+		config := createTestConfig()
+		config.BaudRate = 9600
+		config.ReplaySpeed = 1.0
+		return config
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(c *Config)
+		shouldError bool
+	}{
+		{"Valid config", func(c *Config) {}, false},
+		{"Too few satellites", func(c *Config) { c.Satellites = 3 }, true},
+		{"Too many satellites", func(c *Config) { c.Satellites = 13 }, true},
+		{"Negative radius", func(c *Config) { c.Radius = -1.0 }, true},
+		{"Negative jitter", func(c *Config) { c.Jitter = -0.1 }, true},
+		{"High altitude jitter", func(c *Config) { c.AltitudeJitter = 1.1 }, true},
+		{"Zero baud rate", func(c *Config) { c.BaudRate = 0 }, true},
+		{"Negative speed", func(c *Config) { c.Speed = -1.0 }, true},
+		{"High course", func(c *Config) { c.Course = 360.0 }, true},
+		{"Zero replay speed", func(c *Config) { c.ReplaySpeed = 0 }, true},
+		{"Empty talker ID", func(c *Config) { c.TalkerID = "" }, false},
+		{"GN talker ID", func(c *Config) { c.TalkerID = "GN" }, false},
+		{"Lowercase talker ID", func(c *Config) { c.TalkerID = "gp" }, true},
+		{"Single letter talker ID", func(c *Config) { c.TalkerID = "G" }, true},
+		{"Three letter talker ID", func(c *Config) { c.TalkerID = "GPS" }, true},
+		{"Numeric talker ID", func(c *Config) { c.TalkerID = "G1" }, true},
+		{"Route and replay together", func(c *Config) { c.RouteFile = "route.gpx"; c.ReplayFile = "track.gpx" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.modify(&config)
+
+			err := config.Validate()
+			if (err != nil) != tt.shouldError {
+				t.Errorf("Expected error: %v, got: %v", tt.shouldError, err)
+			}
+		})
+	}
+}
+
+// createReplaySimulator creates a locked simulator replaying the given points in memory
+func createReplaySimulator(points []TrackPoint, interpolate bool) *GPSSimulator {
+	sim := createTestSimulator()
+	sim.Config.ReplayFile = "in-memory.gpx"
+	sim.Config.ReplaySpeed = 1.0
+	sim.Config.ReplayInterpolate = interpolate
+	sim.replayPoints = points
+	sim.currentLat = points[0].Lat
+	sim.currentLon = points[0].Lon
+	sim.currentAlt = points[0].Elevation
+	return sim
+}
+
+func TestReplayInterpolationSequentialTimestamps(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Elevation: 100, Time: base},
+		{Lat: 37.0010, Lon: -121.9990, Elevation: 200, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	sim.replayStartTime = time.Now().Add(-5 * time.Second)
+	sim.updateReplayPosition()
+
+	if sim.replayIndex != 0 {
+		t.Fatalf("Expected to be on the first segment, got index %d", sim.replayIndex)
+	}
+
+	// Halfway through the segment in time should be roughly halfway in space
+	if math.Abs(sim.currentLat-37.0005) > 0.00005 || math.Abs(sim.currentLon-(-121.9995)) > 0.00005 {
+		t.Errorf("Expected midpoint ~37.0005, -121.9995, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+	if math.Abs(sim.currentAlt-150) > 5 {
+		t.Errorf("Expected altitude ~150, got %f", sim.currentAlt)
+	}
+
+	// The interpolated position must lie on the segment between the two points
+	total := sim.calculateDistance(points[0].Lat, points[0].Lon, points[1].Lat, points[1].Lon)
+	viaPosition := sim.calculateDistance(points[0].Lat, points[0].Lon, sim.currentLat, sim.currentLon) +
+		sim.calculateDistance(sim.currentLat, sim.currentLon, points[1].Lat, points[1].Lon)
+	if math.Abs(viaPosition-total) > 0.5 {
+		t.Errorf("Interpolated position is %.2fm off the segment", viaPosition-total)
+	}
+
+	// Without interpolation the position snaps to the active point
+	snapping := createReplaySimulator(points, false)
+	snapping.replayStartTime = time.Now().Add(-5 * time.Second)
+	snapping.updateReplayPosition()
+	if snapping.currentLat != points[0].Lat || snapping.currentAlt != points[0].Elevation {
+		t.Errorf("Expected snapping to first point without interpolation, got %f, %f", snapping.currentLat, snapping.currentAlt)
+	}
+}
+
+func TestReplayInterpolationGreatCircle(t *testing.T) {
+	// A long east-west segment at high latitude, where the great circle
+	// bows toward the pole away from a straight line in latitude/longitude
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 60.0, Lon: -10.0, Time: base},
+		{Lat: 60.0, Lon: 10.0, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	sim.replayStartTime = time.Now().Add(-5 * time.Second)
+	sim.updateReplayPosition()
+
+	// The midpoint is equidistant from both ends and north of the parallel
+	fromStart := sim.calculateDistance(points[0].Lat, points[0].Lon, sim.currentLat, sim.currentLon)
+	toEnd := sim.calculateDistance(sim.currentLat, sim.currentLon, points[1].Lat, points[1].Lon)
+	if math.Abs(fromStart-toEnd) > 0.01*(fromStart+toEnd) {
+		t.Errorf("Expected the midpoint halfway along the segment, got %.0fm from the start and %.0fm to the end", fromStart, toEnd)
+	}
+	if math.Abs(sim.currentLon) > 0.5 || sim.currentLat <= 60.0 {
+		t.Errorf("Expected the great circle midpoint north of 60N near 0E, got %f, %f", sim.currentLat, sim.currentLon)
+	}
+}
+
+func TestReplayInterpolationProgression(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	previous := sim.currentLat
+	for _, elapsed := range []time.Duration{2, 4, 6, 8} {
+		sim.replayStartTime = time.Now().Add(-elapsed * time.Second)
+		sim.updateReplayPosition()
+		if sim.currentLat <= previous {
+			t.Errorf("Position should advance smoothly: at %ds lat %f, previous %f", elapsed, sim.currentLat, previous)
+		}
+		previous = sim.currentLat
+	}
+}
+
+func TestReplayInterpolationNoExtrapolation(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+
+	// Exactly at the last point
+	sim.replayStartTime = time.Now().Add(-10 * time.Second)
+	sim.updateReplayPosition()
+	if sim.currentLat > points[1].Lat {
+		t.Errorf("Position %f extrapolated past the last point %f", sim.currentLat, points[1].Lat)
+	}
+
+	// Past the end the replay completes without moving beyond the last point
+	sim.replayStartTime = time.Now().Add(-20 * time.Second)
+	sim.updateReplayPosition()
+	if !sim.replayCompleted {
+		t.Error("Replay should be completed past the last point")
+	}
+	if sim.currentLat > points[1].Lat {
+		t.Errorf("Position %f extrapolated past the last point %f", sim.currentLat, points[1].Lat)
+	}
+}
+
+func TestReplayInterpolationZeroDurationGap(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0005, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	for _, elapsed := range []time.Duration{0, 1, 5, 9} {
+		sim.replayStartTime = time.Now().Add(-elapsed * time.Second)
+		sim.updateReplayPosition()
+
+		for _, v := range []float64{sim.currentLat, sim.currentLon, sim.currentAlt, sim.currentSpeed, sim.currentCourse} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Fatalf("Zero-duration gap produced invalid value at %ds: %v", elapsed, v)
+			}
+		}
+	}
+}
+
+func TestReplayInterpolationIndexBased(t *testing.T) {
+	// Non-sequential timestamps use the fixed 1 second interval between points
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Elevation: 0, Time: base.Add(time.Hour)},
+		{Lat: 37.0010, Lon: -122.0000, Elevation: 10, Time: base},
+		{Lat: 37.0020, Lon: -122.0000, Elevation: 20, Time: base.Add(2 * time.Hour)},
+	}
+
+	sim := createReplaySimulator(points, true)
+	sim.replayStartTime = time.Now().Add(-1500 * time.Millisecond)
+	sim.updateReplayPosition()
+
+	if sim.replayIndex != 1 {
+		t.Fatalf("Expected index 1 after 1.5s, got %d", sim.replayIndex)
+	}
+	if math.Abs(sim.currentLat-37.0015) > 0.0001 {
+		t.Errorf("Expected lat ~37.0015 halfway between points 1 and 2, got %f", sim.currentLat)
+	}
+	if math.Abs(sim.currentAlt-15) > 1 {
+		t.Errorf("Expected altitude ~15, got %f", sim.currentAlt)
+	}
+}
+
+func TestNewGPSSimulatorWithReplayReverse(t *testing.T) {
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, "test_replay_reverse.gpx")
+
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <name>Test Track</name>
+    <trkseg>
+      <trkpt lat="37.774900" lon="-122.419400">
+        <ele>50.0</ele>
+        <time>2024-01-15T10:00:00Z</time>
+      </trkpt>
+      <trkpt lat="37.775000" lon="-122.419300">
+        <ele>52.0</ele>
+        <time>2024-01-15T10:00:10Z</time>
+      </trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 1.0
+	config.ReplayReverse = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator with reverse replay: %v", err)
+	}
+
+	// Initial position is the last track point
+	if sim.currentLat != 37.775000 || sim.currentLon != -122.419300 || sim.currentAlt != 52.0 {
+		t.Errorf("Expected initial position at last point, got %f, %f, %f", sim.currentLat, sim.currentLon, sim.currentAlt)
+	}
+}
+
+func TestReverseTrackPoints(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Time: base},
+		{Lat: 37.0010, Time: base.Add(10 * time.Second)},
+		{Lat: 37.0020, Time: base.Add(30 * time.Second)},
+	}
+
+	reversed := reverseTrackPoints(points)
+	expectedLats := []float64{37.0020, 37.0010, 37.0000}
+	expectedOffsets := []time.Duration{0, 20 * time.Second, 30 * time.Second}
+	for i := range reversed {
+		if reversed[i].Lat != expectedLats[i] {
+			t.Errorf("Point %d: expected lat %f, got %f", i, expectedLats[i], reversed[i].Lat)
+		}
+		if offset := reversed[i].Time.Sub(base); offset != expectedOffsets[i] {
+			t.Errorf("Point %d: expected offset %v, got %v", i, expectedOffsets[i], offset)
+		}
+	}
+
+	// The input slice is left untouched
+	if points[0].Lat != 37.0000 {
+		t.Error("reverseTrackPoints should not modify its input")
+	}
+}
+
+func TestReplayReverseSequentialTimestamps(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+		{Lat: 37.0020, Lon: -122.0000, Time: base.Add(30 * time.Second)},
+	}
+
+	forward := createReplaySimulator(points, false)
+	forward.replayStartTime = time.Now()
+	forward.updateReplayPosition()
+
+	sim := createReplaySimulator(reverseTrackPoints(points), false)
+
+	// The 20s gap between the last two points is replayed first
+	sim.replayStartTime = time.Now().Add(-15 * time.Second)
+	sim.updateReplayPosition()
+	if sim.currentLat != 37.0020 {
+		t.Errorf("Expected to still be at the last point after 15s, got lat %f", sim.currentLat)
+	}
+
+	sim.replayStartTime = time.Now().Add(-25 * time.Second)
+	sim.updateReplayPosition()
+	if sim.currentLat != 37.0010 {
+		t.Errorf("Expected the middle point after 25s, got lat %f", sim.currentLat)
+	}
+
+	// Reverse travel heads south, opposite to the forward course
+	diff := math.Abs(math.Mod(sim.currentCourse-forward.currentCourse+360, 360) - 180)
+	if diff > 1.0 {
+		t.Errorf("Expected reverse course ~180° from forward %f, got %f", forward.currentCourse, sim.currentCourse)
+	}
+}
+
+func TestReplayReverseIndexBased(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base.Add(time.Hour)},
+		{Lat: 37.0000, Lon: -122.0010, Time: base},
+		{Lat: 37.0000, Lon: -122.0020, Time: base.Add(2 * time.Hour)},
+	}
+
+	forward := createReplaySimulator(points, false)
+	forward.replayStartTime = time.Now()
+	forward.updateReplayPosition()
+
+	sim := createReplaySimulator(reverseTrackPoints(points), false)
+	if sim.hasSequentialTimestamps() {
+		t.Fatal("Reversing non-sequential timestamps should keep index-based progression")
+	}
+
+	sim.replayStartTime = time.Now().Add(-1500 * time.Millisecond)
+	sim.updateReplayPosition()
+	if sim.currentLon != -122.0010 {
+		t.Errorf("Expected the middle point after 1.5s, got lon %f", sim.currentLon)
+	}
+
+	diff := math.Abs(math.Mod(sim.currentCourse-forward.currentCourse+360, 360) - 180)
+	if diff > 1.0 {
+		t.Errorf("Expected reverse course ~180° from forward %f, got %f", forward.currentCourse, sim.currentCourse)
+	}
+}
+
+func TestReplayReverseLoopWrapsToLastPoint(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Lat: 37.0000, Lon: -122.0000, Time: base},
+		{Lat: 37.0010, Lon: -122.0000, Time: base.Add(10 * time.Second)},
+	}
+
+	sim := createReplaySimulator(reverseTrackPoints(points), false)
+	sim.Config.ReplayLoop = true
+	sim.replayStartTime = time.Now().Add(-11 * time.Second)
+	sim.updateReplayPosition()
+
+	if sim.replayIndex != 0 || sim.currentLat != 37.0010 {
+		t.Errorf("Expected loop to wrap back to the last track point, got index %d lat %f", sim.replayIndex, sim.currentLat)
+	}
+}
+
+func TestCollapseSegmentGaps(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	points := []TrackPoint{
+		{Time: base, Segment: 0},
+		{Time: base.Add(10 * time.Second), Segment: 0},
+		{Time: base.Add(5 * time.Minute), Segment: 1},
+		{Time: base.Add(5*time.Minute + 10*time.Second), Segment: 1},
+	}
+
+	collapsed := collapseSegmentGaps(points)
+	expected := []time.Duration{0, 10 * time.Second, 20 * time.Second, 30 * time.Second}
+	for i, want := range expected {
+		if got := collapsed[i].Time.Sub(base); got != want {
+			t.Errorf("Point %d: expected offset %v, got %v", i, want, got)
+		}
+	}
+
+	// The input slice is left untouched
+	if !points[2].Time.Equal(base.Add(5 * time.Minute)) {
+		t.Error("collapseSegmentGaps should not modify its input")
+	}
+}
+
+func TestReplaySegmentGaps(t *testing.T) {
+	tests := []struct {
+		name        string
+		preserve    bool
+		elapsed     time.Duration
+		expectIndex int
+		expectSpeed bool // whether the receiver should be moving
+	}{
+		// Preserved: 2 minutes in is inside the 4m40s pause after the first segment
+		{"Preserved gap holds still", true, 2 * time.Minute, 2, false},
+		{"Preserved gap resumes after pause", true, 5*time.Minute + 5*time.Second, 3, true},
+		// Collapsed: the second segment starts 10s after the first ends
+		{"Collapsed gap moves on", false, 35 * time.Second, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.ReplayFile = filepath.Join("testdata", "multi_segment.gpx")
+			config.ReplaySpeed = 1.0
+			config.ReplaySegmentGaps = tt.preserve
+			config.ReplayInterpolate = true
+
+			sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("Failed to create GPS simulator: %v", err)
+			}
+
+			sim.replayStartTime = time.Now().Add(-tt.elapsed)
+			sim.updateReplayPosition()
+
+			if sim.replayIndex != tt.expectIndex {
+				t.Errorf("Expected index %d, got %d", tt.expectIndex, sim.replayIndex)
+			}
+			if moving := sim.currentSpeed > 0.01; moving != tt.expectSpeed {
+				t.Errorf("Expected moving=%v, got speed %f", tt.expectSpeed, sim.currentSpeed)
+			}
+			if !tt.expectSpeed && sim.currentLat != sim.replayPoints[tt.expectIndex].Lat {
+				t.Errorf("Expected to hold at the end of the segment, got lat %f", sim.currentLat)
+			}
+		})
+	}
+}
+
+// seededOutput generates 100 output cycles from a fixed start time with the given seed
+func seededOutput(t *testing.T, seed int64) string {
+	config := createTestConfig()
+	config.TimeToLock = 5 * time.Second
+	config.Speed = 5.0
+	config.DOPJitter = 0.2
+	config.Quiet = true
+	config.Seed = seed
+
+	sim, err := NewGPSSimulator(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.SetClock(newFakeClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	buffer := &bytes.Buffer{}
+	if _, err := sim.GenerateTo(buffer, 100*config.OutputRate); err != nil {
+		t.Fatalf("Failed to generate output: %v", err)
+	}
+	return buffer.String()
+}
+
+func TestSeedReproducibleOutput(t *testing.T) {
+	first := seededOutput(t, 42)
+	second := seededOutput(t, 42)
+	if first != second {
+		t.Error("Expected identical NMEA output from simulators with the same seed")
+	}
+	if strings.Count(first, "$GPGGA") != 100 {
+		t.Errorf("Expected 100 GGA sentences, got %d", strings.Count(first, "$GPGGA"))
+	}
+
+	if seededOutput(t, 43) == first {
+		t.Error("Expected different output from a different seed")
+	}
+}
+
+func TestReplayHonorSegments(t *testing.T) {
+	config := createTestConfig()
+	config.ReplayFile = filepath.Join("testdata", "multi_segment.gpx")
+	config.ReplaySpeed = 1.0
+	config.ReplayHonorSegments = true
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	sim.SetClock(clock)
+	sim.lockTime = start
+	sim.isLocked = true
+	sim.replayStartTime = start
+
+	// Still in the first segment 25s in, holding at its last point
+	clock.Advance(25 * time.Second)
+	sim.update()
+	if !sim.isLocked || sim.replayIndex != 2 {
+		t.Fatalf("Expected a fix at the end of the first segment, got locked=%v index %d", sim.isLocked, sim.replayIndex)
+	}
+	if sim.currentSpeed != 0 {
+		t.Errorf("Expected no motion across the segment boundary, got %f knots", sim.currentSpeed)
+	}
+
+	// Entering the second segment loses the fix without moving into it
+	clock.Advance(10 * time.Second)
+	sim.update()
+	if sim.isLocked {
+		t.Fatal("Expected the fix to be lost at the segment boundary")
+	}
+	if sim.currentLat != sim.replayPoints[2].Lat {
+		t.Errorf("Expected to stay at the end of the first segment, got lat %f", sim.currentLat)
+	}
+	if sim.currentFix(clock.Now()).Locked {
+		t.Error("Expected the reported fix to be unlocked during the outage")
+	}
+
+	// The fix returns after the outage in the new segment
+	clock.Advance(segmentOutageDuration + time.Second)
+	sim.update()
+	if !sim.isLocked || sim.replayPoints[sim.replayIndex].Segment != 1 {
+		t.Errorf("Expected the fix back in the second segment, got locked=%v segment %d",
+			sim.isLocked, sim.replayPoints[sim.replayIndex].Segment)
+	}
+}
+
+func TestCourseHeldBelowMinSpeed(t *testing.T) {
+	config := createTestConfig()
+	config.Speed = 0
+	config.Course = 90
+	config.Jitter = 1.0
+	config.MinSpeedForCourse = 0.5
+	config.Seed = 1
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create simulator: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		sim.updateSpeedAndCourse()
+		if sim.currentCourse != 90 {
+			t.Fatalf("Update %d: expected course held at 90, got %f", i, sim.currentCourse)
+		}
+	}
+
+	rmc := sentenceFields(sim.generateRMC(time.Now()))
+	if rmc[8] != "" {
+		t.Errorf("Expected empty RMC course below minimum speed, got %q", rmc[8])
+	}
+	vtg := sentenceFields(sim.generateVTG())
+	if vtg[1] != "" || vtg[3] != "" {
+		t.Errorf("Expected empty VTG courses below minimum speed, got %q and %q", vtg[1], vtg[3])
+	}
+
+	// Above the threshold the course is reported and jitters again
+	sim.Config.Speed = 10
+	sim.updateSpeedAndCourse()
+	if rmc := sentenceFields(sim.generateRMC(time.Now())); rmc[8] == "" {
+		t.Error("Expected RMC course above minimum speed")
+	}
+}
+
+func TestValidateMinSpeedForCourse(t *testing.T) {
+	config := createTestConfig()
+	config.BaudRate = 9600
+	config.ReplaySpeed = 1.0
+	config.MinSpeedForCourse = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected error for negative minimum speed for course")
+	}
+}
+
+func TestUpdatePositionNearPole(t *testing.T) {
+	tests := []struct {
+		name   string
+		lat    float64
+		course float64
+		radius float64
+		jitter float64
+	}{
+		{"East near north pole", 89.999, 90, 0, 0},
+		{"East near north pole within radius", 89.999, 90, 100, 0},
+		{"East near south pole with jitter", -89.999, 90, 100, 0.5},
+		{"East at the pole", 90, 90, 0, 0},
+		{"North over the pole", 89.9999, 0, 0, 0},
+		{"South over the pole", -89.9999, 180, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Latitude = tt.lat
+			config.Longitude = 179.99
+			config.Radius = tt.radius
+			config.Jitter = tt.jitter
+			config.Speed = 50
+			config.Course = tt.course
+			config.Seed = 1
+			sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("Failed to create GPS simulator: %v", err)
+			}
+			sim.isLocked = true
+			clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+			sim.SetClock(clock)
+
+			for i := 0; i < 20; i++ {
+				clock.Advance(time.Second)
+				sim.updatePosition()
+
+				lat, lon := sim.currentLat, sim.currentLon
+				if math.IsNaN(lat) || math.IsNaN(lon) || math.IsInf(lat, 0) || math.IsInf(lon, 0) {
+					t.Fatalf("Update %d: position %v, %v is not finite", i+1, lat, lon)
+				}
+				if lat < -90 || lat > 90 || lon < -180 || lon >= 180 {
+					t.Fatalf("Update %d: position %.6f, %.6f is out of range", i+1, lat, lon)
+				}
+				if tt.radius > 0 && sim.distanceFromCenter(lat, lon) > tt.radius*1.01 {
+					t.Fatalf("Update %d: %.1fm from the center, beyond the %.0fm radius", i+1, sim.distanceFromCenter(lat, lon), tt.radius)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeCoordinate(t *testing.T) {
+	tests := []struct {
+		lat, lon         float64
+		wantLat, wantLon float64
+	}{
+		{45, 10, 45, 10},
+		{45, 190, 45, -170},
+		{45, -190, 45, 170},
+		{45, 180, 45, -180},
+		{90.5, 10, 89.5, -170},
+		{-90.5, -10, -89.5, 170},
+		{10, 1e9 + 20, 10, -60},
+	}
+	for _, tt := range tests {
+		lat, lon := normalizeCoordinate(tt.lat, tt.lon)
+		if math.Abs(lat-tt.wantLat) > 1e-9 || math.Abs(lon-tt.wantLon) > 1e-6 {
+			t.Errorf("normalizeCoordinate(%v, %v) = %v, %v, expected %v, %v", tt.lat, tt.lon, lat, lon, tt.wantLat, tt.wantLon)
+		}
+	}
+}
+
+func TestAntimeridianWrapping(t *testing.T) {
+	config := createTestConfig()
+	config.Latitude = 0
+	config.Longitude = 179.99
+	config.Radius = 5000
+	config.Jitter = 0
+	config.Speed = 50 // About 25.7 m/s
+	config.Course = 90
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	sim.isLocked = true
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	// 0.01° at the equator is about 1113m, crossed in under 45 seconds
+	for i := 0; i < 60; i++ {
+		clock.Advance(time.Second)
+		sim.updatePosition()
+		if sim.currentLon < -180 || sim.currentLon >= 180 {
+			t.Fatalf("Update %d: longitude %.6f is out of range", i+1, sim.currentLon)
+		}
+	}
+
+	if sim.currentLon > -179.99 || sim.currentLon < -179.999 {
+		t.Errorf("Expected longitude wrapped to about -179.996, got %.6f", sim.currentLon)
+	}
+	// Travelled about 1543m east, not most of the way around the world
+	if distance := sim.distanceFromCenter(sim.currentLat, sim.currentLon); math.Abs(distance-1543) > 20 {
+		t.Errorf("Expected about 1543m from the center across the antimeridian, got %.1fm", distance)
+	}
+	if bearing := sim.calculateBearing(config.Latitude, config.Longitude, sim.currentLat, sim.currentLon); math.Abs(bearing-90) > 0.01 {
+		t.Errorf("Expected a bearing of 90° across the antimeridian, got %.2f°", bearing)
+	}
+
+	// Great-circle movement wraps the same way
+	lat, lon := sim.calculateDestination(0, 179.99, 90, 5000)
+	if lon >= -179.9 || lon < -180 || math.Abs(lat) > 1e-9 {
+		t.Errorf("Expected a destination just west of -180°, got %.6f, %.6f", lat, lon)
+	}
+	if distance := sim.calculateDistance(0, 179.99, 0, -179.99); math.Abs(distance-2224) > 1 {
+		t.Errorf("Expected about 2224m between 179.99° and -179.99°, got %.1fm", distance)
+	}
+}
@@ -0,0 +1,136 @@
+package gps
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sky conditions presetting the satellite elevation and signal strength ranges
+const (
+	SkyOpen   = "open"   // Clear view of the sky with strong signals
+	SkyUrban  = "urban"  // Urban canyon: low satellites blocked and signals weakened by reflections
+	SkyIndoor = "indoor" // Indoors or under heavy cover with weak signals
+)
+
+// SkyConditions lists the supported sky conditions
+var SkyConditions = []string{SkyOpen, SkyUrban, SkyIndoor}
+
+// valueRange is an inclusive range of whole values
+type valueRange struct {
+	min, max int
+}
+
+// random returns a value in the range drawn from s's random source
+func (r valueRange) random(s *GPSSimulator) int {
+	if r.max <= r.min {
+		return r.min
+	}
+	return s.random().Intn(r.max-r.min+1) + r.min
+}
+
+// clamp returns v kept within the range
+func (r valueRange) clamp(v int) int {
+	if v < r.min {
+		return r.min
+	}
+	if v > r.max {
+		return r.max
+	}
+	return v
+}
+
+// skyRanges are the elevation and SNR ranges of each sky condition
+var skyRanges = map[string]struct{ elevation, snr valueRange }{
+	SkyOpen:   {elevation: valueRange{5, 85}, snr: valueRange{30, 50}},
+	SkyUrban:  {elevation: valueRange{20, 85}, snr: valueRange{15, 35}},
+	SkyIndoor: {elevation: valueRange{5, 85}, snr: valueRange{5, 25}},
+}
+
+// ParseSkyCondition converts a sky condition name (case-insensitive) to its
+// canonical form. An empty name is allowed and keeps the default ranges.
+func ParseSkyCondition(name string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if normalized == "" {
+		return "", nil
+	}
+	for _, sky := range SkyConditions {
+		if normalized == sky {
+			return sky, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sky condition %q (valid: %s)", name, strings.Join(SkyConditions, ", "))
+}
+
+// validateSky checks the sky condition and the satellite elevation and SNR
+// ranges
+func (c Config) validateSky() error {
+	if _, err := ParseSkyCondition(c.SkyCondition); err != nil {
+		return fmt.Errorf("Invalid sky condition: %v", err)
+	}
+	for _, elevation := range []*int{c.MinElevation, c.MaxElevation} {
+		if elevation != nil && (*elevation < 0 || *elevation > 90) {
+			return errors.New("Satellite elevations must be between 0 and 90 degrees")
+		}
+	}
+	for _, snr := range []*int{c.MinSNR, c.MaxSNR} {
+		if snr != nil && (*snr < 0 || *snr > 99) {
+			return errors.New("Satellite SNRs must be between 0 and 99 dB")
+		}
+	}
+	_, elevation, _, snr := c.satelliteRanges()
+	if elevation.min > elevation.max {
+		return fmt.Errorf("Minimum satellite elevation %d is above the maximum %d", elevation.min, elevation.max)
+	}
+	if snr.min > snr.max {
+		return fmt.Errorf("Minimum satellite SNR %d is above the maximum %d", snr.min, snr.max)
+	}
+	return nil
+}
+
+// satelliteRanges returns the elevation and SNR ranges satellites start in
+// and the bounds they are kept within as they move. A sky condition sets
+// both to its ranges, and MinElevation, MaxElevation, MinSNR and MaxSNR
+// override them when set. Otherwise satellites start at 10-80 degrees and
+// 20-50 dB and drift within 5-85 degrees and 15-55 dB.
+func (c Config) satelliteRanges() (elevationStart, elevationBounds, snrStart, snrBounds valueRange) {
+	elevationStart, elevationBounds = valueRange{10, 80}, valueRange{5, 85}
+	snrStart, snrBounds = valueRange{20, 50}, valueRange{15, 55}
+
+	sky, _ := ParseSkyCondition(c.SkyCondition)
+	if ranges, ok := skyRanges[sky]; ok {
+		elevationStart, elevationBounds = ranges.elevation, ranges.elevation
+		snrStart, snrBounds = ranges.snr, ranges.snr
+	}
+
+	if c.MinElevation != nil {
+		elevationStart.min, elevationBounds.min = *c.MinElevation, *c.MinElevation
+	}
+	if c.MaxElevation != nil {
+		elevationStart.max, elevationBounds.max = *c.MaxElevation, *c.MaxElevation
+	}
+	if c.MinSNR != nil {
+		snrStart.min, snrBounds.min = *c.MinSNR, *c.MinSNR
+	}
+	if c.MaxSNR != nil {
+		snrStart.max, snrBounds.max = *c.MaxSNR, *c.MaxSNR
+	}
+	return elevationStart, elevationBounds, snrStart, snrBounds
+}
+
+// newSatellite returns a satellite with a random position and signal
+// within the configured ranges
+func (s *GPSSimulator) newSatellite(id int, c Constellation) Satellite {
+	elevation, _, snr, _ := s.Config.satelliteRanges()
+	sat := Satellite{
+		ID:            id,
+		Elevation:     elevation.random(s),
+		Azimuth:       s.random().Intn(360), // 0-359 degrees
+		SNR:           snr.random(s),
+		Constellation: c,
+	}
+	if s.Config.SatelliteChurn {
+		sat.setting = s.random().Intn(2) == 0
+	}
+	return sat
+}
@@ -0,0 +1,143 @@
+package gps
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSkyConditionRanges(t *testing.T) {
+	tests := []struct {
+		name                       string
+		configure                  func(*Config)
+		minElevation, maxElevation int
+		minSNR, maxSNR             int
+	}{
+		{"Default", func(c *Config) {}, 5, 85, 15, 55},
+		{"Open", func(c *Config) { c.SkyCondition = SkyOpen }, 5, 85, 30, 50},
+		{"Urban", func(c *Config) { c.SkyCondition = SkyUrban }, 20, 85, 15, 35},
+		{"Indoor", func(c *Config) { c.SkyCondition = SkyIndoor }, 5, 85, 5, 25},
+		{"Explicit", func(c *Config) {
+			c.MinElevation, c.MaxElevation = intPtr(30), intPtr(60)
+			c.MinSNR, c.MaxSNR = intPtr(10), intPtr(20)
+		}, 30, 60, 10, 20},
+		{"Preset override", func(c *Config) {
+			c.SkyCondition = SkyIndoor
+			c.MaxSNR = intPtr(12)
+		}, 5, 85, 5, 12},
+		{"Explicit zero", func(c *Config) {
+			c.MinElevation, c.MaxElevation = intPtr(0), intPtr(10)
+			c.MinSNR, c.MaxSNR = intPtr(0), intPtr(0)
+		}, 0, 10, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			tt.configure(&sim.Config)
+			sim.initializeSatellites()
+
+			for step := 0; step < 500; step++ {
+				for _, sat := range sim.Satellites {
+					if sat.Elevation < tt.minElevation || sat.Elevation > tt.maxElevation {
+						t.Fatalf("Step %d: satellite %d elevation %d outside %d-%d", step, sat.ID, sat.Elevation, tt.minElevation, tt.maxElevation)
+					}
+					if sat.SNR < tt.minSNR || sat.SNR > tt.maxSNR {
+						t.Fatalf("Step %d: satellite %d SNR %d outside %d-%d", step, sat.ID, sat.SNR, tt.minSNR, tt.maxSNR)
+					}
+				}
+				sim.updateSatellites()
+			}
+		})
+	}
+}
+
+func TestSkyConditionGSV(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.SkyCondition = SkyIndoor
+	sim.initializeSatellites()
+
+	found := 0
+	for _, sentence := range sim.generateGSV() {
+		fields := strings.Split(strings.Split(sentence, "*")[0], ",")
+		// Each satellite is four fields (PRN, elevation, azimuth, SNR) after the header
+		for i := 7; i < len(fields); i += 4 {
+			if fields[i] == "" {
+				continue
+			}
+			snr, err := strconv.Atoi(fields[i])
+			if err != nil {
+				t.Fatalf("Invalid SNR field %q in %s", fields[i], sentence)
+			}
+			if snr > 25 {
+				t.Errorf("Expected indoor SNR at most 25, got %d in %s", snr, sentence)
+			}
+			found++
+		}
+	}
+	if found != sim.Config.Satellites {
+		t.Errorf("Expected %d SNR fields, got %d", sim.Config.Satellites, found)
+	}
+}
+
+func TestSkyConditionValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   bool
+	}{
+		{"Preset", func(c *Config) { c.SkyCondition = "Urban" }, false},
+		{"Explicit ranges", func(c *Config) { c.MinSNR, c.MaxSNR = intPtr(10), intPtr(30) }, false},
+		{"Explicit zero", func(c *Config) { c.MinElevation, c.MinSNR = intPtr(0), intPtr(0) }, false},
+		{"Unknown preset", func(c *Config) { c.SkyCondition = "cave" }, true},
+		{"Elevation above 90", func(c *Config) { c.MaxElevation = intPtr(95) }, true},
+		{"Negative SNR", func(c *Config) { c.MinSNR = intPtr(-1) }, true},
+		{"SNR min above max", func(c *Config) { c.MinSNR, c.MaxSNR = intPtr(40), intPtr(30) }, true},
+		{"SNR min above preset max", func(c *Config) {
+			c.SkyCondition = SkyIndoor
+			c.MinSNR = intPtr(30)
+		}, true},
+		{"Elevation min above max", func(c *Config) { c.MinElevation, c.MaxElevation = intPtr(70), intPtr(50) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.BaudRate = 9600
+			config.ReplaySpeed = 1.0
+			tt.configure(&config)
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSkyCondition(t *testing.T) {
+	for input, expected := range map[string]string{"": "", "open": SkyOpen, " URBAN ": SkyUrban, "Indoor": SkyIndoor} {
+		got, err := ParseSkyCondition(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseSkyCondition(%q) = %q, %v; expected %q", input, got, err, expected)
+		}
+	}
+	if _, err := ParseSkyCondition("forest"); err == nil {
+		t.Error("Expected an error for an unknown sky condition")
+	}
+}
+
+func TestValueRangeRandomInclusive(t *testing.T) {
+	sim := createTestSimulator()
+	r := valueRange{3, 5}
+
+	seen := map[int]bool{}
+	for i := 0; i < 200; i++ {
+		v := r.random(sim)
+		if v < 3 || v > 5 {
+			t.Fatalf("Expected a value within 3-5, got %d", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected every value from 3 to 5 including both ends, got %v", seen)
+	}
+}
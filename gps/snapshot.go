@@ -0,0 +1,58 @@
+package gps
+
+import "time"
+
+// StateSnapshot is a copy of the simulator state at one instant
+type StateSnapshot struct {
+	Time              time.Time // Simulated time the snapshot was taken
+	Latitude          float64   // Decimal degrees
+	Longitude         float64   // Decimal degrees
+	ReportedLatitude  float64   // Latitude reported in NMEA, offset from Latitude by BiasMeters
+	ReportedLongitude float64   // Longitude reported in NMEA, offset from Longitude by BiasMeters
+	Altitude          float64   // Meters above mean sea level
+	Speed             float64   // Knots
+	Course            float64   // Degrees from true north
+	Locked            bool      // Whether the receiver has a fix
+	Paused            bool      // Whether the simulation is paused
+	SatellitesInView  int       // Satellites reported in view (acquired so far while acquiring a fix)
+	ReplayIndex       int       // Index of the current replay track point
+	ReplayCompleted   bool      // Whether a replay has finished its first pass
+	ReplayProgress    float64   // Percentage of the current replay track played (0-100)
+	ReplayFile        string    // Replay file being played (empty when not replaying)
+	ReplayFileIndex   int       // Index of ReplayFile in the replay playlist
+	NMEADropped       int       // Lines of the replayed NMEA log dropped for a missing or bad checksum or not being sentences
+}
+
+// Snapshot returns a copy of the current simulator state. It is safe to call
+// concurrently with Run, since it holds the read lock that each simulation cycle excludes.
+func (s *GPSSimulator) Snapshot() StateSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	inView := len(s.Satellites)
+	if !s.isLocked {
+		inView = len(s.acquiredSatellites())
+	}
+
+	reportedLat, reportedLon := s.reportedPosition()
+
+	return StateSnapshot{
+		Time:              s.now(),
+		Latitude:          s.currentLat,
+		Longitude:         s.currentLon,
+		ReportedLatitude:  reportedLat,
+		ReportedLongitude: reportedLon,
+		Altitude:          s.currentAlt,
+		Speed:             s.currentSpeed,
+		Course:            s.currentCourse,
+		Locked:            s.isLocked,
+		Paused:            s.paused,
+		SatellitesInView:  inView,
+		ReplayIndex:       s.replayIndex,
+		ReplayCompleted:   s.replayCompleted,
+		ReplayProgress:    s.replayProgress(),
+		ReplayFile:        s.replayFileName(),
+		ReplayFileIndex:   s.replayFileIndex,
+		NMEADropped:       s.nmeaDropped,
+	}
+}
@@ -0,0 +1,87 @@
+package gps
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	sim := createTestSimulator()
+	sim.currentAlt = 50
+	sim.currentSpeed = 3
+	sim.currentCourse = 45
+	sim.replayIndex = 7
+
+	snapshot := sim.Snapshot()
+
+	if snapshot.Latitude != sim.currentLat || snapshot.Longitude != sim.currentLon || snapshot.Altitude != 50 {
+		t.Errorf("Snapshot position %f, %f, %f does not match simulator", snapshot.Latitude, snapshot.Longitude, snapshot.Altitude)
+	}
+	if snapshot.Speed != 3 || snapshot.Course != 45 {
+		t.Errorf("Expected speed 3 and course 45, got %f and %f", snapshot.Speed, snapshot.Course)
+	}
+	if !snapshot.Locked || snapshot.SatellitesInView != len(sim.Satellites) || snapshot.ReplayIndex != 7 {
+		t.Errorf("Unexpected snapshot state: %+v", snapshot)
+	}
+
+	// Snapshots are copies that do not follow later changes
+	sim.currentSpeed = 10
+	if snapshot.Speed != 3 {
+		t.Error("Snapshot should not change when the simulator does")
+	}
+
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Minute)
+	if snapshot := sim.Snapshot(); snapshot.Locked || snapshot.SatellitesInView != 0 {
+		t.Errorf("Expected unlocked snapshot with no satellites in view, got %+v", snapshot)
+	}
+}
+
+func TestSnapshotConcurrentWithRun(t *testing.T) {
+	config := createTestConfig()
+	config.TimeToLock = 20 * time.Millisecond
+	config.OutputRate = time.Millisecond
+	config.Duration = 200 * time.Millisecond
+	config.Speed = 20
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sim.Run()
+		close(done)
+	}()
+
+	// Several readers snapshot while the run loop updates state
+	var wg sync.WaitGroup
+	locked := make([]bool, 4)
+	for i := range locked {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if sim.Snapshot().Locked {
+					locked[i] = true
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	for i, sawLock := range locked {
+		if !sawLock {
+			t.Errorf("Reader %d never observed the lock", i)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package gps
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSubscriberBuffer is the channel buffer size used when SubscribeSentences is given a non-positive size
+const DefaultSubscriberBuffer = 64
+
+// sentenceStream fans out formatted NMEA sentences to channel subscribers and
+// per-cycle data to callbacks
+type sentenceStream struct {
+	mu          sync.Mutex
+	subscribers map[chan string]*subscriber
+	closed      bool
+	callbacks   []func(NMEAData)
+	block       strings.Builder // Sentences emitted in the current output cycle
+	emitted     uint64          // Sentences (or gpsd reports) emitted so far
+	dropped     uint64          // Sentences dropped for subscribers not keeping up
+}
+
+// SentenceFilter selects the sentences a stream subscriber receives
+type SentenceFilter struct {
+	Sentences []string // Sentence types to receive whatever their talker ID (e.g. GGA, RMC); empty receives all
+	MaxRate   float64  // Most sentences of each type to receive per second of simulated time; 0 receives every one
+}
+
+// subscriber is a stream subscription and the state of its filter
+type subscriber struct {
+	types    map[string]bool     // Sentence types received (nil receives all)
+	interval time.Duration       // Least time between sentences of a type (0 for no limit)
+	last     map[string]received // When each type was last received
+}
+
+// received marks the output burst a sentence type was last received in
+type received struct {
+	burst uint64
+	at    time.Time
+}
+
+// newSubscriber validates filter and returns a subscriber applying it
+func newSubscriber(filter SentenceFilter) (*subscriber, error) {
+	if filter.MaxRate < 0 {
+		return nil, errors.New("subscription rate must not be negative")
+	}
+	sentences, err := parseSentences(filter.Sentences)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscriber{last: make(map[string]received)}
+	if len(sentences) > 0 {
+		sub.types = make(map[string]bool)
+		for _, sentence := range sentences {
+			sub.types[sentence] = true
+		}
+	}
+	if filter.MaxRate > 0 {
+		sub.interval = time.Duration(float64(time.Second) / filter.MaxRate)
+	}
+	return sub, nil
+}
+
+// accepts reports whether the subscriber receives a sentence emitted at now
+// in an output burst, recording it if so. Every part of a multi-sentence
+// group such as GSV is in the same burst, so the group is received whole or
+// not at all.
+func (sub *subscriber) accepts(sentence string, burst uint64, now time.Time) bool {
+	sentenceType := streamSentenceType(sentence)
+	if sub.types != nil && !sub.types[sentenceType] {
+		return false
+	}
+	if sub.interval > 0 {
+		last, seen := sub.last[sentenceType]
+		if seen && burst != last.burst && now.Sub(last.at) < sub.interval {
+			return false
+		}
+		if !seen || burst != last.burst {
+			sub.last[sentenceType] = received{burst: burst, at: now}
+		}
+	}
+	return true
+}
+
+// streamSentenceType returns the type of an emitted sentence without its
+// talker ID (e.g. GGA for $GPGGA), or an empty string for other output such
+// as gpsd reports
+func streamSentenceType(sentence string) string {
+	address, _, _ := strings.Cut(sentence, ",")
+	if !strings.HasPrefix(address, "$") || len(address) < 6 {
+		return ""
+	}
+	return address[3:]
+}
+
+// NMEAData describes one output cycle: the reported fix alongside the raw
+// sentences (gpsd reports in gpsd mode), so consumers need not parse them
+// themselves. The JSON field names are stable.
+type NMEAData struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Latitude   float64   `json:"latitude"`   // Decimal degrees
+	Longitude  float64   `json:"longitude"`  // Decimal degrees
+	Altitude   float64   `json:"altitude"`   // Meters above mean sea level
+	Speed      float64   `json:"speed"`      // Knots
+	Course     float64   `json:"course"`     // Degrees from true north
+	Satellites int       `json:"satellites"` // Satellites used, as reported in GGA
+	FixQuality int       `json:"fixQuality"` // GGA quality indicator (0 = no fix)
+	HDOP       float64   `json:"hdop"`       // 0 without a fix
+	Sentences  string    `json:"sentences"`  // Raw sentence block emitted in the cycle
+	Fix        Fix       `json:"fix"`        // Structured fix matching the cycle's GGA
+}
+
+// SubscribeSentences returns a channel that receives every formatted NMEA sentence
+// as it is generated, along with a function that cancels the subscription.
+// Sentences are dropped for a subscriber whose buffer is full so a slow consumer
+// never blocks the simulation loop. The channel is closed when the simulator stops.
+func (s *GPSSimulator) SubscribeSentences(buffer int) (<-chan string, func()) {
+	ch, cancel, _ := s.SubscribeFilteredSentences(SentenceFilter{}, buffer)
+	return ch, cancel
+}
+
+// SubscribeFilteredSentences is like SubscribeSentences, but the channel
+// only receives the sentences passing filter, so each consumer of one
+// simulator can ask for its own sentence types and rate. It returns an
+// error for an unknown sentence type or a negative rate.
+func (s *GPSSimulator) SubscribeFilteredSentences(filter SentenceFilter, buffer int) (<-chan string, func(), error) {
+	sub, err := newSubscriber(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+	ch := make(chan string, buffer)
+
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+
+	// Subscribing after the simulator has stopped yields an already closed channel
+	if s.stream.closed {
+		close(ch)
+		return ch, func() {}, nil
+	}
+
+	if s.stream.subscribers == nil {
+		s.stream.subscribers = make(map[chan string]*subscriber)
+	}
+	s.stream.subscribers[ch] = sub
+
+	cancel := func() {
+		s.stream.mu.Lock()
+		defer s.stream.mu.Unlock()
+		if _, ok := s.stream.subscribers[ch]; ok {
+			delete(s.stream.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, cancel, nil
+}
+
+// AddCallback registers fn to receive an NMEAData after every output cycle.
+// It is safe to call while Run is active. Callbacks run on the simulation
+// goroutine while the simulator is locked, so they must return quickly and
+// must not call back into methods such as Pause, Seek or Snapshot.
+func (s *GPSSimulator) AddCallback(fn func(NMEAData)) {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	s.stream.callbacks = append(s.stream.callbacks, fn)
+}
+
+// nmeaData builds the NMEAData for the current output cycle, reporting the
+// same fix as the cycle's GGA sentence
+func (s *GPSSimulator) nmeaData(timestamp time.Time, sentences string) NMEAData {
+	fix := s.currentFix(timestamp)
+	data := NMEAData{
+		Timestamp:  timestamp,
+		Latitude:   fix.Lat,
+		Longitude:  fix.Lon,
+		Altitude:   fix.Altitude,
+		Speed:      fix.SpeedKnots,
+		Course:     fix.CourseDeg,
+		Satellites: fix.SatellitesUsed,
+		HDOP:       fix.HDOP,
+		Sentences:  sentences,
+		Fix:        fix,
+	}
+
+	switch {
+	case s.isLocked:
+		data.FixQuality = s.fixQuality()
+	case s.isEstimatedFix():
+		data.FixQuality = FixQualityEstimated
+	}
+
+	return data
+}
+
+// notifyCallbacks passes the sentences emitted since the last call to every
+// registered callback
+func (s *GPSSimulator) notifyCallbacks(timestamp time.Time) {
+	s.stream.mu.Lock()
+	callbacks := s.stream.callbacks
+	sentences := s.stream.block.String()
+	s.stream.block.Reset()
+	s.stream.mu.Unlock()
+
+	if len(callbacks) == 0 {
+		return
+	}
+	data := s.nmeaData(timestamp, sentences)
+	for _, fn := range callbacks {
+		fn(data)
+	}
+}
+
+// emit writes a sentence to the NMEA writer and publishes it to all subscribers
+func (s *GPSSimulator) emit(sentence string) {
+	io.WriteString(s.nmeaWriter, sentence)
+	s.burstBytes += len(sentence)
+
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	s.stream.emitted++
+	if len(s.stream.callbacks) > 0 {
+		s.stream.block.WriteString(sentence)
+	}
+	var now time.Time
+	for ch, sub := range s.stream.subscribers {
+		if sub.interval > 0 && now.IsZero() {
+			now = s.now()
+		}
+		if !sub.accepts(sentence, s.bursts, now) {
+			continue
+		}
+		select {
+		case ch <- sentence:
+		default:
+			// Subscriber is not keeping up; drop the sentence rather than block
+			s.stream.dropped++
+		}
+	}
+}
+
+// closeSubscribers closes every subscriber channel and rejects new subscriptions
+func (s *GPSSimulator) closeSubscribers() {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	for ch := range s.stream.subscribers {
+		close(ch)
+	}
+	s.stream.subscribers = nil
+	s.stream.closed = true
+}
@@ -0,0 +1,371 @@
+package gps
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// drainSentences reads everything currently buffered on a subscription channel
+func drainSentences(ch <-chan string) []string {
+	var sentences []string
+	for {
+		select {
+		case sentence, ok := <-ch:
+			if !ok {
+				return sentences
+			}
+			sentences = append(sentences, sentence)
+		default:
+			return sentences
+		}
+	}
+}
+
+func TestSubscribeSentencesOrdering(t *testing.T) {
+	sim := createTestSimulator()
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+
+	ch, cancel := sim.SubscribeSentences(100)
+	defer cancel()
+
+	sim.outputNMEA()
+
+	received := drainSentences(ch)
+	if len(received) == 0 {
+		t.Fatal("Expected sentences on subscription channel")
+	}
+
+	// Channel output must match the writer output sentence for sentence, in order
+	if strings.Join(received, "") != buffer.String() {
+		t.Errorf("Subscription output does not match writer output\nchannel: %q\nwriter:  %q",
+			strings.Join(received, ""), buffer.String())
+	}
+
+	expectedOrder := []string{"$GPGGA", "$GPRMC", "$GPGLL", "$GPVTG", "$GPGSA", "$GPGSV", "$GPZDA"}
+	for i, prefix := range expectedOrder {
+		if i >= len(received) {
+			t.Fatalf("Expected at least %d sentences, got %d", len(expectedOrder), len(received))
+		}
+		if !strings.HasPrefix(received[i], prefix) {
+			t.Errorf("Sentence %d: expected prefix %s, got %s", i, prefix, received[i])
+		}
+	}
+}
+
+func TestSubscribeSentencesMultipleSubscribers(t *testing.T) {
+	sim := createTestSimulator()
+
+	ch1, cancel1 := sim.SubscribeSentences(100)
+	defer cancel1()
+	ch2, cancel2 := sim.SubscribeSentences(100)
+	defer cancel2()
+
+	sim.outputNMEA()
+
+	received1 := drainSentences(ch1)
+	received2 := drainSentences(ch2)
+
+	if len(received1) == 0 {
+		t.Fatal("First subscriber should receive sentences")
+	}
+	if strings.Join(received1, "") != strings.Join(received2, "") {
+		t.Error("Both subscribers should receive identical sentences")
+	}
+}
+
+func TestSubscribeSentencesSlowConsumer(t *testing.T) {
+	sim := createTestSimulator()
+
+	ch, cancel := sim.SubscribeSentences(1)
+	defer cancel()
+
+	// Nobody is reading, so outputNMEA must still return promptly
+	done := make(chan struct{})
+	go func() {
+		sim.outputNMEA()
+		sim.outputNMEA()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("outputNMEA blocked on a slow subscriber")
+	}
+
+	received := drainSentences(ch)
+	if len(received) != 1 {
+		t.Errorf("Expected exactly 1 buffered sentence, got %d", len(received))
+	}
+}
+
+func TestSubscribeSentencesCancel(t *testing.T) {
+	sim := createTestSimulator()
+
+	ch, cancel := sim.SubscribeSentences(100)
+	cancel()
+	cancel() // Cancelling twice must be safe
+
+	if _, ok := <-ch; ok {
+		t.Error("Channel should be closed after cancel")
+	}
+
+	// Emitting after cancellation must not panic
+	sim.outputNMEA()
+}
+
+func TestSubscribeSentencesDefaultBuffer(t *testing.T) {
+	sim := createTestSimulator()
+
+	ch, cancel := sim.SubscribeSentences(0)
+	defer cancel()
+
+	if cap(ch) != DefaultSubscriberBuffer {
+		t.Errorf("Expected default buffer %d, got %d", DefaultSubscriberBuffer, cap(ch))
+	}
+}
+
+func TestSubscribeSentencesClosedOnStop(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.Duration = 50 * time.Millisecond
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	ch, cancel := sim.SubscribeSentences(1000)
+	defer cancel()
+
+	go sim.Run()
+
+	var received []string
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case sentence, ok := <-ch:
+			if !ok {
+				if len(received) == 0 {
+					t.Error("Expected sentences before the channel was closed")
+				}
+				// Subscribing after stop yields a closed channel
+				late, _ := sim.SubscribeSentences(1)
+				if _, ok := <-late; ok {
+					t.Error("Subscription after stop should be closed")
+				}
+				return
+			}
+			received = append(received, sentence)
+		case <-timeout:
+			t.Fatal("Subscription channel was not closed when the simulator stopped")
+		}
+	}
+}
+
+func TestSubscribeSentencesReplay(t *testing.T) {
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, "stream_replay.gpx")
+
+	gpxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <trkseg>
+      <trkpt lat="37.774900" lon="-122.419400"><ele>50.0</ele></trkpt>
+      <trkpt lat="37.775000" lon="-122.419300"><ele>52.0</ele></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+	if err := os.WriteFile(tempFile, []byte(gpxContent), 0644); err != nil {
+		t.Fatalf("Failed to write test GPX file: %v", err)
+	}
+
+	config := createTestConfig()
+	config.OutputRate = 10 * time.Millisecond
+	config.TimeToLock = 0
+	config.ReplayFile = tempFile
+	config.ReplaySpeed = 20.0
+	config.Quiet = true
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+
+	ch, cancel := sim.SubscribeSentences(1000)
+	defer cancel()
+
+	go sim.Run()
+
+	var received []string
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case sentence, ok := <-ch:
+			if !ok {
+				found := false
+				for _, s := range received {
+					if strings.HasPrefix(s, "$GPGGA") && strings.Contains(s, ",1,") {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Error("Expected a locked GGA sentence from replay")
+				}
+				return
+			}
+			received = append(received, sentence)
+		case <-timeout:
+			t.Fatal("Subscription channel was not closed when the replay completed")
+		}
+	}
+}
+
+func TestSubscribeFilteredSentences(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.OutputRate = 200 * time.Millisecond
+	clock := newFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim.clock = clock
+	sim.startTime = clock.Now()
+
+	// A dashboard wanting positions at 1 Hz alongside a full raw stream
+	positions, cancelPositions, err := sim.SubscribeFilteredSentences(SentenceFilter{Sentences: []string{"GGA", "RMC"}, MaxRate: 1}, 100)
+	if err != nil {
+		t.Fatalf("SubscribeFilteredSentences failed: %v", err)
+	}
+	defer cancelPositions()
+	raw, cancelRaw := sim.SubscribeSentences(500)
+	defer cancelRaw()
+
+	// Two seconds of 5 Hz output
+	for i := 0; i < 10; i++ {
+		sim.output()
+		clock.Advance(sim.Config.OutputRate)
+	}
+
+	counts := make(map[string]int)
+	for _, sentence := range drainSentences(positions) {
+		counts[streamSentenceType(sentence)]++
+	}
+	if len(counts) != 2 || counts[SentenceGGA] != 2 || counts[SentenceRMC] != 2 {
+		t.Errorf("Expected 2 GGA and 2 RMC at 1 Hz, got %v", counts)
+	}
+
+	counts = make(map[string]int)
+	for _, sentence := range drainSentences(raw) {
+		counts[streamSentenceType(sentence)]++
+	}
+	if counts[SentenceGGA] != 10 || counts[SentenceGSV] < 10 || counts[SentenceGSA] != 10 {
+		t.Errorf("Expected the full stream every cycle, got %v", counts)
+	}
+}
+
+func TestSubscribeFilteredSentencesKeepsGroups(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Satellites = sim.Satellites[:0]
+	sim.Config.Satellites = 12
+	sim.initializeSatellites()
+
+	ch, cancel, err := sim.SubscribeFilteredSentences(SentenceFilter{Sentences: []string{"gsv"}, MaxRate: 1}, 100)
+	if err != nil {
+		t.Fatalf("SubscribeFilteredSentences failed: %v", err)
+	}
+	defer cancel()
+
+	sim.output()
+	received := drainSentences(ch)
+	if expected := len(sim.generateGSV()); len(received) != expected || expected < 2 {
+		t.Errorf("Expected all %d GSV parts of the cycle, got %d", expected, len(received))
+	}
+}
+
+func TestSubscribeFilteredSentencesInvalid(t *testing.T) {
+	sim := createTestSimulator()
+	for _, filter := range []SentenceFilter{
+		{Sentences: []string{"XYZ"}},
+		{MaxRate: -1},
+	} {
+		if _, _, err := sim.SubscribeFilteredSentences(filter, 10); err == nil {
+			t.Errorf("Expected an error for filter %+v", filter)
+		}
+	}
+}
+
+func TestAddCallback(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.FixQuality = FixQualityRTKFixed
+	sim.currentAlt = 123.4
+	sim.currentSpeed = 5.5
+	sim.currentCourse = 270
+	buffer := &bytes.Buffer{}
+	sim.nmeaWriter = buffer
+
+	var received []NMEAData
+	sim.AddCallback(func(data NMEAData) {
+		received = append(received, data)
+	})
+
+	sim.outputNMEA()
+
+	if len(received) != 1 {
+		t.Fatalf("Expected one callback per output cycle, got %d", len(received))
+	}
+	data := received[0]
+
+	if data.Latitude != sim.currentLat || data.Longitude != sim.currentLon || data.Altitude != 123.4 {
+		t.Errorf("Position %f, %f, %f does not match simulator", data.Latitude, data.Longitude, data.Altitude)
+	}
+	if data.Speed != 5.5 || data.Course != 270 {
+		t.Errorf("Expected speed 5.5 and course 270, got %f and %f", data.Speed, data.Course)
+	}
+	if data.Satellites != len(sim.Satellites) || data.FixQuality != FixQualityRTKFixed {
+		t.Errorf("Expected %d satellites with quality 4, got %d with %d", len(sim.Satellites), data.Satellites, data.FixQuality)
+	}
+	if data.HDOP != sim.currentDOP().HDOP {
+		t.Errorf("Expected HDOP %f, got %f", sim.currentDOP().HDOP, data.HDOP)
+	}
+	if data.Timestamp.IsZero() {
+		t.Error("Expected a timestamp")
+	}
+	if data.Sentences != buffer.String() {
+		t.Errorf("Expected the raw sentence block %q, got %q", buffer.String(), data.Sentences)
+	}
+
+	// Each cycle carries only its own sentences
+	buffer.Reset()
+	sim.isLocked = false
+	sim.lockTime = time.Now().Add(time.Minute)
+	sim.outputNMEA()
+	if len(received) != 2 {
+		t.Fatalf("Expected a second callback, got %d", len(received))
+	}
+	if received[1].Sentences != buffer.String() || received[1].FixQuality != 0 || received[1].HDOP != 0 {
+		t.Errorf("Expected a no-fix cycle with only its own sentences, got %+v", received[1])
+	}
+}
+
+func TestNMEADataJSON(t *testing.T) {
+	data, err := json.Marshal(NMEAData{})
+	if err != nil {
+		t.Fatalf("Failed to marshal NMEAData: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal NMEAData: %v", err)
+	}
+	for _, key := range []string{"timestamp", "latitude", "longitude", "altitude", "speed", "course", "satellites", "fixQuality", "hdop", "sentences", "fix"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("Expected JSON field %q in %s", key, data)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gps
+
+import (
+	"fmt"
+	"os"
+)
+
+// serialBitsPerByte is the number of bits sent per byte on a serial line
+// with 8N1 framing (start bit, 8 data bits, stop bit)
+const serialBitsPerByte = 10
+
+// throughputCheck tracks the average burst size while locked, to warn when
+// the serial port cannot keep up with the output rate
+type throughputCheck struct {
+	bursts int
+	bytes  int
+	warned bool
+}
+
+// checkThroughput records the bytes emitted in the output cycle just
+// finished and warns once on stderr when the average locked burst needs more
+// bits per second at OutputRate than the serial BaudRate carries, as output
+// then falls behind and arrives in bursts
+func (s *GPSSimulator) checkThroughput() {
+	burst := s.burstBytes
+	s.burstBytes = 0
+
+	if s.Config.SerialPort == "" || s.Config.BaudRate <= 0 || s.Config.OutputRate <= 0 || !s.isLocked || s.throughput.warned {
+		return
+	}
+	s.throughput.bursts++
+	s.throughput.bytes += burst
+
+	average := float64(s.throughput.bytes) / float64(s.throughput.bursts)
+	required := average * serialBitsPerByte / s.Config.OutputRate.Seconds()
+	if required > float64(s.Config.BaudRate) {
+		s.throughput.warned = true
+		if !s.Config.Quiet {
+			fmt.Fprintf(os.Stderr, "Warning: output at %v needs about %.0f baud for %.0f-byte bursts, more than %d baud on %s; output will fall behind. Lower -rate, emit fewer sentences or raise -baud\n",
+				s.Config.OutputRate, required, average, s.Config.BaudRate, s.Config.SerialPort)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gps
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHighRateBurstCount(t *testing.T) {
+	config := createTestConfig()
+	config.OutputRate = 100 * time.Millisecond
+	config.TimeToLock = 0
+	config.Duration = time.Second
+	config.Quiet = true
+	buffer := &bytes.Buffer{}
+
+	sim, err := NewGPSSimulator(config, buffer)
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	runUntilComplete(t, sim, nil)
+
+	// Every burst starts with GGA
+	bursts := strings.Count(buffer.String(), "$GPGGA")
+	if bursts < 9 || bursts > 11 {
+		t.Errorf("Expected 10±1 bursts at 10 Hz over a second, got %d", bursts)
+	}
+}
+
+func TestThroughputWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		baudRate int
+		rate     time.Duration
+		warn     bool
+	}{
+		{"1 Hz at 4800 baud", 4800, time.Second, false},
+		{"10 Hz at 4800 baud", 4800, 100 * time.Millisecond, true},
+		{"10 Hz at 115200 baud", 115200, 100 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := createTestSimulator()
+			sim.Config.SerialPort = "/dev/ttyTEST"
+			sim.Config.BaudRate = tt.baudRate
+			sim.Config.OutputRate = tt.rate
+			sim.updateDOP()
+
+			oldStderr := os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stderr = w
+
+			for i := 0; i < 3; i++ {
+				sim.outputNMEA()
+				sim.checkThroughput()
+			}
+
+			w.Close()
+			os.Stderr = oldStderr
+			captured := make([]byte, 1000)
+			n, _ := r.Read(captured)
+			output := string(captured[:n])
+
+			if sim.throughput.warned != tt.warn {
+				t.Errorf("Expected warned to be %v, got %v", tt.warn, sim.throughput.warned)
+			}
+			if warnings := strings.Count(output, "Warning: output at"); tt.warn && warnings != 1 {
+				t.Errorf("Expected exactly one warning, got %d in %q", warnings, output)
+			} else if !tt.warn && warnings != 0 {
+				t.Errorf("Expected no warning, got %q", output)
+			}
+			if sim.burstBytes != 0 {
+				t.Errorf("Expected burst bytes to be reset, got %d", sim.burstBytes)
+			}
+		})
+	}
+}
+
+func TestThroughputIgnoredWithoutSerialPort(t *testing.T) {
+	sim := createTestSimulator()
+	sim.Config.BaudRate = 300
+	sim.Config.OutputRate = 100 * time.Millisecond
+	sim.updateDOP()
+
+	sim.outputNMEA()
+	sim.checkThroughput()
+
+	if sim.throughput.warned || sim.throughput.bursts != 0 {
+		t.Error("Expected no throughput check without a serial port")
+	}
+}
@@ -0,0 +1,93 @@
+package gps
+
+import (
+	"fmt"
+	"os"
+)
+
+// TrackSink records the simulated track to a file. The GPX, GeoJSON, KML and
+// CSV writers are track sinks, each fed the fix of every locked output cycle;
+// the CSV log also records the cycles before lock and during dropouts.
+type TrackSink interface {
+	// AddFix adds a fix to the track
+	AddFix(fix Fix)
+	// GetTrackPointCount returns the number of fixes added so far
+	GetTrackPointCount() int
+	// WriteToFile writes the track so far to the file
+	WriteToFile() error
+	// Close writes the remaining track and closes the file
+	Close() error
+}
+
+// trackOutput is an enabled track sink with its format and file name for
+// messages
+type trackOutput struct {
+	format   string
+	filename string
+	sink     TrackSink
+	unlocked bool // Also record cycles without a fix
+}
+
+// trackOutputs returns the enabled track sinks
+func (s *GPSSimulator) trackOutputs() []trackOutput {
+	var outputs []trackOutput
+	if s.gpxWriter != nil {
+		outputs = append(outputs, trackOutput{"GPX", s.Config.GPXFile, s.gpxWriter, false})
+	}
+	if s.geoJSONWriter != nil {
+		outputs = append(outputs, trackOutput{"GeoJSON", s.Config.GeoJSONFile, s.geoJSONWriter, false})
+	}
+	if s.kmlWriter != nil {
+		outputs = append(outputs, trackOutput{"KML", s.Config.KMLFile, s.kmlWriter, false})
+	}
+	if s.csvWriter != nil {
+		outputs = append(outputs, trackOutput{"CSV", s.Config.CSVFile, s.csvWriter, true})
+	}
+	return outputs
+}
+
+// updateTracks adds the current position to every enabled track sink,
+// skipping those that only record locked positions while GPS is not locked
+func (s *GPSSimulator) updateTracks() {
+	outputs := s.trackOutputs()
+	if len(outputs) == 0 {
+		return
+	}
+	fix := s.currentFix(s.fixTime(s.now()))
+	for _, output := range outputs {
+		if !s.isLocked && !output.unlocked {
+			continue
+		}
+		output.sink.AddFix(fix)
+
+		// Write to file periodically to avoid losing data if program is interrupted
+		// Write every 10 points to balance between performance and data safety
+		if output.sink.GetTrackPointCount()%10 == 0 {
+			if err := output.sink.WriteToFile(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s data: %v\n", output.format, err)
+			}
+		}
+	}
+}
+
+// closeTracks writes the final data of every enabled track sink and closes
+// its file
+func (s *GPSSimulator) closeTracks() {
+	for _, output := range s.trackOutputs() {
+		if !s.Config.Quiet {
+			fmt.Fprintf(os.Stderr, "Writing %s file: %s with %d track points\n",
+				output.format, output.filename, output.sink.GetTrackPointCount())
+		}
+		if err := output.sink.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing %s file: %v\n", output.format, err)
+		}
+	}
+}
+
+// abortTracks closes every enabled track sink without reporting, when the
+// simulator fails to start
+func (s *GPSSimulator) abortTracks() {
+	for _, output := range s.trackOutputs() {
+		output.sink.Close()
+	}
+}
@@ -0,0 +1,33 @@
+package gps
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// createTrackSimulator returns a simulator locked from the start, with the
+// track outputs set by configure, ticked for the given number of one second
+// output cycles
+func createTrackSimulator(t *testing.T, configure func(*Config), ticks int) *GPSSimulator {
+	t.Helper()
+	config := createTestConfig()
+	config.TimeToLock = 0
+	config.Quiet = true
+	if configure != nil {
+		configure(&config)
+	}
+
+	sim, err := NewGPSSimulator(config, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Failed to create GPS simulator: %v", err)
+	}
+	clock := newFakeClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	sim.SetClock(clock)
+
+	for i := 0; i < ticks; i++ {
+		clock.Advance(time.Second)
+		sim.tick()
+	}
+	return sim
+}
@@ -0,0 +1,62 @@
+package gps
+
+import "math"
+
+// positionTransition drives the receiver toward a new simulation center after
+// UpdateConfig moves it, so the position does not jump
+type positionTransition struct {
+	target Coordinate
+	speed  float64 // Meters per second
+}
+
+// startTransition begins driving the receiver from its current position to
+// target, at Config.Speed or fast enough to arrive within
+// Config.TransitionDuration. Without either the receiver is moved directly.
+func (s *GPSSimulator) startTransition(target Coordinate) {
+	distance := s.calculateDistance(s.currentLat, s.currentLon, target.Lat, target.Lon)
+
+	// Convert speed from knots to meters per second
+	speed := s.Config.Speed * 0.514444
+	if s.Config.TransitionDuration > 0 {
+		speed = distance / s.Config.TransitionDuration.Seconds()
+	}
+
+	if speed <= 0 || distance <= routeArrivalRadius {
+		s.currentLat = target.Lat
+		s.currentLon = target.Lon
+		s.transition = nil
+		return
+	}
+	s.transition = &positionTransition{target: target, speed: speed}
+}
+
+// updateTransition moves the receiver along the great circle toward the
+// transition target, heading straight for it without jitter, and ends the
+// transition on arrival
+func (s *GPSSimulator) updateTransition() {
+	now := s.now()
+	deltaTime := now.Sub(s.lastUpdateTime).Seconds()
+	s.lastUpdateTime = now
+
+	t := s.transition
+	s.currentCourse = s.calculateBearing(s.currentLat, s.currentLon, t.target.Lat, t.target.Lon)
+	s.currentSpeed = t.speed / 0.514444
+
+	remaining := s.calculateDistance(s.currentLat, s.currentLon, t.target.Lat, t.target.Lon)
+	step := t.speed * math.Max(deltaTime, 0)
+	if step >= remaining {
+		s.currentLat = t.target.Lat
+		s.currentLon = t.target.Lon
+		s.transition = nil
+		return
+	}
+	s.currentLat, s.currentLon = s.calculateDestination(s.currentLat, s.currentLon, s.currentCourse, step)
+}
+
+// IsTransitioning reports whether the receiver is still moving toward a new
+// center set with UpdateConfig
+func (s *GPSSimulator) IsTransitioning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.transition != nil
+}